@@ -5,6 +5,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"log"
 	"os"
@@ -22,10 +23,21 @@ import (
 func main() {
 	addr := flag.String("addr", ":8080", "listen address")
 	nodeID := flag.String("node-id", "local-dev-node", "local agent node ID")
+	snapshotFile := flag.String("snapshot-file", "", "persist the in-memory store to this file (empty disables)")
+	snapshotInterval := flag.Duration("snapshot-interval", 30*time.Second, "how often to write the store snapshot")
+	micRenewWindow := flag.Duration("mic-renew-window", 7*24*time.Hour, "renew MICs expiring within this window")
+	routeSweepInterval := flag.Duration("route-sweep-interval", time.Minute, "how often to delete routes past their TTL")
 	flag.Parse()
 
 	// --- State store (always in-memory for all-in-one) ---
 	s := store.NewMemoryStore()
+	if *snapshotFile != "" {
+		if err := s.LoadSnapshotFile(*snapshotFile); err == nil {
+			log.Printf("store restored from snapshot %s", *snapshotFile)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			log.Fatalf("load snapshot: %v", err)
+		}
+	}
 
 	// --- CA ---
 	ks := ca.NewMemoryKeyStore()
@@ -44,12 +56,40 @@ func main() {
 
 	// --- Fleet controller ---
 	fc := controller.NewFleetController(s)
+	srv.SetEventSource(fc)
+	srv.SetNodeTransitioner(fc)
 	go fc.Start(ctx)
 
+	// --- MIC renewal ---
+	mr := controller.NewMICRenewer(s, authority, *micRenewWindow)
+	go mr.Start(ctx)
+
+	// --- Route TTL expiry ---
+	rj := controller.NewRouteJanitor(s, *routeSweepInterval)
+	go rj.Start(ctx)
+
 	// --- Reconciler ---
 	rc := controller.NewReconciler(s, "")
 	go rc.Start(ctx)
 
+	// --- Periodic snapshots ---
+	if *snapshotFile != "" && *snapshotInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(*snapshotInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if err := s.SaveSnapshotFile(*snapshotFile); err != nil {
+						log.Printf("snapshot: %v", err)
+					}
+				}
+			}
+		}()
+	}
+
 	// --- Local node agent ---
 	serverURL := "http://127.0.0.1" + *addr
 	ag := agent.NewNodeAgent(*nodeID, serverURL)
@@ -61,9 +101,8 @@ func main() {
 		}
 	}()
 
-	// Give the server a moment to start, then register the local agent.
-	time.Sleep(200 * time.Millisecond)
-	if err := ag.Register("127.0.0.1"); err != nil {
+	// Register the local agent, retrying until the server is listening.
+	if err := ag.RegisterWithRetry(ctx, "127.0.0.1"); err != nil {
 		log.Printf("agent register (non-fatal): %v", err)
 	}
 
@@ -81,4 +120,11 @@ func main() {
 	if err := srv.GracefulShutdown(shutCtx); err != nil {
 		log.Printf("graceful shutdown error: %v", err)
 	}
+	if *snapshotFile != "" {
+		if err := s.SaveSnapshotFile(*snapshotFile); err != nil {
+			log.Printf("final snapshot: %v", err)
+		} else {
+			log.Printf("store snapshot written to %s", *snapshotFile)
+		}
+	}
 }
@@ -11,6 +11,8 @@ import (
 	"syscall"
 	"time"
 
+	clientv3 "go.etcd.io/etcd/client/v3"
+
 	"github.com/strand-protocol/strand/strand-cloud/pkg/apiserver"
 	"github.com/strand-protocol/strand/strand-cloud/pkg/ca"
 	"github.com/strand-protocol/strand/strand-cloud/pkg/controller"
@@ -20,6 +22,9 @@ import (
 func main() {
 	addr := flag.String("addr", ":8080", "listen address")
 	storeType := flag.String("store-type", "memory", "state store backend: memory or etcd")
+	micRenewWindow := flag.Duration("mic-renew-window", 7*24*time.Hour, "renew MICs expiring within this window")
+	routeSweepInterval := flag.Duration("route-sweep-interval", time.Minute, "how often to delete routes past their TTL")
+	migrate := flag.Bool("migrate", false, "upgrade every etcd record to the current schema version, then exit")
 	flag.Parse()
 
 	// --- State store ---
@@ -28,6 +33,10 @@ func main() {
 	// environment variable (takes precedence over the flag). For etcd, set
 	// STRAND_ETCD_ENDPOINTS to a comma-separated list of endpoints, e.g.:
 	//   STRAND_STORE_TYPE=etcd STRAND_ETCD_ENDPOINTS=http://localhost:2379
+	// and STRAND_ETCD_PREFIX to keep this deployment's keys apart from others
+	// sharing the cluster (default /strand/v1). A secured cluster takes
+	// STRAND_ETCD_CA_FILE, STRAND_ETCD_CERT_FILE and STRAND_ETCD_KEY_FILE for
+	// TLS, and STRAND_ETCD_USERNAME and STRAND_ETCD_PASSWORD for auth.
 	if envType := os.Getenv("STRAND_STORE_TYPE"); envType != "" {
 		*storeType = envType
 	}
@@ -41,15 +50,35 @@ func main() {
 		if envEndpoints := os.Getenv("STRAND_ETCD_ENDPOINTS"); envEndpoints != "" {
 			endpoints = strings.Split(envEndpoints, ",")
 		}
-		etcdStore, err := storepkg.NewEtcdStore(endpoints)
+		var etcdOpts []storepkg.EtcdOption
+		if envPrefix := os.Getenv("STRAND_ETCD_PREFIX"); envPrefix != "" {
+			etcdOpts = append(etcdOpts, storepkg.WithKeyPrefix(envPrefix))
+		}
+		etcdConfig, err := etcdClientConfig(endpoints)
+		if err != nil {
+			log.Fatalf("configure etcd client: %v", err)
+		}
+		etcdStore, err := storepkg.NewEtcdStoreWithConfig(etcdConfig, etcdOpts...)
 		if err != nil {
 			log.Fatalf("connect to etcd %v: %v", endpoints, err)
 		}
 		log.Printf("connected to etcd at %v", endpoints)
+		if *migrate {
+			n, err := etcdStore.MigrateRecords(context.Background())
+			if err != nil {
+				log.Fatalf("migrate store: %v", err)
+			}
+			log.Printf("migrated %d records to schema version %d", n, storepkg.SchemaVersion())
+			etcdStore.Close()
+			return
+		}
 		s = etcdStore
 	default:
 		log.Fatalf("unsupported store type: %s (supported: memory, etcd)", *storeType)
 	}
+	if *migrate {
+		log.Fatalf("-migrate requires the etcd store")
+	}
 
 	// --- CA ---
 	ks := ca.NewMemoryKeyStore()
@@ -65,10 +94,20 @@ func main() {
 
 	// --- Fleet controller ---
 	fc := controller.NewFleetController(s)
+	srv.SetEventSource(fc)
+	srv.SetNodeTransitioner(fc)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go fc.Start(ctx)
 
+	// --- MIC renewal ---
+	mr := controller.NewMICRenewer(s, authority, *micRenewWindow)
+	go mr.Start(ctx)
+
+	// --- Route TTL expiry ---
+	rj := controller.NewRouteJanitor(s, *routeSweepInterval)
+	go rj.Start(ctx)
+
 	// --- Reconciler (no desired version set by default) ---
 	rc := controller.NewReconciler(s, "")
 	go rc.Start(ctx)
@@ -92,3 +131,23 @@ func main() {
 		log.Fatalf("server error: %v", err)
 	}
 }
+
+// etcdClientConfig builds the etcd client configuration for endpoints from
+// the STRAND_ETCD_* TLS and auth environment variables. TLS is used when any
+// of the certificate files is set.
+func etcdClientConfig(endpoints []string) (clientv3.Config, error) {
+	cfg := clientv3.Config{
+		Endpoints: endpoints,
+		Username:  os.Getenv("STRAND_ETCD_USERNAME"),
+		Password:  os.Getenv("STRAND_ETCD_PASSWORD"),
+	}
+	caFile, certFile, keyFile := os.Getenv("STRAND_ETCD_CA_FILE"), os.Getenv("STRAND_ETCD_CERT_FILE"), os.Getenv("STRAND_ETCD_KEY_FILE")
+	if caFile != "" || certFile != "" || keyFile != "" {
+		tlsConfig, err := storepkg.LoadEtcdTLS(caFile, certFile, keyFile)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.TLS = tlsConfig
+	}
+	return cfg, nil
+}
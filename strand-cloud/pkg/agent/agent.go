@@ -4,22 +4,67 @@ package agent
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
 )
 
+// Default bounds of the exponential backoff between registration attempts.
+const (
+	DefaultRetryMin = 500 * time.Millisecond
+	DefaultRetryMax = 30 * time.Second
+)
+
 // NodeAgent runs on every Strand node. It registers with the control plane,
 // sends periodic heartbeats, and reports metrics.
 type NodeAgent struct {
 	NodeID    string
 	ServerURL string
 	Client    *http.Client
+	// RetryMin and RetryMax bound the exponential backoff used by
+	// RegisterWithRetry: the first retry waits about RetryMin and each later
+	// one doubles, up to RetryMax. Zero values use DefaultRetryMin and
+	// DefaultRetryMax.
+	RetryMin time.Duration
+	RetryMax time.Duration
+
+	mu     sync.Mutex
+	config Config
+	// configChanged wakes StartHeartbeatLoop after UpdateConfig.
+	configChanged chan struct{}
+	// address is the last address passed to Register, reused when the
+	// heartbeat loop re-registers.
+	address string
+}
+
+// statusError is a non-success HTTP response from the control plane.
+type statusError struct {
+	op   string
+	code int
+	body string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s: unexpected status %d: %s", e.op, e.code, e.body)
+}
+
+// retryable reports whether a request that failed with err may succeed if
+// repeated: transport errors, 5xx and 429 responses are, other 4xx are not.
+func retryable(err error) bool {
+	var se *statusError
+	if !errors.As(err, &se) {
+		return true
+	}
+	return se.code >= 500 || se.code == http.StatusTooManyRequests
 }
 
 // NewNodeAgent creates a NodeAgent targeting the given control plane URL.
@@ -30,48 +75,128 @@ func NewNodeAgent(nodeID, serverURL string) *NodeAgent {
 		Client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		configChanged: make(chan struct{}, 1),
+	}
+}
+
+// NewNodeAgentWithConfig is NewNodeAgent with an initial Config.
+func NewNodeAgentWithConfig(nodeID, serverURL string, cfg Config) *NodeAgent {
+	a := NewNodeAgent(nodeID, serverURL)
+	a.config = cfg.clone()
+	return a
+}
+
+// do sends a request to the control plane path, authenticated with the
+// configured API key.
+func (a *NodeAgent) do(method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, a.ServerURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil && body != http.NoBody {
+		req.Header.Set("Content-Type", "application/json")
 	}
+	if key := a.Config().APIKey; key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	return a.Client.Do(req)
 }
 
 // Register registers this node with the control plane by POSTing to
 // /api/v1/nodes. If the node already exists the error is non-fatal.
 func (a *NodeAgent) Register(address string) error {
+	a.mu.Lock()
+	a.address = address
+	cfg := a.config.clone()
+	a.mu.Unlock()
 	node := model.Node{
 		ID:      a.NodeID,
 		Address: address,
 		Status:  "online",
+		Labels:  cfg.Labels,
+		Region:  cfg.Region,
 	}
 	body, err := json.Marshal(node)
 	if err != nil {
 		return fmt.Errorf("marshal node: %w", err)
 	}
-	resp, err := a.Client.Post(a.ServerURL+"/api/v1/nodes", "application/json", bytes.NewReader(body))
+	resp, err := a.do(http.MethodPost, "/api/v1/nodes", bytes.NewReader(body))
 	if err != nil {
 		return fmt.Errorf("register node: %w", err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusConflict {
 		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("register node: unexpected status %d: %s", resp.StatusCode, string(b))
+		return &statusError{op: "register node", code: resp.StatusCode, body: string(b)}
 	}
 	log.Printf("agent: node %s registered", a.NodeID)
 	return nil
 }
 
-// Heartbeat sends a single heartbeat to the control plane.
+// RegisterWithRetry calls Register until it succeeds, waiting with
+// exponential backoff and jitter between attempts, so an agent started before
+// the control plane, or during an outage, registers once it is reachable.
+// It gives up early on a response that cannot succeed on retry, such as 400
+// or 401, and returns when ctx is done.
+func (a *NodeAgent) RegisterWithRetry(ctx context.Context, address string) error {
+	delay, maxDelay := a.RetryMin, a.RetryMax
+	if delay <= 0 {
+		delay = DefaultRetryMin
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultRetryMax
+	}
+	for attempt := 1; ; attempt++ {
+		err := a.Register(address)
+		if err == nil {
+			return nil
+		}
+		if !retryable(err) {
+			return err
+		}
+		// Wait between half and all of the current delay so a fleet of
+		// agents does not retry in lockstep.
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		log.Printf("agent: register attempt %d failed, retrying in %s: %v", attempt, wait, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("register node: %w (last error: %v)", ctx.Err(), err)
+		case <-timer.C:
+		}
+		delay = min(delay*2, maxDelay)
+	}
+}
+
+// reconnect re-registers the node after a failed heartbeat, in case the
+// control plane restarted and lost it. It does nothing if Register was never
+// called, since the address to register is unknown.
+func (a *NodeAgent) reconnect(ctx context.Context) error {
+	a.mu.Lock()
+	address := a.address
+	a.mu.Unlock()
+	if address == "" {
+		return nil
+	}
+	return a.RegisterWithRetry(ctx, address)
+}
+
+// Heartbeat sends a single heartbeat to the control plane, carrying the
+// configured labels and region.
 func (a *NodeAgent) Heartbeat() error {
-	return sendHeartbeat(a.Client, a.ServerURL, a.NodeID, nil)
+	return a.sendHeartbeat(nil)
 }
 
 // ReportMetrics sends node metrics as part of the heartbeat.
 func (a *NodeAgent) ReportMetrics(m model.NodeMetrics) error {
-	return sendHeartbeat(a.Client, a.ServerURL, a.NodeID, &m)
+	return a.sendHeartbeat(&m)
 }
 
 // PollCommands is a placeholder for command polling. In a full implementation
 // this would GET /api/v1/nodes/{id}/commands.
 func (a *NodeAgent) PollCommands() ([]string, error) {
-	resp, err := a.Client.Get(a.ServerURL + "/api/v1/nodes/" + a.NodeID)
+	resp, err := a.do(http.MethodGet, "/api/v1/nodes/"+a.NodeID, nil)
 	if err != nil {
 		return nil, fmt.Errorf("poll commands: %w", err)
 	}
@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"maps"
+	"time"
+)
+
+// Config is the reloadable part of a NodeAgent's configuration. Change it with
+// UpdateConfig while the agent runs.
+type Config struct {
+	// Labels and Region describe the node to the control plane. They are sent
+	// at registration and with every heartbeat.
+	Labels map[string]string
+	Region string
+	// HeartbeatInterval, when non-zero, overrides the interval passed to
+	// StartHeartbeatLoop.
+	HeartbeatInterval time.Duration
+	// APIKey, when set, is sent as a Bearer token with every request, for
+	// control planes that have API keys configured.
+	APIKey string
+}
+
+func (c Config) clone() Config {
+	c.Labels = maps.Clone(c.Labels)
+	return c
+}
+
+// Config returns a copy of the agent's current configuration.
+func (a *NodeAgent) Config() Config {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.config.clone()
+}
+
+// UpdateConfig replaces the agent's configuration without reconnecting. The
+// next heartbeat carries the new labels and region, every later request uses
+// the new API key, and a running StartHeartbeatLoop switches to a changed
+// HeartbeatInterval straight away. Labels removed entirely are not cleared on
+// the control plane, since an empty label set is not sent.
+func (a *NodeAgent) UpdateConfig(cfg Config) {
+	a.mu.Lock()
+	a.config = cfg.clone()
+	a.mu.Unlock()
+	select {
+	case a.configChanged <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
+)
+
+// FlashFirmware downloads img from img.URL, checks it with img.Verify and
+// passes the verified content to flash, which writes it to the node. The
+// download is staged in a temporary file so flash never sees a byte of an
+// image whose checksum does not match.
+func (a *NodeAgent) FlashFirmware(img *model.FirmwareImage, flash func(io.Reader) error) error {
+	resp, err := a.Client.Get(img.URL)
+	if err != nil {
+		return fmt.Errorf("download firmware %s: %w", img.ID, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &statusError{op: "download firmware " + img.ID, code: resp.StatusCode, body: string(b)}
+	}
+
+	staged, err := os.CreateTemp("", "strand-firmware-*")
+	if err != nil {
+		return fmt.Errorf("stage firmware %s: %w", img.ID, err)
+	}
+	defer os.Remove(staged.Name())
+	defer staged.Close()
+
+	if err := img.Verify(io.TeeReader(resp.Body, staged)); err != nil {
+		return err
+	}
+	if _, err := staged.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("stage firmware %s: %w", img.ID, err)
+	}
+	if err := flash(staged); err != nil {
+		return fmt.Errorf("flash firmware %s: %w", img.ID, err)
+	}
+	log.Printf("agent: node %s flashed firmware %s (%s)", a.NodeID, img.ID, img.Version)
+	return nil
+}
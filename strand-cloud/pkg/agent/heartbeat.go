@@ -13,21 +13,23 @@ import (
 	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
 )
 
-// sendHeartbeat POSTs to /api/v1/nodes/{id}/heartbeat, optionally including
-// metrics in the request body.
-func sendHeartbeat(client *http.Client, serverURL, nodeID string, metrics *model.NodeMetrics) error {
-	url := serverURL + "/api/v1/nodes/" + nodeID + "/heartbeat"
-	var body io.Reader
-	if metrics != nil {
-		b, err := json.Marshal(metrics)
+// sendHeartbeat POSTs to /api/v1/nodes/{id}/heartbeat, including metrics and
+// the configured labels and region in the request body when there are any.
+func (a *NodeAgent) sendHeartbeat(metrics *model.NodeMetrics) error {
+	cfg := a.Config()
+	var body io.Reader = http.NoBody
+	if metrics != nil || len(cfg.Labels) > 0 || cfg.Region != "" {
+		hb := model.NodeHeartbeat{Labels: cfg.Labels, Region: cfg.Region}
+		if metrics != nil {
+			hb.NodeMetrics = *metrics
+		}
+		b, err := json.Marshal(hb)
 		if err != nil {
-			return fmt.Errorf("marshal metrics: %w", err)
+			return fmt.Errorf("marshal heartbeat: %w", err)
 		}
 		body = bytes.NewReader(b)
-	} else {
-		body = http.NoBody
 	}
-	resp, err := client.Post(url, "application/json", body)
+	resp, err := a.do(http.MethodPost, "/api/v1/nodes/"+a.NodeID+"/heartbeat", body)
 	if err != nil {
 		return fmt.Errorf("heartbeat: %w", err)
 	}
@@ -39,9 +41,14 @@ func sendHeartbeat(client *http.Client, serverURL, nodeID string, metrics *model
 	return nil
 }
 
-// StartHeartbeatLoop runs periodic heartbeats at the given interval until ctx
-// is cancelled.
+// StartHeartbeatLoop runs periodic heartbeats at the given interval, or at the
+// agent's Config.HeartbeatInterval when set, until ctx is cancelled. After a failed heartbeat the agent re-registers with
+// RegisterWithRetry, so a control plane that was unreachable or restarted
+// without the node is recovered without restarting the agent.
 func StartHeartbeatLoop(ctx context.Context, agent *NodeAgent, interval time.Duration) {
+	if d := agent.Config().HeartbeatInterval; d > 0 {
+		interval = d
+	}
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	log.Printf("agent: heartbeat loop started (every %s)", interval)
@@ -50,9 +57,18 @@ func StartHeartbeatLoop(ctx context.Context, agent *NodeAgent, interval time.Dur
 		case <-ctx.Done():
 			log.Println("agent: heartbeat loop stopped")
 			return
+		case <-agent.configChanged:
+			if d := agent.Config().HeartbeatInterval; d > 0 && d != interval {
+				interval = d
+				ticker.Reset(interval)
+				log.Printf("agent: heartbeat interval changed to %s", interval)
+			}
 		case <-ticker.C:
 			if err := agent.Heartbeat(); err != nil {
 				log.Printf("agent: heartbeat error: %v", err)
+				if err := agent.reconnect(ctx); err != nil && ctx.Err() == nil {
+					log.Printf("agent: re-register error: %v", err)
+				}
 			}
 		}
 	}
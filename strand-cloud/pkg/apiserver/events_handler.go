@@ -0,0 +1,85 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/strand-protocol/strand/strand-cloud/pkg/controller"
+)
+
+// EventSource supplies fleet events to the /api/v1/events stream.
+// *controller.FleetController implements it.
+type EventSource interface {
+	Subscribe() (<-chan controller.Event, func())
+}
+
+// sseHeartbeatInterval is how often an idle event stream sends a comment
+// line so proxies and clients do not time the connection out.
+const sseHeartbeatInterval = 15 * time.Second
+
+// SetEventSource connects the server to the source streamed by
+// GET /api/v1/events. Until it is called the endpoint returns 503.
+func (s *Server) SetEventSource(src EventSource) {
+	s.eventsMu.Lock()
+	s.events = src
+	s.eventsMu.Unlock()
+}
+
+// handleEvents streams fleet events as Server-Sent Events. Each event is a
+// single "data:" line holding its JSON encoding.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	s.metrics.IncRequest()
+	s.eventsMu.RLock()
+	src := s.events
+	s.eventsMu.RUnlock()
+	if src == nil {
+		writeError(w, http.StatusServiceUnavailable, "event stream not available")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// The stream outlives the server's WriteTimeout; lift the deadline for
+	// this response only. Writers that cannot do so still stream until the
+	// timeout fires.
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	events, cancel := src.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case evt, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(evt)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
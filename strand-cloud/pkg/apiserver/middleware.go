@@ -7,25 +7,52 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
+
+	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
 )
 
 // contextKey is an unexported type for context keys in this package.
 type contextKey int
 
-const roleContextKey contextKey = 1
+const (
+	roleContextKey contextKey = 1
+	// actorContextKey holds the matched APIKeyInfo.Description.
+	actorContextKey contextKey = 2
+	// tenantContextKey holds the matched APIKeyInfo.TenantID.
+	tenantContextKey contextKey = 3
+	// ipLimitContextKey holds the ipLimit charged by ipRateLimitMiddleware.
+	ipLimitContextKey contextKey = 4
+)
 
 const (
 	// maxRequestBodyBytes limits request body size to 1 MiB to prevent DoS.
 	maxRequestBodyBytes = 1 << 20 // 1 MiB
+
+	// deniedAuditPerMinute and deniedAuditBurst bound how many denied
+	// requests from one client IP are written to the audit log. The rest are
+	// counted and reported on that IP's next entry.
+	deniedAuditPerMinute = 30
+	deniedAuditBurst     = 10
+	// maxDeniedSources caps how many client IPs have suppressed denials
+	// awaiting report; beyond it further suppressed denials go uncounted.
+	maxDeniedSources = 4096
+	// maxAuditPathLen caps the request path stored in a denied entry.
+	maxAuditPathLen = 256
 )
 
 // applyMiddleware wraps the given handler with the standard middleware chain.
-// Order (outermost to innermost): recovery -> auth -> rbac -> rateLimiter -> requestBodyLimit -> cors -> securityHeaders -> logging -> requestID
+// Order (outermost to innermost): recovery -> ipRateLimiter -> auth -> rbac -> rateLimiter -> requestBodyLimit -> cors -> securityHeaders -> logging -> requestID
+//
+// The per-IP limiter sits outside auth so unauthenticated traffic is limited
+// too; the global and per-tenant limiters need the authenticated tenant.
 func (s *Server) applyMiddleware(h http.Handler) http.Handler {
 	h = requestIDMiddleware(h)
 	h = loggingMiddleware(h)
@@ -35,6 +62,7 @@ func (s *Server) applyMiddleware(h http.Handler) http.Handler {
 	h = s.rateLimitMiddleware(h)
 	h = s.rbacMiddleware(h)
 	h = s.apiKeyMiddleware(h)
+	h = s.ipRateLimitMiddleware(h)
 	h = recoveryMiddleware(h)
 	return h
 }
@@ -58,6 +86,7 @@ func (s *Server) apiKeyMiddleware(next http.Handler) http.Handler {
 		authHeader := r.Header.Get("Authorization")
 		token := strings.TrimPrefix(authHeader, "Bearer ")
 		if token == authHeader || token == "" {
+			s.auditDenied(r, http.StatusUnauthorized, "authenticate", "missing bearer token")
 			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
 			return
 		}
@@ -73,10 +102,15 @@ func (s *Server) apiKeyMiddleware(next http.Handler) http.Handler {
 			}
 		}
 		if !found {
+			s.auditDenied(r, http.StatusUnauthorized, "authenticate", "invalid api key")
 			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
 			return
 		}
 		ctx := context.WithValue(r.Context(), roleContextKey, matchedInfo.Role)
+		ctx = context.WithValue(ctx, actorContextKey, matchedInfo.Description)
+		if matchedInfo.TenantID != "" {
+			ctx = context.WithValue(ctx, tenantContextKey, matchedInfo.TenantID)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -86,6 +120,9 @@ func (s *Server) apiKeyMiddleware(next http.Handler) http.Handler {
 //   - RoleOperator: GET, POST, PUT
 //   - RoleAdmin:    all methods (GET, POST, PUT, DELETE, OPTIONS)
 //
+// Reading the audit log requires RoleAdmin. Rejected requests are recorded
+// in the audit log with a denied outcome.
+//
 // The role is read from the context value set by apiKeyMiddleware.
 func (s *Server) rbacMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -100,29 +137,98 @@ func (s *Server) rbacMiddleware(next http.Handler) http.Handler {
 			return
 		}
 		role, _ := r.Context().Value(roleContextKey).(Role)
+		required := RoleViewer
 		switch r.Method {
 		case http.MethodGet:
-			// All roles may read.
-		case http.MethodPost, http.MethodPut:
-			if role < RoleOperator {
-				http.Error(w, `{"error":"forbidden"}`, http.StatusForbidden)
-				return
-			}
-		case http.MethodDelete:
-			if role < RoleAdmin {
-				http.Error(w, `{"error":"forbidden"}`, http.StatusForbidden)
-				return
+			// All roles may read, except the audit log.
+			if strings.HasPrefix(r.URL.Path, "/api/v1/audit") {
+				required = RoleAdmin
 			}
+		case http.MethodPost, http.MethodPut:
+			required = RoleOperator
 		default:
-			if role < RoleAdmin {
-				http.Error(w, `{"error":"forbidden"}`, http.StatusForbidden)
-				return
-			}
+			required = RoleAdmin
+		}
+		if role < required {
+			s.auditDenied(r, http.StatusForbidden, "authorize", fmt.Sprintf("%s role cannot %s (requires %s)", role, r.Method, required))
+			http.Error(w, `{"error":"forbidden"}`, http.StatusForbidden)
+			return
 		}
 		next.ServeHTTP(w, r)
 	})
 }
 
+// auditDenied records a rejected request in the audit log. It captures the
+// method, path (truncated to maxAuditPathLen) and client IP, and for
+// authorization failures the API key's description, but never the presented
+// token. Entries are rate limited per client IP so a flood of bad requests
+// cannot fill the log; an entry notes in its "suppressed" metadata how many
+// denials from the same IP were dropped since the previous one.
+func (s *Server) auditDenied(r *http.Request, status int, action, reason string) {
+	ip := clientIP(r, s.opts.TrustedProxyDepth)
+	suppressed, ok := s.admitDeniedAudit(ip)
+	if !ok {
+		return
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	actor, _ := r.Context().Value(actorContextKey).(string)
+	path := truncateAuditPath(r.URL.Path)
+	entry := &model.AuditEntry{
+		ID:           hex.EncodeToString(b),
+		ActorID:      actor,
+		ActorType:    "api_key",
+		Action:       action,
+		ResourceType: "http",
+		ResourceID:   path,
+		Metadata: map[string]string{
+			"method": r.Method,
+			"path":   path,
+			"status": strconv.Itoa(status),
+			"reason": reason,
+		},
+		IPAddress: ip,
+		Outcome:   model.AuditOutcomeDenied,
+		CreatedAt: time.Now(),
+	}
+	if suppressed > 0 {
+		entry.Metadata["suppressed"] = strconv.Itoa(suppressed)
+	}
+	if err := s.store.AuditLog().Append(entry); err != nil {
+		log.Printf("audit denied request: %v", err)
+	}
+}
+
+// admitDeniedAudit reports whether a denied request from ip may be written
+// to the audit log and, if so, how many earlier ones were suppressed.
+func (s *Server) admitDeniedAudit(ip string) (suppressed int, ok bool) {
+	allowed := s.deniedAudits.bucket(ip).allow()
+	s.deniedMu.Lock()
+	defer s.deniedMu.Unlock()
+	if !allowed {
+		if _, tracked := s.deniedSuppressed[ip]; tracked || len(s.deniedSuppressed) < maxDeniedSources {
+			s.deniedSuppressed[ip]++
+		}
+		return 0, false
+	}
+	suppressed = s.deniedSuppressed[ip]
+	delete(s.deniedSuppressed, ip)
+	return suppressed, true
+}
+
+// truncateAuditPath shortens a client-chosen path to maxAuditPathLen bytes,
+// cutting on a rune boundary and marking the cut with "...".
+func truncateAuditPath(p string) string {
+	if len(p) <= maxAuditPathLen {
+		return p
+	}
+	n := maxAuditPathLen
+	for n > 0 && !utf8.RuneStart(p[n]) {
+		n--
+	}
+	return p[:n] + "..."
+}
+
 // requestBodyLimitMiddleware wraps the request body with http.MaxBytesReader to
 // prevent memory exhaustion from oversized payloads. Returns 413 if exceeded.
 func requestBodyLimitMiddleware(next http.Handler) http.Handler {
@@ -145,6 +251,12 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap exposes the underlying writer to http.ResponseController, so
+// streaming handlers can flush and adjust deadlines through the wrapper.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // requestIDMiddleware adds a unique X-Request-ID header to each request and
 // response if one is not already present.
 func requestIDMiddleware(next http.Handler) http.Handler {
@@ -232,24 +344,192 @@ func (tb *tokenBucket) allow() bool {
 	return true
 }
 
-// rateLimitMiddleware enforces a global request rate limit (1000 req/min).
-// Returns 429 Too Many Requests when the limit is exceeded.
-func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
-	// 1000 requests per minute ≈ 16.67 req/s, burst of 50.
-	limiter := newTokenBucket(1000.0/60.0, 50)
+// refund returns a token taken by allow, up to the bucket's size.
+func (tb *tokenBucket) refund() {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.tokens++
+	if tb.tokens > tb.maxTok {
+		tb.tokens = tb.maxTok
+	}
+}
+
+// burstOf returns rl's bucket size, defaulting to PerMinute.
+func burstOf(rl RateLimit) float64 {
+	if rl.Burst > 0 {
+		return float64(rl.Burst)
+	}
+	return float64(rl.PerMinute)
+}
+
+// appliedBucket is a token bucket that applies to a request, with the
+// per-minute limit it reports in X-RateLimit-Limit.
+type appliedBucket struct {
+	bucket *tokenBucket
+	limit  int
+}
+
+// ipLimit is the per-IP bucket ipRateLimitMiddleware charged a request to,
+// with the tokens it held beforehand, for rateLimitMiddleware's headers.
+type ipLimit struct {
+	limit     int
+	remaining float64
+}
+
+// ipRateLimitMiddleware enforces ServerOptions.PerIPRateLimit ahead of
+// authentication, so a source sending requests with missing or invalid keys
+// is limited like any other, returning 429 Too Many Requests when the
+// client's bucket is empty. A PerMinute of zero disables it.
+func (s *Server) ipRateLimitMiddleware(next http.Handler) http.Handler {
+	rl := s.opts.PerIPRateLimit
+	if rl.PerMinute <= 0 {
+		return next
+	}
+	perIP := newKeyedLimiter(float64(rl.PerMinute)/60.0, burstOf(rl))
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("X-RateLimit-Limit", "1000")
-		remaining := int(limiter.remaining())
-		w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", remaining))
-		if !limiter.allow() {
+		b := perIP.bucket(clientIP(r, s.opts.TrustedProxyDepth))
+		left := b.remaining()
+		if !b.allow() {
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rl.PerMinute))
+			w.Header().Set("X-RateLimit-Remaining", "0")
 			w.Header().Set("Retry-After", "60")
 			http.Error(w, `{"error":"rate limit exceeded"}`, http.StatusTooManyRequests)
 			return
 		}
+		ctx := context.WithValue(r.Context(), ipLimitContextKey, ipLimit{limit: rl.PerMinute, remaining: left})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// rateLimitMiddleware enforces ServerOptions.RateLimit and
+// PerTenantRateLimit, returning 429 Too Many Requests when any bucket that
+// applies to the request is empty; a rejected request is charged to none of
+// them. The X-RateLimit-* headers describe the
+// tightest of those buckets and the per-IP one already charged by
+// ipRateLimitMiddleware, the one with the fewest tokens left, so clients
+// back off according to the limit they will actually hit. A PerMinute of zero
+// disables that limiter; with all three disabled no headers are sent.
+func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
+	var global *tokenBucket
+	if rl := s.opts.RateLimit; rl.PerMinute > 0 {
+		global = newTokenBucket(float64(rl.PerMinute)/60.0, burstOf(rl))
+	}
+	var perTenant *keyedLimiter
+	if rl := s.opts.PerTenantRateLimit; rl.PerMinute > 0 {
+		perTenant = newKeyedLimiter(float64(rl.PerMinute)/60.0, burstOf(rl))
+	}
+	if global == nil && perTenant == nil && s.opts.PerIPRateLimit.PerMinute <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		applied := make([]appliedBucket, 0, 2)
+		if global != nil {
+			applied = append(applied, appliedBucket{global, s.opts.RateLimit.PerMinute})
+		}
+		if tenant, _ := r.Context().Value(tenantContextKey).(string); perTenant != nil && tenant != "" {
+			applied = append(applied, appliedBucket{perTenant.bucket(tenant), s.opts.PerTenantRateLimit.PerMinute})
+		}
+
+		limit, remaining := 0, -1.0
+		if ip, ok := r.Context().Value(ipLimitContextKey).(ipLimit); ok {
+			limit, remaining = ip.limit, ip.remaining
+		}
+		for _, b := range applied {
+			if left := b.bucket.remaining(); remaining < 0 || left < remaining {
+				limit, remaining = b.limit, left
+			}
+		}
+		if remaining >= 0 {
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+			w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", int(remaining)))
+		}
+		for i, b := range applied {
+			if !b.bucket.allow() {
+				// Give back the tokens already taken, so a tenant whose own
+				// bucket is empty does not drain the global one with 429s.
+				for _, taken := range applied[:i] {
+					taken.bucket.refund()
+				}
+				w.Header().Set("Retry-After", "60")
+				http.Error(w, `{"error":"rate limit exceeded"}`, http.StatusTooManyRequests)
+				return
+			}
+		}
 		next.ServeHTTP(w, r)
 	})
 }
 
+// limiterSweepInterval is how often idle per-key buckets are discarded.
+const limiterSweepInterval = time.Minute
+
+// keyedLimiter keeps one token bucket per key, a client IP or tenant ID.
+type keyedLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	ratePerS  float64
+	burst     float64
+	lastSweep time.Time
+}
+
+func newKeyedLimiter(ratePerSec, burst float64) *keyedLimiter {
+	return &keyedLimiter{
+		buckets:   make(map[string]*tokenBucket),
+		ratePerS:  ratePerSec,
+		burst:     burst,
+		lastSweep: time.Now(),
+	}
+}
+
+// bucket returns key's bucket, creating it on first use.
+func (l *keyedLimiter) bucket(key string) *tokenBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Sub(l.lastSweep) > limiterSweepInterval {
+		// A bucket that has refilled completely carries no state worth
+		// keeping; dropping it bounds memory under many distinct sources.
+		for k, b := range l.buckets {
+			if b.remaining() >= b.maxTok {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = newTokenBucket(l.ratePerS, l.burst)
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// clientIP identifies the request's source for per-IP rate limiting. With
+// trustedProxies > 0 it takes the X-Forwarded-For entry appended by the
+// outermost trusted proxy, i.e. the trustedProxies-th entry from the right;
+// entries further left are client-controlled and ignored. A request with
+// fewer entries than that did not come through every proxy, so none of its
+// entries can be trusted and the connection's RemoteAddr is used.
+func clientIP(r *http.Request, trustedProxies int) string {
+	if trustedProxies > 0 {
+		var hops []string
+		for _, h := range r.Header.Values("X-Forwarded-For") {
+			for _, part := range strings.Split(h, ",") {
+				if part = strings.TrimSpace(part); part != "" {
+					hops = append(hops, part)
+				}
+			}
+		}
+		if len(hops) >= trustedProxies {
+			return hops[len(hops)-trustedProxies]
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // securityHeadersMiddleware adds defensive HTTP headers to every response.
 func securityHeadersMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -8,6 +8,22 @@ import (
 	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
 )
 
+// NodeTransitioner applies validated node state changes and records them as
+// fleet events. *controller.FleetController implements it.
+type NodeTransitioner interface {
+	Transition(nodeID string, to model.NodeState, reason string) error
+}
+
+// SetNodeTransitioner routes status changes made with PUT /api/v1/nodes/{id}
+// through t, so they emit fleet events. Until it is called such changes are
+// still checked against the model.NodeState transition table but recorded
+// without an event.
+func (s *Server) SetNodeTransitioner(t NodeTransitioner) {
+	s.eventsMu.Lock()
+	s.transitioner = t
+	s.eventsMu.Unlock()
+}
+
 func (s *Server) handleListNodes(w http.ResponseWriter, r *http.Request) {
 	s.metrics.IncRequest()
 	nodes, err := s.store.Nodes().List()
@@ -52,8 +68,20 @@ func (s *Server) handleCreateNode(w http.ResponseWriter, r *http.Request) {
 	if node.Status == "" {
 		node.Status = "online"
 	}
+	if err := resolveTenant(r, &node.TenantID); err != nil {
+		s.writeQuotaError(w, err)
+		return
+	}
 	node.LastSeen = time.Now()
-	if err := s.store.Nodes().Create(&node); err != nil {
+	s.quotaMu.Lock()
+	if err := s.checkNodeQuota(&node); err != nil {
+		s.quotaMu.Unlock()
+		s.writeQuotaError(w, err)
+		return
+	}
+	err := s.store.Nodes().Create(&node)
+	s.quotaMu.Unlock()
+	if err != nil {
 		s.metrics.IncError()
 		writeError(w, http.StatusConflict, err.Error())
 		return
@@ -77,11 +105,67 @@ func (s *Server) handleUpdateNode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	node.ID = id
+	if err := resolveTenant(r, &node.TenantID); err != nil {
+		s.writeQuotaError(w, err)
+		return
+	}
+	// A node moving to another tenant must fit that tenant's quota; hold
+	// quotaMu from the check until the move is written.
+	s.quotaMu.Lock()
+	defer s.quotaMu.Unlock()
+	current, err := s.store.Nodes().Get(id)
+	if err != nil {
+		s.metrics.IncError()
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if node.TenantID != current.TenantID {
+		if err := s.checkNodeQuota(&node); err != nil {
+			s.writeQuotaError(w, err)
+			return
+		}
+	}
+	// An omitted status keeps the current one; a new one must be a legal
+	// transition from it.
+	from, to := model.NodeState(current.Status), model.NodeState(node.Status)
+	if to == "" {
+		to = from
+	}
+	node.Status = string(to)
+	if err := ValidateNode(&node); err != nil {
+		s.metrics.IncError()
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if to != from {
+		if err := from.ValidateTransition(to); err != nil {
+			s.metrics.IncError()
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+	}
+
+	s.eventsMu.RLock()
+	transitioner := s.transitioner
+	s.eventsMu.RUnlock()
+	if transitioner != nil {
+		// Write the other fields first and let the transitioner move the
+		// state, so the change is recorded as a fleet event.
+		node.Status = string(from)
+	}
 	if err := s.store.Nodes().Update(&node); err != nil {
 		s.metrics.IncError()
 		writeError(w, http.StatusNotFound, err.Error())
 		return
 	}
+	if transitioner != nil && to != from {
+		if err := transitioner.Transition(id, to, "status set via API"); err != nil {
+			s.metrics.IncError()
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		node.Status = string(to)
+	}
 	writeJSON(w, http.StatusOK, node)
 }
 
@@ -116,18 +200,69 @@ func (s *Server) handleNodeHeartbeat(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusNotFound, err.Error())
 		return
 	}
-	// Optionally decode metrics from body.
-	var metrics model.NodeMetrics
+	// Optionally decode metrics, labels and region from body. A body that
+	// carries only labels or region leaves the metrics as they were.
+	var hb model.NodeHeartbeat
 	if r.Body != nil && r.ContentLength > 0 {
-		json.NewDecoder(r.Body).Decode(&metrics) //nolint:errcheck
-		node.Metrics = metrics
+		json.NewDecoder(r.Body).Decode(&hb) //nolint:errcheck
+		if hb.NodeMetrics != (model.NodeMetrics{}) || (hb.Labels == nil && hb.Region == "") {
+			node.Metrics = hb.NodeMetrics
+		}
+		if hb.Labels != nil {
+			node.Labels = hb.Labels
+		}
+		if hb.Region != "" {
+			node.Region = hb.Region
+		}
 	}
 	node.LastSeen = time.Now()
-	node.Status = "online"
+	// Recovery from unhealthy is left to the fleet controller so the
+	// transition is validated and recorded; a heartbeat only brings up a node
+	// that has no state yet. Draining and offline nodes return to service
+	// through a status update (handleUpdateNode).
+	if node.Status == "" {
+		node.Status = string(model.NodeStateOnline)
+	}
 	if err := s.store.Nodes().Update(node); err != nil {
 		s.metrics.IncError()
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	sample := model.NodeMetricsSample{Timestamp: node.LastSeen, Metrics: node.Metrics}
+	if err := s.store.Nodes().AppendMetrics(id, sample); err != nil {
+		s.metrics.IncError()
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
+
+// handleNodeMetricsHistory returns the node's recent heartbeat metrics, oldest
+// first. The optional since query parameter (RFC 3339) limits the result to
+// samples taken at or after that time.
+func (s *Server) handleNodeMetricsHistory(w http.ResponseWriter, r *http.Request) {
+	s.metrics.IncRequest()
+	id := r.PathValue("id")
+	if err := ValidateID(id); err != nil {
+		s.metrics.IncError()
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			s.metrics.IncError()
+			writeError(w, http.StatusBadRequest, "invalid since: want an RFC 3339 timestamp")
+			return
+		}
+		since = t
+	}
+	samples, err := s.store.Nodes().MetricsHistory(id, since)
+	if err != nil {
+		s.metrics.IncError()
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, samples)
+}
@@ -0,0 +1,261 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
+)
+
+// apiOperation describes one REST endpoint for the OpenAPI document.
+type apiOperation struct {
+	method  string
+	path    string
+	tag     string
+	summary string
+	body    reflect.Type // request body type; nil if the endpoint takes none
+	resp    reflect.Type // success response type; nil for 204 No Content
+	status  int
+}
+
+// statusResponse is the {"status": "..."} body returned by action endpoints.
+type statusResponse struct {
+	Status string `json:"status"`
+}
+
+// verifyResponse is the body returned by MIC verification.
+type verifyResponse struct {
+	Valid bool `json:"valid"`
+}
+
+// resourceOperations returns the list/create/get/update/delete operations
+// that the API exposes for a resource of type t at base.
+func resourceOperations(tag, base, noun string, t reflect.Type) []apiOperation {
+	item := base + "/{id}"
+	list := reflect.SliceOf(t)
+	return []apiOperation{
+		{http.MethodGet, base, tag, "List " + noun + "s", nil, list, http.StatusOK},
+		{http.MethodPost, base, tag, "Create a " + noun, t, t, http.StatusCreated},
+		{http.MethodGet, item, tag, "Get a " + noun, nil, t, http.StatusOK},
+		{http.MethodPut, item, tag, "Update a " + noun, t, t, http.StatusOK},
+		{http.MethodDelete, item, tag, "Delete a " + noun, nil, nil, http.StatusNoContent},
+	}
+}
+
+// apiOperations lists the endpoints described by /openapi.json. Keep it in
+// step with registerRoutes.
+func apiOperations() []apiOperation {
+	var ops []apiOperation
+	ops = append(ops, resourceOperations("nodes", "/api/v1/nodes", "node", reflect.TypeFor[model.Node]())...)
+	ops = append(ops, apiOperation{http.MethodPost, "/api/v1/nodes/{id}/heartbeat", "nodes",
+		"Record a node heartbeat, optionally updating its metrics, labels and region",
+		reflect.TypeFor[model.NodeHeartbeat](), reflect.TypeFor[statusResponse](), http.StatusOK})
+	ops = append(ops, apiOperation{http.MethodGet, "/api/v1/nodes/{id}/metrics", "nodes",
+		"List a node's recent heartbeat metrics, oldest first; filter with ?since=<RFC 3339>",
+		nil, reflect.TypeFor[[]model.NodeMetricsSample](), http.StatusOK})
+	ops = append(ops, resourceOperations("routes", "/api/v1/routes", "route", reflect.TypeFor[model.Route]())...)
+
+	mic := reflect.TypeFor[model.MIC]()
+	issue := reflect.TypeFor[issueMICRequest]()
+	ops = append(ops,
+		apiOperation{http.MethodGet, "/api/v1/trust/mics", "trust", "List MICs", nil, reflect.SliceOf(mic), http.StatusOK},
+		apiOperation{http.MethodPost, "/api/v1/trust/mics", "trust", "Issue a MIC", issue, mic, http.StatusCreated},
+		apiOperation{http.MethodPost, "/api/v1/trust/mics:batch", "trust", "Issue a batch of MICs",
+			reflect.SliceOf(issue), reflect.SliceOf(mic), http.StatusCreated},
+		apiOperation{http.MethodGet, "/api/v1/trust/mics/{id}", "trust", "Get a MIC", nil, mic, http.StatusOK},
+		apiOperation{http.MethodPost, "/api/v1/trust/mics/{id}/verify", "trust", "Verify a MIC's signature and validity",
+			nil, reflect.TypeFor[verifyResponse](), http.StatusOK},
+		apiOperation{http.MethodPost, "/api/v1/trust/mics/{id}/revoke", "trust", "Revoke a MIC",
+			nil, reflect.TypeFor[statusResponse](), http.StatusOK},
+		apiOperation{http.MethodDelete, "/api/v1/trust/mics/{id}", "trust", "Delete a MIC", nil, nil, http.StatusNoContent},
+	)
+
+	ops = append(ops, resourceOperations("firmware", "/api/v1/firmware", "firmware image", reflect.TypeFor[model.FirmwareImage]())...)
+	ops = append(ops, resourceOperations("tenants", "/api/v1/tenants", "tenant", reflect.TypeFor[model.Tenant]())...)
+	ops = append(ops, resourceOperations("clusters", "/api/v1/clusters", "cluster", reflect.TypeFor[model.Cluster]())...)
+	return ops
+}
+
+// openAPISpec is the encoded OpenAPI document, built on first use.
+var openAPISpec = sync.OnceValue(func() []byte {
+	data, err := json.MarshalIndent(buildOpenAPISpec(apiOperations()), "", "  ")
+	if err != nil {
+		panic("apiserver: encode OpenAPI spec: " + err.Error())
+	}
+	return data
+})
+
+// handleOpenAPI serves the OpenAPI 3 description of the REST API.
+func (s *Server) handleOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openAPISpec())
+}
+
+// buildOpenAPISpec assembles an OpenAPI 3.0 document for ops, deriving the
+// component schemas from the Go types' JSON encoding.
+func buildOpenAPISpec(ops []apiOperation) map[string]any {
+	schemas := schemaSet{"Error": map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"error": map[string]any{"type": "string"}},
+	}}
+	errorResponse := func(desc string) map[string]any {
+		return map[string]any{
+			"description": desc,
+			"content":     jsonContent(map[string]any{"$ref": "#/components/schemas/Error"}),
+		}
+	}
+
+	paths := map[string]map[string]any{}
+	for _, op := range ops {
+		responses := map[string]any{
+			"400": errorResponse("Invalid request"),
+			"401": errorResponse("Missing or invalid API key"),
+			"403": errorResponse("API key role does not permit this operation"),
+		}
+		success := map[string]any{"description": http.StatusText(op.status)}
+		if op.resp != nil {
+			success["content"] = jsonContent(schemas.schemaFor(op.resp))
+		}
+		responses[strconv.Itoa(op.status)] = success
+
+		operation := map[string]any{
+			"tags":        []string{op.tag},
+			"summary":     op.summary,
+			"operationId": operationID(op),
+			"responses":   responses,
+		}
+		if strings.Contains(op.path, "{id}") {
+			responses["404"] = errorResponse("Not found")
+			operation["parameters"] = []any{map[string]any{
+				"name": "id", "in": "path", "required": true,
+				"schema": map[string]any{"type": "string"},
+			}}
+		}
+		if op.body != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(schemas.schemaFor(op.body)),
+			}
+		}
+		if paths[op.path] == nil {
+			paths[op.path] = map[string]any{}
+		}
+		paths[op.path][strings.ToLower(op.method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Strand Cloud API",
+			"version": "v1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "API key from ServerOptions.APIKeys. Viewer keys may GET, operator keys may also POST and PUT, admin keys may DELETE.",
+				},
+			},
+		},
+		"security": []any{map[string]any{"bearerAuth": []string{}}},
+	}
+}
+
+// operationID derives a stable operation ID such as "get_api_v1_nodes_id".
+func operationID(op apiOperation) string {
+	id := strings.ToLower(op.method) + op.path
+	return strings.NewReplacer("/", "_", "{", "", "}", "", ":", "_").Replace(id)
+}
+
+func jsonContent(schema any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+var (
+	timeType     = reflect.TypeFor[time.Time]()
+	durationType = reflect.TypeFor[time.Duration]()
+)
+
+// schemaSet collects named component schemas, keyed by exported type name.
+type schemaSet map[string]any
+
+// schemaFor returns the schema for t, registering struct types as components
+// and referring to them by $ref.
+func (s schemaSet) schemaFor(t reflect.Type) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case durationType:
+		return map[string]any{"type": "integer", "format": "int64", "description": "Duration in nanoseconds"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.schemaFor(t.Elem())
+	case reflect.Struct:
+		name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+		if _, ok := s[name]; !ok {
+			s[name] = nil // reserve the name before recursing
+			s[name] = s.objectSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": s.schemaFor(t.Elem())}
+	case reflect.Array:
+		return map[string]any{"type": "array", "items": s.schemaFor(t.Elem()), "minItems": t.Len(), "maxItems": t.Len()}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schemaFor(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	return map[string]any{}
+}
+
+// objectSchema describes a struct by its exported fields' JSON names.
+// Untagged embedded structs contribute their fields directly, as they do in
+// encoding/json.
+func (s schemaSet) objectSchema(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	s.addProperties(props, t)
+	return map[string]any{"type": "object", "properties": props}
+}
+
+func (s schemaSet) addProperties(props map[string]any, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" && f.Anonymous && f.Type.Kind() == reflect.Struct {
+			s.addProperties(props, f.Type)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = s.schemaFor(f.Type)
+	}
+}
@@ -0,0 +1,120 @@
+package apiserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
+)
+
+// quotaError is a request refused by tenant checks. code is the HTTP status
+// to report: 400 for an unknown tenant, 403 when the quota is used up or the
+// API key acts for another tenant.
+type quotaError struct {
+	code int
+	msg  string
+}
+
+func (e *quotaError) Error() string { return e.msg }
+
+// resolveTenant settles which tenant a node or route written by r belongs
+// to. A request authenticated with a tenant's API key (APIKeyInfo.TenantID)
+// acts for that tenant: an omitted TenantID is set to it and any other is
+// refused, so quotas cannot be dodged by naming another tenant or none.
+// Keys without a tenant may name any tenant, or none.
+func resolveTenant(r *http.Request, tenantID *string) error {
+	keyTenant, _ := r.Context().Value(tenantContextKey).(string)
+	if keyTenant == "" {
+		return nil
+	}
+	if *tenantID == "" {
+		*tenantID = keyTenant
+		return nil
+	}
+	if *tenantID != keyTenant {
+		return &quotaError{
+			code: http.StatusForbidden,
+			msg:  fmt.Sprintf("api key for tenant %s cannot act for tenant %s", keyTenant, *tenantID),
+		}
+	}
+	return nil
+}
+
+// checkTenantQuota reports whether tenantID may own another resource of the
+// given kind ("nodes" or "routes"), given a limit read from the tenant and a
+// count of what it already owns. An empty tenantID is not subject to quotas,
+// nor is a limit of zero. Callers hold s.quotaMu across the check and the
+// create so concurrent requests cannot both take the last slot.
+func (s *Server) checkTenantQuota(tenantID, kind string, limit func(*model.Tenant) int, count func() (int, error)) error {
+	if tenantID == "" {
+		return nil
+	}
+	tenant, err := s.store.Tenants().Get(tenantID)
+	if err != nil {
+		return &quotaError{code: http.StatusBadRequest, msg: fmt.Sprintf("unknown tenant %q", tenantID)}
+	}
+	allowed := limit(tenant)
+	if allowed <= 0 {
+		return nil
+	}
+	n, err := count()
+	if err != nil {
+		return &quotaError{code: http.StatusInternalServerError, msg: err.Error()}
+	}
+	if n >= allowed {
+		return &quotaError{
+			code: http.StatusForbidden,
+			msg:  fmt.Sprintf("quota exceeded: tenant %s is limited to %d %s", tenantID, allowed, kind),
+		}
+	}
+	return nil
+}
+
+// checkNodeQuota applies Tenant.MaxNodes to a node joining n.TenantID,
+// whether created or moved there from another tenant.
+func (s *Server) checkNodeQuota(n *model.Node) error {
+	return s.checkTenantQuota(n.TenantID, "nodes",
+		func(t *model.Tenant) int { return t.MaxNodes },
+		func() (int, error) {
+			nodes, err := s.store.Nodes().List()
+			count := 0
+			for i := range nodes {
+				if nodes[i].TenantID == n.TenantID {
+					count++
+				}
+			}
+			return count, err
+		})
+}
+
+// checkRouteQuota applies Tenant.MaxRoutes to a route joining r.TenantID,
+// whether created or moved there from another tenant. Expired routes
+// awaiting the route janitor do not count.
+func (s *Server) checkRouteQuota(r *model.Route) error {
+	return s.checkTenantQuota(r.TenantID, "routes",
+		func(t *model.Tenant) int { return t.MaxRoutes },
+		func() (int, error) {
+			routes, err := s.store.Routes().List()
+			now := time.Now()
+			count := 0
+			for i := range routes {
+				if routes[i].TenantID == r.TenantID && !routes[i].Expired(now) {
+					count++
+				}
+			}
+			return count, err
+		})
+}
+
+// writeQuotaError reports err from a quota check.
+func (s *Server) writeQuotaError(w http.ResponseWriter, err error) {
+	s.metrics.IncError()
+	code := http.StatusInternalServerError
+	var qe *quotaError
+	if errors.As(err, &qe) {
+		code = qe.code
+	}
+	writeError(w, code, err.Error())
+}
@@ -16,7 +16,15 @@ func (s *Server) handleListRoutes(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	writeJSON(w, http.StatusOK, routes)
+	// Expired routes stay hidden until the route janitor deletes them.
+	now := time.Now()
+	live := routes[:0]
+	for _, route := range routes {
+		if !route.Expired(now) {
+			live = append(live, route)
+		}
+	}
+	writeJSON(w, http.StatusOK, live)
 }
 
 func (s *Server) handleGetRoute(w http.ResponseWriter, r *http.Request) {
@@ -28,6 +36,11 @@ func (s *Server) handleGetRoute(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusNotFound, err.Error())
 		return
 	}
+	if route.Expired(time.Now()) {
+		s.metrics.IncError()
+		writeError(w, http.StatusNotFound, "route "+id+" expired")
+		return
+	}
 	writeJSON(w, http.StatusOK, route)
 }
 
@@ -45,7 +58,19 @@ func (s *Server) handleCreateRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	route.CreatedAt = time.Now()
-	if err := s.store.Routes().Create(&route); err != nil {
+	if err := resolveTenant(r, &route.TenantID); err != nil {
+		s.writeQuotaError(w, err)
+		return
+	}
+	s.quotaMu.Lock()
+	if err := s.checkRouteQuota(&route); err != nil {
+		s.quotaMu.Unlock()
+		s.writeQuotaError(w, err)
+		return
+	}
+	err := s.store.Routes().Create(&route)
+	s.quotaMu.Unlock()
+	if err != nil {
 		s.metrics.IncError()
 		writeError(w, http.StatusConflict, err.Error())
 		return
@@ -64,6 +89,25 @@ func (s *Server) handleUpdateRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	route.ID = id
+	if err := resolveTenant(r, &route.TenantID); err != nil {
+		s.writeQuotaError(w, err)
+		return
+	}
+	// A route moving to another tenant must fit that tenant's quota; hold
+	// quotaMu from the check until the move is written.
+	s.quotaMu.Lock()
+	defer s.quotaMu.Unlock()
+	// The TTL counts from creation, so an update keeps the original
+	// CreatedAt rather than the zero time a client omitting it would send.
+	if existing, err := s.store.Routes().Get(id); err == nil {
+		route.CreatedAt = existing.CreatedAt
+		if route.TenantID != existing.TenantID {
+			if err := s.checkRouteQuota(&route); err != nil {
+				s.writeQuotaError(w, err)
+				return
+			}
+		}
+	}
 	if err := s.store.Routes().Update(&route); err != nil {
 		s.metrics.IncError()
 		writeError(w, http.StatusNotFound, err.Error())
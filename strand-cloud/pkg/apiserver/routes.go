@@ -14,6 +14,9 @@ func (s *Server) registerRoutes() {
 	// Metrics endpoint
 	s.mux.HandleFunc("GET /metrics", s.handleMetrics)
 
+	// OpenAPI description of the routes below
+	s.mux.HandleFunc("GET /openapi.json", s.handleOpenAPI)
+
 	// Nodes
 	s.mux.HandleFunc("GET /api/v1/nodes", s.handleListNodes)
 	s.mux.HandleFunc("POST /api/v1/nodes", s.handleCreateNode)
@@ -21,6 +24,7 @@ func (s *Server) registerRoutes() {
 	s.mux.HandleFunc("PUT /api/v1/nodes/{id}", s.handleUpdateNode)
 	s.mux.HandleFunc("DELETE /api/v1/nodes/{id}", s.handleDeleteNode)
 	s.mux.HandleFunc("POST /api/v1/nodes/{id}/heartbeat", s.handleNodeHeartbeat)
+	s.mux.HandleFunc("GET /api/v1/nodes/{id}/metrics", s.handleNodeMetricsHistory)
 
 	// Routes
 	s.mux.HandleFunc("GET /api/v1/routes", s.handleListRoutes)
@@ -32,6 +36,7 @@ func (s *Server) registerRoutes() {
 	// Trust / MICs
 	s.mux.HandleFunc("GET /api/v1/trust/mics", s.handleListMICs)
 	s.mux.HandleFunc("POST /api/v1/trust/mics", s.handleIssueMIC)
+	s.mux.HandleFunc("POST /api/v1/trust/mics:batch", s.handleIssueMICBatch)
 	s.mux.HandleFunc("GET /api/v1/trust/mics/{id}", s.handleGetMIC)
 	s.mux.HandleFunc("POST /api/v1/trust/mics/{id}/verify", s.handleVerifyMIC)
 	s.mux.HandleFunc("POST /api/v1/trust/mics/{id}/revoke", s.handleRevokeMIC)
@@ -61,6 +66,9 @@ func (s *Server) registerRoutes() {
 	// Audit log
 	s.mux.HandleFunc("GET /api/v1/audit", s.handleListAuditLog)
 
+	// Fleet events (SSE)
+	s.mux.HandleFunc("GET /api/v1/events", s.handleEvents)
+
 	// Billing
 	s.mux.HandleFunc("GET /api/v1/billing/plans", s.handleListPlans)
 	s.mux.HandleFunc("GET /api/v1/billing/usage", s.handleGetUsage)
@@ -3,8 +3,11 @@ package apiserver
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/strand-protocol/strand/strand-cloud/pkg/ca"
@@ -24,10 +27,26 @@ const (
 	RoleAdmin
 )
 
+// String returns the lower-case role name.
+func (r Role) String() string {
+	switch r {
+	case RoleViewer:
+		return "viewer"
+	case RoleOperator:
+		return "operator"
+	case RoleAdmin:
+		return "admin"
+	}
+	return fmt.Sprintf("Role(%d)", int(r))
+}
+
 // APIKeyInfo associates a Bearer token with its description and RBAC role.
 type APIKeyInfo struct {
 	Description string
 	Role        Role
+	// TenantID, when set, is the tenant the key acts for; its requests share
+	// that tenant's PerTenantRateLimit bucket.
+	TenantID string
 }
 
 // ServerOptions holds optional configuration for the Server.
@@ -41,6 +60,30 @@ type ServerOptions struct {
 	APIKeys map[string]APIKeyInfo
 	// AllowedOrigins lists the origins allowed for CORS. Reserved for future use.
 	AllowedOrigins []string
+	// RateLimit configures the global token-bucket request limiter.
+	RateLimit RateLimit
+	// PerIPRateLimit, when PerMinute is non-zero, additionally gives each
+	// client IP its own token bucket so one abusive source cannot exhaust the
+	// global limit for everyone.
+	PerIPRateLimit RateLimit
+	// PerTenantRateLimit, when PerMinute is non-zero, additionally gives each
+	// tenant (see APIKeyInfo.TenantID) its own token bucket. Requests made
+	// with keys that name no tenant are not subject to it.
+	PerTenantRateLimit RateLimit
+	// TrustedProxyDepth is the number of reverse proxies in front of the
+	// server whose X-Forwarded-For entries are trusted when identifying the
+	// client IP for PerIPRateLimit. Zero uses the connection's RemoteAddr.
+	TrustedProxyDepth int
+}
+
+// RateLimit configures a token-bucket rate limiter.
+type RateLimit struct {
+	// PerMinute is the sustained number of requests allowed per minute.
+	// Zero disables the limiter (unlimited), for trusted internal deployments.
+	PerMinute int
+	// Burst is the bucket size: how many requests may arrive at once. Zero
+	// means PerMinute.
+	Burst int
 }
 
 // DefaultServerOptions returns sensible defaults.
@@ -49,6 +92,7 @@ func DefaultServerOptions() ServerOptions {
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
+		RateLimit:    RateLimit{PerMinute: 1000, Burst: 50},
 	}
 }
 
@@ -60,6 +104,21 @@ type Server struct {
 	metrics    *observability.Metrics
 	mux        *http.ServeMux
 	opts       ServerOptions
+
+	eventsMu sync.RWMutex
+	events   EventSource
+	// transitioner applies node status changes (SetNodeTransitioner);
+	// guarded by eventsMu.
+	transitioner NodeTransitioner
+
+	// quotaMu serialises tenant quota checks with the creates they guard.
+	quotaMu sync.Mutex
+
+	// deniedAudits limits denied-request audit entries per client IP;
+	// deniedSuppressed counts those dropped since each IP's last entry.
+	deniedAudits     *keyedLimiter
+	deniedMu         sync.Mutex
+	deniedSuppressed map[string]int
 }
 
 // NewServer creates a Server wired to the given Store, CA, and options.
@@ -70,6 +129,9 @@ func NewServer(s store.Store, authority *ca.CA, opts ServerOptions) *Server {
 		metrics: observability.NewMetrics(),
 		mux:     http.NewServeMux(),
 		opts:    opts,
+
+		deniedAudits:     newKeyedLimiter(deniedAuditPerMinute/60.0, deniedAuditBurst),
+		deniedSuppressed: make(map[string]int),
 	}
 	srv.registerRoutes()
 	handler := srv.applyMiddleware(srv.mux)
@@ -89,10 +151,16 @@ func (s *Server) ListenAndServe(addr string) error {
 	return s.httpServer.ListenAndServe()
 }
 
-// GracefulShutdown performs a graceful shutdown of the HTTP server.
+// GracefulShutdown performs a graceful shutdown of the HTTP server, then
+// flushes the store so audit entries written by the last requests are
+// persisted before it returns.
 func (s *Server) GracefulShutdown(ctx context.Context) error {
 	log.Println("strand-cloud API server shutting down")
-	return s.httpServer.Shutdown(ctx)
+	shutdownErr := s.httpServer.Shutdown(ctx)
+	if err := s.store.Flush(ctx); err != nil {
+		return errors.Join(shutdownErr, fmt.Errorf("flush store: %w", err))
+	}
+	return shutdownErr
 }
 
 // Handler returns the root http.Handler (useful for testing with httptest).
@@ -113,21 +113,25 @@ func applyPlanDefaults(t *model.Tenant) {
 	case "starter":
 		t.MaxClusters = 1
 		t.MaxNodes = 10
+		t.MaxRoutes = 100
 		t.MaxMICsMonth = 1000
 		t.TrafficGBIncl = 10
 	case "pro":
 		t.MaxClusters = 3
 		t.MaxNodes = 150
+		t.MaxRoutes = 1500
 		t.MaxMICsMonth = 10000
 		t.TrafficGBIncl = 100
 	case "enterprise":
 		t.MaxClusters = 999
 		t.MaxNodes = 9999
+		t.MaxRoutes = 99999
 		t.MaxMICsMonth = 999999
 		t.TrafficGBIncl = 10000
 	default: // free
 		t.MaxClusters = 1
 		t.MaxNodes = 3
+		t.MaxRoutes = 30
 		t.MaxMICsMonth = 100
 		t.TrafficGBIncl = 1
 	}
@@ -2,6 +2,7 @@ package apiserver
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -45,41 +46,48 @@ type issueMICRequest struct {
 	ValidDays    int      `json:"valid_days"`
 }
 
-func (s *Server) handleIssueMIC(w http.ResponseWriter, r *http.Request) {
-	s.metrics.IncRequest()
-	var req issueMICRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.metrics.IncError()
-		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
-		return
-	}
+// maxMICBatch caps the number of MICs issued by one batch request.
+const maxMICBatch = 500
+
+// newMIC validates an issuance request and returns the unsigned MIC it
+// describes, valid from now.
+func newMIC(req issueMICRequest, now time.Time) (*model.MIC, error) {
 	if req.ID == "" || req.NodeID == "" {
-		s.metrics.IncError()
-		writeError(w, http.StatusBadRequest, "id and node_id are required")
-		return
+		return nil, fmt.Errorf("id and node_id are required")
 	}
 	if err := ValidateID(req.ID); err != nil {
-		s.metrics.IncError()
-		writeError(w, http.StatusBadRequest, "invalid id: "+err.Error())
-		return
+		return nil, fmt.Errorf("invalid id: %w", err)
 	}
 	if err := ValidateID(req.NodeID); err != nil {
-		s.metrics.IncError()
-		writeError(w, http.StatusBadRequest, "invalid node_id: "+err.Error())
-		return
+		return nil, fmt.Errorf("invalid node_id: %w", err)
 	}
 	validity := 365
 	if req.ValidDays > 0 {
 		validity = req.ValidDays
 	}
-	now := time.Now()
-	mic := &model.MIC{
+	return &model.MIC{
 		ID:           req.ID,
 		NodeID:       req.NodeID,
 		ModelHash:    req.ModelHash,
 		Capabilities: req.Capabilities,
 		ValidFrom:    now,
 		ValidUntil:   now.Add(time.Duration(validity) * 24 * time.Hour),
+	}, nil
+}
+
+func (s *Server) handleIssueMIC(w http.ResponseWriter, r *http.Request) {
+	s.metrics.IncRequest()
+	var req issueMICRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.metrics.IncError()
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	mic, err := newMIC(req, time.Now())
+	if err != nil {
+		s.metrics.IncError()
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 	if err := s.ca.IssueMIC(mic); err != nil {
 		s.metrics.IncError()
@@ -94,6 +102,67 @@ func (s *Server) handleIssueMIC(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusCreated, mic)
 }
 
+// handleIssueMICBatch issues every MIC in a JSON array of issuance requests.
+// The batch is all-or-nothing: if any request is invalid, fails to sign, or
+// collides with an existing MIC, none are stored.
+func (s *Server) handleIssueMICBatch(w http.ResponseWriter, r *http.Request) {
+	s.metrics.IncRequest()
+	var reqs []issueMICRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+		s.metrics.IncError()
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	if len(reqs) == 0 || len(reqs) > maxMICBatch {
+		s.metrics.IncError()
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("batch must contain 1 to %d MICs", maxMICBatch))
+		return
+	}
+
+	now := time.Now()
+	mics := make([]*model.MIC, 0, len(reqs))
+	seen := make(map[string]bool, len(reqs))
+	for i, req := range reqs {
+		mic, err := newMIC(req, now)
+		if err != nil {
+			s.metrics.IncError()
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("mics[%d]: %v", i, err))
+			return
+		}
+		if seen[mic.ID] {
+			s.metrics.IncError()
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("mics[%d]: duplicate id %q", i, mic.ID))
+			return
+		}
+		seen[mic.ID] = true
+		if _, err := s.store.MICs().Get(mic.ID); err == nil {
+			s.metrics.IncError()
+			writeError(w, http.StatusConflict, fmt.Sprintf("mics[%d]: mic %q already exists", i, mic.ID))
+			return
+		}
+		mics = append(mics, mic)
+	}
+	for i, mic := range mics {
+		if err := s.ca.IssueMIC(mic); err != nil {
+			s.metrics.IncError()
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("mics[%d]: issue mic: %v", i, err))
+			return
+		}
+	}
+	for i, mic := range mics {
+		if err := s.store.MICs().Create(mic); err != nil {
+			// Roll back the part of the batch already stored.
+			for _, done := range mics[:i] {
+				_ = s.store.MICs().Delete(done.ID)
+			}
+			s.metrics.IncError()
+			writeError(w, http.StatusConflict, fmt.Sprintf("mics[%d]: %v", i, err))
+			return
+		}
+	}
+	writeJSON(w, http.StatusCreated, mics)
+}
+
 func (s *Server) handleVerifyMIC(w http.ResponseWriter, r *http.Request) {
 	s.metrics.IncRequest()
 	id := r.PathValue("id")
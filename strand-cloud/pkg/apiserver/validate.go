@@ -25,14 +25,6 @@ func ValidateID(id string) error {
 	return nil
 }
 
-// validNodeStatuses is the set of accepted node status values.
-var validNodeStatuses = map[string]bool{
-	"online":  true,
-	"offline": true,
-	"degraded": true,
-	"draining": true,
-}
-
 // ValidateNode checks that a Node has valid fields.
 func ValidateNode(n *model.Node) error {
 	if n.ID == "" {
@@ -46,8 +38,8 @@ func ValidateNode(n *model.Node) error {
 			return fmt.Errorf("node address %q is not a valid host:port", n.Address)
 		}
 	}
-	if n.Status != "" && !validNodeStatuses[n.Status] {
-		return fmt.Errorf("node status %q is invalid (allowed: online, offline, degraded, draining)", n.Status)
+	if n.Status != "" && !model.NodeState(n.Status).Valid() {
+		return fmt.Errorf("node status %q is invalid (allowed: online, degraded, unhealthy, draining, offline)", n.Status)
 	}
 	return nil
 }
@@ -16,20 +16,98 @@ import (
 
 const rootKeyID = "strand-root-ca"
 
+// DefaultRotationGrace is how long a retired root public key keeps verifying
+// MICs after Rotate, unless changed with SetRotationGrace.
+const DefaultRotationGrace = 7 * 24 * time.Hour
+
 // CA is the central certificate authority for the Strand control plane.
 type CA struct {
 	mu       sync.RWMutex
 	keyStore KeyStore
 	revoked  map[string]bool
+	// retired holds previous root public keys still accepted by VerifyMIC.
+	retired       []retiredKey
+	rotationGrace time.Duration
+}
+
+// retiredKey is a root public key replaced by Rotate, accepted until expires.
+type retiredKey struct {
+	pub     ed25519.PublicKey
+	expires time.Time
 }
 
 // NewCA creates a CA backed by the given KeyStore. Call GenerateCA() to create
 // the root key pair before issuing MICs.
 func NewCA(ks KeyStore) *CA {
 	return &CA{
-		keyStore: ks,
-		revoked:  make(map[string]bool),
+		keyStore:      ks,
+		revoked:       make(map[string]bool),
+		rotationGrace: DefaultRotationGrace,
+	}
+}
+
+// SetRotationGrace sets how long the key replaced by a later Rotate call
+// keeps verifying MICs. It does not affect keys already retired.
+func (c *CA) SetRotationGrace(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rotationGrace = d
+}
+
+// Rotate replaces the root key pair with a freshly generated one. New MICs are
+// signed with the new key; MICs signed with the previous key keep verifying
+// for the rotation grace period, giving the fleet time to be re-issued.
+func (c *CA) Rotate() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	oldPub, err := c.keyStore.LoadPublicKey(rootKeyID)
+	if err != nil {
+		return fmt.Errorf("load current public key: %w", err)
 	}
+	if err := c.GenerateCA(); err != nil {
+		return err
+	}
+	now := time.Now()
+	c.pruneRetiredLocked(now)
+	c.retired = append(c.retired, retiredKey{pub: oldPub, expires: now.Add(c.rotationGrace)})
+	return nil
+}
+
+// ActiveKeys returns every root public key VerifyMIC currently accepts: the
+// current key first, followed by retired keys still inside their grace
+// period.
+func (c *CA) ActiveKeys() ([]ed25519.PublicKey, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.activeKeysLocked(time.Now())
+}
+
+// activeKeysLocked implements ActiveKeys; c.mu must be held.
+func (c *CA) activeKeysLocked(now time.Time) ([]ed25519.PublicKey, error) {
+	pub, err := c.keyStore.LoadPublicKey(rootKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("load public key: %w", err)
+	}
+	keys := []ed25519.PublicKey{pub}
+	for _, rk := range c.retired {
+		if now.Before(rk.expires) {
+			keys = append(keys, rk.pub)
+		}
+	}
+	return keys, nil
+}
+
+// pruneRetiredLocked drops retired keys whose grace period has ended; c.mu
+// must be held for writing.
+func (c *CA) pruneRetiredLocked(now time.Time) {
+	kept := c.retired[:0]
+	for _, rk := range c.retired {
+		if now.Before(rk.expires) {
+			kept = append(kept, rk)
+		}
+	}
+	c.retired = kept
 }
 
 // GenerateCA creates a new Ed25519 root key pair and persists it in the KeyStore.
@@ -62,7 +140,9 @@ func (c *CA) IssueMIC(mic *model.MIC) error {
 }
 
 // VerifyMIC checks the signature of a MIC, whether it has been revoked, and
-// whether it is within its validity window.
+// whether it is within its validity window. The signature is accepted under
+// any of ActiveKeys, so MICs issued before a Rotate keep verifying for the
+// grace period.
 //
 // The read lock is held for the entire duration to prevent a TOCTOU race where
 // a concurrent RevokeMIC call could complete between the revocation check and
@@ -83,13 +163,18 @@ func (c *CA) VerifyMIC(mic *model.MIC) (bool, error) {
 			mic.ID, mic.ValidFrom, mic.ValidUntil, now)
 	}
 
-	pub, err := c.keyStore.LoadPublicKey(rootKeyID)
+	keys, err := c.activeKeysLocked(now)
 	if err != nil {
-		return false, fmt.Errorf("load public key: %w", err)
+		return false, err
 	}
 
 	payload := c.micPayload(mic)
-	return ed25519.Verify(pub, payload, mic.Signature), nil
+	for _, pub := range keys {
+		if ed25519.Verify(pub, payload, mic.Signature) {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 // RevokeMIC marks the given MIC ID as revoked. Subsequent calls to VerifyMIC
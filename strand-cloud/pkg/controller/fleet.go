@@ -4,29 +4,42 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"sync"
 	"time"
 
+	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
 	"github.com/strand-protocol/strand/strand-cloud/pkg/store"
 )
 
-// Event describes a fleet-level event emitted by controllers.
+// Event describes a fleet-level event emitted by controllers. Node state
+// transitions have Type "node_<to>" and carry the From and To states.
 type Event struct {
-	Type    string    `json:"type"`
-	NodeID  string    `json:"node_id"`
-	Message string    `json:"message"`
-	Time    time.Time `json:"time"`
+	Type    string          `json:"type"`
+	NodeID  string          `json:"node_id"`
+	From    model.NodeState `json:"from,omitempty"`
+	To      model.NodeState `json:"to,omitempty"`
+	Message string          `json:"message"`
+	Time    time.Time       `json:"time"`
 }
 
-// FleetController periodically checks node health and marks stale nodes as
-// unhealthy. It emits Events for each state change.
+// FleetController periodically checks node health, marking stale nodes as
+// unhealthy and recovered nodes as online. Every state change goes through
+// the model.NodeState transition table and emits an Event.
 type FleetController struct {
 	store          store.Store
 	checkInterval  time.Duration
 	unhealthyAfter time.Duration
-	events         []Event
+
+	mu     sync.Mutex
+	events []Event
+	subs   map[chan Event]struct{}
 }
 
+// subscriberBuffer is the capacity of each channel returned by Subscribe.
+const subscriberBuffer = 64
+
 // NewFleetController creates a FleetController with default timings.
 func NewFleetController(s store.Store) *FleetController {
 	return &FleetController{
@@ -47,18 +60,59 @@ func (fc *FleetController) Start(ctx context.Context) {
 			log.Println("fleet controller stopped")
 			return
 		case <-ticker.C:
-			fc.checkHealth()
+			fc.CheckHealth()
 		}
 	}
 }
 
-// Events returns accumulated events (thread-safe reads not required since
-// the controller is the sole writer and events are append-only).
+// Events returns a copy of the accumulated events.
 func (fc *FleetController) Events() []Event {
-	return fc.events
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return append([]Event(nil), fc.events...)
+}
+
+// Subscribe returns a channel that receives every Event emitted from now on,
+// and a function that cancels the subscription and closes the channel.
+// Delivery never blocks the controller: a subscriber that falls more than
+// subscriberBuffer events behind misses the overflow.
+func (fc *FleetController) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	fc.mu.Lock()
+	if fc.subs == nil {
+		fc.subs = make(map[chan Event]struct{})
+	}
+	fc.subs[ch] = struct{}{}
+	fc.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			fc.mu.Lock()
+			delete(fc.subs, ch)
+			fc.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
+
+// Transition moves the node with the given ID to state to, recording reason
+// in the emitted Event. It returns an error without modifying the node if the
+// transition is not allowed by the model.NodeState table.
+func (fc *FleetController) Transition(nodeID string, to model.NodeState, reason string) error {
+	n, err := fc.store.Nodes().Get(nodeID)
+	if err != nil {
+		return err
+	}
+	return fc.transition(n, to, reason, time.Now())
 }
 
-func (fc *FleetController) checkHealth() {
+// CheckHealth runs a single health pass over all nodes. Start calls it on
+// every tick. Online and degraded nodes whose last heartbeat is older than
+// their threshold (Node.HeartbeatTimeout, or the controller default) become
+// unhealthy; unhealthy nodes that have heartbeated again become online.
+func (fc *FleetController) CheckHealth() {
 	nodes, err := fc.store.Nodes().List()
 	if err != nil {
 		log.Printf("fleet controller: list nodes: %v", err)
@@ -67,23 +121,63 @@ func (fc *FleetController) checkHealth() {
 	now := time.Now()
 	for i := range nodes {
 		n := &nodes[i]
-		if n.Status == "unhealthy" {
-			continue
-		}
-		if now.Sub(n.LastSeen) > fc.unhealthyAfter {
-			n.Status = "unhealthy"
-			if err := fc.store.Nodes().Update(n); err != nil {
-				log.Printf("fleet controller: update node %s: %v", n.ID, err)
-				continue
+		stale := now.Sub(n.LastSeen) > fc.heartbeatTimeout(n)
+		switch model.NodeState(n.Status) {
+		case model.NodeStateOnline, model.NodeStateDegraded:
+			if stale {
+				reason := "last seen " + n.LastSeen.Format(time.RFC3339)
+				if err := fc.transition(n, model.NodeStateUnhealthy, reason, now); err != nil {
+					log.Printf("fleet controller: %v", err)
+				}
 			}
-			evt := Event{
-				Type:    "node_unhealthy",
-				NodeID:  n.ID,
-				Message: "node marked unhealthy: last seen " + n.LastSeen.Format(time.RFC3339),
-				Time:    now,
+		case model.NodeStateUnhealthy:
+			if !stale {
+				reason := "heartbeat resumed at " + n.LastSeen.Format(time.RFC3339)
+				if err := fc.transition(n, model.NodeStateOnline, reason, now); err != nil {
+					log.Printf("fleet controller: %v", err)
+				}
 			}
-			fc.events = append(fc.events, evt)
-			log.Printf("fleet controller: %s", evt.Message)
 		}
 	}
 }
+
+// heartbeatTimeout returns the staleness threshold for n: its own
+// HeartbeatTimeout if set, otherwise the controller default.
+func (fc *FleetController) heartbeatTimeout(n *model.Node) time.Duration {
+	if n.HeartbeatTimeout > 0 {
+		return n.HeartbeatTimeout
+	}
+	return fc.unhealthyAfter
+}
+
+// transition validates and persists a state change for n and emits an Event.
+func (fc *FleetController) transition(n *model.Node, to model.NodeState, reason string, now time.Time) error {
+	from := model.NodeState(n.Status)
+	if err := from.ValidateTransition(to); err != nil {
+		return fmt.Errorf("node %s: %w", n.ID, err)
+	}
+	n.Status = string(to)
+	if err := fc.store.Nodes().Update(n); err != nil {
+		n.Status = string(from)
+		return fmt.Errorf("update node %s: %w", n.ID, err)
+	}
+	evt := Event{
+		Type:    "node_" + string(to),
+		NodeID:  n.ID,
+		From:    from,
+		To:      to,
+		Message: fmt.Sprintf("node %s %s -> %s: %s", n.ID, from, to, reason),
+		Time:    now,
+	}
+	fc.mu.Lock()
+	fc.events = append(fc.events, evt)
+	for ch := range fc.subs {
+		select {
+		case ch <- evt:
+		default:
+		}
+	}
+	fc.mu.Unlock()
+	log.Printf("fleet controller: %s", evt.Message)
+	return nil
+}
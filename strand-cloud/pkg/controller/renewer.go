@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/strand-protocol/strand/strand-cloud/pkg/ca"
+	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
+	"github.com/strand-protocol/strand/strand-cloud/pkg/store"
+)
+
+// MICRenewer periodically re-signs MICs that are close to expiry so nodes do
+// not silently lose trust when their certificate's validity window ends.
+// Each renewal keeps the MIC's original validity length, restarting it from
+// the time of renewal, and is recorded in the audit log.
+type MICRenewer struct {
+	store         store.Store
+	ca            *ca.CA
+	checkInterval time.Duration
+	renewWindow   time.Duration
+}
+
+// NewMICRenewer creates a MICRenewer that renews MICs expiring within window.
+func NewMICRenewer(s store.Store, authority *ca.CA, window time.Duration) *MICRenewer {
+	return &MICRenewer{
+		store:         s,
+		ca:            authority,
+		checkInterval: time.Hour,
+		renewWindow:   window,
+	}
+}
+
+// Start runs the renewal loop until ctx is cancelled.
+func (mr *MICRenewer) Start(ctx context.Context) {
+	ticker := time.NewTicker(mr.checkInterval)
+	defer ticker.Stop()
+	log.Println("mic renewer started")
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("mic renewer stopped")
+			return
+		case <-ticker.C:
+			mr.Renew()
+		}
+	}
+}
+
+// Renew runs a single renewal pass and returns the number of MICs renewed.
+// Start calls it on every tick. Revoked MICs and MICs that have already
+// expired are left alone: an expired node has to be re-enrolled.
+func (mr *MICRenewer) Renew() int {
+	mics, err := mr.store.MICs().List()
+	if err != nil {
+		log.Printf("mic renewer: list mics: %v", err)
+		return 0
+	}
+	now := time.Now()
+	renewed := 0
+	for i := range mics {
+		mic := &mics[i]
+		if mic.Revoked || mr.ca.IsRevoked(mic.ID) {
+			continue
+		}
+		if !now.Before(mic.ValidUntil) || mic.ValidUntil.Sub(now) > mr.renewWindow {
+			continue
+		}
+		if err := mr.renew(mic, now); err != nil {
+			log.Printf("mic renewer: %v", err)
+			continue
+		}
+		renewed++
+	}
+	return renewed
+}
+
+// renew re-signs mic with a validity window starting at now and records the
+// renewal in the audit log.
+func (mr *MICRenewer) renew(mic *model.MIC, now time.Time) error {
+	oldUntil := mic.ValidUntil
+	validity := mic.ValidUntil.Sub(mic.ValidFrom)
+	if validity <= mr.renewWindow {
+		// A window no longer than the renewal threshold would be renewed
+		// again on the next pass; always move expiry past it.
+		validity = 2 * mr.renewWindow
+	}
+	mic.ValidFrom = now
+	mic.ValidUntil = now.Add(validity)
+	if err := mr.ca.IssueMIC(mic); err != nil {
+		return err
+	}
+	if err := mr.store.MICs().Update(mic); err != nil {
+		return err
+	}
+
+	entry := &model.AuditEntry{
+		ID:           newAuditID(),
+		ActorID:      "mic-renewer",
+		ActorType:    "controller",
+		Action:       "renew",
+		ResourceType: "mic",
+		ResourceID:   mic.ID,
+		Metadata: map[string]string{
+			"node_id":         mic.NodeID,
+			"old_valid_until": oldUntil.Format(time.RFC3339),
+			"new_valid_until": mic.ValidUntil.Format(time.RFC3339),
+		},
+		CreatedAt: now,
+	}
+	if err := mr.store.AuditLog().Append(entry); err != nil {
+		log.Printf("mic renewer: audit mic %s: %v", mic.ID, err)
+	}
+	log.Printf("mic renewer: renewed mic %s for node %s until %s", mic.ID, mic.NodeID, mic.ValidUntil.Format(time.RFC3339))
+	return nil
+}
+
+// newAuditID returns a random hex identifier for an audit entry.
+func newAuditID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
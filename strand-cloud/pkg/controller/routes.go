@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/strand-protocol/strand/strand-cloud/pkg/store"
+)
+
+// RouteJanitor periodically deletes routes whose TTL has run out. The API
+// server already hides expired routes, so the janitor only reclaims storage
+// and keeps route listings in the store short.
+type RouteJanitor struct {
+	store         store.Store
+	checkInterval time.Duration
+}
+
+// NewRouteJanitor creates a RouteJanitor that sweeps every interval.
+func NewRouteJanitor(s store.Store, interval time.Duration) *RouteJanitor {
+	return &RouteJanitor{store: s, checkInterval: interval}
+}
+
+// Start runs the sweep loop until ctx is cancelled.
+func (rj *RouteJanitor) Start(ctx context.Context) {
+	ticker := time.NewTicker(rj.checkInterval)
+	defer ticker.Stop()
+	log.Println("route janitor started")
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("route janitor stopped")
+			return
+		case <-ticker.C:
+			rj.Sweep()
+		}
+	}
+}
+
+// Sweep deletes every expired route and returns how many it deleted. Start
+// calls it on every tick.
+func (rj *RouteJanitor) Sweep() int {
+	routes, err := rj.store.Routes().List()
+	if err != nil {
+		log.Printf("route janitor: list routes: %v", err)
+		return 0
+	}
+	now := time.Now()
+	deleted := 0
+	for i := range routes {
+		r := &routes[i]
+		if !r.Expired(now) {
+			continue
+		}
+		if err := rj.store.Routes().Delete(r.ID); err != nil {
+			log.Printf("route janitor: delete route %s: %v", r.ID, err)
+			continue
+		}
+		log.Printf("route janitor: route %s expired at %s", r.ID, r.CreatedAt.Add(r.TTL).Format(time.RFC3339))
+		deleted++
+	}
+	return deleted
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Verify reads the image content from r to the end and checks its SHA-256
+// against Checksum, which is a hex digest with an optional "sha256:" prefix.
+// It returns an error naming both digests if they differ, or if Checksum is
+// not a SHA-256 digest, so a corrupt or truncated download is never flashed.
+func (f *FirmwareImage) Verify(r io.Reader) error {
+	want, err := f.sha256Checksum()
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return fmt.Errorf("firmware %s: read content: %w", f.ID, err)
+	}
+	got := h.Sum(nil)
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("firmware %s: checksum mismatch: got sha256:%x over %d bytes, want sha256:%x (%d bytes)",
+			f.ID, got, n, want, f.Size)
+	}
+	return nil
+}
+
+// sha256Checksum decodes Checksum as a SHA-256 digest.
+func (f *FirmwareImage) sha256Checksum() ([]byte, error) {
+	s := f.Checksum
+	if algo, digest, ok := strings.Cut(s, ":"); ok {
+		if !strings.EqualFold(algo, "sha256") {
+			return nil, fmt.Errorf("firmware %s: unsupported checksum algorithm %q", f.ID, algo)
+		}
+		s = digest
+	}
+	sum, err := hex.DecodeString(s)
+	if err != nil || len(sum) != sha256.Size {
+		return nil, fmt.Errorf("firmware %s: checksum %q is not a SHA-256 hex digest", f.ID, f.Checksum)
+	}
+	return sum, nil
+}
@@ -0,0 +1,66 @@
+package model
+
+import "fmt"
+
+// NodeState is the lifecycle state of a Node, stored in Node.Status.
+type NodeState string
+
+const (
+	// NodeStateOnline is a node that is heartbeating and serving traffic.
+	NodeStateOnline NodeState = "online"
+	// NodeStateDegraded is a node that is heartbeating but reporting reduced
+	// capacity.
+	NodeStateDegraded NodeState = "degraded"
+	// NodeStateUnhealthy is a node whose heartbeats have gone stale.
+	NodeStateUnhealthy NodeState = "unhealthy"
+	// NodeStateDraining is a node being taken out of service; it accepts no
+	// new traffic and may only move to offline.
+	NodeStateDraining NodeState = "draining"
+	// NodeStateOffline is a node that has been removed from service. It must
+	// come back through online.
+	NodeStateOffline NodeState = "offline"
+)
+
+// nodeTransitions lists the states reachable from each state. A draining node
+// has to go offline before it can return to online, so an in-progress drain
+// is never silently undone by a heartbeat.
+var nodeTransitions = map[NodeState][]NodeState{
+	NodeStateOnline:    {NodeStateDegraded, NodeStateUnhealthy, NodeStateDraining, NodeStateOffline},
+	NodeStateDegraded:  {NodeStateOnline, NodeStateUnhealthy, NodeStateDraining, NodeStateOffline},
+	NodeStateUnhealthy: {NodeStateOnline, NodeStateDegraded, NodeStateDraining, NodeStateOffline},
+	NodeStateDraining:  {NodeStateOffline, NodeStateUnhealthy},
+	NodeStateOffline:   {NodeStateOnline},
+}
+
+// Valid reports whether s is one of the defined node states.
+func (s NodeState) Valid() bool {
+	_, ok := nodeTransitions[s]
+	return ok
+}
+
+// CanTransition reports whether a node may move from state s to state to.
+// The empty state (a node that was never assigned one) may move to any valid
+// state.
+func (s NodeState) CanTransition(to NodeState) bool {
+	if !to.Valid() {
+		return false
+	}
+	if s == "" {
+		return true
+	}
+	for _, next := range nodeTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTransition returns an error describing why s cannot move to to, or
+// nil if the transition is allowed.
+func (s NodeState) ValidateTransition(to NodeState) error {
+	if !s.CanTransition(to) {
+		return fmt.Errorf("illegal node state transition %q -> %q", s, to)
+	}
+	return nil
+}
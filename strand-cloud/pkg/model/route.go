@@ -0,0 +1,104 @@
+package model
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Expired reports whether the route's TTL, counted from CreatedAt, has run
+// out at now. A route with no TTL never expires.
+func (r *Route) Expired(now time.Time) bool {
+	return r.TTL > 0 && !now.Before(r.CreatedAt.Add(r.TTL))
+}
+
+// SelectEndpoint picks one of the route's endpoints at random, with
+// probability proportional to its Weight. Non-positive weights count as
+// zero; if no endpoint has a positive weight the choice is uniform. It returns
+// nil for a route without endpoints. A nil rng uses the math/rand global
+// source.
+func (r *Route) SelectEndpoint(rng *rand.Rand) *Endpoint {
+	return selectWeighted(r.Endpoints, rng)
+}
+
+// HealthyEndpoints returns the endpoints whose node nodeStatus reports as
+// online. Filtering on live node state keeps traffic off a down node even
+// when the route object itself is stale.
+func (r *Route) HealthyEndpoints(nodeStatus func(nodeID string) string) []Endpoint {
+	var out []Endpoint
+	for _, ep := range r.Endpoints {
+		if NodeState(nodeStatus(ep.NodeID)) == NodeStateOnline {
+			out = append(out, ep)
+		}
+	}
+	return out
+}
+
+// SelectHealthyEndpoint is SelectEndpoint restricted to HealthyEndpoints. It
+// returns nil if no endpoint is on an online node. The returned Endpoint is a
+// copy, not a pointer into r.Endpoints.
+func (r *Route) SelectHealthyEndpoint(nodeStatus func(nodeID string) string, rng *rand.Rand) *Endpoint {
+	return selectWeighted(r.HealthyEndpoints(nodeStatus), rng)
+}
+
+// Normalize rescales endpoint weights in place so they sum to 1. Negative
+// weights are clamped to zero. If no endpoint has a positive weight, every
+// endpoint gets an equal share.
+func (r *Route) Normalize() {
+	n := len(r.Endpoints)
+	if n == 0 {
+		return
+	}
+	total := 0.0
+	for i := range r.Endpoints {
+		if r.Endpoints[i].Weight < 0 {
+			r.Endpoints[i].Weight = 0
+		}
+		total += r.Endpoints[i].Weight
+	}
+	for i := range r.Endpoints {
+		if total > 0 {
+			r.Endpoints[i].Weight /= total
+		} else {
+			r.Endpoints[i].Weight = 1 / float64(n)
+		}
+	}
+}
+
+// selectWeighted performs weighted random selection over eps.
+func selectWeighted(eps []Endpoint, rng *rand.Rand) *Endpoint {
+	if len(eps) == 0 {
+		return nil
+	}
+	float64n := rand.Float64
+	intn := rand.Intn
+	if rng != nil {
+		float64n = rng.Float64
+		intn = rng.Intn
+	}
+
+	total := 0.0
+	for i := range eps {
+		if eps[i].Weight > 0 {
+			total += eps[i].Weight
+		}
+	}
+	if total <= 0 {
+		return &eps[intn(len(eps))]
+	}
+
+	x := float64n() * total
+	last := -1
+	for i := range eps {
+		if eps[i].Weight <= 0 {
+			continue
+		}
+		last = i
+		x -= eps[i].Weight
+		if x < 0 {
+			return &eps[i]
+		}
+	}
+	// Floating-point rounding can leave x marginally non-negative after the
+	// last positive weight.
+	return &eps[last]
+}
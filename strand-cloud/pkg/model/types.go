@@ -12,6 +12,16 @@ type Node struct {
 	LastSeen        time.Time   `json:"last_seen"`
 	FirmwareVersion string      `json:"firmware_version"`
 	Metrics         NodeMetrics `json:"metrics"`
+	// HeartbeatTimeout overrides the fleet controller's unhealthy threshold
+	// for this node. Zero means use the controller default.
+	HeartbeatTimeout time.Duration `json:"heartbeat_timeout,omitempty"`
+	// Labels and Region are reported by the node agent at registration and
+	// may change with any heartbeat.
+	Labels map[string]string `json:"labels,omitempty"`
+	Region string            `json:"region,omitempty"`
+	// TenantID, when set, is the tenant the node counts against for
+	// Tenant.MaxNodes.
+	TenantID string `json:"tenant_id,omitempty"`
 }
 
 // NodeMetrics contains operational metrics for a node.
@@ -22,6 +32,22 @@ type NodeMetrics struct {
 	AvgLatency  time.Duration `json:"avg_latency"`
 }
 
+// NodeHeartbeat is the optional body of a node heartbeat. The metrics fields
+// sit at the top level, so a bare NodeMetrics body is also a valid heartbeat.
+// Labels and Region, when set, replace the node's current values.
+type NodeHeartbeat struct {
+	NodeMetrics
+	Labels map[string]string `json:"labels,omitempty"`
+	Region string            `json:"region,omitempty"`
+}
+
+// NodeMetricsSample is one timestamped NodeMetrics reading, as recorded from
+// a node heartbeat.
+type NodeMetricsSample struct {
+	Timestamp time.Time   `json:"timestamp"`
+	Metrics   NodeMetrics `json:"metrics"`
+}
+
 // Route represents a network route entry managed by the control plane.
 type Route struct {
 	ID        string        `json:"id"`
@@ -29,6 +55,9 @@ type Route struct {
 	Endpoints []Endpoint    `json:"endpoints"`
 	TTL       time.Duration `json:"ttl"`
 	CreatedAt time.Time     `json:"created_at"`
+	// TenantID, when set, is the tenant the route counts against for
+	// Tenant.MaxRoutes.
+	TenantID string `json:"tenant_id,omitempty"`
 }
 
 // Endpoint represents a single endpoint target within a route.
@@ -72,6 +101,7 @@ type Tenant struct {
 	Status           string            `json:"status"`
 	MaxClusters      int               `json:"max_clusters"`
 	MaxNodes         int               `json:"max_nodes"`
+	MaxRoutes        int               `json:"max_routes"`
 	MaxMICsMonth     int               `json:"max_mics_month"`
 	TrafficGBIncl    float64           `json:"traffic_gb_included"`
 	Metadata         map[string]string `json:"metadata,omitempty"`
@@ -95,7 +125,11 @@ type Cluster struct {
 
 // AuditEntry represents a single audit log event.
 type AuditEntry struct {
-	ID           string            `json:"id"`
+	ID string `json:"id"`
+	// Seq is assigned by the store when the entry is appended. It increases
+	// with every append, so it orders entries even when many share the same
+	// CreatedAt.
+	Seq          uint64            `json:"seq"`
 	TenantID     string            `json:"tenant_id"`
 	ActorID      string            `json:"actor_id"`
 	ActorType    string            `json:"actor_type"`
@@ -104,5 +138,11 @@ type AuditEntry struct {
 	ResourceID   string            `json:"resource_id"`
 	Metadata     map[string]string `json:"metadata,omitempty"`
 	IPAddress    string            `json:"ip_address,omitempty"`
-	CreatedAt    time.Time         `json:"created_at"`
+	// Outcome is AuditOutcomeDenied for rejected attempts; empty otherwise.
+	Outcome   string    `json:"outcome,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
 }
+
+// AuditOutcomeDenied marks an AuditEntry for a request that was refused
+// authentication or authorization.
+const AuditOutcomeDenied = "denied"
@@ -0,0 +1,28 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
+)
+
+// orderAuditEntries sorts entries most recent first and truncates them to
+// limit when limit > 0. Every AuditLogStore lists through it so backends
+// agree on order: by Seq, then by CreatedAt and ID for entries written
+// before sequence numbers were assigned.
+func orderAuditEntries(entries []model.AuditEntry, limit int) []model.AuditEntry {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := &entries[i], &entries[j]
+		if a.Seq != b.Seq {
+			return a.Seq > b.Seq
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries
+}
@@ -2,8 +2,11 @@ package store
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -11,21 +14,42 @@ import (
 	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
 )
 
-// Key-space constants. All Strand keys live under /strand/v1/ to avoid
-// collisions with other etcd tenants.
+// Key-space constants. By default all Strand keys live under /strand/v1/ to
+// avoid collisions with other etcd tenants; WithKeyPrefix moves them.
 const (
 	keyPrefix = "/strand/v1"
 	leaseTTL  = 30 // seconds — used for node heartbeat keys
 )
 
+// keyspace is the etcd key prefix a store's records live under, without a
+// trailing slash.
+type keyspace string
+
 // key builds a fully-qualified etcd key for the given store type and ID.
-func key(storeType, id string) string {
-	return fmt.Sprintf("%s/%s/%s", keyPrefix, storeType, id)
+func (ks keyspace) key(storeType, id string) string {
+	return fmt.Sprintf("%s/%s/%s", ks, storeType, id)
 }
 
 // prefix builds the etcd key prefix for listing all items of a store type.
-func prefix(storeType string) string {
-	return fmt.Sprintf("%s/%s/", keyPrefix, storeType)
+func (ks keyspace) prefix(storeType string) string {
+	return fmt.Sprintf("%s/%s/", ks, storeType)
+}
+
+// EtcdOption configures an EtcdStore.
+type EtcdOption func(*etcdConfig)
+
+type etcdConfig struct {
+	prefix string
+}
+
+// WithKeyPrefix stores every record under prefix instead of /strand/v1, so
+// several Strand deployments can share one etcd cluster. A trailing slash is
+// ignored. Deployments sharing a cluster must use prefixes that do not nest,
+// such as /prod/strand and /staging/strand.
+func WithKeyPrefix(prefix string) EtcdOption {
+	return func(c *etcdConfig) {
+		c.prefix = strings.TrimRight(prefix, "/")
+	}
 }
 
 // ---------------------------------------------------------------------------
@@ -38,6 +62,7 @@ func prefix(storeType string) string {
 // plane replicas are therefore safe.
 type EtcdStore struct {
 	client   *clientv3.Client
+	ks       keyspace
 	nodes    *EtcdNodeStore
 	routes   *EtcdRouteStore
 	mics     *EtcdMICStore
@@ -47,28 +72,76 @@ type EtcdStore struct {
 	auditLog *EtcdAuditLogStore
 }
 
-// NewEtcdStore dials the etcd cluster at endpoints and returns a ready
-// EtcdStore. The caller must call Close when finished.
-func NewEtcdStore(endpoints []string) (*EtcdStore, error) {
-	client, err := clientv3.New(clientv3.Config{
-		Endpoints:   endpoints,
-		DialTimeout: 5 * time.Second,
-	})
+// defaultDialTimeout bounds the initial connection to etcd when the caller's
+// config sets no DialTimeout.
+const defaultDialTimeout = 5 * time.Second
+
+// NewEtcdStore dials the etcd cluster at endpoints with no TLS or
+// credentials, as suits a local development cluster, and returns a ready
+// EtcdStore. Use NewEtcdStoreWithConfig for a secured cluster. The caller
+// must call Close when finished.
+func NewEtcdStore(endpoints []string, opts ...EtcdOption) (*EtcdStore, error) {
+	return NewEtcdStoreWithConfig(clientv3.Config{Endpoints: endpoints}, opts...)
+}
+
+// NewEtcdStoreWithConfig is like NewEtcdStore but dials with the given etcd
+// client configuration, so callers can set TLS, Username and Password and
+// any other client setting. A zero DialTimeout means 5 seconds.
+func NewEtcdStoreWithConfig(config clientv3.Config, opts ...EtcdOption) (*EtcdStore, error) {
+	cfg := etcdConfig{prefix: keyPrefix}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.prefix == "" {
+		return nil, fmt.Errorf("etcd key prefix must not be empty")
+	}
+	ks := keyspace(cfg.prefix)
+	if config.DialTimeout == 0 {
+		config.DialTimeout = defaultDialTimeout
+	}
+	client, err := clientv3.New(config)
 	if err != nil {
 		return nil, fmt.Errorf("etcd dial: %w", err)
 	}
 	return &EtcdStore{
 		client:   client,
-		nodes:    &EtcdNodeStore{client: client},
-		routes:   &EtcdRouteStore{client: client},
-		mics:     &EtcdMICStore{client: client},
-		firmware: &EtcdFirmwareStore{client: client},
-		tenants:  &EtcdTenantStore{client: client},
-		clusters: &EtcdClusterStore{client: client},
-		auditLog: &EtcdAuditLogStore{client: client},
+		ks:       ks,
+		nodes:    &EtcdNodeStore{client: client, ks: ks},
+		routes:   &EtcdRouteStore{client: client, ks: ks},
+		mics:     &EtcdMICStore{client: client, ks: ks},
+		firmware: &EtcdFirmwareStore{client: client, ks: ks},
+		tenants:  &EtcdTenantStore{client: client, ks: ks},
+		clusters: &EtcdClusterStore{client: client, ks: ks},
+		auditLog: &EtcdAuditLogStore{client: client, ks: ks},
 	}, nil
 }
 
+// LoadEtcdTLS builds a client TLS configuration for NewEtcdStoreWithConfig
+// from PEM files: caFile, if set, replaces the system roots for verifying the
+// etcd servers, and certFile and keyFile, if set, are the client certificate
+// presented to clusters that require one.
+func LoadEtcdTLS(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read etcd CA: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("etcd CA %s: no certificates found", caFile)
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load etcd client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
 // Nodes returns the NodeStore sub-store.
 func (s *EtcdStore) Nodes() NodeStore { return s.nodes }
 
@@ -90,6 +163,10 @@ func (s *EtcdStore) Clusters() ClusterStore { return s.clusters }
 // AuditLog returns the AuditLogStore sub-store.
 func (s *EtcdStore) AuditLog() AuditLogStore { return s.auditLog }
 
+// Flush returns nil: every EtcdStore write is a synchronous Put or Txn that
+// etcd has committed by the time it returns, so nothing is pending.
+func (s *EtcdStore) Flush(context.Context) error { return nil }
+
 // Close releases the underlying etcd client connection.
 func (s *EtcdStore) Close() error {
 	return s.client.Close()
@@ -99,9 +176,10 @@ func (s *EtcdStore) Close() error {
 // helpers
 // ---------------------------------------------------------------------------
 
-// etcdPut serialises v as JSON and writes it to the given key.
+// etcdPut serialises v as JSON, stamped with the current schema version, and
+// writes it to the given key.
 func etcdPut(ctx context.Context, client *clientv3.Client, k string, v any) error {
-	data, err := json.Marshal(v)
+	data, err := defaultMigrations.encode(v)
 	if err != nil {
 		return fmt.Errorf("marshal: %w", err)
 	}
@@ -111,9 +189,10 @@ func etcdPut(ctx context.Context, client *clientv3.Client, k string, v any) erro
 	return nil
 }
 
-// etcdGet retrieves the value at key k and deserialises it into v.
-// Returns (false, nil) if the key does not exist.
-func etcdGet(ctx context.Context, client *clientv3.Client, k string, v any) (bool, error) {
+// etcdGet retrieves the value at key k, upgrades it to the current schema
+// version and deserialises it into v. Returns (false, nil) if the key does
+// not exist.
+func etcdGet(ctx context.Context, client *clientv3.Client, ks keyspace, k string, v any) (bool, error) {
 	resp, err := client.Get(ctx, k)
 	if err != nil {
 		return false, fmt.Errorf("etcd get %q: %w", k, err)
@@ -121,15 +200,15 @@ func etcdGet(ctx context.Context, client *clientv3.Client, k string, v any) (boo
 	if len(resp.Kvs) == 0 {
 		return false, nil
 	}
-	if err := json.Unmarshal(resp.Kvs[0].Value, v); err != nil {
+	if err := defaultMigrations.decode(ks.resourceOfKey(k), resp.Kvs[0].Value, v); err != nil {
 		return false, fmt.Errorf("unmarshal %q: %w", k, err)
 	}
 	return true, nil
 }
 
-// etcdList retrieves all key-value pairs with the given prefix and appends
-// decoded objects to *out. T must be a pointer type.
-func etcdList[T any](ctx context.Context, client *clientv3.Client, pfx string) ([]T, error) {
+// etcdList retrieves all key-value pairs with the given prefix and returns
+// them decoded, after upgrading each to the current schema version.
+func etcdList[T any](ctx context.Context, client *clientv3.Client, ks keyspace, pfx string) ([]T, error) {
 	resp, err := client.Get(ctx, pfx, clientv3.WithPrefix())
 	if err != nil {
 		return nil, fmt.Errorf("etcd list %q: %w", pfx, err)
@@ -137,7 +216,7 @@ func etcdList[T any](ctx context.Context, client *clientv3.Client, pfx string) (
 	out := make([]T, 0, len(resp.Kvs))
 	for _, kv := range resp.Kvs {
 		var item T
-		if err := json.Unmarshal(kv.Value, &item); err != nil {
+		if err := defaultMigrations.decode(ks.resourceOfKey(string(kv.Key)), kv.Value, &item); err != nil {
 			return nil, fmt.Errorf("unmarshal %q: %w", string(kv.Key), err)
 		}
 		out = append(out, item)
@@ -148,7 +227,7 @@ func etcdList[T any](ctx context.Context, client *clientv3.Client, pfx string) (
 // etcdCreateIfNotExists atomically writes value v at key k only if k does not
 // already exist. Returns ErrAlreadyExists if the key is present.
 func etcdCreateIfNotExists(ctx context.Context, client *clientv3.Client, k string, v any) error {
-	data, err := json.Marshal(v)
+	data, err := defaultMigrations.encode(v)
 	if err != nil {
 		return fmt.Errorf("marshal: %w", err)
 	}
@@ -186,20 +265,24 @@ func background() context.Context {
 // EtcdNodeStore
 // ---------------------------------------------------------------------------
 
-// EtcdNodeStore implements NodeStore against etcd.
+// EtcdNodeStore implements NodeStore against etcd. Metrics history is kept in
+// process memory rather than etcd, so each API server holds the samples from
+// the heartbeats it received.
 type EtcdNodeStore struct {
-	client *clientv3.Client
+	client  *clientv3.Client
+	ks      keyspace
+	history metricsHistory
 }
 
 // List returns all Node records stored in etcd.
 func (s *EtcdNodeStore) List() ([]model.Node, error) {
-	return etcdList[model.Node](background(), s.client, prefix("nodes"))
+	return etcdList[model.Node](background(), s.client, s.ks, s.ks.prefix("nodes"))
 }
 
 // Get returns the Node with the given ID, or an error if not found.
 func (s *EtcdNodeStore) Get(id string) (*model.Node, error) {
 	var n model.Node
-	found, err := etcdGet(background(), s.client, key("nodes", id), &n)
+	found, err := etcdGet(background(), s.client, s.ks, s.ks.key("nodes", id), &n)
 	if err != nil {
 		return nil, err
 	}
@@ -212,7 +295,7 @@ func (s *EtcdNodeStore) Get(id string) (*model.Node, error) {
 // Create writes a new Node record. Returns an error if one already exists with
 // the same ID.
 func (s *EtcdNodeStore) Create(node *model.Node) error {
-	if err := etcdCreateIfNotExists(background(), s.client, key("nodes", node.ID), node); err != nil {
+	if err := etcdCreateIfNotExists(background(), s.client, s.ks.key("nodes", node.ID), node); err != nil {
 		return fmt.Errorf("node %q already exists", node.ID)
 	}
 	return nil
@@ -225,17 +308,36 @@ func (s *EtcdNodeStore) Update(node *model.Node) error {
 	if err != nil {
 		return err
 	}
-	return etcdPut(background(), s.client, key("nodes", node.ID), node)
+	return etcdPut(background(), s.client, s.ks.key("nodes", node.ID), node)
 }
 
 // Delete removes the Node record with the given ID.
 func (s *EtcdNodeStore) Delete(id string) error {
-	if err := etcdDelete(background(), s.client, key("nodes", id)); err != nil {
+	if err := etcdDelete(background(), s.client, s.ks.key("nodes", id)); err != nil {
 		return fmt.Errorf("node %q not found", id)
 	}
+	s.history.remove(id)
 	return nil
 }
 
+// AppendMetrics records a metrics sample for an existing node.
+func (s *EtcdNodeStore) AppendMetrics(id string, sample model.NodeMetricsSample) error {
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+	s.history.append(id, sample)
+	return nil
+}
+
+// MetricsHistory returns the node's samples taken at or after since, oldest
+// first.
+func (s *EtcdNodeStore) MetricsHistory(id string, since time.Time) ([]model.NodeMetricsSample, error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, err
+	}
+	return s.history.since(id, since), nil
+}
+
 // ---------------------------------------------------------------------------
 // EtcdRouteStore
 // ---------------------------------------------------------------------------
@@ -243,17 +345,18 @@ func (s *EtcdNodeStore) Delete(id string) error {
 // EtcdRouteStore implements RouteStore against etcd.
 type EtcdRouteStore struct {
 	client *clientv3.Client
+	ks     keyspace
 }
 
 // List returns all Route records stored in etcd.
 func (s *EtcdRouteStore) List() ([]model.Route, error) {
-	return etcdList[model.Route](background(), s.client, prefix("routes"))
+	return etcdList[model.Route](background(), s.client, s.ks, s.ks.prefix("routes"))
 }
 
 // Get returns the Route with the given ID, or an error if not found.
 func (s *EtcdRouteStore) Get(id string) (*model.Route, error) {
 	var r model.Route
-	found, err := etcdGet(background(), s.client, key("routes", id), &r)
+	found, err := etcdGet(background(), s.client, s.ks, s.ks.key("routes", id), &r)
 	if err != nil {
 		return nil, err
 	}
@@ -266,7 +369,7 @@ func (s *EtcdRouteStore) Get(id string) (*model.Route, error) {
 // Create writes a new Route record. Returns an error if one already exists
 // with the same ID.
 func (s *EtcdRouteStore) Create(route *model.Route) error {
-	if err := etcdCreateIfNotExists(background(), s.client, key("routes", route.ID), route); err != nil {
+	if err := etcdCreateIfNotExists(background(), s.client, s.ks.key("routes", route.ID), route); err != nil {
 		return fmt.Errorf("route %q already exists", route.ID)
 	}
 	return nil
@@ -278,12 +381,12 @@ func (s *EtcdRouteStore) Update(route *model.Route) error {
 	if err != nil {
 		return err
 	}
-	return etcdPut(background(), s.client, key("routes", route.ID), route)
+	return etcdPut(background(), s.client, s.ks.key("routes", route.ID), route)
 }
 
 // Delete removes the Route record with the given ID.
 func (s *EtcdRouteStore) Delete(id string) error {
-	if err := etcdDelete(background(), s.client, key("routes", id)); err != nil {
+	if err := etcdDelete(background(), s.client, s.ks.key("routes", id)); err != nil {
 		return fmt.Errorf("route %q not found", id)
 	}
 	return nil
@@ -296,17 +399,18 @@ func (s *EtcdRouteStore) Delete(id string) error {
 // EtcdMICStore implements MICStore against etcd.
 type EtcdMICStore struct {
 	client *clientv3.Client
+	ks     keyspace
 }
 
 // List returns all MIC records stored in etcd.
 func (s *EtcdMICStore) List() ([]model.MIC, error) {
-	return etcdList[model.MIC](background(), s.client, prefix("mics"))
+	return etcdList[model.MIC](background(), s.client, s.ks, s.ks.prefix("mics"))
 }
 
 // Get returns the MIC with the given ID, or an error if not found.
 func (s *EtcdMICStore) Get(id string) (*model.MIC, error) {
 	var m model.MIC
-	found, err := etcdGet(background(), s.client, key("mics", id), &m)
+	found, err := etcdGet(background(), s.client, s.ks, s.ks.key("mics", id), &m)
 	if err != nil {
 		return nil, err
 	}
@@ -319,7 +423,7 @@ func (s *EtcdMICStore) Get(id string) (*model.MIC, error) {
 // Create writes a new MIC record. Returns an error if one already exists with
 // the same ID.
 func (s *EtcdMICStore) Create(mic *model.MIC) error {
-	if err := etcdCreateIfNotExists(background(), s.client, key("mics", mic.ID), mic); err != nil {
+	if err := etcdCreateIfNotExists(background(), s.client, s.ks.key("mics", mic.ID), mic); err != nil {
 		return fmt.Errorf("mic %q already exists", mic.ID)
 	}
 	return nil
@@ -331,12 +435,12 @@ func (s *EtcdMICStore) Update(mic *model.MIC) error {
 	if err != nil {
 		return err
 	}
-	return etcdPut(background(), s.client, key("mics", mic.ID), mic)
+	return etcdPut(background(), s.client, s.ks.key("mics", mic.ID), mic)
 }
 
 // Delete removes the MIC record with the given ID.
 func (s *EtcdMICStore) Delete(id string) error {
-	if err := etcdDelete(background(), s.client, key("mics", id)); err != nil {
+	if err := etcdDelete(background(), s.client, s.ks.key("mics", id)); err != nil {
 		return fmt.Errorf("mic %q not found", id)
 	}
 	return nil
@@ -350,7 +454,7 @@ func (s *EtcdMICStore) Revoke(id string) error {
 		return err
 	}
 	m.Revoked = true
-	return etcdPut(background(), s.client, key("mics", id), m)
+	return etcdPut(background(), s.client, s.ks.key("mics", id), m)
 }
 
 // ---------------------------------------------------------------------------
@@ -360,17 +464,18 @@ func (s *EtcdMICStore) Revoke(id string) error {
 // EtcdFirmwareStore implements FirmwareStore against etcd.
 type EtcdFirmwareStore struct {
 	client *clientv3.Client
+	ks     keyspace
 }
 
 // List returns all FirmwareImage records stored in etcd.
 func (s *EtcdFirmwareStore) List() ([]model.FirmwareImage, error) {
-	return etcdList[model.FirmwareImage](background(), s.client, prefix("firmware"))
+	return etcdList[model.FirmwareImage](background(), s.client, s.ks, s.ks.prefix("firmware"))
 }
 
 // Get returns the FirmwareImage with the given ID, or an error if not found.
 func (s *EtcdFirmwareStore) Get(id string) (*model.FirmwareImage, error) {
 	var f model.FirmwareImage
-	found, err := etcdGet(background(), s.client, key("firmware", id), &f)
+	found, err := etcdGet(background(), s.client, s.ks, s.ks.key("firmware", id), &f)
 	if err != nil {
 		return nil, err
 	}
@@ -383,7 +488,7 @@ func (s *EtcdFirmwareStore) Get(id string) (*model.FirmwareImage, error) {
 // Create writes a new FirmwareImage record. Returns an error if one already
 // exists with the same ID.
 func (s *EtcdFirmwareStore) Create(fw *model.FirmwareImage) error {
-	if err := etcdCreateIfNotExists(background(), s.client, key("firmware", fw.ID), fw); err != nil {
+	if err := etcdCreateIfNotExists(background(), s.client, s.ks.key("firmware", fw.ID), fw); err != nil {
 		return fmt.Errorf("firmware %q already exists", fw.ID)
 	}
 	return nil
@@ -395,12 +500,12 @@ func (s *EtcdFirmwareStore) Update(fw *model.FirmwareImage) error {
 	if err != nil {
 		return err
 	}
-	return etcdPut(background(), s.client, key("firmware", fw.ID), fw)
+	return etcdPut(background(), s.client, s.ks.key("firmware", fw.ID), fw)
 }
 
 // Delete removes the FirmwareImage record with the given ID.
 func (s *EtcdFirmwareStore) Delete(id string) error {
-	if err := etcdDelete(background(), s.client, key("firmware", id)); err != nil {
+	if err := etcdDelete(background(), s.client, s.ks.key("firmware", id)); err != nil {
 		return fmt.Errorf("firmware %q not found", id)
 	}
 	return nil
@@ -413,15 +518,16 @@ func (s *EtcdFirmwareStore) Delete(id string) error {
 // EtcdTenantStore implements TenantStore against etcd.
 type EtcdTenantStore struct {
 	client *clientv3.Client
+	ks     keyspace
 }
 
 func (s *EtcdTenantStore) List() ([]model.Tenant, error) {
-	return etcdList[model.Tenant](background(), s.client, prefix("tenants"))
+	return etcdList[model.Tenant](background(), s.client, s.ks, s.ks.prefix("tenants"))
 }
 
 func (s *EtcdTenantStore) Get(id string) (*model.Tenant, error) {
 	var t model.Tenant
-	found, err := etcdGet(background(), s.client, key("tenants", id), &t)
+	found, err := etcdGet(background(), s.client, s.ks, s.ks.key("tenants", id), &t)
 	if err != nil {
 		return nil, err
 	}
@@ -446,7 +552,7 @@ func (s *EtcdTenantStore) GetBySlug(slug string) (*model.Tenant, error) {
 }
 
 func (s *EtcdTenantStore) Create(tenant *model.Tenant) error {
-	if err := etcdCreateIfNotExists(background(), s.client, key("tenants", tenant.ID), tenant); err != nil {
+	if err := etcdCreateIfNotExists(background(), s.client, s.ks.key("tenants", tenant.ID), tenant); err != nil {
 		return fmt.Errorf("tenant %q already exists", tenant.ID)
 	}
 	return nil
@@ -457,11 +563,11 @@ func (s *EtcdTenantStore) Update(tenant *model.Tenant) error {
 	if err != nil {
 		return err
 	}
-	return etcdPut(background(), s.client, key("tenants", tenant.ID), tenant)
+	return etcdPut(background(), s.client, s.ks.key("tenants", tenant.ID), tenant)
 }
 
 func (s *EtcdTenantStore) Delete(id string) error {
-	if err := etcdDelete(background(), s.client, key("tenants", id)); err != nil {
+	if err := etcdDelete(background(), s.client, s.ks.key("tenants", id)); err != nil {
 		return fmt.Errorf("tenant %q not found", id)
 	}
 	return nil
@@ -474,10 +580,11 @@ func (s *EtcdTenantStore) Delete(id string) error {
 // EtcdClusterStore implements ClusterStore against etcd.
 type EtcdClusterStore struct {
 	client *clientv3.Client
+	ks     keyspace
 }
 
 func (s *EtcdClusterStore) List(tenantID string) ([]model.Cluster, error) {
-	all, err := etcdList[model.Cluster](background(), s.client, prefix("clusters"))
+	all, err := etcdList[model.Cluster](background(), s.client, s.ks, s.ks.prefix("clusters"))
 	if err != nil {
 		return nil, err
 	}
@@ -495,7 +602,7 @@ func (s *EtcdClusterStore) List(tenantID string) ([]model.Cluster, error) {
 
 func (s *EtcdClusterStore) Get(id string) (*model.Cluster, error) {
 	var c model.Cluster
-	found, err := etcdGet(background(), s.client, key("clusters", id), &c)
+	found, err := etcdGet(background(), s.client, s.ks, s.ks.key("clusters", id), &c)
 	if err != nil {
 		return nil, err
 	}
@@ -506,7 +613,7 @@ func (s *EtcdClusterStore) Get(id string) (*model.Cluster, error) {
 }
 
 func (s *EtcdClusterStore) Create(cluster *model.Cluster) error {
-	if err := etcdCreateIfNotExists(background(), s.client, key("clusters", cluster.ID), cluster); err != nil {
+	if err := etcdCreateIfNotExists(background(), s.client, s.ks.key("clusters", cluster.ID), cluster); err != nil {
 		return fmt.Errorf("cluster %q already exists", cluster.ID)
 	}
 	return nil
@@ -517,11 +624,11 @@ func (s *EtcdClusterStore) Update(cluster *model.Cluster) error {
 	if err != nil {
 		return err
 	}
-	return etcdPut(background(), s.client, key("clusters", cluster.ID), cluster)
+	return etcdPut(background(), s.client, s.ks.key("clusters", cluster.ID), cluster)
 }
 
 func (s *EtcdClusterStore) Delete(id string) error {
-	if err := etcdDelete(background(), s.client, key("clusters", id)); err != nil {
+	if err := etcdDelete(background(), s.client, s.ks.key("clusters", id)); err != nil {
 		return fmt.Errorf("cluster %q not found", id)
 	}
 	return nil
@@ -531,34 +638,73 @@ func (s *EtcdClusterStore) Delete(id string) error {
 // EtcdAuditLogStore
 // ---------------------------------------------------------------------------
 
-// EtcdAuditLogStore implements AuditLogStore against etcd. Audit entries are
-// stored with monotonically increasing keys so that listing returns them in
-// insertion order.
+// EtcdAuditLogStore implements AuditLogStore against etcd. Each entry takes
+// the next value of a shared counter key as its Seq, in the same transaction
+// that writes it, so sequence numbers are unique and increasing across all
+// control plane replicas.
 type EtcdAuditLogStore struct {
 	client *clientv3.Client
+	ks     keyspace
+}
+
+// auditSeqKey holds the last assigned AuditEntry.Seq. It sits outside the
+// audit/ prefix so listing does not pick it up.
+func (ks keyspace) auditSeqKey() string {
+	return string(ks) + "/audit-seq"
+}
+
+// auditKey is the etcd key of an audit entry: a compound tenant/seq/id key for
+// efficient per-tenant listing.
+func (ks keyspace) auditKey(e *model.AuditEntry) string {
+	return fmt.Sprintf("%s/audit/%s/%020d/%s", ks, e.TenantID, e.Seq, e.ID)
 }
 
 func (s *EtcdAuditLogStore) Append(entry *model.AuditEntry) error {
-	// Use a compound key: tenant/timestamp/id for efficient per-tenant listing.
-	k := fmt.Sprintf("%s/audit/%s/%s/%s", keyPrefix, entry.TenantID, entry.CreatedAt.Format(time.RFC3339Nano), entry.ID)
-	return etcdPut(background(), s.client, k, entry)
+	ctx := background()
+	seqKey := s.ks.auditSeqKey()
+	for {
+		resp, err := s.client.Get(ctx, seqKey)
+		if err != nil {
+			return fmt.Errorf("etcd get %q: %w", seqKey, err)
+		}
+		var last uint64
+		var rev int64
+		if len(resp.Kvs) > 0 {
+			rev = resp.Kvs[0].ModRevision
+			if _, err := fmt.Sscan(string(resp.Kvs[0].Value), &last); err != nil {
+				return fmt.Errorf("parse %q: %w", seqKey, err)
+			}
+		}
+		e := *entry
+		e.Seq = last + 1
+		data, err := defaultMigrations.encode(&e)
+		if err != nil {
+			return fmt.Errorf("marshal: %w", err)
+		}
+		k := s.ks.auditKey(&e)
+		txn, err := s.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(seqKey), "=", rev)).
+			Then(clientv3.OpPut(seqKey, fmt.Sprint(e.Seq)), clientv3.OpPut(k, string(data))).
+			Commit()
+		if err != nil {
+			return fmt.Errorf("etcd txn append %q: %w", k, err)
+		}
+		if txn.Succeeded {
+			entry.Seq = e.Seq
+			return nil
+		}
+		// Another replica appended first; retry with the new counter.
+	}
 }
 
 func (s *EtcdAuditLogStore) List(tenantID string, limit int) ([]model.AuditEntry, error) {
-	pfx := fmt.Sprintf("%s/audit/", keyPrefix)
+	pfx := s.ks.prefix("audit")
 	if tenantID != "" {
-		pfx = fmt.Sprintf("%s/audit/%s/", keyPrefix, tenantID)
+		pfx = s.ks.key("audit", tenantID) + "/"
 	}
-	all, err := etcdList[model.AuditEntry](background(), s.client, pfx)
+	all, err := etcdList[model.AuditEntry](background(), s.client, s.ks, pfx)
 	if err != nil {
 		return nil, err
 	}
-	// Reverse for most-recent-first.
-	for i, j := 0, len(all)-1; i < j; i, j = i+1, j-1 {
-		all[i], all[j] = all[j], all[i]
-	}
-	if limit > 0 && len(all) > limit {
-		all = all[:limit]
-	}
-	return all, nil
+	return orderAuditEntries(all, limit), nil
 }
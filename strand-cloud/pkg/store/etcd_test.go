@@ -1,11 +1,17 @@
 package store
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"slices"
+	"sort"
 	"strings"
 	"testing"
 	"time"
 
+	clientv3 "go.etcd.io/etcd/client/v3"
+
 	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
 )
 
@@ -30,6 +36,105 @@ func TestEtcdStore(t *testing.T) {
 	t.Run("Routes", func(t *testing.T) { testRouteStore(t, s.Routes()) })
 	t.Run("MICs", func(t *testing.T) { testMICStore(t, s.MICs()) })
 	t.Run("Firmware", func(t *testing.T) { testFirmwareStore(t, s.Firmware()) })
+	t.Run("Watch", func(t *testing.T) { testWatch(t, s) })
+	t.Run("AuditOrder", func(t *testing.T) { testAuditOrder(t, s.AuditLog()) })
+}
+
+// TestEtcdStoreTLS is an integration test against a TLS-secured etcd
+// cluster. It requires the cluster's endpoints and the PEM files to reach it:
+//
+//	STRAND_TEST_ETCD_TLS=https://localhost:2379 STRAND_TEST_ETCD_CA=ca.pem \
+//	STRAND_TEST_ETCD_CERT=client.pem STRAND_TEST_ETCD_KEY=client-key.pem \
+//	go test ./pkg/store/...
+//
+// STRAND_TEST_ETCD_USER and STRAND_TEST_ETCD_PASSWORD add credentials for a
+// cluster with auth enabled.
+func TestEtcdStoreTLS(t *testing.T) {
+	addr := os.Getenv("STRAND_TEST_ETCD_TLS")
+	if addr == "" {
+		t.Skip("set STRAND_TEST_ETCD_TLS and STRAND_TEST_ETCD_{CA,CERT,KEY} to run the TLS etcd integration test")
+	}
+	tlsConfig, err := LoadEtcdTLS(os.Getenv("STRAND_TEST_ETCD_CA"), os.Getenv("STRAND_TEST_ETCD_CERT"), os.Getenv("STRAND_TEST_ETCD_KEY"))
+	if err != nil {
+		t.Fatalf("LoadEtcdTLS: %v", err)
+	}
+	s, err := NewEtcdStoreWithConfig(clientv3.Config{
+		Endpoints: strings.Split(addr, ","),
+		TLS:       tlsConfig,
+		Username:  os.Getenv("STRAND_TEST_ETCD_USER"),
+		Password:  os.Getenv("STRAND_TEST_ETCD_PASSWORD"),
+	})
+	if err != nil {
+		t.Fatalf("NewEtcdStoreWithConfig: %v", err)
+	}
+	defer s.Close()
+
+	node := &model.Node{ID: "etcd-tls-node-" + uniqueSuffix(), Address: "10.0.0.1:6477", Status: "online"}
+	if err := s.Nodes().Create(node); err != nil {
+		t.Fatalf("Create over TLS: %v", err)
+	}
+	defer s.Nodes().Delete(node.ID)
+	got, err := s.Nodes().Get(node.ID)
+	if err != nil {
+		t.Fatalf("Get over TLS: %v", err)
+	}
+	if got.Address != node.Address || got.Status != node.Status {
+		t.Errorf("round-tripped node = %+v, want %+v", got, node)
+	}
+}
+
+// TestEtcdKeyPrefix checks that stores opened with different WithKeyPrefix
+// values share an etcd cluster without seeing each other's records. It
+// requires a running etcd cluster, like TestEtcdStore.
+func TestEtcdKeyPrefix(t *testing.T) {
+	addr := os.Getenv("STRAND_TEST_ETCD")
+	if addr == "" {
+		t.Skip("set STRAND_TEST_ETCD=http://localhost:2379 to run etcd integration tests")
+	}
+	endpoints := strings.Split(addr, ",")
+	suffix := uniqueSuffix()
+	open := func(prefix string) *EtcdStore {
+		t.Helper()
+		s, err := NewEtcdStore(endpoints, WithKeyPrefix(prefix))
+		if err != nil {
+			t.Fatalf("NewEtcdStore(%q): %v", prefix, err)
+		}
+		t.Cleanup(func() {
+			s.client.Delete(background(), string(s.ks)+"/", clientv3.WithPrefix())
+			s.Close()
+		})
+		return s
+	}
+	a := open("/strand-test-a-" + suffix + "/")
+	b := open("/strand-test-b-" + suffix)
+
+	node := &model.Node{ID: "shared-id", Address: "10.0.0.1:6477", Status: "online"}
+	if err := a.Nodes().Create(node); err != nil {
+		t.Fatalf("a: Create: %v", err)
+	}
+	if _, err := b.Nodes().Get(node.ID); err == nil {
+		t.Error("b sees a's node")
+	}
+	// The same ID is free in the other deployment.
+	if err := b.Nodes().Create(&model.Node{ID: node.ID, Address: "10.0.0.2:6477", Status: "online"}); err != nil {
+		t.Fatalf("b: Create with a's node ID: %v", err)
+	}
+	for name, s := range map[string]*EtcdStore{"a": a, "b": b} {
+		nodes, err := s.Nodes().List()
+		if err != nil || len(nodes) != 1 {
+			t.Errorf("%s: List = %v, %v, want only its own node", name, nodes, err)
+		}
+	}
+
+	if err := a.AuditLog().Append(&model.AuditEntry{ID: "audit-1", TenantID: "t"}); err != nil {
+		t.Fatalf("a: Append: %v", err)
+	}
+	if entries, err := b.AuditLog().List("", 0); err != nil || len(entries) != 0 {
+		t.Errorf("b: audit List = %v, %v, want none of a's entries", entries, err)
+	}
+	if n, err := b.MigrateRecords(background()); err != nil || n != 0 {
+		t.Errorf("b: MigrateRecords = %d, %v, want nothing to migrate", n, err)
+	}
 }
 
 // ---------------------------------------------------------------------------
@@ -306,3 +411,160 @@ func testFirmwareStore(t *testing.T, fs FirmwareStore) {
 func uniqueSuffix() string {
 	return time.Now().Format("20060102T150405.000")
 }
+
+// ---------------------------------------------------------------------------
+// Watch tests
+// ---------------------------------------------------------------------------
+
+func testWatch(t *testing.T, s Store) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := s.Watch(ctx, ResourceNodes)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	id := "etcd-watch-node-" + uniqueSuffix()
+	next := func(want ChangeType) ChangeEvent {
+		t.Helper()
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case ev, ok := <-events:
+				if !ok {
+					t.Fatalf("watch channel closed, want %s event", want)
+				}
+				if ev.ID != id {
+					continue // change made by another client
+				}
+				if ev.Type != want {
+					t.Fatalf("event type = %s, want %s", ev.Type, want)
+				}
+				return ev
+			case <-timeout:
+				t.Fatalf("timed out waiting for %s event", want)
+			}
+		}
+	}
+
+	ns := s.Nodes()
+	if err := ns.Create(&model.Node{ID: id, Status: "active"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	next(ChangeCreated)
+
+	if err := ns.Update(&model.Node{ID: id, Status: "draining"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	ev := next(ChangeUpdated)
+	if n, ok := ev.Object.(model.Node); !ok || n.Status != "draining" {
+		t.Errorf("updated object = %#v, want node with status draining", ev.Object)
+	}
+
+	if err := ns.Delete(id); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	ev = next(ChangeDeleted)
+	if n, ok := ev.Object.(model.Node); !ok || n.ID != id {
+		t.Errorf("deleted object = %#v, want previous value of %s", ev.Object, id)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Audit log ordering
+// ---------------------------------------------------------------------------
+
+// appendBurst appends n entries spread over three tenants, all sharing one
+// CreatedAt as a burst of writes within the clock resolution would.
+func appendBurst(t *testing.T, as AuditLogStore, run string, n int) {
+	t.Helper()
+	now := time.Now().UTC()
+	for i := 0; i < n; i++ {
+		e := &model.AuditEntry{
+			// IDs deliberately do not sort in append order.
+			ID:        fmt.Sprintf("%s-%03d", run, (i*37)%n),
+			TenantID:  fmt.Sprintf("%s-tenant-%d", run, i%3),
+			Action:    "create",
+			CreatedAt: now,
+		}
+		if err := as.Append(e); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		if e.Seq == 0 {
+			t.Fatal("Append did not assign Seq")
+		}
+	}
+}
+
+func auditIDs(entries []model.AuditEntry) []string {
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+// TestAuditLogOrderingAcrossBackends verifies that the memory store and the
+// etcd store's listing order entries identically by sequence number. Without
+// etcd, the etcd side is simulated by feeding List's post-processing the
+// entries in etcd's key order.
+func TestAuditLogOrderingAcrossBackends(t *testing.T) {
+	const n = 60
+	mem := NewMemoryStore().AuditLog()
+	appendBurst(t, mem, "burst", n)
+
+	all, err := mem.List("", 0)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(all) != n {
+		t.Fatalf("List returned %d entries, want %d", len(all), n)
+	}
+	if !sort.SliceIsSorted(all, func(i, j int) bool { return all[i].Seq > all[j].Seq }) {
+		t.Fatalf("memory List not in descending Seq order: %v", auditIDs(all))
+	}
+
+	// A prefix scan returns entries in key order: grouped by tenant, then
+	// ascending Seq.
+	scanned := slices.Clone(all)
+	ks := keyspace(keyPrefix)
+	sort.Slice(scanned, func(i, j int) bool { return ks.auditKey(&scanned[i]) < ks.auditKey(&scanned[j]) })
+	for _, tc := range []struct {
+		tenant string
+		limit  int
+	}{{"", 0}, {"", 7}, {"burst-tenant-1", 0}, {"burst-tenant-2", 5}} {
+		want, _ := mem.List(tc.tenant, tc.limit)
+		var fromScan []model.AuditEntry
+		for _, e := range scanned {
+			if tc.tenant == "" || e.TenantID == tc.tenant {
+				fromScan = append(fromScan, e)
+			}
+		}
+		got := orderAuditEntries(fromScan, tc.limit)
+		if !slices.Equal(auditIDs(got), auditIDs(want)) {
+			t.Errorf("List(%q, %d): etcd order %v, memory order %v", tc.tenant, tc.limit, auditIDs(got), auditIDs(want))
+		}
+	}
+}
+
+func testAuditOrder(t *testing.T, as AuditLogStore) {
+	t.Helper()
+	run := "etcd-audit-" + uniqueSuffix()
+	const n = 30
+	mem := NewMemoryStore().AuditLog()
+	appendBurst(t, mem, run, n)
+	appendBurst(t, as, run, n)
+
+	for _, tenant := range []string{run + "-tenant-0", run + "-tenant-1", run + "-tenant-2"} {
+		want, _ := mem.List(tenant, 0)
+		got, err := as.List(tenant, 0)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if !slices.Equal(auditIDs(got), auditIDs(want)) {
+			t.Errorf("List(%q): etcd order %v, memory order %v", tenant, auditIDs(got), auditIDs(want))
+		}
+	}
+}
@@ -3,15 +3,27 @@
 // etcd-backed store (for production).
 package store
 
-import "github.com/strand-protocol/strand/strand-cloud/pkg/model"
+import (
+	"context"
+	"time"
 
-// NodeStore provides CRUD operations for Node records.
+	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
+)
+
+// NodeStore provides CRUD operations for Node records, plus a bounded history
+// of recent metrics samples per node.
 type NodeStore interface {
 	List() ([]model.Node, error)
 	Get(id string) (*model.Node, error)
 	Create(node *model.Node) error
 	Update(node *model.Node) error
 	Delete(id string) error
+	// AppendMetrics records a metrics sample for an existing node, evicting
+	// the oldest sample once MetricsHistorySize are held.
+	AppendMetrics(id string, sample model.NodeMetricsSample) error
+	// MetricsHistory returns the node's samples taken at or after since,
+	// oldest first. A zero since returns the whole history.
+	MetricsHistory(id string, since time.Time) ([]model.NodeMetricsSample, error)
 }
 
 // RouteStore provides CRUD operations for Route records.
@@ -76,4 +88,15 @@ type Store interface {
 	Tenants() TenantStore
 	Clusters() ClusterStore
 	AuditLog() AuditLogStore
+
+	// Watch streams create/update/delete events for records of resourceType
+	// (one of the Resource* constants) until ctx is cancelled, at which point
+	// the returned channel is closed. Only changes made after Watch returns
+	// are reported.
+	Watch(ctx context.Context, resourceType string) (<-chan ChangeEvent, error)
+
+	// Flush persists any writes the store has buffered, such as batched
+	// audit entries, returning once they are durable or ctx is done. Stores
+	// that write synchronously return nil immediately.
+	Flush(ctx context.Context) error
 }
@@ -1,8 +1,10 @@
 package store
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
 )
@@ -41,13 +43,17 @@ func (m *MemoryStore) Tenants() TenantStore     { return m.tenants }
 func (m *MemoryStore) Clusters() ClusterStore   { return m.clusters }
 func (m *MemoryStore) AuditLog() AuditLogStore  { return m.auditLog }
 
+// Flush is a no-op: the memory store applies every write immediately.
+func (m *MemoryStore) Flush(context.Context) error { return nil }
+
 // ---------------------------------------------------------------------------
 // Node store
 // ---------------------------------------------------------------------------
 
 type memoryNodeStore struct {
-	mu   sync.RWMutex
-	data map[string]model.Node
+	mu      sync.RWMutex
+	data    map[string]model.Node
+	history metricsHistory
 }
 
 func (s *memoryNodeStore) List() ([]model.Node, error) {
@@ -97,9 +103,29 @@ func (s *memoryNodeStore) Delete(id string) error {
 		return fmt.Errorf("node %q not found", id)
 	}
 	delete(s.data, id)
+	s.history.remove(id)
 	return nil
 }
 
+func (s *memoryNodeStore) AppendMetrics(id string, sample model.NodeMetricsSample) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, exists := s.data[id]; !exists {
+		return fmt.Errorf("node %q not found", id)
+	}
+	s.history.append(id, sample)
+	return nil
+}
+
+func (s *memoryNodeStore) MetricsHistory(id string, since time.Time) ([]model.NodeMetricsSample, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, exists := s.data[id]; !exists {
+		return nil, fmt.Errorf("node %q not found", id)
+	}
+	return s.history.since(id, since), nil
+}
+
 // ---------------------------------------------------------------------------
 // Route store
 // ---------------------------------------------------------------------------
@@ -439,11 +465,14 @@ func (s *memoryClusterStore) Delete(id string) error {
 type memoryAuditLogStore struct {
 	mu      sync.RWMutex
 	entries []model.AuditEntry
+	seq     uint64 // last assigned AuditEntry.Seq
 }
 
 func (s *memoryAuditLogStore) Append(entry *model.AuditEntry) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.seq++
+	entry.Seq = s.seq
 	s.entries = append(s.entries, *entry)
 	return nil
 }
@@ -452,14 +481,10 @@ func (s *memoryAuditLogStore) List(tenantID string, limit int) ([]model.AuditEnt
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	out := make([]model.AuditEntry, 0)
-	// Walk backwards for most-recent-first ordering.
-	for i := len(s.entries) - 1; i >= 0; i-- {
-		if tenantID == "" || s.entries[i].TenantID == tenantID {
-			out = append(out, s.entries[i])
-			if limit > 0 && len(out) >= limit {
-				break
-			}
+	for _, e := range s.entries {
+		if tenantID == "" || e.TenantID == tenantID {
+			out = append(out, e)
 		}
 	}
-	return out, nil
+	return orderAuditEntries(out, limit), nil
 }
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"sync"
+	"time"
+
+	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
+)
+
+// MetricsHistorySize is the number of metrics samples kept per node; at a 10s
+// heartbeat interval it covers the last hour.
+const MetricsHistorySize = 360
+
+// metricsRing is a fixed-capacity ring buffer of metrics samples.
+type metricsRing struct {
+	buf   []model.NodeMetricsSample
+	start int // index of the oldest sample
+	n     int
+}
+
+func (r *metricsRing) push(s model.NodeMetricsSample) {
+	if r.buf == nil {
+		r.buf = make([]model.NodeMetricsSample, MetricsHistorySize)
+	}
+	if r.n < len(r.buf) {
+		r.buf[(r.start+r.n)%len(r.buf)] = s
+		r.n++
+		return
+	}
+	r.buf[r.start] = s
+	r.start = (r.start + 1) % len(r.buf)
+}
+
+// since returns a copy of the samples taken at or after t, oldest first.
+func (r *metricsRing) since(t time.Time) []model.NodeMetricsSample {
+	out := make([]model.NodeMetricsSample, 0, r.n)
+	for i := 0; i < r.n; i++ {
+		s := r.buf[(r.start+i)%len(r.buf)]
+		if !s.Timestamp.Before(t) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// metricsHistory holds the per-node rings. It is process-local: history is
+// for graphing recent behaviour and is not persisted or replicated.
+type metricsHistory struct {
+	mu    sync.Mutex
+	rings map[string]*metricsRing
+}
+
+func (h *metricsHistory) append(id string, s model.NodeMetricsSample) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.rings == nil {
+		h.rings = make(map[string]*metricsRing)
+	}
+	r, ok := h.rings[id]
+	if !ok {
+		r = &metricsRing{}
+		h.rings[id] = r
+	}
+	r.push(s)
+}
+
+func (h *metricsHistory) since(id string, t time.Time) []model.NodeMetricsSample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r, ok := h.rings[id]
+	if !ok {
+		return []model.NodeMetricsSample{}
+	}
+	return r.since(t)
+}
+
+func (h *metricsHistory) remove(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.rings, id)
+}
@@ -0,0 +1,201 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// schemaVersionField is the JSON field every serialised record carries its
+// schema version in. Records written before versioning existed have none and
+// are treated as version 1.
+const schemaVersionField = "schema_version"
+
+// MigrationFunc upgrades one serialised record in place. resourceType is the
+// record's key-space segment (one of the Resource* constants, or "audit"), so
+// a migration that changes one model can leave the others untouched.
+type MigrationFunc func(resourceType string, record map[string]any) error
+
+// migrationStep is a registered migration from one schema version.
+type migrationStep struct {
+	to int
+	fn MigrationFunc
+}
+
+// migrations is a registry of schema migrations keyed by source version.
+type migrations struct {
+	mu    sync.RWMutex
+	steps map[int]migrationStep
+}
+
+// defaultMigrations holds the migrations registered with Migrate.
+var defaultMigrations migrations
+
+// Migrate registers fn as the upgrade of records from schema version from to
+// version to. Migrations chain: the current schema version is reached by
+// applying the migration registered for version 1, then the one for its
+// target, and so on. Register migrations at startup, before the store is
+// used; records are then upgraded when read, and EtcdStore.MigrateRecords
+// rewrites them all eagerly. Migrate panics if from is below 1, to is not
+// above from, or a migration from the same version is already registered.
+func Migrate(from, to int, fn MigrationFunc) {
+	defaultMigrations.register(from, to, fn)
+}
+
+// SchemaVersion returns the schema version new records are written with:
+// 1 plus however far the registered migrations reach.
+func SchemaVersion() int {
+	return defaultMigrations.version()
+}
+
+func (m *migrations) register(from, to int, fn MigrationFunc) {
+	if from < 1 || to <= from || fn == nil {
+		panic(fmt.Sprintf("store: invalid migration %d -> %d", from, to))
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.steps == nil {
+		m.steps = make(map[int]migrationStep)
+	}
+	if _, dup := m.steps[from]; dup {
+		panic(fmt.Sprintf("store: duplicate migration from version %d", from))
+	}
+	m.steps[from] = migrationStep{to: to, fn: fn}
+}
+
+func (m *migrations) version() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v := 1
+	for {
+		step, ok := m.steps[v]
+		if !ok {
+			return v
+		}
+		v = step.to
+	}
+}
+
+// encode serialises v as a JSON object stamped with the current schema
+// version.
+func (m *migrations) encode(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return stampVersion(data, m.version())
+}
+
+// decode unmarshals a stored record of resourceType into v, first upgrading
+// it if it was written at an older schema version. Records from a newer
+// version are decoded as they are.
+func (m *migrations) decode(resourceType string, data []byte, v any) error {
+	data, _, err := m.upgrade(resourceType, data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// upgrade applies the migrations between data's schema version and the
+// current one, reporting whether any ran.
+func (m *migrations) upgrade(resourceType string, data []byte) ([]byte, bool, error) {
+	var hdr struct {
+		SchemaVersion int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &hdr); err != nil {
+		return nil, false, err
+	}
+	from := max(hdr.SchemaVersion, 1)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.steps[from]; !ok {
+		return data, false, nil
+	}
+	var record map[string]any
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, false, err
+	}
+	v := from
+	for {
+		step, ok := m.steps[v]
+		if !ok {
+			break
+		}
+		if err := step.fn(resourceType, record); err != nil {
+			return nil, false, fmt.Errorf("migrate %s record from version %d to %d: %w", resourceType, v, step.to, err)
+		}
+		v = step.to
+	}
+	record[schemaVersionField] = v
+	out, err := json.Marshal(record)
+	if err != nil {
+		return nil, false, err
+	}
+	return out, true, nil
+}
+
+// stampVersion sets the schema version field of the JSON object data.
+func stampVersion(data []byte, version int) ([]byte, error) {
+	if len(data) < 2 || data[0] != '{' {
+		return nil, fmt.Errorf("record is not a JSON object")
+	}
+	body := bytes.TrimSpace(data[1 : len(data)-1])
+	out := fmt.Appendf(nil, `{"%s":%d`, schemaVersionField, version)
+	if len(body) > 0 {
+		out = append(out, ',')
+		out = append(out, body...)
+	}
+	return append(out, '}'), nil
+}
+
+// resourceOfKey returns the key-space segment of a fully-qualified etcd key,
+// e.g. "nodes" for /strand/v1/nodes/n1.
+func (ks keyspace) resourceOfKey(k string) string {
+	rest := strings.TrimPrefix(k, string(ks)+"/")
+	rt, _, _ := strings.Cut(rest, "/")
+	return rt
+}
+
+// MigrateRecords rewrites every stored record that predates the current
+// schema version, returning how many were upgraded. Each rewrite is
+// conditional on the record not having changed since it was read, so it is
+// safe to run while the control plane is serving; a record modified
+// concurrently is left for the next read or run to upgrade.
+func (s *EtcdStore) MigrateRecords(ctx context.Context) (int, error) {
+	resp, err := s.client.Get(ctx, string(s.ks)+"/", clientv3.WithPrefix())
+	if err != nil {
+		return 0, fmt.Errorf("etcd list %q: %w", s.ks, err)
+	}
+	n := 0
+	for _, kv := range resp.Kvs {
+		k := string(kv.Key)
+		if k == s.ks.auditSeqKey() {
+			continue
+		}
+		data, changed, err := defaultMigrations.upgrade(s.ks.resourceOfKey(k), kv.Value)
+		if err != nil {
+			return n, fmt.Errorf("%q: %w", k, err)
+		}
+		if !changed {
+			continue
+		}
+		txn, err := s.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(k), "=", kv.ModRevision)).
+			Then(clientv3.OpPut(k, string(data))).
+			Commit()
+		if err != nil {
+			return n, fmt.Errorf("etcd txn migrate %q: %w", k, err)
+		}
+		if txn.Succeeded {
+			n++
+		}
+	}
+	return n, nil
+}
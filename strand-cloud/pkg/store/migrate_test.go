@@ -0,0 +1,81 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
+)
+
+func TestMigrationUpgradesOnRead(t *testing.T) {
+	var m migrations
+	// v2 renamed the node field "addr" to "address".
+	m.register(1, 2, func(rt string, rec map[string]any) error {
+		if rt == ResourceNodes {
+			rec["address"] = rec["addr"]
+			delete(rec, "addr")
+		}
+		return nil
+	})
+	if got := m.version(); got != 2 {
+		t.Fatalf("version = %d, want 2", got)
+	}
+
+	v1 := []byte(`{"id":"n1","addr":"10.0.0.1:6477","status":"online"}`)
+	var n model.Node
+	if err := m.decode(ResourceNodes, v1, &n); err != nil {
+		t.Fatalf("decode v1 record: %v", err)
+	}
+	if n.ID != "n1" || n.Address != "10.0.0.1:6477" || n.Status != "online" {
+		t.Errorf("decoded v1 record = %+v, want address migrated", n)
+	}
+
+	// The node migration leaves other resource types' records alone.
+	var r map[string]any
+	if err := m.decode(ResourceRoutes, []byte(`{"id":"r1","addr":"x"}`), &r); err != nil {
+		t.Fatalf("decode v1 route: %v", err)
+	}
+	if r["addr"] != "x" {
+		t.Errorf("route record changed by node migration: %v", r)
+	}
+
+	// Records written now carry v2 and are not migrated again.
+	data, err := m.encode(&n)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	var hdr map[string]any
+	json.Unmarshal(data, &hdr)
+	if hdr[schemaVersionField] != float64(2) {
+		t.Errorf("encoded record schema version = %v, want 2", hdr[schemaVersionField])
+	}
+	if _, changed, err := m.upgrade(ResourceNodes, data); err != nil || changed {
+		t.Errorf("upgrade of a current record: changed=%v err=%v", changed, err)
+	}
+}
+
+func TestMigrationChain(t *testing.T) {
+	var m migrations
+	m.register(1, 2, func(_ string, rec map[string]any) error {
+		rec["status"] = "v2"
+		return nil
+	})
+	m.register(2, 3, func(_ string, rec map[string]any) error {
+		rec["status"] = rec["status"].(string) + "+v3"
+		return nil
+	})
+
+	var n model.Node
+	if err := m.decode(ResourceNodes, []byte(`{"id":"n1"}`), &n); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if n.Status != "v2+v3" {
+		t.Errorf("status = %q, want both migrations applied in order", n.Status)
+	}
+	if err := m.decode(ResourceNodes, []byte(`{"schema_version":2,"id":"n1","status":"x"}`), &n); err != nil {
+		t.Fatalf("decode v2: %v", err)
+	}
+	if n.Status != "x+v3" {
+		t.Errorf("status = %q, want only the v2 -> v3 migration applied", n.Status)
+	}
+}
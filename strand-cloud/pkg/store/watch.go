@@ -0,0 +1,290 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
+)
+
+// Resource types accepted by Store.Watch. They match the key-space segments
+// used by the etcd backend.
+const (
+	ResourceNodes    = "nodes"
+	ResourceRoutes   = "routes"
+	ResourceMICs     = "mics"
+	ResourceFirmware = "firmware"
+	ResourceTenants  = "tenants"
+	ResourceClusters = "clusters"
+)
+
+// ChangeType describes what happened to a watched record.
+type ChangeType string
+
+const (
+	ChangeCreated ChangeType = "created"
+	ChangeUpdated ChangeType = "updated"
+	ChangeDeleted ChangeType = "deleted"
+)
+
+// ChangeEvent is delivered by Store.Watch for every create, update, or delete
+// of a record of the watched resource type.
+type ChangeEvent struct {
+	Type         ChangeType `json:"type"`
+	ResourceType string     `json:"resource_type"`
+	ID           string     `json:"id"`
+	// Object is the record after the change (model.Node, model.Route, ...),
+	// or its last known state for ChangeDeleted. It may be nil for deletes
+	// when the backend cannot recover the previous value.
+	Object any `json:"object,omitempty"`
+}
+
+// watchBuffer is the capacity of the channel returned by Watch.
+const watchBuffer = 64
+
+// memoryWatchInterval is how often the memory store polls for changes.
+const memoryWatchInterval = 100 * time.Millisecond
+
+// validResourceType reports whether rt can be watched.
+func validResourceType(rt string) bool {
+	switch rt {
+	case ResourceNodes, ResourceRoutes, ResourceMICs, ResourceFirmware, ResourceTenants, ResourceClusters:
+		return true
+	}
+	return false
+}
+
+// decodeResource unmarshals a JSON record of the given resource type into its
+// model type, upgrading it to the current schema version first.
+func decodeResource(rt string, data []byte) (any, error) {
+	var (
+		v   any
+		err error
+	)
+	data, _, err = defaultMigrations.upgrade(rt, data)
+	if err != nil {
+		return nil, err
+	}
+	switch rt {
+	case ResourceNodes:
+		var n model.Node
+		err = json.Unmarshal(data, &n)
+		v = n
+	case ResourceRoutes:
+		var r model.Route
+		err = json.Unmarshal(data, &r)
+		v = r
+	case ResourceMICs:
+		var m model.MIC
+		err = json.Unmarshal(data, &m)
+		v = m
+	case ResourceFirmware:
+		var f model.FirmwareImage
+		err = json.Unmarshal(data, &f)
+		v = f
+	case ResourceTenants:
+		var t model.Tenant
+		err = json.Unmarshal(data, &t)
+		v = t
+	case ResourceClusters:
+		var c model.Cluster
+		err = json.Unmarshal(data, &c)
+		v = c
+	default:
+		return nil, fmt.Errorf("unknown resource type %q", rt)
+	}
+	return v, err
+}
+
+// ---------------------------------------------------------------------------
+// MemoryStore
+// ---------------------------------------------------------------------------
+
+// memoryRecord is a point-in-time copy of one record used for diffing.
+type memoryRecord struct {
+	obj  any
+	data []byte
+}
+
+// Watch streams changes to records of resourceType until ctx is cancelled.
+// The memory backend polls and diffs the sub-store, so rapid successive
+// changes to the same record between polls are coalesced into one event.
+func (m *MemoryStore) Watch(ctx context.Context, resourceType string) (<-chan ChangeEvent, error) {
+	if !validResourceType(resourceType) {
+		return nil, fmt.Errorf("unknown resource type %q", resourceType)
+	}
+
+	prev, err := m.records(resourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan ChangeEvent, watchBuffer)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(memoryWatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			cur, err := m.records(resourceType)
+			if err != nil {
+				continue
+			}
+			for _, ev := range diffRecords(resourceType, prev, cur) {
+				select {
+				case ch <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+			prev = cur
+		}
+	}()
+	return ch, nil
+}
+
+// records returns a copy of every record of the given type keyed by ID.
+func (m *MemoryStore) records(rt string) (map[string]memoryRecord, error) {
+	out := make(map[string]memoryRecord)
+	add := func(id string, v any) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		out[id] = memoryRecord{obj: v, data: data}
+		return nil
+	}
+
+	var err error
+	switch rt {
+	case ResourceNodes:
+		m.nodes.mu.RLock()
+		for id, v := range m.nodes.data {
+			if err = add(id, v); err != nil {
+				break
+			}
+		}
+		m.nodes.mu.RUnlock()
+	case ResourceRoutes:
+		m.routes.mu.RLock()
+		for id, v := range m.routes.data {
+			if err = add(id, v); err != nil {
+				break
+			}
+		}
+		m.routes.mu.RUnlock()
+	case ResourceMICs:
+		m.mics.mu.RLock()
+		for id, v := range m.mics.data {
+			if err = add(id, v); err != nil {
+				break
+			}
+		}
+		m.mics.mu.RUnlock()
+	case ResourceFirmware:
+		m.firmware.mu.RLock()
+		for id, v := range m.firmware.data {
+			if err = add(id, v); err != nil {
+				break
+			}
+		}
+		m.firmware.mu.RUnlock()
+	case ResourceTenants:
+		m.tenants.mu.RLock()
+		for id, v := range m.tenants.data {
+			if err = add(id, v); err != nil {
+				break
+			}
+		}
+		m.tenants.mu.RUnlock()
+	case ResourceClusters:
+		m.clusters.mu.RLock()
+		for id, v := range m.clusters.data {
+			if err = add(id, v); err != nil {
+				break
+			}
+		}
+		m.clusters.mu.RUnlock()
+	}
+	return out, err
+}
+
+// diffRecords compares two record sets and returns the resulting events.
+func diffRecords(rt string, prev, cur map[string]memoryRecord) []ChangeEvent {
+	var events []ChangeEvent
+	for id, c := range cur {
+		p, existed := prev[id]
+		switch {
+		case !existed:
+			events = append(events, ChangeEvent{Type: ChangeCreated, ResourceType: rt, ID: id, Object: c.obj})
+		case string(p.data) != string(c.data):
+			events = append(events, ChangeEvent{Type: ChangeUpdated, ResourceType: rt, ID: id, Object: c.obj})
+		}
+	}
+	for id, p := range prev {
+		if _, still := cur[id]; !still {
+			events = append(events, ChangeEvent{Type: ChangeDeleted, ResourceType: rt, ID: id, Object: p.obj})
+		}
+	}
+	return events
+}
+
+// ---------------------------------------------------------------------------
+// EtcdStore
+// ---------------------------------------------------------------------------
+
+// Watch streams changes to records of resourceType until ctx is cancelled,
+// using an etcd watch on the resource's key prefix. Deletes carry the
+// previous value when etcd still has it.
+func (s *EtcdStore) Watch(ctx context.Context, resourceType string) (<-chan ChangeEvent, error) {
+	if !validResourceType(resourceType) {
+		return nil, fmt.Errorf("unknown resource type %q", resourceType)
+	}
+
+	pfx := s.ks.prefix(resourceType)
+	wch := s.client.Watch(clientv3.WithRequireLeader(ctx), pfx, clientv3.WithPrefix(), clientv3.WithPrevKV())
+
+	ch := make(chan ChangeEvent, watchBuffer)
+	go func() {
+		defer close(ch)
+		for resp := range wch {
+			if resp.Err() != nil {
+				return
+			}
+			for _, ev := range resp.Events {
+				ce := ChangeEvent{
+					ResourceType: resourceType,
+					ID:           strings.TrimPrefix(string(ev.Kv.Key), pfx),
+				}
+				switch {
+				case ev.Type == clientv3.EventTypeDelete:
+					ce.Type = ChangeDeleted
+					if ev.PrevKv != nil {
+						ce.Object, _ = decodeResource(resourceType, ev.PrevKv.Value)
+					}
+				case ev.IsCreate():
+					ce.Type = ChangeCreated
+					ce.Object, _ = decodeResource(resourceType, ev.Kv.Value)
+				default:
+					ce.Type = ChangeUpdated
+					ce.Object, _ = decodeResource(resourceType, ev.Kv.Value)
+				}
+				select {
+				case ch <- ce:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
@@ -0,0 +1,228 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/strand-protocol/strand/strand-cloud/pkg/agent"
+	"github.com/strand-protocol/strand/strand-cloud/pkg/apiserver"
+	"github.com/strand-protocol/strand/strand-cloud/pkg/ca"
+	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
+	"github.com/strand-protocol/strand/strand-cloud/pkg/store"
+)
+
+// reservePort returns a loopback address that is free at the time of the
+// call, for starting a control plane after the agent.
+func reservePort(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+// startControlPlaneAt serves a fresh in-memory control plane on addr.
+func startControlPlaneAt(t *testing.T, addr string) *httptest.Server {
+	t.Helper()
+	authority := ca.NewCA(ca.NewMemoryKeyStore())
+	if err := authority.GenerateCA(); err != nil {
+		t.Fatalf("generate CA: %v", err)
+	}
+	srv := apiserver.NewServer(store.NewMemoryStore(), authority, apiserver.DefaultServerOptions())
+	ts := httptest.NewUnstartedServer(srv.Handler())
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("listen %s: %v", addr, err)
+	}
+	ts.Listener.Close()
+	ts.Listener = ln
+	ts.Start()
+	return ts
+}
+
+// nodeRegistered reports whether the control plane at baseURL knows nodeID.
+func nodeRegistered(baseURL, nodeID string) bool {
+	resp, err := http.Get(baseURL + "/api/v1/nodes/" + nodeID)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+func TestAgentRegisterWithRetry(t *testing.T) {
+	addr := reservePort(t)
+	ag := agent.NewNodeAgent("node-late-cp", "http://"+addr)
+	ag.RetryMin, ag.RetryMax = 10*time.Millisecond, 50*time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- ag.RegisterWithRetry(ctx, "10.0.0.7:6477") }()
+
+	// The control plane comes up only after the agent has started retrying.
+	time.Sleep(150 * time.Millisecond)
+	ts := startControlPlaneAt(t, addr)
+	defer ts.Close()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("RegisterWithRetry: %v", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("agent did not register once the control plane came up")
+	}
+	if !nodeRegistered(ts.URL, "node-late-cp") {
+		t.Error("node not found on the control plane after RegisterWithRetry")
+	}
+}
+
+func TestAgentRegisterWithRetryGivesUpOnContext(t *testing.T) {
+	ag := agent.NewNodeAgent("node-no-cp", "http://"+reservePort(t))
+	ag.RetryMin, ag.RetryMax = 10*time.Millisecond, 20*time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := ag.RegisterWithRetry(ctx, "10.0.0.8:6477"); err == nil {
+		t.Fatal("RegisterWithRetry succeeded without a control plane")
+	}
+}
+
+func TestAgentHeartbeatLoopReregisters(t *testing.T) {
+	addr := reservePort(t)
+	first := startControlPlaneAt(t, addr)
+	ag := agent.NewNodeAgent("node-restart", first.URL)
+	ag.RetryMin, ag.RetryMax = 10*time.Millisecond, 50*time.Millisecond
+	if err := ag.Register("10.0.0.9:6477"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go agent.StartHeartbeatLoop(ctx, ag, 20*time.Millisecond)
+
+	// Restart the control plane with an empty store: heartbeats fail until
+	// the agent registers again.
+	first.Close()
+	time.Sleep(60 * time.Millisecond)
+	second := startControlPlaneAt(t, addr)
+	defer second.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !nodeRegistered(second.URL, "node-restart") {
+		if time.Now().After(deadline) {
+			t.Fatal("agent did not re-register with the restarted control plane")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestAgentRegisterWithRetryStopsOnClientError(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+	ag := agent.NewNodeAgent("node-bad-addr", ts.URL)
+	ag.RetryMin, ag.RetryMax = time.Hour, time.Hour // a retry would hang the test
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ag.RegisterWithRetry(ctx, "not-a-host-port"); err == nil {
+		t.Fatal("RegisterWithRetry accepted an invalid address")
+	}
+	if ctx.Err() != nil {
+		t.Fatal("RegisterWithRetry retried a 400 response instead of returning it")
+	}
+}
+
+// fetchNode reads a node as an admin would; token may be empty.
+func fetchNode(t *testing.T, baseURL, token, nodeID string) (model.Node, bool) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, baseURL+"/api/v1/nodes/"+nodeID, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return model.Node{}, false
+	}
+	defer resp.Body.Close()
+	var n model.Node
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&n) != nil {
+		return model.Node{}, false
+	}
+	return n, true
+}
+
+func TestAgentUpdateConfigAppliesOnNextHeartbeat(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	ag := agent.NewNodeAgentWithConfig("node-reload", ts.URL, agent.Config{
+		Labels: map[string]string{"tier": "gpu"},
+		Region: "eu-west-1",
+	})
+	if err := ag.Register("10.0.0.8:6477"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if n, ok := fetchNode(t, ts.URL, "", "node-reload"); !ok || n.Labels["tier"] != "gpu" || n.Region != "eu-west-1" {
+		t.Fatalf("registered node = %+v, want initial labels and region", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go agent.StartHeartbeatLoop(ctx, ag, time.Hour)
+
+	// The hour-long interval only ends because UpdateConfig shortens it; the
+	// labels must then arrive without a new registration.
+	ag.UpdateConfig(agent.Config{
+		Labels:            map[string]string{"tier": "cpu", "rack": "r12"},
+		Region:            "us-east-2",
+		HeartbeatInterval: 20 * time.Millisecond,
+	})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		n, ok := fetchNode(t, ts.URL, "", "node-reload")
+		if ok && n.Labels["tier"] == "cpu" && n.Labels["rack"] == "r12" && n.Region == "us-east-2" {
+			if n.Address != "10.0.0.8:6477" {
+				t.Errorf("Address = %q, want it unchanged by the heartbeat", n.Address)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("node = %+v, want updated labels and region after a heartbeat", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := ag.Config().HeartbeatInterval; got != 20*time.Millisecond {
+		t.Errorf("Config().HeartbeatInterval = %s, want 20ms", got)
+	}
+}
+
+func TestAgentConfigAPIKey(t *testing.T) {
+	ts := newAuthTestServer(t)
+	defer ts.Close()
+
+	ag := agent.NewNodeAgent("node-auth", ts.URL)
+	if err := ag.Register("10.0.0.9:6477"); err == nil {
+		t.Fatal("Register without credentials succeeded, want 401")
+	}
+
+	ag.UpdateConfig(agent.Config{APIKey: "operator-token", Region: "ap-south-1"})
+	if err := ag.Register("10.0.0.9:6477"); err != nil {
+		t.Fatalf("Register with API key: %v", err)
+	}
+	if err := ag.Heartbeat(); err != nil {
+		t.Fatalf("Heartbeat with API key: %v", err)
+	}
+	if n, ok := fetchNode(t, ts.URL, "admin-token", "node-auth"); !ok || n.Region != "ap-south-1" {
+		t.Errorf("node = %+v, want region ap-south-1", n)
+	}
+}
@@ -1,15 +1,24 @@
 package tests
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/strand-protocol/strand/strand-cloud/pkg/apiserver"
 	"github.com/strand-protocol/strand/strand-cloud/pkg/ca"
+	"github.com/strand-protocol/strand/strand-cloud/pkg/controller"
 	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
 	"github.com/strand-protocol/strand/strand-cloud/pkg/store"
 )
@@ -28,6 +37,22 @@ func newTestServer(t *testing.T) *httptest.Server {
 	return httptest.NewServer(srv.Handler())
 }
 
+// newTestServerWithOptions is like newTestServer but lets the caller adjust
+// the default ServerOptions first.
+func newTestServerWithOptions(t *testing.T, configure func(*apiserver.ServerOptions)) *httptest.Server {
+	t.Helper()
+	s := store.NewMemoryStore()
+	ks := ca.NewMemoryKeyStore()
+	authority := ca.NewCA(ks)
+	if err := authority.GenerateCA(); err != nil {
+		t.Fatalf("generate CA: %v", err)
+	}
+	opts := apiserver.DefaultServerOptions()
+	configure(&opts)
+	srv := apiserver.NewServer(s, authority, opts)
+	return httptest.NewServer(srv.Handler())
+}
+
 // newAuthTestServer creates a server with API keys and RBAC configured.
 func newAuthTestServer(t *testing.T) *httptest.Server {
 	t.Helper()
@@ -170,6 +195,72 @@ func TestNodeCRUD(t *testing.T) {
 	}
 }
 
+func TestNodeStatusTransitions(t *testing.T) {
+	s := store.NewMemoryStore()
+	authority := ca.NewCA(ca.NewMemoryKeyStore())
+	if err := authority.GenerateCA(); err != nil {
+		t.Fatalf("generate CA: %v", err)
+	}
+	srv := apiserver.NewServer(s, authority, apiserver.DefaultServerOptions())
+	fc := controller.NewFleetController(s)
+	srv.SetNodeTransitioner(fc)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	body, _ := json.Marshal(model.Node{ID: "n1", Address: "10.0.0.1:6477"})
+	resp, err := http.Post(ts.URL+"/api/v1/nodes", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	resp.Body.Close()
+
+	put := func(status string) int {
+		t.Helper()
+		body, _ := json.Marshal(model.Node{ID: "n1", Address: "10.0.0.1:6477", Status: status})
+		req, _ := http.NewRequest(http.MethodPut, ts.URL+"/api/v1/nodes/n1", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	steps := []struct {
+		status string
+		want   int
+	}{
+		{"bogus", http.StatusBadRequest},
+		{"draining", http.StatusOK},
+		{"online", http.StatusConflict}, // a drain must finish offline first
+		{"offline", http.StatusOK},
+		{"", http.StatusOK}, // omitted status keeps offline
+		{"online", http.StatusOK},
+	}
+	for _, st := range steps {
+		if got := put(st.status); got != st.want {
+			t.Fatalf("PUT status %q: got %d, want %d", st.status, got, st.want)
+		}
+	}
+
+	n, err := s.Nodes().Get("n1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if n.Status != "online" {
+		t.Errorf("final status = %q, want online", n.Status)
+	}
+	var got []string
+	for _, e := range fc.Events() {
+		got = append(got, string(e.From)+"->"+string(e.To))
+	}
+	want := []string{"online->draining", "draining->offline", "offline->online"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Route CRUD
 // ---------------------------------------------------------------------------
@@ -201,6 +292,143 @@ func TestRouteCRUD(t *testing.T) {
 	}
 }
 
+// ---------------------------------------------------------------------------
+// Tenant quotas
+// ---------------------------------------------------------------------------
+
+func TestTenantNodeQuota(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	post := func(path string, v any) *http.Response {
+		t.Helper()
+		body, _ := json.Marshal(v)
+		resp, err := http.Post(ts.URL+path, "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		return resp
+	}
+
+	resp := post("/api/v1/tenants", model.Tenant{ID: "acme", Name: "Acme", Slug: "acme"})
+	var tenant model.Tenant
+	json.NewDecoder(resp.Body).Decode(&tenant)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create tenant: status %d", resp.StatusCode)
+	}
+	tenant.MaxNodes = 2
+	body, _ := json.Marshal(tenant)
+	req, _ := http.NewRequest(http.MethodPut, ts.URL+"/api/v1/tenants/"+tenant.ID, bytes.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("update tenant: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("update tenant: status %d", resp.StatusCode)
+	}
+
+	for i := 1; i <= 2; i++ {
+		resp := post("/api/v1/nodes", model.Node{ID: fmt.Sprintf("acme-%d", i), Address: "10.0.0.1:6477", TenantID: tenant.ID})
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("node %d within quota: status %d", i, resp.StatusCode)
+		}
+	}
+
+	resp = post("/api/v1/nodes", model.Node{ID: "acme-3", Address: "10.0.0.1:6477", TenantID: tenant.ID})
+	var e map[string]string
+	json.NewDecoder(resp.Body).Decode(&e)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden || !strings.Contains(e["error"], "quota exceeded") {
+		t.Fatalf("node over quota: status %d, error %q; want 403 quota exceeded", resp.StatusCode, e["error"])
+	}
+
+	// Nodes of other tenants, or of none, are not affected.
+	resp = post("/api/v1/nodes", model.Node{ID: "untenanted", Address: "10.0.0.2:6477"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("node without tenant: status %d", resp.StatusCode)
+	}
+	resp = post("/api/v1/nodes", model.Node{ID: "ghost-1", Address: "10.0.0.3:6477", TenantID: "no-such-tenant"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("node of unknown tenant: status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestTenantQuotaFollowsAPIKeyAndMoves(t *testing.T) {
+	ts := newTestServerWithOptions(t, func(o *apiserver.ServerOptions) {
+		o.APIKeys = map[string]apiserver.APIKeyInfo{
+			"admin-token": {Description: "admin", Role: apiserver.RoleAdmin},
+			"acme-token":  {Description: "acme", Role: apiserver.RoleOperator, TenantID: "acme"},
+		}
+	})
+	defer ts.Close()
+
+	do := func(method, path, token string, v any) (int, string, []byte) {
+		t.Helper()
+		body, _ := json.Marshal(v)
+		req, _ := http.NewRequest(method, ts.URL+path, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		raw, _ := io.ReadAll(resp.Body)
+		var e map[string]string
+		json.Unmarshal(raw, &e)
+		return resp.StatusCode, e["error"], raw
+	}
+
+	if code, _, _ := do(http.MethodPost, "/api/v1/tenants", "admin-token", model.Tenant{ID: "acme", Name: "Acme", Slug: "acme"}); code != http.StatusCreated {
+		t.Fatalf("create tenant: status %d", code)
+	}
+	if code, _, _ := do(http.MethodPut, "/api/v1/tenants/acme", "admin-token", model.Tenant{ID: "acme", Name: "Acme", Slug: "acme", MaxNodes: 1, MaxRoutes: 1}); code != http.StatusOK {
+		t.Fatalf("update tenant: status %d", code)
+	}
+
+	// The tenant's key fills in its own tenant and is held to its quota.
+	code, _, raw := do(http.MethodPost, "/api/v1/nodes", "acme-token", model.Node{ID: "acme-1", Address: "10.0.0.1:6477"})
+	var created model.Node
+	json.Unmarshal(raw, &created)
+	if code != http.StatusCreated || created.TenantID != "acme" {
+		t.Fatalf("first node: status %d tenant %q, want 201 acme", code, created.TenantID)
+	}
+	if code, msg, _ := do(http.MethodPost, "/api/v1/nodes", "acme-token", model.Node{ID: "acme-2", Address: "10.0.0.1:6477"}); code != http.StatusForbidden || !strings.Contains(msg, "quota exceeded") {
+		t.Errorf("node over quota without a tenant in the body: status %d %q, want 403 quota exceeded", code, msg)
+	}
+	if code, _, _ := do(http.MethodPost, "/api/v1/nodes", "acme-token", model.Node{ID: "acme-3", Address: "10.0.0.1:6477", TenantID: "other"}); code != http.StatusForbidden {
+		t.Errorf("node for another tenant: status %d, want 403", code)
+	}
+
+	// Moving a resource into a full tenant is refused like creating one.
+	if code, _, _ := do(http.MethodPost, "/api/v1/nodes", "admin-token", model.Node{ID: "free-1", Address: "10.0.0.2:6477"}); code != http.StatusCreated {
+		t.Fatalf("untenanted node: status %d", code)
+	}
+	if code, msg, _ := do(http.MethodPut, "/api/v1/nodes/free-1", "admin-token", model.Node{Address: "10.0.0.2:6477", TenantID: "acme"}); code != http.StatusForbidden || !strings.Contains(msg, "quota exceeded") {
+		t.Errorf("move node into full tenant: status %d %q, want 403 quota exceeded", code, msg)
+	}
+	if code, _, _ := do(http.MethodPut, "/api/v1/nodes/acme-1", "acme-token", model.Node{Address: "10.0.0.9:6477"}); code != http.StatusOK {
+		t.Errorf("update within tenant: status %d, want 200", code)
+	}
+
+	route := func(id, tenant string) model.Route {
+		return model.Route{ID: id, TenantID: tenant, Endpoints: []model.Endpoint{{NodeID: "acme-1", Address: "10.0.0.1", Weight: 1}}}
+	}
+	if code, _, _ := do(http.MethodPost, "/api/v1/routes", "acme-token", route("acme-r1", "")); code != http.StatusCreated {
+		t.Fatalf("first route: status %d", code)
+	}
+	if code, _, _ := do(http.MethodPost, "/api/v1/routes", "admin-token", route("free-r1", "")); code != http.StatusCreated {
+		t.Fatalf("untenanted route: status %d", code)
+	}
+	if code, msg, _ := do(http.MethodPut, "/api/v1/routes/free-r1", "admin-token", route("", "acme")); code != http.StatusForbidden || !strings.Contains(msg, "quota exceeded") {
+		t.Errorf("move route into full tenant: status %d %q, want 403 quota exceeded", code, msg)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // MIC issue + verify + revoke via API
 // ---------------------------------------------------------------------------
@@ -251,6 +479,80 @@ func TestMICIssueVerifyRevoke(t *testing.T) {
 	}
 }
 
+func TestMICIssueBatch(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	var batch []map[string]interface{}
+	for i := 0; i < 5; i++ {
+		batch = append(batch, map[string]interface{}{
+			"id":           fmt.Sprintf("mic-batch-%d", i),
+			"node_id":      fmt.Sprintf("node-batch-%d", i),
+			"capabilities": []string{"route"},
+			"valid_days":   30,
+		})
+	}
+	body, _ := json.Marshal(batch)
+	resp, err := http.Post(ts.URL+"/api/v1/trust/mics:batch", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("issue batch: %v", err)
+	}
+	var issued []model.MIC
+	json.NewDecoder(resp.Body).Decode(&issued)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	if len(issued) != 5 {
+		t.Fatalf("expected 5 MICs, got %d", len(issued))
+	}
+
+	for _, mic := range issued {
+		resp, err := http.Post(ts.URL+"/api/v1/trust/mics/"+mic.ID+"/verify", "application/json", nil)
+		if err != nil {
+			t.Fatalf("verify %s: %v", mic.ID, err)
+		}
+		var verifyResp map[string]bool
+		json.NewDecoder(resp.Body).Decode(&verifyResp)
+		resp.Body.Close()
+		if !verifyResp["valid"] {
+			t.Errorf("MIC %s from batch does not verify", mic.ID)
+		}
+	}
+}
+
+func TestMICIssueBatchAllOrNothing(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	// The last entry collides with the first, so nothing is issued.
+	batch := []map[string]interface{}{
+		{"id": "mic-aon-1", "node_id": "node-1"},
+		{"id": "mic-aon-2", "node_id": "node-2"},
+		{"id": "mic-aon-1", "node_id": "node-3"},
+	}
+	body, _ := json.Marshal(batch)
+	resp, err := http.Post(ts.URL+"/api/v1/trust/mics:batch", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("issue batch: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/v1/trust/mics")
+	if err != nil {
+		t.Fatalf("list mics: %v", err)
+	}
+	var mics []model.MIC
+	json.NewDecoder(resp.Body).Decode(&mics)
+	resp.Body.Close()
+	if len(mics) != 0 {
+		t.Fatalf("failed batch stored %d MICs, want 0", len(mics))
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Firmware CRUD
 // ---------------------------------------------------------------------------
@@ -313,6 +615,62 @@ func TestMetrics(t *testing.T) {
 	}
 }
 
+func TestOpenAPISpec(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/openapi.json")
+	if err != nil {
+		t.Fatalf("GET /openapi.json: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var spec struct {
+		OpenAPI    string                               `json:"openapi"`
+		Paths      map[string]map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas         map[string]map[string]any `json:"schemas"`
+			SecuritySchemes map[string]map[string]any `json:"securitySchemes"`
+		} `json:"components"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&spec); err != nil {
+		t.Fatalf("decode spec: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("openapi = %q, want 3.x", spec.OpenAPI)
+	}
+	for path, methods := range map[string][]string{
+		"/api/v1/nodes":                  {"get", "post"},
+		"/api/v1/nodes/{id}":             {"get", "put", "delete"},
+		"/api/v1/nodes/{id}/heartbeat":   {"post"},
+		"/api/v1/routes/{id}":            {"get", "put", "delete"},
+		"/api/v1/firmware":               {"get", "post"},
+		"/api/v1/trust/mics":             {"get", "post"},
+		"/api/v1/trust/mics/{id}/verify": {"post"},
+		"/api/v1/tenants/{id}":           {"get", "put", "delete"},
+		"/api/v1/clusters":               {"get", "post"},
+	} {
+		for _, m := range methods {
+			if spec.Paths[path][m] == nil {
+				t.Errorf("spec is missing %s %s", strings.ToUpper(m), path)
+			}
+		}
+	}
+	node := spec.Components.Schemas["Node"]
+	props, _ := node["properties"].(map[string]any)
+	if _, ok := props["last_seen"]; !ok {
+		t.Errorf("Node schema properties = %v, want JSON field names such as last_seen", props)
+	}
+	if _, ok := spec.Components.Schemas["IssueMICRequest"]; !ok {
+		t.Error("spec is missing the IssueMICRequest schema")
+	}
+	if spec.Components.SecuritySchemes["bearerAuth"]["scheme"] != "bearer" {
+		t.Errorf("securitySchemes = %v, want bearer auth", spec.Components.SecuritySchemes)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Heartbeat endpoint
 // ---------------------------------------------------------------------------
@@ -344,6 +702,68 @@ func TestNodeHeartbeat(t *testing.T) {
 	}
 }
 
+func TestNodeMetricsHistory(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	body, _ := json.Marshal(model.Node{ID: "hist-node", Address: "10.0.0.6:6477"})
+	resp, _ := http.Post(ts.URL+"/api/v1/nodes", "application/json", bytes.NewReader(body))
+	resp.Body.Close()
+
+	for conns := 1; conns <= 3; conns++ {
+		body, _ := json.Marshal(model.NodeMetrics{Connections: conns})
+		resp, err := http.Post(ts.URL+"/api/v1/nodes/hist-node/heartbeat", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("heartbeat: %v", err)
+		}
+		resp.Body.Close()
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	fetch := func(query string) []model.NodeMetricsSample {
+		t.Helper()
+		resp, err := http.Get(ts.URL + "/api/v1/nodes/hist-node/metrics" + query)
+		if err != nil {
+			t.Fatalf("get metrics: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("get metrics%s: expected 200, got %d", query, resp.StatusCode)
+		}
+		var samples []model.NodeMetricsSample
+		if err := json.NewDecoder(resp.Body).Decode(&samples); err != nil {
+			t.Fatalf("decode metrics: %v", err)
+		}
+		return samples
+	}
+
+	all := fetch("")
+	if len(all) != 3 {
+		t.Fatalf("expected 3 samples, got %d", len(all))
+	}
+	for i, s := range all {
+		if s.Metrics.Connections != i+1 {
+			t.Errorf("sample %d has connections %d, want %d (oldest first)", i, s.Metrics.Connections, i+1)
+		}
+	}
+
+	since := fetch("?since=" + url.QueryEscape(all[1].Timestamp.Format(time.RFC3339Nano)))
+	if len(since) != 2 || since[0].Metrics.Connections != 2 || since[1].Metrics.Connections != 3 {
+		t.Errorf("since filter returned %+v, want the last two samples", since)
+	}
+
+	resp, _ = http.Get(ts.URL + "/api/v1/nodes/hist-node/metrics?since=yesterday")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid since: expected 400, got %d", resp.StatusCode)
+	}
+	resp, _ = http.Get(ts.URL + "/api/v1/nodes/missing-node/metrics")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown node: expected 404, got %d", resp.StatusCode)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // X-Request-ID middleware
 // ---------------------------------------------------------------------------
@@ -402,6 +822,85 @@ func TestRBACUnauthenticated(t *testing.T) {
 	}
 }
 
+// batchingStore wraps a MemoryStore with an audit log that buffers appends
+// until Flush, like a store that batches writes to its backend.
+type batchingStore struct {
+	*store.MemoryStore
+	audit *batchingAuditLog
+}
+
+func (s *batchingStore) AuditLog() store.AuditLogStore { return s.audit }
+
+func (s *batchingStore) Flush(ctx context.Context) error {
+	s.audit.mu.Lock()
+	defer s.audit.mu.Unlock()
+	for i := range s.audit.pending {
+		if err := s.MemoryStore.AuditLog().Append(&s.audit.pending[i]); err != nil {
+			return err
+		}
+	}
+	s.audit.pending = nil
+	return nil
+}
+
+type batchingAuditLog struct {
+	mu      sync.Mutex
+	pending []model.AuditEntry
+	backend store.AuditLogStore
+}
+
+func (l *batchingAuditLog) Append(entry *model.AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending = append(l.pending, *entry)
+	return nil
+}
+
+func (l *batchingAuditLog) List(tenantID string, limit int) ([]model.AuditEntry, error) {
+	return l.backend.List(tenantID, limit)
+}
+
+func TestGracefulShutdownFlushesStore(t *testing.T) {
+	mem := store.NewMemoryStore()
+	bs := &batchingStore{MemoryStore: mem, audit: &batchingAuditLog{backend: mem.AuditLog()}}
+	authority := ca.NewCA(ca.NewMemoryKeyStore())
+	if err := authority.GenerateCA(); err != nil {
+		t.Fatalf("generate CA: %v", err)
+	}
+	opts := apiserver.DefaultServerOptions()
+	opts.APIKeys = map[string]apiserver.APIKeyInfo{"admin-token": {Description: "admin", Role: apiserver.RoleAdmin}}
+	srv := apiserver.NewServer(bs, authority, opts)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	// Rejected requests are audited; the entries sit in the batch.
+	for i := 0; i < 3; i++ {
+		resp, err := http.Get(ts.URL + "/api/v1/nodes")
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		resp.Body.Close()
+	}
+	if got, _ := mem.AuditLog().List("", 0); len(got) != 0 {
+		t.Fatalf("backend has %d audit entries before shutdown, want 0 (buffered)", len(got))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.GracefulShutdown(ctx); err != nil {
+		t.Fatalf("GracefulShutdown: %v", err)
+	}
+	got, _ := mem.AuditLog().List("", 0)
+	if len(got) != 3 {
+		t.Fatalf("backend has %d audit entries after shutdown, want 3", len(got))
+	}
+	for _, e := range got {
+		if e.Outcome != model.AuditOutcomeDenied {
+			t.Errorf("entry %s outcome = %q, want denied", e.ID, e.Outcome)
+		}
+	}
+}
+
 func TestRBACHealthzNoAuth(t *testing.T) {
 	ts := newAuthTestServer(t)
 	defer ts.Close()
@@ -521,6 +1020,133 @@ func TestRBACAdminCanDelete(t *testing.T) {
 	}
 }
 
+func TestRBACDeniedRequestsAudited(t *testing.T) {
+	ts := newAuthTestServer(t)
+	defer ts.Close()
+
+	// Unauthenticated request with a bogus token.
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/nodes", nil)
+	req.Header.Set("Authorization", "Bearer not-a-real-token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("bogus token: expected 401, got %d", resp.StatusCode)
+	}
+
+	// Authenticated viewer attempting a write.
+	req, _ = http.NewRequest(http.MethodDelete, ts.URL+"/api/v1/nodes/n1", nil)
+	req.Header.Set("Authorization", "Bearer viewer-token")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("viewer DELETE: expected 403, got %d", resp.StatusCode)
+	}
+
+	// Only admins may read the audit log.
+	req, _ = http.NewRequest(http.MethodGet, ts.URL+"/api/v1/audit", nil)
+	req.Header.Set("Authorization", "Bearer operator-token")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("operator GET /audit: expected 403, got %d", resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, ts.URL+"/api/v1/audit", nil)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	var entries []model.AuditEntry
+	json.NewDecoder(resp.Body).Decode(&entries)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("admin GET /audit: expected 200, got %d", resp.StatusCode)
+	}
+
+	// Most recent first: operator audit read, viewer delete, bogus token.
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 denied entries, got %d: %+v", len(entries), entries)
+	}
+	for _, e := range entries {
+		if e.Outcome != model.AuditOutcomeDenied {
+			t.Errorf("entry %+v: outcome %q, want denied", e, e.Outcome)
+		}
+		if e.IPAddress == "" {
+			t.Errorf("entry %+v: missing IP address", e)
+		}
+		raw, _ := json.Marshal(e)
+		if strings.Contains(string(raw), "not-a-real-token") || strings.Contains(string(raw), "viewer-token") {
+			t.Errorf("audit entry leaks the token: %s", raw)
+		}
+	}
+	bogus := entries[2]
+	if bogus.Action != "authenticate" || bogus.Metadata["path"] != "/api/v1/nodes" || bogus.Metadata["status"] != "401" {
+		t.Errorf("401 entry = %+v", bogus)
+	}
+	viewer := entries[1]
+	if viewer.Action != "authorize" || viewer.ActorID != "viewer" || viewer.Metadata["method"] != http.MethodDelete {
+		t.Errorf("403 entry = %+v", viewer)
+	}
+}
+
+func TestDeniedAuditBounded(t *testing.T) {
+	authority := ca.NewCA(ca.NewMemoryKeyStore())
+	if err := authority.GenerateCA(); err != nil {
+		t.Fatalf("generate CA: %v", err)
+	}
+	mem := store.NewMemoryStore()
+	opts := apiserver.DefaultServerOptions()
+	opts.RateLimit = apiserver.RateLimit{}
+	opts.APIKeys = map[string]apiserver.APIKeyInfo{
+		"admin-token": {Description: "admin", Role: apiserver.RoleAdmin},
+	}
+	h := apiserver.NewServer(mem, authority, opts).Handler()
+
+	deny := func(remote, path string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remote
+		req.Header.Set("Authorization", "Bearer wrong-token")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("%s: status %d, want 401", path, rec.Code)
+		}
+	}
+
+	// A flood from one source is written only up to the burst...
+	long := "/api/v1/" + strings.Repeat("x", 10000)
+	for i := 0; i < 100; i++ {
+		deny("203.0.113.7:4000", long)
+	}
+	// ...while another source is still audited.
+	deny("198.51.100.9:4000", "/api/v1/nodes")
+
+	entries, _ := mem.AuditLog().List("", 0)
+	perIP := map[string]int{}
+	for _, e := range entries {
+		perIP[e.IPAddress]++
+		if len(e.ResourceID) > 300 || len(e.Metadata["path"]) > 300 {
+			t.Errorf("entry stores a %d-byte path", len(e.Metadata["path"]))
+		}
+	}
+	if n := perIP["203.0.113.7"]; n == 0 || n > 10 {
+		t.Errorf("flooding IP has %d entries, want 1..10", n)
+	}
+	if n := perIP["198.51.100.9"]; n != 1 {
+		t.Errorf("other IP has %d entries, want 1", n)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Path parameter injection (P1 security)
 // ---------------------------------------------------------------------------
@@ -560,3 +1186,363 @@ func TestRequestBodyTooLarge(t *testing.T) {
 		t.Fatalf("expected 413 or 400 for oversized body, got %d", resp.StatusCode)
 	}
 }
+
+// ---------------------------------------------------------------------------
+// Rate limiting
+// ---------------------------------------------------------------------------
+
+func TestRateLimitConfigured(t *testing.T) {
+	ts := newTestServerWithOptions(t, func(o *apiserver.ServerOptions) {
+		o.RateLimit = apiserver.RateLimit{PerMinute: 60, Burst: 3}
+	})
+	defer ts.Close()
+
+	for i := 1; i <= 4; i++ {
+		resp, err := http.Get(ts.URL + "/healthz")
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		resp.Body.Close()
+		if got := resp.Header.Get("X-RateLimit-Limit"); got != "60" {
+			t.Errorf("request %d: X-RateLimit-Limit = %q, want 60", i, got)
+		}
+		want := http.StatusOK
+		if i == 4 {
+			want = http.StatusTooManyRequests
+		}
+		if resp.StatusCode != want {
+			t.Fatalf("request %d: status %d, want %d", i, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	ts := newTestServerWithOptions(t, func(o *apiserver.ServerOptions) {
+		o.RateLimit = apiserver.RateLimit{}
+	})
+	defer ts.Close()
+
+	// Well past the default burst of 50.
+	for i := 1; i <= 120; i++ {
+		resp, err := http.Get(ts.URL + "/healthz")
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200 with rate limiting disabled", i, resp.StatusCode)
+		}
+		if got := resp.Header.Get("X-RateLimit-Limit"); got != "" {
+			t.Fatalf("request %d: X-RateLimit-Limit = %q, want no header", i, got)
+		}
+	}
+}
+
+func TestRateLimitHeadersReportTightestBucket(t *testing.T) {
+	ts := newTestServerWithOptions(t, func(o *apiserver.ServerOptions) {
+		o.APIKeys = map[string]apiserver.APIKeyInfo{
+			"acme-token":  {Description: "acme", Role: apiserver.RoleViewer, TenantID: "acme"},
+			"plain-token": {Description: "plain", Role: apiserver.RoleViewer},
+		}
+		o.RateLimit = apiserver.RateLimit{PerMinute: 600, Burst: 50}
+		o.PerTenantRateLimit = apiserver.RateLimit{PerMinute: 60, Burst: 3}
+	})
+	defer ts.Close()
+
+	get := func(token string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/nodes", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// The tenant's bucket is lower than the global one, so it is reported.
+	for i, wantRemaining := range []string{"3", "2", "1", "0"} {
+		resp := get("acme-token")
+		if got := resp.Header.Get("X-RateLimit-Limit"); got != "60" {
+			t.Errorf("tenant request %d: X-RateLimit-Limit = %q, want the tenant's 60", i+1, got)
+		}
+		if got := resp.Header.Get("X-RateLimit-Remaining"); got != wantRemaining {
+			t.Errorf("tenant request %d: X-RateLimit-Remaining = %q, want %s", i+1, got, wantRemaining)
+		}
+		want := http.StatusOK
+		if i == 3 {
+			want = http.StatusTooManyRequests
+		}
+		if resp.StatusCode != want {
+			t.Errorf("tenant request %d: status %d, want %d", i+1, resp.StatusCode, want)
+		}
+	}
+
+	// A key with no tenant is limited by the global bucket alone.
+	resp := get("plain-token")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("untenanted request: status %d, want 200", resp.StatusCode)
+	}
+	if got := resp.Header.Get("X-RateLimit-Limit"); got != "600" {
+		t.Errorf("untenanted request: X-RateLimit-Limit = %q, want the global 600", got)
+	}
+	if got, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); got < 40 {
+		t.Errorf("untenanted request: X-RateLimit-Remaining = %d, want the global bucket's", got)
+	}
+}
+
+func TestTenantRateLimitSparesGlobalBucket(t *testing.T) {
+	ts := newTestServerWithOptions(t, func(o *apiserver.ServerOptions) {
+		o.APIKeys = map[string]apiserver.APIKeyInfo{
+			"acme-token":  {Description: "acme", Role: apiserver.RoleViewer, TenantID: "acme"},
+			"plain-token": {Description: "plain", Role: apiserver.RoleViewer},
+		}
+		o.RateLimit = apiserver.RateLimit{PerMinute: 6, Burst: 10}
+		o.PerTenantRateLimit = apiserver.RateLimit{PerMinute: 6, Burst: 1}
+		o.PerIPRateLimit = apiserver.RateLimit{}
+	})
+	defer ts.Close()
+
+	get := func(token string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/nodes", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+	globalRemaining := func() int {
+		t.Helper()
+		resp := get("plain-token")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("untenanted request: status %d, want 200", resp.StatusCode)
+		}
+		n, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+		return n
+	}
+
+	if resp := get("acme-token"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("first tenant request: status %d, want 200", resp.StatusCode)
+	}
+	before := globalRemaining()
+
+	// More 429s than the global bucket holds.
+	for i := 0; i < 20; i++ {
+		if resp := get("acme-token"); resp.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("exhausted tenant request %d: status %d, want 429", i+1, resp.StatusCode)
+		}
+	}
+	// The next untenanted request takes one token from where before left it.
+	if after := globalRemaining(); after != before-1 {
+		t.Errorf("global X-RateLimit-Remaining = %d after the tenant's 429s, want %d", after, before-1)
+	}
+}
+
+// newRateLimitedHandler returns a server handler with only the per-IP limiter
+// active.
+func newRateLimitedHandler(t *testing.T, perIP apiserver.RateLimit, proxyDepth int) http.Handler {
+	t.Helper()
+	authority := ca.NewCA(ca.NewMemoryKeyStore())
+	if err := authority.GenerateCA(); err != nil {
+		t.Fatalf("generate CA: %v", err)
+	}
+	opts := apiserver.DefaultServerOptions()
+	opts.RateLimit = apiserver.RateLimit{}
+	opts.PerIPRateLimit = perIP
+	opts.TrustedProxyDepth = proxyDepth
+	return apiserver.NewServer(store.NewMemoryStore(), authority, opts).Handler()
+}
+
+func TestPerIPRateLimit(t *testing.T) {
+	h := newRateLimitedHandler(t, apiserver.RateLimit{PerMinute: 60, Burst: 2}, 0)
+
+	get := func(remote string) int {
+		req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 1; i <= 2; i++ {
+		if code := get("203.0.113.7:4000"); code != http.StatusOK {
+			t.Fatalf("abusive IP request %d: status %d, want 200", i, code)
+		}
+	}
+	// A different source port is still the same client.
+	if code := get("203.0.113.7:4001"); code != http.StatusTooManyRequests {
+		t.Fatalf("abusive IP over limit: status %d, want 429", code)
+	}
+	if code := get("198.51.100.9:5000"); code != http.StatusOK {
+		t.Fatalf("other IP: status %d, want 200", code)
+	}
+}
+
+func TestPerIPRateLimitTrustedProxy(t *testing.T) {
+	// One trusted proxy: the client is the right-most X-Forwarded-For entry.
+	h := newRateLimitedHandler(t, apiserver.RateLimit{PerMinute: 60, Burst: 1}, 1)
+
+	get := func(xff string) int {
+		req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+		req.RemoteAddr = "10.0.0.1:8080" // the proxy
+		req.Header.Set("X-Forwarded-For", xff)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := get("203.0.113.7"); code != http.StatusOK {
+		t.Fatalf("first request: status %d, want 200", code)
+	}
+	// Prepending a spoofed hop does not give the same client a fresh bucket.
+	if code := get("192.0.2.55, 203.0.113.7"); code != http.StatusTooManyRequests {
+		t.Fatalf("spoofed hop: status %d, want 429", code)
+	}
+	// Another client behind the same proxy is limited separately.
+	if code := get("198.51.100.9"); code != http.StatusOK {
+		t.Fatalf("other client behind proxy: status %d, want 200", code)
+	}
+}
+
+func TestPerIPRateLimitShortForwardedFor(t *testing.T) {
+	// Two trusted proxies, but the caller connects directly with a single
+	// forged hop: it must be keyed on its own address, not on the forged one.
+	h := newRateLimitedHandler(t, apiserver.RateLimit{PerMinute: 60, Burst: 1}, 2)
+
+	get := func(xff string) int {
+		req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+		req.RemoteAddr = "203.0.113.7:4000"
+		req.Header.Set("X-Forwarded-For", xff)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := get("192.0.2.1"); code != http.StatusOK {
+		t.Fatalf("first request: status %d, want 200", code)
+	}
+	if code := get("192.0.2.2"); code != http.StatusTooManyRequests {
+		t.Fatalf("forged hop: status %d, want 429", code)
+	}
+}
+
+func TestPerIPRateLimitBeforeAuth(t *testing.T) {
+	authority := ca.NewCA(ca.NewMemoryKeyStore())
+	if err := authority.GenerateCA(); err != nil {
+		t.Fatalf("generate CA: %v", err)
+	}
+	opts := apiserver.DefaultServerOptions()
+	opts.RateLimit = apiserver.RateLimit{}
+	opts.PerIPRateLimit = apiserver.RateLimit{PerMinute: 60, Burst: 2}
+	opts.APIKeys = map[string]apiserver.APIKeyInfo{
+		"admin-token": {Description: "admin", Role: apiserver.RoleAdmin},
+	}
+	h := apiserver.NewServer(store.NewMemoryStore(), authority, opts).Handler()
+
+	get := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/nodes", nil)
+		req.RemoteAddr = "203.0.113.7:4000"
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Requests with a bad key spend the source's bucket...
+	for i := 1; i <= 2; i++ {
+		if code := get("wrong-token"); code != http.StatusUnauthorized {
+			t.Fatalf("bad key request %d: status %d, want 401", i, code)
+		}
+	}
+	// ...so further attempts from it are refused before auth runs, whether
+	// or not they carry a valid key.
+	if code := get("wrong-token"); code != http.StatusTooManyRequests {
+		t.Fatalf("bad key after burst: status %d, want 429", code)
+	}
+	if code := get("admin-token"); code != http.StatusTooManyRequests {
+		t.Fatalf("valid key after burst: status %d, want 429", code)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Event stream
+// ---------------------------------------------------------------------------
+
+func TestEventsStream(t *testing.T) {
+	s := store.NewMemoryStore()
+	ks := ca.NewMemoryKeyStore()
+	authority := ca.NewCA(ks)
+	if err := authority.GenerateCA(); err != nil {
+		t.Fatalf("generate CA: %v", err)
+	}
+	opts := apiserver.DefaultServerOptions()
+	opts.APIKeys = map[string]apiserver.APIKeyInfo{
+		"viewer-token": {Description: "viewer", Role: apiserver.RoleViewer},
+	}
+	srv := apiserver.NewServer(s, authority, opts)
+	fc := controller.NewFleetController(s)
+	srv.SetEventSource(fc)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	node := &model.Node{ID: "node-sse", Address: "10.0.0.9:6477", Status: "online", LastSeen: time.Now().Add(-time.Hour)}
+	if err := s.Nodes().Create(node); err != nil {
+		t.Fatalf("create node: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/v1/events", nil)
+	req.Header.Set("Authorization", "Bearer viewer-token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /events: expected 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	// The subscription exists once headers arrive, so this event is streamed.
+	fc.CheckHealth()
+
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var evt controller.Event
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &evt); err != nil {
+			t.Fatalf("decode event %q: %v", line, err)
+		}
+		if evt.Type != "node_unhealthy" || evt.NodeID != "node-sse" {
+			t.Fatalf("event = %+v, want node_unhealthy for node-sse", evt)
+		}
+		return
+	}
+	t.Fatalf("stream ended without an event: %v", sc.Err())
+}
+
+func TestEventsStreamUnavailable(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/events")
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("GET /events without source: expected 503, got %d", resp.StatusCode)
+	}
+}
@@ -164,3 +164,58 @@ func TestCA_MultipleIssuances(t *testing.T) {
 		}
 	}
 }
+
+func TestCA_RotateGracePeriod(t *testing.T) {
+	authority := newTestCA(t)
+	authority.SetRotationGrace(200 * time.Millisecond)
+
+	oldPub, _ := authority.PublicKey()
+	old := &model.MIC{
+		ID:         "mic-pre-rotate",
+		NodeID:     "node-1",
+		ValidFrom:  time.Now().Add(-time.Minute),
+		ValidUntil: time.Now().Add(time.Hour),
+	}
+	if err := authority.IssueMIC(old); err != nil {
+		t.Fatalf("issue mic: %v", err)
+	}
+
+	if err := authority.Rotate(); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	newPub, _ := authority.PublicKey()
+	if newPub.Equal(oldPub) {
+		t.Fatal("Rotate did not change the root key")
+	}
+	keys, err := authority.ActiveKeys()
+	if err != nil {
+		t.Fatalf("ActiveKeys: %v", err)
+	}
+	if len(keys) != 2 || !keys[0].Equal(newPub) || !keys[1].Equal(oldPub) {
+		t.Fatalf("ActiveKeys during grace = %d keys, want [new, old]", len(keys))
+	}
+
+	// During the grace period both old and new signatures verify.
+	if valid, err := authority.VerifyMIC(old); err != nil || !valid {
+		t.Fatalf("pre-rotation MIC during grace: valid=%v err=%v", valid, err)
+	}
+	fresh := &model.MIC{ID: "mic-post-rotate", NodeID: "node-1", ValidFrom: old.ValidFrom, ValidUntil: old.ValidUntil}
+	if err := authority.IssueMIC(fresh); err != nil {
+		t.Fatalf("issue mic: %v", err)
+	}
+	if valid, err := authority.VerifyMIC(fresh); err != nil || !valid {
+		t.Fatalf("post-rotation MIC: valid=%v err=%v", valid, err)
+	}
+
+	// After the grace period only the new key is accepted.
+	time.Sleep(300 * time.Millisecond)
+	if valid, _ := authority.VerifyMIC(old); valid {
+		t.Fatal("pre-rotation MIC still valid after grace period")
+	}
+	if valid, err := authority.VerifyMIC(fresh); err != nil || !valid {
+		t.Fatalf("post-rotation MIC after grace: valid=%v err=%v", valid, err)
+	}
+	if keys, _ := authority.ActiveKeys(); len(keys) != 1 {
+		t.Fatalf("ActiveKeys after grace = %d keys, want 1", len(keys))
+	}
+}
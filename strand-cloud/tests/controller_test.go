@@ -0,0 +1,274 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/strand-protocol/strand/strand-cloud/pkg/apiserver"
+	"github.com/strand-protocol/strand/strand-cloud/pkg/ca"
+	"github.com/strand-protocol/strand/strand-cloud/pkg/controller"
+	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
+	"github.com/strand-protocol/strand/strand-cloud/pkg/store"
+)
+
+func TestFleetController_HealthTransitions(t *testing.T) {
+	s := store.NewMemoryStore()
+	fc := controller.NewFleetController(s)
+
+	node := &model.Node{ID: "node-1", Status: string(model.NodeStateOnline), LastSeen: time.Now().Add(-time.Hour)}
+	if err := s.Nodes().Create(node); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// Stale heartbeat: online -> unhealthy.
+	fc.CheckHealth()
+	got, _ := s.Nodes().Get("node-1")
+	if got.Status != string(model.NodeStateUnhealthy) {
+		t.Fatalf("status = %q, want unhealthy", got.Status)
+	}
+
+	// A further pass with no new heartbeat must not emit another event.
+	fc.CheckHealth()
+
+	// Heartbeat resumes: unhealthy -> online.
+	got.LastSeen = time.Now()
+	if err := s.Nodes().Update(got); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	fc.CheckHealth()
+	got, _ = s.Nodes().Get("node-1")
+	if got.Status != string(model.NodeStateOnline) {
+		t.Fatalf("status = %q, want online", got.Status)
+	}
+
+	events := fc.Events()
+	want := []struct {
+		typ      string
+		from, to model.NodeState
+	}{
+		{"node_unhealthy", model.NodeStateOnline, model.NodeStateUnhealthy},
+		{"node_online", model.NodeStateUnhealthy, model.NodeStateOnline},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.Type != w.typ || e.From != w.from || e.To != w.to || e.NodeID != "node-1" {
+			t.Errorf("event %d = %+v, want %s %s -> %s", i, e, w.typ, w.from, w.to)
+		}
+	}
+}
+
+func TestFleetController_RejectsIllegalTransition(t *testing.T) {
+	s := store.NewMemoryStore()
+	fc := controller.NewFleetController(s)
+
+	if err := s.Nodes().Create(&model.Node{ID: "node-d", Status: string(model.NodeStateOnline), LastSeen: time.Now()}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := fc.Transition("node-d", model.NodeStateDraining, "maintenance"); err != nil {
+		t.Fatalf("online -> draining: %v", err)
+	}
+
+	// draining -> online skips going offline first and must be rejected.
+	if err := fc.Transition("node-d", model.NodeStateOnline, "operator"); err == nil {
+		t.Fatal("draining -> online: expected error, got nil")
+	}
+	got, _ := s.Nodes().Get("node-d")
+	if got.Status != string(model.NodeStateDraining) {
+		t.Errorf("status after rejected transition = %q, want draining", got.Status)
+	}
+	if n := len(fc.Events()); n != 1 {
+		t.Errorf("got %d events, want 1 (rejected transition must not emit)", n)
+	}
+
+	// The legal path back is draining -> offline -> online.
+	if err := fc.Transition("node-d", model.NodeStateOffline, "drained"); err != nil {
+		t.Fatalf("draining -> offline: %v", err)
+	}
+	if err := fc.Transition("node-d", model.NodeStateOnline, "reconciled"); err != nil {
+		t.Fatalf("offline -> online: %v", err)
+	}
+}
+
+func TestNodeState_CanTransition(t *testing.T) {
+	cases := []struct {
+		from, to model.NodeState
+		ok       bool
+	}{
+		{model.NodeStateOnline, model.NodeStateUnhealthy, true},
+		{model.NodeStateUnhealthy, model.NodeStateOnline, true},
+		{model.NodeStateDegraded, model.NodeStateDraining, true},
+		{model.NodeStateDraining, model.NodeStateOnline, false},
+		{model.NodeStateOffline, model.NodeStateDegraded, false},
+		{"", model.NodeStateOnline, true},
+		{model.NodeStateOnline, "bogus", false},
+	}
+	for _, c := range cases {
+		if got := c.from.CanTransition(c.to); got != c.ok {
+			t.Errorf("%q -> %q: CanTransition = %v, want %v", c.from, c.to, got, c.ok)
+		}
+	}
+}
+
+func TestFleetController_PerNodeHeartbeatTimeout(t *testing.T) {
+	s := store.NewMemoryStore()
+	fc := controller.NewFleetController(s)
+
+	lastSeen := time.Now().Add(-20 * time.Second)
+	edge := &model.Node{ID: "edge", Status: string(model.NodeStateOnline), LastSeen: lastSeen, HeartbeatTimeout: 5 * time.Second}
+	dc := &model.Node{ID: "dc", Status: string(model.NodeStateOnline), LastSeen: lastSeen, HeartbeatTimeout: 5 * time.Minute}
+	for _, n := range []*model.Node{edge, dc} {
+		if err := s.Nodes().Create(n); err != nil {
+			t.Fatalf("Create %s: %v", n.ID, err)
+		}
+	}
+
+	fc.CheckHealth()
+
+	if got, _ := s.Nodes().Get("edge"); got.Status != string(model.NodeStateUnhealthy) {
+		t.Errorf("edge status = %q, want unhealthy", got.Status)
+	}
+	if got, _ := s.Nodes().Get("dc"); got.Status != string(model.NodeStateOnline) {
+		t.Errorf("dc status = %q, want online", got.Status)
+	}
+	events := fc.Events()
+	if len(events) != 1 || events[0].NodeID != "edge" {
+		t.Errorf("events = %+v, want a single transition for edge", events)
+	}
+}
+
+func TestFleetController_DefaultHeartbeatTimeout(t *testing.T) {
+	s := store.NewMemoryStore()
+	fc := controller.NewFleetController(s)
+
+	// 20s of staleness is within the 30s controller default.
+	if err := s.Nodes().Create(&model.Node{ID: "plain", Status: string(model.NodeStateOnline), LastSeen: time.Now().Add(-20 * time.Second)}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	fc.CheckHealth()
+	if got, _ := s.Nodes().Get("plain"); got.Status != string(model.NodeStateOnline) {
+		t.Errorf("status = %q, want online", got.Status)
+	}
+}
+
+func TestMICRenewer_RenewsExpiringMIC(t *testing.T) {
+	s := store.NewMemoryStore()
+	authority := ca.NewCA(ca.NewMemoryKeyStore())
+	if err := authority.GenerateCA(); err != nil {
+		t.Fatalf("generate CA: %v", err)
+	}
+
+	now := time.Now()
+	soon := &model.MIC{ID: "mic-soon", NodeID: "node-1", ValidFrom: now.Add(-29 * 24 * time.Hour), ValidUntil: now.Add(time.Hour)}
+	later := &model.MIC{ID: "mic-later", NodeID: "node-2", ValidFrom: now, ValidUntil: now.Add(30 * 24 * time.Hour)}
+	revoked := &model.MIC{ID: "mic-revoked", NodeID: "node-3", ValidFrom: now, ValidUntil: now.Add(time.Hour), Revoked: true}
+	for _, m := range []*model.MIC{soon, later, revoked} {
+		if err := authority.IssueMIC(m); err != nil {
+			t.Fatalf("IssueMIC: %v", err)
+		}
+		if err := s.MICs().Create(m); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	mr := controller.NewMICRenewer(s, authority, 24*time.Hour)
+	if n := mr.Renew(); n != 1 {
+		t.Fatalf("Renew() = %d, want 1", n)
+	}
+
+	got, _ := s.MICs().Get("mic-soon")
+	if !got.ValidUntil.After(soon.ValidUntil.Add(28 * 24 * time.Hour)) {
+		t.Fatalf("ValidUntil = %v, want extended by ~30 days from %v", got.ValidUntil, soon.ValidUntil)
+	}
+	if ok, err := authority.VerifyMIC(got); err != nil || !ok {
+		t.Fatalf("renewed MIC does not verify: %v, %v", ok, err)
+	}
+	if g, _ := s.MICs().Get("mic-later"); !g.ValidUntil.Equal(later.ValidUntil) {
+		t.Errorf("MIC outside the window was renewed")
+	}
+	if g, _ := s.MICs().Get("mic-revoked"); !g.ValidUntil.Equal(revoked.ValidUntil) {
+		t.Errorf("revoked MIC was renewed")
+	}
+
+	entries, _ := s.AuditLog().List("", 0)
+	if len(entries) != 1 || entries[0].Action != "renew" || entries[0].ResourceID != "mic-soon" {
+		t.Fatalf("audit log = %+v, want one renew entry for mic-soon", entries)
+	}
+
+	// The renewed MIC is now outside the window.
+	if n := mr.Renew(); n != 0 {
+		t.Fatalf("second Renew() = %d, want 0", n)
+	}
+}
+
+func TestRouteJanitor_ExpiresRoutes(t *testing.T) {
+	s := store.NewMemoryStore()
+	authority := ca.NewCA(ca.NewMemoryKeyStore())
+	if err := authority.GenerateCA(); err != nil {
+		t.Fatalf("generate CA: %v", err)
+	}
+	ts := httptest.NewServer(apiserver.NewServer(s, authority, apiserver.DefaultServerOptions()).Handler())
+	defer ts.Close()
+
+	const ttl = 100 * time.Millisecond
+	for _, r := range []model.Route{
+		{ID: "short", TTL: ttl, Endpoints: []model.Endpoint{{NodeID: "n1", Address: "10.0.0.1:6477", Weight: 1}}},
+		{ID: "forever", Endpoints: []model.Endpoint{{NodeID: "n2", Address: "10.0.0.2:6477", Weight: 1}}},
+	} {
+		body, _ := json.Marshal(r)
+		resp, err := http.Post(ts.URL+"/api/v1/routes", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("create route %s: %v", r.ID, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("create route %s: status %d", r.ID, resp.StatusCode)
+		}
+	}
+
+	rj := controller.NewRouteJanitor(s, time.Hour)
+	if n := rj.Sweep(); n != 0 {
+		t.Fatalf("Sweep before expiry deleted %d routes", n)
+	}
+
+	time.Sleep(ttl + 50*time.Millisecond)
+
+	// Resolution skips the expired route before the janitor has run.
+	resp, err := http.Get(ts.URL + "/api/v1/routes/short")
+	if err != nil {
+		t.Fatalf("get route: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET expired route: status %d, want 404", resp.StatusCode)
+	}
+	resp, err = http.Get(ts.URL + "/api/v1/routes")
+	if err != nil {
+		t.Fatalf("list routes: %v", err)
+	}
+	var listed []model.Route
+	json.NewDecoder(resp.Body).Decode(&listed)
+	resp.Body.Close()
+	if len(listed) != 1 || listed[0].ID != "forever" {
+		t.Errorf("listed routes %+v, want only \"forever\"", listed)
+	}
+	if _, err := s.Routes().Get("short"); err != nil {
+		t.Fatalf("expired route removed before the janitor ran: %v", err)
+	}
+
+	if n := rj.Sweep(); n != 1 {
+		t.Errorf("Sweep deleted %d routes, want 1", n)
+	}
+	if _, err := s.Routes().Get("short"); err == nil {
+		t.Error("expired route still stored after Sweep")
+	}
+	if _, err := s.Routes().Get("forever"); err != nil {
+		t.Errorf("route without TTL deleted: %v", err)
+	}
+}
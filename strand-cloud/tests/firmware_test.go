@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/strand-protocol/strand/strand-cloud/pkg/agent"
+	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
+)
+
+func testFirmware(content []byte) *model.FirmwareImage {
+	return &model.FirmwareImage{
+		ID:       "fw-test",
+		Version:  "1.2.0",
+		Platform: "linux-amd64",
+		Size:     int64(len(content)),
+		Checksum: fmt.Sprintf("sha256:%x", sha256.Sum256(content)),
+	}
+}
+
+func TestFirmwareVerify(t *testing.T) {
+	content := bytes.Repeat([]byte("strand firmware "), 4096)
+	fw := testFirmware(content)
+
+	if err := fw.Verify(bytes.NewReader(content)); err != nil {
+		t.Errorf("Verify(matching content): %v", err)
+	}
+	upper := *fw
+	upper.Checksum = strings.ToUpper(strings.TrimPrefix(fw.Checksum, "sha256:"))
+	if err := upper.Verify(bytes.NewReader(content)); err != nil {
+		t.Errorf("Verify with a bare upper-case digest: %v", err)
+	}
+
+	corrupt := bytes.Clone(content)
+	corrupt[100] ^= 0xff
+	for name, body := range map[string][]byte{
+		"corrupt":   corrupt,
+		"truncated": content[:len(content)-1],
+	} {
+		err := fw.Verify(bytes.NewReader(body))
+		if err == nil {
+			t.Errorf("Verify(%s content) succeeded, want checksum mismatch", name)
+			continue
+		}
+		if msg := err.Error(); !strings.Contains(msg, "checksum mismatch") || !strings.Contains(msg, fw.Checksum) {
+			t.Errorf("Verify(%s content) = %q, want a mismatch error naming %s", name, msg, fw.Checksum)
+		}
+	}
+
+	for _, checksum := range []string{"", "md5:d41d8cd98f00b204e9800998ecf8427e", "sha256:not-hex", "sha256:deadbeef"} {
+		bad := *fw
+		bad.Checksum = checksum
+		if err := bad.Verify(bytes.NewReader(content)); err == nil {
+			t.Errorf("Verify with checksum %q succeeded, want an error", checksum)
+		}
+	}
+}
+
+func TestAgentFlashFirmware(t *testing.T) {
+	content := bytes.Repeat([]byte{0xde, 0xad, 0xbe, 0xef}, 1024)
+	served := content
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(served)
+	}))
+	defer ts.Close()
+
+	fw := testFirmware(content)
+	fw.URL = ts.URL + "/fw-test.bin"
+	ag := agent.NewNodeAgent("node-flash", ts.URL)
+
+	var flashed []byte
+	flash := func(r io.Reader) error {
+		var err error
+		flashed, err = io.ReadAll(r)
+		return err
+	}
+	if err := ag.FlashFirmware(fw, flash); err != nil {
+		t.Fatalf("FlashFirmware: %v", err)
+	}
+	if !bytes.Equal(flashed, content) {
+		t.Errorf("flashed %d bytes, want the %d-byte image", len(flashed), len(content))
+	}
+
+	served = append(bytes.Clone(content[:len(content)-4]), 0, 0, 0, 0)
+	called := false
+	err := ag.FlashFirmware(fw, func(r io.Reader) error {
+		called = true
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("FlashFirmware(corrupt download) = %v, want checksum mismatch", err)
+	}
+	if called {
+		t.Error("flash was called with an image that failed verification")
+	}
+}
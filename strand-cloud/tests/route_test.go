@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
+)
+
+func TestRouteSelectEndpointDistribution(t *testing.T) {
+	r := &model.Route{
+		ID: "route-skewed",
+		Endpoints: []model.Endpoint{
+			{NodeID: "a", Address: "10.0.0.1:6477", Weight: 7},
+			{NodeID: "b", Address: "10.0.0.2:6477", Weight: 2},
+			{NodeID: "c", Address: "10.0.0.3:6477", Weight: 1},
+			{NodeID: "d", Address: "10.0.0.4:6477", Weight: 0},
+		},
+	}
+	want := map[string]float64{"a": 0.7, "b": 0.2, "c": 0.1, "d": 0}
+
+	rng := rand.New(rand.NewSource(1))
+	const trials = 100000
+	counts := make(map[string]int)
+	for i := 0; i < trials; i++ {
+		ep := r.SelectEndpoint(rng)
+		if ep == nil {
+			t.Fatal("SelectEndpoint returned nil")
+		}
+		counts[ep.NodeID]++
+	}
+	for id, p := range want {
+		got := float64(counts[id]) / trials
+		if math.Abs(got-p) > 0.01 {
+			t.Errorf("endpoint %s selected %.3f of the time, want %.3f", id, got, p)
+		}
+	}
+	if counts["d"] != 0 {
+		t.Errorf("zero-weight endpoint selected %d times", counts["d"])
+	}
+}
+
+func TestRouteSelectEndpointEdgeCases(t *testing.T) {
+	empty := &model.Route{ID: "empty"}
+	if ep := empty.SelectEndpoint(nil); ep != nil {
+		t.Fatalf("empty route: got %+v, want nil", ep)
+	}
+
+	// All-zero weights fall back to uniform selection.
+	r := &model.Route{Endpoints: []model.Endpoint{{NodeID: "a"}, {NodeID: "b"}}}
+	rng := rand.New(rand.NewSource(2))
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		seen[r.SelectEndpoint(rng).NodeID] = true
+	}
+	if !seen["a"] || !seen["b"] {
+		t.Fatalf("uniform fallback selected %v, want both endpoints", seen)
+	}
+}
+
+func TestRouteNormalize(t *testing.T) {
+	r := &model.Route{Endpoints: []model.Endpoint{{Weight: 3}, {Weight: 1}, {Weight: -2}}}
+	r.Normalize()
+	want := []float64{0.75, 0.25, 0}
+	for i, ep := range r.Endpoints {
+		if math.Abs(ep.Weight-want[i]) > 1e-9 {
+			t.Errorf("endpoint %d weight = %v, want %v", i, ep.Weight, want[i])
+		}
+	}
+
+	zero := &model.Route{Endpoints: []model.Endpoint{{}, {}, {}, {}}}
+	zero.Normalize()
+	for i, ep := range zero.Endpoints {
+		if ep.Weight != 0.25 {
+			t.Errorf("zero-weight endpoint %d weight = %v, want 0.25", i, ep.Weight)
+		}
+	}
+}
+
+func TestRouteHealthyEndpoints(t *testing.T) {
+	r := &model.Route{
+		ID: "route-health",
+		Endpoints: []model.Endpoint{
+			{NodeID: "up-1", Weight: 1},
+			{NodeID: "down", Weight: 10},
+			{NodeID: "up-2", Weight: 1},
+		},
+	}
+	status := map[string]string{"up-1": "online", "down": "unhealthy", "up-2": "online"}
+	nodeStatus := func(id string) string { return status[id] }
+
+	healthy := r.HealthyEndpoints(nodeStatus)
+	if len(healthy) != 2 || healthy[0].NodeID != "up-1" || healthy[1].NodeID != "up-2" {
+		t.Fatalf("HealthyEndpoints = %+v, want up-1 and up-2", healthy)
+	}
+
+	// The unhealthy endpoint carries most of the weight but is never chosen.
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < 10000; i++ {
+		ep := r.SelectHealthyEndpoint(nodeStatus, rng)
+		if ep == nil {
+			t.Fatal("SelectHealthyEndpoint returned nil")
+		}
+		if ep.NodeID == "down" {
+			t.Fatal("selected endpoint on unhealthy node")
+		}
+	}
+
+	status["up-1"], status["up-2"] = "offline", "draining"
+	if ep := r.SelectHealthyEndpoint(nodeStatus, rng); ep != nil {
+		t.Fatalf("no online nodes: got %+v, want nil", ep)
+	}
+}
+
+func TestRouteExpired(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := &model.Route{TTL: time.Minute, CreatedAt: created}
+	if r.Expired(created.Add(59 * time.Second)) {
+		t.Error("route expired before its TTL")
+	}
+	if !r.Expired(created.Add(time.Minute)) {
+		t.Error("route not expired at CreatedAt + TTL")
+	}
+	forever := &model.Route{CreatedAt: created}
+	if forever.Expired(created.Add(1000 * time.Hour)) {
+		t.Error("route without TTL expired")
+	}
+}
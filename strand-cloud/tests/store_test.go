@@ -1,6 +1,10 @@
 package tests
 
 import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -87,6 +91,47 @@ func TestNodeStore_CRUD(t *testing.T) {
 	}
 }
 
+func TestNodeStore_MetricsHistoryBounded(t *testing.T) {
+	s := store.NewMemoryStore()
+	ns := s.Nodes()
+	if err := ns.AppendMetrics("node-1", model.NodeMetricsSample{}); err == nil {
+		t.Fatal("expected error appending metrics for unknown node")
+	}
+	if err := ns.Create(&model.Node{ID: "node-1"}); err != nil {
+		t.Fatalf("create node: %v", err)
+	}
+
+	start := time.Now()
+	total := store.MetricsHistorySize + 5
+	for i := 0; i < total; i++ {
+		sample := model.NodeMetricsSample{
+			Timestamp: start.Add(time.Duration(i) * time.Second),
+			Metrics:   model.NodeMetrics{Connections: i},
+		}
+		if err := ns.AppendMetrics("node-1", sample); err != nil {
+			t.Fatalf("append metrics: %v", err)
+		}
+	}
+
+	hist, err := ns.MetricsHistory("node-1", time.Time{})
+	if err != nil {
+		t.Fatalf("metrics history: %v", err)
+	}
+	if len(hist) != store.MetricsHistorySize {
+		t.Fatalf("expected %d samples, got %d", store.MetricsHistorySize, len(hist))
+	}
+	if hist[0].Metrics.Connections != 5 || hist[len(hist)-1].Metrics.Connections != total-1 {
+		t.Errorf("history spans %d..%d, want 5..%d", hist[0].Metrics.Connections, hist[len(hist)-1].Metrics.Connections, total-1)
+	}
+
+	if err := ns.Delete("node-1"); err != nil {
+		t.Fatalf("delete node: %v", err)
+	}
+	if _, err := ns.MetricsHistory("node-1", time.Time{}); err == nil {
+		t.Error("expected error reading history of deleted node")
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Route store
 // ---------------------------------------------------------------------------
@@ -243,3 +288,184 @@ func TestFirmwareStore_CRUD(t *testing.T) {
 		t.Fatalf("expected 0 firmware, got %d", len(list))
 	}
 }
+
+// ---------------------------------------------------------------------------
+// Snapshot / restore
+// ---------------------------------------------------------------------------
+
+func TestMemoryStore_SnapshotRestore(t *testing.T) {
+	src := store.NewMemoryStore()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	nodes := []model.Node{
+		{ID: "node-a", Address: "10.0.0.1", Status: "online", LastSeen: now, SAD: []byte{1, 2}},
+		{ID: "node-b", Address: "10.0.0.2", Status: "offline", LastSeen: now},
+	}
+	for i := range nodes {
+		if err := src.Nodes().Create(&nodes[i]); err != nil {
+			t.Fatalf("create node: %v", err)
+		}
+	}
+	route := &model.Route{
+		ID:        "route-1",
+		SAD:       []byte{0xAA},
+		Endpoints: []model.Endpoint{{NodeID: "node-a", Address: "10.0.0.1:6477", Weight: 0.5}},
+		TTL:       time.Minute,
+		CreatedAt: now,
+	}
+	if err := src.Routes().Create(route); err != nil {
+		t.Fatalf("create route: %v", err)
+	}
+	tenant := &model.Tenant{ID: "t-1", Name: "Acme", Slug: "acme", CreatedAt: now}
+	if err := src.Tenants().Create(tenant); err != nil {
+		t.Fatalf("create tenant: %v", err)
+	}
+	if err := src.AuditLog().Append(&model.AuditEntry{ID: "a-1", TenantID: "t-1", Action: "create", CreatedAt: now}); err != nil {
+		t.Fatalf("append audit: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := src.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	dst := store.NewMemoryStore()
+	if err := dst.LoadSnapshot(&buf); err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+
+	gotNodes, _ := dst.Nodes().List()
+	if len(gotNodes) != 2 {
+		t.Fatalf("restored %d nodes, want 2", len(gotNodes))
+	}
+	for _, want := range nodes {
+		got, err := dst.Nodes().Get(want.ID)
+		if err != nil {
+			t.Fatalf("get restored node %s: %v", want.ID, err)
+		}
+		if got.Address != want.Address || got.Status != want.Status || !got.LastSeen.Equal(want.LastSeen) || !bytes.Equal(got.SAD, want.SAD) {
+			t.Errorf("node %s: got %+v, want %+v", want.ID, got, want)
+		}
+	}
+
+	gotRoute, err := dst.Routes().Get("route-1")
+	if err != nil {
+		t.Fatalf("get restored route: %v", err)
+	}
+	if gotRoute.TTL != route.TTL || len(gotRoute.Endpoints) != 1 || gotRoute.Endpoints[0] != route.Endpoints[0] {
+		t.Errorf("route: got %+v, want %+v", gotRoute, route)
+	}
+
+	// The slug index is rebuilt on load.
+	if got, err := dst.Tenants().GetBySlug("acme"); err != nil || got.ID != "t-1" {
+		t.Errorf("GetBySlug after restore: got %+v, %v", got, err)
+	}
+
+	entries, _ := dst.AuditLog().List("t-1", 0)
+	if len(entries) != 1 || entries[0].ID != "a-1" {
+		t.Errorf("audit log after restore: %+v", entries)
+	}
+}
+
+func TestMemoryStore_LoadSnapshotRejectsUnknownVersion(t *testing.T) {
+	s := store.NewMemoryStore()
+	if err := s.LoadSnapshot(strings.NewReader(`{"version": 99}`)); err == nil {
+		t.Fatal("expected error for unsupported snapshot version")
+	}
+}
+
+func TestMemoryStore_SnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+
+	src := store.NewMemoryStore()
+	if err := src.Nodes().Create(&model.Node{ID: "node-f", Address: "10.0.0.9"}); err != nil {
+		t.Fatalf("create node: %v", err)
+	}
+	if err := src.SaveSnapshotFile(path); err != nil {
+		t.Fatalf("SaveSnapshotFile: %v", err)
+	}
+
+	dst := store.NewMemoryStore()
+	if err := dst.LoadSnapshotFile(path); err != nil {
+		t.Fatalf("LoadSnapshotFile: %v", err)
+	}
+	if _, err := dst.Nodes().Get("node-f"); err != nil {
+		t.Errorf("node missing after file restore: %v", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Watch
+// ---------------------------------------------------------------------------
+
+func TestMemoryStore_Watch(t *testing.T) {
+	s := store.NewMemoryStore()
+	if err := s.Nodes().Create(&model.Node{ID: "existing", Status: "online"}); err != nil {
+		t.Fatalf("Create existing: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := s.Watch(ctx, store.ResourceNodes)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	next := func(want store.ChangeType) store.ChangeEvent {
+		t.Helper()
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				t.Fatalf("watch channel closed, want %s event", want)
+			}
+			if ev.Type != want || ev.ResourceType != store.ResourceNodes {
+				t.Fatalf("event = %s/%s, want %s/%s", ev.ResourceType, ev.Type, store.ResourceNodes, want)
+			}
+			return ev
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %s event", want)
+		}
+		return store.ChangeEvent{}
+	}
+
+	if err := s.Nodes().Create(&model.Node{ID: "node-w", Status: "online"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	ev := next(store.ChangeCreated)
+	if ev.ID != "node-w" {
+		t.Errorf("created ID = %q, want node-w", ev.ID)
+	}
+
+	if err := s.Nodes().Update(&model.Node{ID: "node-w", Status: "offline"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	ev = next(store.ChangeUpdated)
+	if n, ok := ev.Object.(model.Node); !ok || n.Status != "offline" {
+		t.Errorf("updated object = %#v, want node with status offline", ev.Object)
+	}
+
+	if err := s.Nodes().Delete("node-w"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	ev = next(store.ChangeDeleted)
+	if n, ok := ev.Object.(model.Node); ev.ID != "node-w" || !ok || n.ID != "node-w" {
+		t.Errorf("deleted event = %#v, want last known node-w", ev)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("unexpected event after cancel")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("watch channel not closed after cancel")
+	}
+}
+
+func TestMemoryStore_WatchUnknownResource(t *testing.T) {
+	s := store.NewMemoryStore()
+	if _, err := s.Watch(context.Background(), "widgets"); err == nil {
+		t.Error("expected error for unknown resource type")
+	}
+}
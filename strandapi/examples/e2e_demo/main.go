@@ -71,11 +71,11 @@ func main() {
 		WithCapability(sad.CodeGen).
 		ContextWindow(128000).
 		LatencySLA(500).
-		Build()
+		BuildSAD()
 	if err != nil {
 		log.Fatalf("build SAD: %v", err)
 	}
-	fmt.Printf("Model selector (SAD): %d bytes\n", len(modelSAD))
+	fmt.Printf("Model selector (SAD): %s, context %d\n", modelSAD.ModelType, modelSAD.ContextWindow)
 
 	// ---------------------------------------------------------------
 	// 3. Connect the client
@@ -93,12 +93,12 @@ func main() {
 	// ---------------------------------------------------------------
 	req := &protocol.InferenceRequest{
 		ID:          [16]byte{0x01, 0x02, 0x03, 0x04},
-		ModelSAD:    modelSAD,
 		Prompt:      "The Strand Protocol replaces TCP/IP with an AI-native networking stack",
 		MaxTokens:   512,
 		Temperature: 0.7,
 		Metadata:    map[string]string{"demo": "e2e"},
 	}
+	req.SetModelSAD(modelSAD)
 
 	fmt.Printf("Prompt: %q\n\n", req.Prompt)
 	fmt.Print("Streaming response: ")
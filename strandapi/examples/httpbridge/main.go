@@ -5,10 +5,18 @@
 // Endpoints:
 //
 //	GET  /                          — status page (HTML)
-//	GET  /v1/models                 — list available models
+//	GET  /v1/models                 — list available models (?capability=code_gen filters)
 //	POST /v1/chat/completions       — chat inference (streaming SSE or JSON)
 //	POST /v1/completions            — legacy completions
 //	GET  /healthz                   — health check
+//
+// By default requests are served by a local mock handler. Set
+// STRANDAPI_UPSTREAM to the overlay address of a Strand inference node to
+// forward requests there instead; when an HTTP client disconnects mid-stream
+// the bridge sends a CANCEL frame upstream so the node stops generating. Set
+// STRANDAPI_OVERLAY_ADDR to also serve the same handler to native StrandAPI
+// clients on that overlay address; its transport counters then appear in
+// GET /metrics.
 package main
 
 import (
@@ -23,11 +31,15 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/strand-protocol/strand/strandapi/pkg/client"
+	"github.com/strand-protocol/strand/strandapi/pkg/cors"
 	"github.com/strand-protocol/strand/strandapi/pkg/protocol"
+	"github.com/strand-protocol/strand/strandapi/pkg/sad"
 	"github.com/strand-protocol/strand/strandapi/pkg/server"
 )
 
@@ -56,6 +68,61 @@ func (h *streamHandler) HandleTokenStream(_ context.Context, req *protocol.Infer
 	return nil
 }
 
+// --------------------------------------------------------------------------
+// Upstream inference handler (forwards to a Strand node)
+// --------------------------------------------------------------------------
+
+// upstreamCancelTimeout bounds how long the bridge spends telling the node
+// about a cancelled request and draining its remaining frames.
+const upstreamCancelTimeout = 2 * time.Second
+
+// upstreamHandler forwards inference requests to a remote Strand node over
+// the overlay transport. The client does not demultiplex concurrent streams,
+// so requests are serialised on a single upstream connection.
+type upstreamHandler struct {
+	mu sync.Mutex
+	c  *client.Client
+}
+
+func (h *upstreamHandler) HandleTokenStream(ctx context.Context, req *protocol.InferenceRequest, sender server.TokenSender) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if req.ID == ([16]byte{}) {
+		_, _ = rand.Read(req.ID[:])
+	}
+
+	ch, err := h.c.StreamTokens(ctx, req)
+	if err != nil {
+		return err
+	}
+	for chunk := range ch {
+		if err := sender.Send(chunk); err != nil {
+			break
+		}
+	}
+
+	if ctx.Err() == nil {
+		return nil
+	}
+
+	// The HTTP client went away: tell the node to stop generating and drain
+	// whatever it already sent so the next request starts on a clean stream.
+	cctx, cancel := context.WithTimeout(context.Background(), upstreamCancelTimeout)
+	defer cancel()
+	if err := h.c.CancelStream(cctx, req.ID); err != nil {
+		log.Printf("httpbridge: cancel upstream request: %v", err)
+		return ctx.Err()
+	}
+	for {
+		opcode, _, err := h.c.RawRecv(cctx)
+		if err != nil || opcode == protocol.OpTokenStreamEnd || opcode == protocol.OpError {
+			break
+		}
+	}
+	return ctx.Err()
+}
+
 // --------------------------------------------------------------------------
 // OpenAI-compatible types
 // --------------------------------------------------------------------------
@@ -306,35 +373,94 @@ async function runInference(stream) {
 </html>`)
 }
 
-func handleModels(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"object": "list",
-		"data": []map[string]interface{}{
-			{
-				"id":       "strand-mock-v1",
-				"object":   "model",
-				"created":  time.Now().Unix(),
-				"owned_by": "strand-protocol",
-				"sad":      "sad:llm-inference:mock:128k",
-			},
-		},
-	})
+// bridgeModel is an entry in the /v1/models listing.
+type bridgeModel struct {
+	ID         string
+	Descriptor string // human-readable SAD string
+	SAD        sad.SAD
 }
 
-func handleMetrics(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]int64{
-		"request_count": metrics.requestCount.Load(),
-		"error_count":   metrics.errorCount.Load(),
-		"stream_count":  metrics.streamCount.Load(),
-	})
+// bridgeModels are the models the bridge advertises.
+var bridgeModels = []bridgeModel{
+	{
+		ID:         "strand-mock-v1",
+		Descriptor: "sad:llm-inference:mock:128k",
+		SAD:        sad.SAD{Version: 1, ModelType: "llm", Capabilities: sad.TextGen, ContextWindow: 128 * 1024},
+	},
+}
+
+// handleModels lists models. Each capability query parameter, repeated or
+// comma-separated, narrows the list to models whose SAD has that capability
+// (see sad.Matches); unknown capability names are rejected with 400.
+func handleModels(models []bridgeModel) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var query sad.SAD
+		for _, param := range r.URL.Query()["capability"] {
+			for _, name := range strings.Split(param, ",") {
+				if strings.TrimSpace(name) == "" {
+					continue
+				}
+				flag, err := sad.ParseCapability(name)
+				if err != nil {
+					writeJSONError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+					return
+				}
+				query.Capabilities |= flag
+			}
+		}
+
+		data := []map[string]interface{}{}
+		for _, m := range models {
+			if !sad.Matches(&m.SAD, &query) {
+				continue
+			}
+			data = append(data, map[string]interface{}{
+				"id":           m.ID,
+				"object":       "model",
+				"created":      time.Now().Unix(),
+				"owned_by":     "strand-protocol",
+				"sad":          m.Descriptor,
+				"capabilities": sad.CapabilityNames(m.SAD.Capabilities),
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"object": "list",
+			"data":   data,
+		})
+	}
+}
+
+// handleMetrics reports the bridge's request counters and, when srv is
+// serving on a transport that counts traffic, that transport's frame, byte
+// and drop counters.
+func handleMetrics(srv *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		out := map[string]uint64{
+			"request_count": uint64(metrics.requestCount.Load()),
+			"error_count":   uint64(metrics.errorCount.Load()),
+			"stream_count":  uint64(metrics.streamCount.Load()),
+		}
+		if srv != nil {
+			if st, ok := srv.Stats(); ok {
+				out["transport_frames_sent"] = st.FramesSent
+				out["transport_frames_received"] = st.FramesReceived
+				out["transport_bytes_sent"] = st.BytesSent
+				out["transport_bytes_received"] = st.BytesReceived
+				out["transport_frames_dropped"] = st.FramesDropped
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	}
 }
 
 // maxAllowedTokens is the upper bound for max_tokens in a request.
 const maxAllowedTokens = 100000
 
-func handleChat(sh *streamHandler) http.HandlerFunc {
+// handleChat serves chat completions, rendering the conversation with
+// chatTemplate (see protocol.RenderChat).
+func handleChat(sh server.StreamHandler, chatTemplate string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		metrics.requestCount.Add(1)
 
@@ -366,26 +492,37 @@ func handleChat(sh *streamHandler) http.HandlerFunc {
 			return
 		}
 
-		// Flatten messages into a single prompt.
-		var prompt strings.Builder
-		for _, m := range req.Messages {
-			if m.Role == "user" {
-				if prompt.Len() > 0 {
-					prompt.WriteString("\n")
-				}
-				prompt.WriteString(m.Content)
-			}
+		// Render every turn, including system and assistant messages, into a
+		// single prompt.
+		var hasUser bool
+		messages := make([]protocol.ChatMessage, len(req.Messages))
+		for i, m := range req.Messages {
+			messages[i] = protocol.ChatMessage{Role: m.Role, Content: m.Content}
+			hasUser = hasUser || m.Role == protocol.ChatRoleUser
 		}
-		if prompt.Len() == 0 {
+		if !hasUser {
 			metrics.errorCount.Add(1)
 			writeJSONError(w, http.StatusBadRequest, "invalid_request_error", "no user message")
 			return
 		}
+		prompt, err := protocol.RenderChat(messages, chatTemplate)
+		if err != nil {
+			metrics.errorCount.Add(1)
+			writeJSONError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+			return
+		}
 
+		// Carry the X-Request-ID into the protocol so server-side logs can be
+		// correlated with the HTTP request.
+		httpReqID := r.Header.Get("X-Request-ID")
 		strandReq := &protocol.InferenceRequest{
-			Prompt:    prompt.String(),
+			ID:        protocol.RequestIDFromString(httpReqID),
+			Prompt:    prompt,
 			MaxTokens: uint32(req.MaxTokens),
-			Metadata:  map[string]string{"model": req.Model},
+			Metadata: map[string]string{
+				"model":                         req.Model,
+				protocol.InferenceMetaRequestID: httpReqID,
+			},
 		}
 
 		if req.Stream {
@@ -434,7 +571,7 @@ func handleChat(sh *streamHandler) http.HandlerFunc {
 				FinishReason: "stop",
 			}},
 		}
-		resp.Usage.PromptTokens = len(strings.Fields(prompt.String()))
+		resp.Usage.PromptTokens = len(strings.Fields(prompt))
 		resp.Usage.CompletionTokens = len(strings.Fields(text))
 		resp.Usage.TotalTokens = resp.Usage.PromptTokens + resp.Usage.CompletionTokens
 
@@ -483,45 +620,15 @@ func (c *collectSender) Send(chunk *protocol.TokenStreamChunk) error {
 // Middleware
 // --------------------------------------------------------------------------
 
-// corsAllowedOrigins parses the STRANDAPI_CORS_ORIGINS env var (comma-separated)
-// and returns an origin allowlist. Defaults to "http://localhost:9000".
-func corsAllowedOrigins() []string {
+// corsPolicy builds the CORS allowlist from the STRANDAPI_CORS_ORIGINS env
+// var: comma-separated exact origins or wildcard subdomain patterns such as
+// "https://*.example.com". Defaults to "http://localhost:9000".
+func corsPolicy() (*cors.Policy, error) {
 	raw := os.Getenv("STRANDAPI_CORS_ORIGINS")
 	if raw == "" {
-		return []string{"http://localhost:9000"}
-	}
-	var origins []string
-	for _, o := range strings.Split(raw, ",") {
-		o = strings.TrimSpace(o)
-		if o != "" {
-			origins = append(origins, o)
-		}
+		raw = "http://localhost:9000"
 	}
-	return origins
-}
-
-// corsMiddleware checks the request Origin against the allowlist and sets
-// CORS headers only when matched. Never emits a wildcard "*".
-func corsMiddleware(allowed []string, next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		origin := r.Header.Get("Origin")
-		if origin != "" {
-			for _, a := range allowed {
-				if a == origin {
-					w.Header().Set("Access-Control-Allow-Origin", origin)
-					w.Header().Set("Access-Control-Allow-Credentials", "true")
-					break
-				}
-			}
-		}
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
+	return cors.ParseList(raw)
 }
 
 // securityHeaders adds defensive HTTP headers to every response.
@@ -536,7 +643,9 @@ func securityHeaders(next http.Handler) http.Handler {
 	})
 }
 
-// requestIDMiddleware generates a unique X-Request-ID for every request.
+// requestIDMiddleware generates a unique X-Request-ID for every request that
+// lacks one, and sets it on the request as well as the response so handlers
+// can forward it.
 func requestIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		id := r.Header.Get("X-Request-ID")
@@ -544,6 +653,7 @@ func requestIDMiddleware(next http.Handler) http.Handler {
 			b := make([]byte, 16)
 			_, _ = rand.Read(b)
 			id = hex.EncodeToString(b)
+			r.Header.Set("X-Request-ID", id)
 		}
 		w.Header().Set("X-Request-ID", id)
 		next.ServeHTTP(w, r)
@@ -563,7 +673,28 @@ func main() {
 		httpAddr = os.Args[1]
 	}
 
-	sh := &streamHandler{}
+	var sh server.StreamHandler = &streamHandler{}
+	if upstream := os.Getenv("STRANDAPI_UPSTREAM"); upstream != "" {
+		c, err := client.Dial(upstream)
+		if err != nil {
+			log.Fatalf("upstream: %v", err)
+		}
+		defer c.Close()
+		sh = &upstreamHandler{c: c}
+		log.Printf("forwarding inference to upstream node %s", upstream)
+	}
+
+	var overlaySrv *server.Server
+	if addr := os.Getenv("STRANDAPI_OVERLAY_ADDR"); addr != "" {
+		overlaySrv = server.New(nil, server.WithStreamHandler(sh))
+		go func() {
+			if err := overlaySrv.ListenAndServe(addr); err != nil {
+				log.Fatalf("overlay: %v", err)
+			}
+		}()
+		defer overlaySrv.Stop()
+		log.Printf("serving StrandAPI on overlay address %s", addr)
+	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", handleRoot)
@@ -571,14 +702,20 @@ func main() {
 		w.Header().Set("Content-Type", "application/json")
 		fmt.Fprint(w, `{"status":"ok"}`)
 	})
-	mux.HandleFunc("/metrics", handleMetrics)
-	mux.HandleFunc("/v1/models", handleModels)
-	mux.HandleFunc("/v1/chat/completions", handleChat(sh))
-	mux.HandleFunc("/v1/completions", handleChat(sh)) // alias
+	mux.HandleFunc("/metrics", handleMetrics(overlaySrv))
+	mux.HandleFunc("/v1/models", handleModels(bridgeModels))
+	// STRANDAPI_CHAT_TEMPLATE overrides how chat turns are flattened into
+	// the prompt; see protocol.RenderChat for the placeholder syntax.
+	chatTemplate := os.Getenv("STRANDAPI_CHAT_TEMPLATE")
+	mux.HandleFunc("/v1/chat/completions", handleChat(sh, chatTemplate))
+	mux.HandleFunc("/v1/completions", handleChat(sh, chatTemplate)) // alias
 
 	// Apply middleware: request ID -> security headers -> CORS -> mux
-	allowed := corsAllowedOrigins()
-	handler := requestIDMiddleware(securityHeaders(corsMiddleware(allowed, mux)))
+	policy, err := corsPolicy()
+	if err != nil {
+		log.Fatalf("STRANDAPI_CORS_ORIGINS: %v", err)
+	}
+	handler := requestIDMiddleware(securityHeaders(policy.Handler(mux)))
 
 	srv := &http.Server{
 		Addr:         httpAddr,
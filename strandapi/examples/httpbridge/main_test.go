@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/strand-protocol/strand/strandapi/pkg/client"
+	"github.com/strand-protocol/strand/strandapi/pkg/protocol"
+	"github.com/strand-protocol/strand/strandapi/pkg/sad"
+	"github.com/strand-protocol/strand/strandapi/pkg/server"
+	"github.com/strand-protocol/strand/strandapi/pkg/transport"
+)
+
+// recordingHandler remembers the last request it was asked to serve.
+type recordingHandler struct {
+	req *protocol.InferenceRequest
+}
+
+func (h *recordingHandler) HandleTokenStream(_ context.Context, req *protocol.InferenceRequest, sender server.TokenSender) error {
+	h.req = req
+	return sender.Send(&protocol.TokenStreamChunk{RequestID: req.ID, Token: "ok"})
+}
+
+func TestChatPropagatesRequestID(t *testing.T) {
+	const body = `{"model":"strand-mock-v1","messages":[{"role":"user","content":"hi"}],"max_tokens":8}`
+
+	t.Run("client supplied", func(t *testing.T) {
+		h := &recordingHandler{}
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("X-Request-ID", "trace-1234")
+		rec := httptest.NewRecorder()
+		requestIDMiddleware(handleChat(h, "")).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+		}
+		if h.req == nil {
+			t.Fatal("handler was not called")
+		}
+		if got := h.req.Metadata[protocol.InferenceMetaRequestID]; got != "trace-1234" {
+			t.Errorf("metadata request_id = %q, want %q", got, "trace-1234")
+		}
+		if h.req.ID != protocol.RequestIDFromString("trace-1234") {
+			t.Errorf("request ID = %x, want it derived from X-Request-ID", h.req.ID)
+		}
+		if h.req.Metadata["model"] != "strand-mock-v1" {
+			t.Errorf("metadata model = %q, want strand-mock-v1", h.req.Metadata["model"])
+		}
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		h := &recordingHandler{}
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		requestIDMiddleware(handleChat(h, "")).ServeHTTP(rec, req)
+
+		id := rec.Header().Get("X-Request-ID")
+		if id == "" {
+			t.Fatal("response has no X-Request-ID")
+		}
+		if h.req == nil {
+			t.Fatal("handler was not called")
+		}
+		if got := h.req.Metadata[protocol.InferenceMetaRequestID]; got != id {
+			t.Errorf("metadata request_id = %q, want the generated %q", got, id)
+		}
+		if h.req.ID != protocol.RequestIDFromString(id) {
+			t.Errorf("request ID = %x, want it derived from %q", h.req.ID, id)
+		}
+	})
+}
+
+func TestModelsCapabilityFilter(t *testing.T) {
+	models := []bridgeModel{
+		{ID: "chat", SAD: sad.SAD{Capabilities: sad.TextGen}},
+		{ID: "coder", SAD: sad.SAD{Capabilities: sad.TextGen | sad.CodeGen}},
+		{ID: "coder-tools", SAD: sad.SAD{Capabilities: sad.CodeGen | sad.ToolUse}},
+		{ID: "embedder", SAD: sad.SAD{Capabilities: sad.Embedding}},
+	}
+	list := func(t *testing.T, query string) []string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handleModels(models).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/models"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status %d, body %s", query, rec.Code, rec.Body)
+		}
+		var resp struct {
+			Data []struct {
+				ID           string   `json:"id"`
+				Capabilities []string `json:"capabilities"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		ids := []string{}
+		for _, m := range resp.Data {
+			ids = append(ids, m.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"chat", "coder", "coder-tools", "embedder"}},
+		{"?capability=code_gen", []string{"coder", "coder-tools"}},
+		{"?capability=code_gen,tool_use", []string{"coder-tools"}},
+		{"?capability=text_gen&capability=code_gen", []string{"coder"}},
+		{"?capability=vision", []string{}},
+	}
+	for _, tc := range tests {
+		if got := list(t, tc.query); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("GET /v1/models%s = %v, want %v", tc.query, got, tc.want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handleModels(models).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/models?capability=telepathy", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown capability: status %d, want 400", rec.Code)
+	}
+}
+
+func TestMetricsReportOverlayTransportStats(t *testing.T) {
+	tr, err := transport.ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := server.New(nil, server.WithStreamHandler(&streamHandler{}))
+	go srv.Serve(tr)
+	defer srv.Stop()
+
+	c, err := client.Dial(tr.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	const requests = 3
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < requests; i++ {
+		resp, err := c.Infer(ctx, &protocol.InferenceRequest{Prompt: "one two", Metadata: map[string]string{}})
+		if err != nil {
+			t.Fatalf("Infer: %v", err)
+		}
+		if resp.Text != "one two" {
+			t.Errorf("Infer text = %q, want %q", resp.Text, "one two")
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handleMetrics(srv).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	var got map[string]uint64
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode metrics %s: %v", rec.Body, err)
+	}
+	// Each request is one frame in and, since Infer does not ask for a
+	// stream, one collected response frame out.
+	if got["transport_frames_received"] != requests {
+		t.Errorf("transport_frames_received = %d, want %d", got["transport_frames_received"], requests)
+	}
+	if got["transport_frames_sent"] != requests {
+		t.Errorf("transport_frames_sent = %d, want %d", got["transport_frames_sent"], requests)
+	}
+	if got["transport_bytes_received"] == 0 || got["transport_bytes_sent"] == 0 {
+		t.Errorf("byte counters = %d in, %d out, want both non-zero", got["transport_bytes_received"], got["transport_bytes_sent"])
+	}
+	if _, ok := got["transport_frames_dropped"]; !ok {
+		t.Error("metrics missing transport_frames_dropped")
+	}
+
+	rec = httptest.NewRecorder()
+	handleMetrics(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if strings.Contains(rec.Body.String(), "transport_") {
+		t.Errorf("metrics without an overlay server = %s, want no transport counters", rec.Body)
+	}
+}
@@ -45,7 +45,7 @@ func (h *mockStreamHandler) HandleTokenStream(_ context.Context, req *protocol.I
 type noopHandler struct{}
 
 func (h *noopHandler) HandleInference(_ context.Context, _ *protocol.InferenceRequest) (*protocol.InferenceResponse, error) {
-	return nil, fmt.Errorf("use streaming mode")
+	return nil, &protocol.ErrorMessage{Code: protocol.ErrUnsupported, Message: "use streaming mode"}
 }
 
 func main() {
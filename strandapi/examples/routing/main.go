@@ -1,6 +1,7 @@
 // Command routing demonstrates SAD-based semantic routing. It builds
 // several model descriptors, creates a mock routing table, and resolves
-// a request SAD to the best matching node with scoring.
+// a request SAD to the best matching node with sad.Rank, using the load
+// each node reported in its heartbeats to break ties.
 //
 // Usage:
 //
@@ -9,9 +10,8 @@ package main
 
 import (
 	"fmt"
-	"math"
-	"sort"
 
+	"github.com/strand-protocol/strand/strandapi/pkg/protocol"
 	"github.com/strand-protocol/strand/strandapi/pkg/sad"
 )
 
@@ -21,87 +21,6 @@ type node struct {
 	Descriptor *sad.SAD
 }
 
-// scoredNode pairs a node with its resolution score.
-type scoredNode struct {
-	Node  node
-	Score float64
-}
-
-// resolve finds the best matching nodes for a request SAD, using weighted
-// multi-constraint scoring per CLAUDE.md §2.2.
-func resolve(request *sad.SAD, nodes []node) []scoredNode {
-	// Default weights from the spec:
-	//   CAPABILITY=0.3, LATENCY=0.25, COST=0.2, CONTEXT_WINDOW=0.15, TRUST=0.1
-	const (
-		wCap     = 0.30
-		wLatency = 0.25
-		wCost    = 0.20
-		wCtx     = 0.15
-		wTrust   = 0.10
-	)
-
-	var results []scoredNode
-
-	for _, n := range nodes {
-		// Hard constraint: context window must meet request minimum.
-		if request.ContextWindow > 0 && n.Descriptor.ContextWindow < request.ContextWindow {
-			continue // disqualified
-		}
-
-		// Capability score: fraction of requested capabilities present.
-		capScore := 0.0
-		if request.Capabilities != 0 {
-			matched := request.Capabilities & n.Descriptor.Capabilities
-			total := popcount(request.Capabilities)
-			if total > 0 {
-				capScore = float64(popcount(matched)) / float64(total)
-			}
-		} else {
-			capScore = 1.0 // no requirements → full score
-		}
-
-		// Latency score: how well the node meets the SLA (lower is better).
-		latScore := 1.0
-		if request.LatencySLA > 0 && n.Descriptor.LatencySLA > 0 {
-			ratio := float64(n.Descriptor.LatencySLA) / float64(request.LatencySLA)
-			if ratio <= 1.0 {
-				latScore = 1.0
-			} else {
-				latScore = math.Max(0.0, 1.0-((ratio-1.0)*0.5))
-			}
-		}
-
-		// Context window score: bonus for exceeding minimum.
-		ctxScore := 1.0
-		if request.ContextWindow > 0 {
-			ratio := float64(n.Descriptor.ContextWindow) / float64(request.ContextWindow)
-			ctxScore = math.Min(ratio, 2.0) / 2.0 // cap at 1.0 for 2x
-		}
-
-		// Cost and trust are not modeled in the SAD struct yet, so use 1.0.
-		costScore := 1.0
-		trustScore := 1.0
-
-		total := wCap*capScore + wLatency*latScore + wCost*costScore + wCtx*ctxScore + wTrust*trustScore
-
-		results = append(results, scoredNode{Node: n, Score: total})
-	}
-
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
-	})
-	return results
-}
-
-func popcount(v uint32) uint32 {
-	var count uint32
-	for v != 0 {
-		count += v & 1
-		v >>= 1
-	}
-	return count
-}
-
 func capString(caps uint32) string {
 	var names []string
 	if caps&sad.TextGen != 0 {
@@ -183,6 +102,31 @@ func main() {
 				LatencySLA:    100,
 			},
 		},
+		{
+			Name: "code-fast-node-2",
+			Descriptor: &sad.SAD{
+				Version:       1,
+				ModelType:     "llm",
+				Capabilities:  sad.TextGen | sad.CodeGen,
+				ContextWindow: 32000,
+				LatencySLA:    100,
+			},
+		},
+	}
+
+	// Health as last reported in each node's heartbeats.
+	health := map[string]protocol.NodeHealth{
+		"code-fast-node":   {Load: 0.85, ActiveStreams: 14, QueueDepth: 6},
+		"code-fast-node-2": {Load: 0.20, ActiveStreams: 3},
+	}
+	hp := sad.HealthFunc(func(id string) (sad.Health, bool) {
+		h, ok := health[id]
+		return sad.Health{Load: h.Load, QueueDepth: h.QueueDepth}, ok
+	})
+
+	candidates := make([]sad.Candidate, len(nodes))
+	for i, n := range nodes {
+		candidates[i] = sad.Candidate{ID: n.Name, SAD: n.Descriptor}
 	}
 
 	fmt.Println("Registered nodes:")
@@ -208,6 +152,13 @@ func main() {
 				LatencySLA:    500,
 			},
 		},
+		{
+			name: "Fast code completion",
+			sad: &sad.SAD{
+				Capabilities: sad.TextGen | sad.CodeGen,
+				LatencySLA:   100,
+			},
+		},
 		{
 			name: "Fast embedding lookup",
 			sad: &sad.SAD{
@@ -229,15 +180,15 @@ func main() {
 		fmt.Printf("  Required: caps=%s  ctx>=%dk  lat<=%dms\n",
 			capString(r.sad.Capabilities), r.sad.ContextWindow/1000, r.sad.LatencySLA)
 
-		results := resolve(r.sad, nodes)
+		results := sad.Rank(r.sad, candidates, hp)
 		if len(results) == 0 {
 			fmt.Println("  Result: no matching nodes")
 		} else {
 			fmt.Println("  Results (best first):")
 			for i, s := range results {
-				fmt.Printf("    %d. %-20s  score=%.3f\n", i+1, s.Node.Name, s.Score)
+				fmt.Printf("    %d. %-20s  score=%.3f\n", i+1, s.ID, s.Score)
 			}
-			fmt.Printf("  -> Routed to: %s (score %.3f)\n", results[0].Node.Name, results[0].Score)
+			fmt.Printf("  -> Routed to: %s (score %.3f)\n", results[0].ID, results[0].Score)
 		}
 		fmt.Println()
 	}
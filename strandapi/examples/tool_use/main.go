@@ -57,7 +57,9 @@ func main() {
 		fmt.Printf("[server] Received request: %q\n", req.Prompt)
 
 		// Step 1: Start streaming some initial text.
-		srvTransport.Send(ctx, protocol.OpTokenStreamStart, nil)
+		start := strandbuf.NewBuffer(16)
+		(&protocol.StreamStart{RequestID: req.ID}).Encode(start)
+		srvTransport.Send(ctx, protocol.OpTokenStreamStart, start.Bytes())
 		sendToken := func(seq uint32, text string) {
 			chunk := &protocol.TokenStreamChunk{RequestID: req.ID, SeqNum: seq, Token: text}
 			buf := strandbuf.NewBuffer(64)
@@ -149,8 +151,14 @@ func main() {
 			if err := invoke.Decode(strandbuf.NewReader(payload)); err == nil {
 				fmt.Printf("\n[client] Tool invoked: %s(%s)\n", invoke.ToolName, string(invoke.Arguments))
 
-				// Handle the tool call: simple calculator.
-				result := handleCalculator(invoke.Arguments)
+				// Reject malformed arguments before running the tool.
+				var result string
+				if err := protocol.ValidateToolArgs(invoke, []byte(calculatorSchema)); err != nil {
+					result = "error: " + err.Error()
+				} else {
+					// Handle the tool call: simple calculator.
+					result = handleCalculator(invoke.Arguments)
+				}
 				fmt.Printf("[client] Tool result: %s\n", result)
 				fmt.Print("[client] Streaming: ")
 
@@ -181,6 +189,14 @@ done:
 	fmt.Println("=== Tool use demo complete ===")
 }
 
+// calculatorSchema describes the arguments accepted by the calculator tool.
+const calculatorSchema = `{
+	"type": "object",
+	"properties": {"expression": {"type": "string"}},
+	"required": ["expression"],
+	"additionalProperties": false
+}`
+
 // handleCalculator is a mock tool that evaluates simple "A * B" expressions.
 func handleCalculator(args []byte) string {
 	// Very simple parser for {"expression": "42 * 137"}
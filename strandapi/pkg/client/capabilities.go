@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/strand-protocol/strand/strandapi/pkg/protocol"
+	"github.com/strand-protocol/strand/strandapi/pkg/sad"
+	"github.com/strand-protocol/strand/strandapi/pkg/strandbuf"
+)
+
+// QueryCapabilities asks the node which models it serves by sending an
+// OpCapabilitiesQuery, and returns the decoded Semantic Address Descriptors
+// from its reply in the order the node listed them. A node configured with no
+// descriptors returns an empty slice.
+func (c *Client) QueryCapabilities(ctx context.Context) ([]*sad.SAD, error) {
+	if err := c.send(ctx, protocol.OpCapabilitiesQuery, nil); err != nil {
+		return nil, sendFailed("capabilities query", err)
+	}
+	opcode, payload, err := c.transport.Recv(ctx)
+	if err != nil {
+		return nil, recvFailed(ctx, "capabilities response", err)
+	}
+	if opcode == protocol.OpError {
+		return nil, newServerError(payload)
+	}
+	if opcode != protocol.OpCapabilitiesResponse {
+		return nil, fmt.Errorf("strandapi client: unexpected opcode 0x%02x, want 0x%02x", opcode, protocol.OpCapabilitiesResponse)
+	}
+	resp := &protocol.CapabilitiesResponse{}
+	if err := resp.Decode(strandbuf.NewReader(payload)); err != nil {
+		return nil, fmt.Errorf("strandapi client: decode capabilities response: %w", err)
+	}
+	sads := make([]*sad.SAD, 0, len(resp.SADs))
+	for i, raw := range resp.SADs {
+		d := &sad.SAD{}
+		if err := d.Decode(strandbuf.NewReader(raw)); err != nil {
+			return nil, fmt.Errorf("strandapi client: decode SAD %d: %w", i, err)
+		}
+		sads = append(sads, d)
+	}
+	return sads, nil
+}
@@ -4,8 +4,11 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/strand-protocol/strand/strandapi/pkg/strandbuf"
 	"github.com/strand-protocol/strand/strandapi/pkg/protocol"
@@ -23,12 +26,33 @@ func WithTransport(t transport.Transport) Option {
 	}
 }
 
+// WithMaxMessageSize sets the largest payload, in bytes, the client will send.
+// It is proposed to the server by Hello, which may lower it further. Payloads
+// above the limit are rejected before reaching the transport.
+func WithMaxMessageSize(n uint32) Option {
+	return func(c *Client) {
+		c.maxMessageSize = n
+	}
+}
+
 // Client is the primary entry point for StrandAPI consumers. It manages the
 // underlying transport and provides typed helpers for every StrandAPI operation.
 type Client struct {
 	transport transport.Transport
 	mu        sync.Mutex
 	closed    bool
+	// maxMessageSize is the outbound payload limit: the configured value until
+	// Hello completes, then the negotiated one. Zero means unlimited.
+	maxMessageSize uint32
+	// reorderWindow is how many early chunks a TokenStream holds back to
+	// restore SeqNum order; see WithReorderWindow.
+	reorderWindow int
+	// compressors are offered in Hello (see WithCompression); compressor is
+	// the one Hello settled on, nil for raw frames.
+	compressors []protocol.Compressor
+	compressor  protocol.Compressor
+	// idGen fills in zero request IDs; see WithIDGenerator.
+	idGen func() [16]byte
 }
 
 // Dial creates a new Client connected to the overlay transport at addr.
@@ -40,29 +64,109 @@ func Dial(addr string, opts ...Option) (*Client, error) {
 	if c.transport == nil {
 		t, err := transport.DialOverlay(addr)
 		if err != nil {
-			return nil, fmt.Errorf("strandapi client: dial: %w", err)
+			return nil, &callError{op: "dial", kind: ErrDialFailed, err: err}
 		}
 		c.transport = t
 	}
 	return c, nil
 }
 
+// Hello performs the OpHello handshake, proposing the client's configured
+// maximum message size, capped to what the transport can carry in one frame
+// (transport.MaxPayloadTransport), and compression codecs. It returns the
+// server's reply and, from then on, enforces the negotiated MaxMessageSize on
+// every outgoing payload and compresses with the negotiated codec, if any.
+func (c *Client) Hello(ctx context.Context) (*protocol.Hello, error) {
+	c.mu.Lock()
+	proposed := c.maxMessageSize
+	c.mu.Unlock()
+	if mt, ok := c.transport.(transport.MaxPayloadTransport); ok {
+		proposed = protocol.NegotiateMaxMessageSize(proposed, uint32(mt.MaxPayload()))
+	}
+
+	msg := &protocol.Hello{Version: protocol.HelloVersion, MaxMessageSize: proposed, Codecs: c.offeredCodecs()}
+	buf := strandbuf.NewBuffer(8)
+	msg.Encode(buf)
+	if err := c.transport.Send(ctx, protocol.OpHello, buf.Bytes()); err != nil {
+		return nil, sendFailed("hello", err)
+	}
+
+	opcode, payload, err := c.transport.Recv(ctx)
+	if err != nil {
+		return nil, recvFailed(ctx, "hello", err)
+	}
+	if opcode == protocol.OpError {
+		return nil, newServerError(payload)
+	}
+	if opcode != protocol.OpHello {
+		return nil, fmt.Errorf("strandapi client: unexpected opcode 0x%02x, want 0x%02x", opcode, protocol.OpHello)
+	}
+	resp := &protocol.Hello{}
+	if err := resp.Decode(strandbuf.NewReader(payload)); err != nil {
+		return nil, fmt.Errorf("strandapi client: decode hello: %w", err)
+	}
+
+	c.mu.Lock()
+	c.maxMessageSize = protocol.NegotiateMaxMessageSize(proposed, resp.MaxMessageSize)
+	c.mu.Unlock()
+	c.negotiateCompression(resp.Codecs)
+	return resp, nil
+}
+
+// MaxMessageSize returns the current outbound payload limit (0 if none).
+func (c *Client) MaxMessageSize() uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.maxMessageSize
+}
+
+// checkSize rejects payloads larger than the negotiated message size. The
+// returned error wraps transport.ErrMessageTooLarge.
+func (c *Client) checkSize(opcode byte, payload []byte) error {
+	limit := c.MaxMessageSize()
+	if limit == 0 || uint32(len(payload)) <= limit {
+		return nil
+	}
+	return fmt.Errorf("strandapi client: %s payload of %d bytes exceeds negotiated maximum of %d: %w",
+		protocol.OpcodeNames[opcode], len(payload), limit, transport.ErrMessageTooLarge)
+}
+
 // Infer sends a synchronous inference request and blocks until the complete
-// response arrives. For streaming use StreamTokens instead.
+// response arrives. For streaming use StreamTokens instead. Against a server
+// that only streams — it answers with a token stream, or with an OpError
+// carrying ErrUnsupported — Infer collects the stream and
+// returns the assembled text, with usage and finish reason taken from the
+// stream summary. A zero req.ID is replaced with one from the
+// client's ID generator (see WithIDGenerator) before sending. A response
+// whose ID differs from req.ID is rejected with ErrResponseMismatch.
 func (c *Client) Infer(ctx context.Context, req *protocol.InferenceRequest) (*protocol.InferenceResponse, error) {
+	c.fillID(req)
 	buf := strandbuf.NewBuffer(256)
-	req.Encode(buf)
+	encodeRequest(buf, req)
+	if err := c.checkSize(protocol.OpInferenceRequest, buf.Bytes()); err != nil {
+		return nil, err
+	}
 
-	if err := c.transport.Send(ctx, protocol.OpInferenceRequest, buf.Bytes()); err != nil {
-		return nil, fmt.Errorf("strandapi client: send inference request: %w", err)
+	if err := c.send(ctx, protocol.OpInferenceRequest, buf.Bytes()); err != nil {
+		return nil, sendFailed("inference request", err)
 	}
 
 	opcode, payload, err := c.transport.Recv(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("strandapi client: recv inference response: %w", err)
+		return nil, recvFailed(ctx, "inference response", err)
+	}
+	if opcode == protocol.OpTokenStreamStart {
+		// The server answers every request by streaming; collect the tokens.
+		if err := checkStreamStart(payload, req.ID); err != nil {
+			return nil, err
+		}
+		return assembleStream(c.readStream(ctx, req.ID), req.ID)
 	}
 	if opcode == protocol.OpError {
-		return nil, fmt.Errorf("strandapi client: server error: %s", string(payload))
+		if isStreamingOnlyError(payload) {
+			return c.inferViaStream(ctx, req)
+		}
+		return nil, newServerError(payload)
 	}
 	if opcode != protocol.OpInferenceResponse {
 		return nil, fmt.Errorf("strandapi client: unexpected opcode 0x%02x, want 0x%02x", opcode, protocol.OpInferenceResponse)
@@ -73,46 +177,257 @@ func (c *Client) Infer(ctx context.Context, req *protocol.InferenceRequest) (*pr
 	if err := resp.Decode(reader); err != nil {
 		return nil, fmt.Errorf("strandapi client: decode inference response: %w", err)
 	}
+	if err := checkResponseID(resp, req.ID); err != nil {
+		return nil, err
+	}
 	return resp, nil
 }
 
-// StreamTokens sends a streaming inference request and returns a channel that
-// yields TokenStreamChunk messages as they arrive. The channel is closed when
-// the stream ends (OpTokenStreamEnd) or an error occurs.
-func (c *Client) StreamTokens(ctx context.Context, req *protocol.InferenceRequest) (<-chan *protocol.TokenStreamChunk, error) {
+// isStreamingOnlyError reports whether an OpError payload says the server
+// has no synchronous inference handler: its code is ErrUnsupported.
+func isStreamingOnlyError(payload []byte) bool {
+	return protocol.ParseErrorMessage(payload).Code == protocol.ErrUnsupported
+}
+
+// inferViaStream re-issues req as a streaming request, flagged with
+// protocol.InferenceMetaStream, and assembles the tokens into a response.
+func (c *Client) inferViaStream(ctx context.Context, req *protocol.InferenceRequest) (*protocol.InferenceResponse, error) {
+	streamReq := *req
+	streamReq.Metadata = make(map[string]string, len(req.Metadata)+1)
+	for k, v := range req.Metadata {
+		streamReq.Metadata[k] = v
+	}
+	streamReq.Metadata[protocol.InferenceMetaStream] = "true"
+
+	ts, err := c.OpenStream(ctx, &streamReq)
+	if err != nil {
+		return nil, err
+	}
+	return assembleStream(ts, req.ID)
+}
+
+// assembleStream drains ts and builds the InferenceResponse Infer would have
+// returned, taking usage and finish reason from the stream summary if sent.
+func assembleStream(ts *TokenStream, id [16]byte) (*protocol.InferenceResponse, error) {
+	resp := &protocol.InferenceResponse{ID: id, FinishReason: "stop"}
+	var text strings.Builder
+	var tokens uint32
+	for chunk := range ts.C {
+		text.WriteString(chunk.Token)
+		tokens++
+	}
+	if err := ts.Err(); err != nil {
+		return nil, err
+	}
+	resp.Text = text.String()
+	resp.CompletionTokens = tokens
+	applySummary(resp, ts.Summary())
+	return resp, nil
+}
+
+// applySummary copies the usage and finish reason of a stream's summary into
+// the response assembled from it. A nil summary leaves resp unchanged.
+func applySummary(resp *protocol.InferenceResponse, summary *protocol.StreamSummary) {
+	if summary == nil {
+		return
+	}
+	resp.PromptTokens = summary.PromptTokens
+	resp.CompletionTokens = summary.CompletionTokens
+	if summary.FinishReason != "" {
+		resp.FinishReason = summary.FinishReason
+	}
+}
+
+// TokenStream is an in-progress streaming inference response returned by
+// OpenStream. Tokens are delivered on C, which is closed when the stream ends
+// or the reader stops; Err then reports which of the two happened.
+type TokenStream struct {
+	// C yields TokenStreamChunk messages in arrival order, or in SeqNum
+	// order when the client has a WithReorderWindow.
+	C <-chan *protocol.TokenStreamChunk
+	// Partials yields the PartialResponse revisions the server sends. It
+	// holds only the newest revision not yet received, so a reader that
+	// falls behind skips superseded ones and never stalls C; it need not be
+	// read at all. It is closed together with C.
+	Partials <-chan *protocol.PartialResponse
+
+	done     chan struct{}
+	summary  *protocol.StreamSummary
+	err      error
+	order    reorderBuffer
+	partials chan *protocol.PartialResponse
+
+	partialMu sync.Mutex
+	partial   *protocol.PartialResponse
+}
+
+// Partial returns the newest PartialResponse received so far, or nil if the
+// server has sent none. It does not block.
+func (s *TokenStream) Partial() *protocol.PartialResponse {
+	s.partialMu.Lock()
+	defer s.partialMu.Unlock()
+	return s.partial
+}
+
+// addPartial records p unless an equal or newer revision was already seen,
+// and offers it on Partials in place of any revision not yet received.
+func (s *TokenStream) addPartial(p *protocol.PartialResponse) {
+	s.partialMu.Lock()
+	if s.partial != nil && p.Revision <= s.partial.Revision {
+		s.partialMu.Unlock()
+		return
+	}
+	s.partial = p
+	s.partialMu.Unlock()
+
+	select {
+	case <-s.partials:
+	default:
+	}
+	s.partials <- p
+}
+
+// Err returns nil if the stream completed normally with OpTokenStreamEnd, or
+// the reason it was cut off: context cancellation or deadline, a transport
+// failure, a malformed frame, or an OpError from the server. A stream that
+// ended normally but skipped SeqNum values reports the first gap as a
+// *SequenceGapError (ErrSequenceGap). It blocks until C is closed. Tokens
+// already received on C remain valid either way.
+func (s *TokenStream) Err() error {
+	<-s.done
+	if s.err == nil && len(s.order.gaps) > 0 {
+		return &s.order.gaps[0]
+	}
+	return s.err
+}
+
+// Gaps returns every SeqNum gap detected in the stream, in order. It blocks
+// until C is closed.
+func (s *TokenStream) Gaps() []SequenceGapError {
+	<-s.done
+	return s.order.gaps
+}
+
+// Summary returns the usage reported in the server's OpTokenStreamEnd frame.
+// It blocks until C is closed and returns nil if the stream did not end
+// normally or the server sent no summary.
+func (s *TokenStream) Summary() *protocol.StreamSummary {
+	<-s.done
+	return s.summary
+}
+
+// OpenStream sends a streaming inference request and returns a TokenStream
+// that yields chunks as they arrive and exposes the final StreamSummary. Like
+// Infer it assigns a zero req.ID a generated one, and it sets req.Stream. A
+// stream start frame naming another request ends the stream with
+// ErrResponseMismatch.
+func (c *Client) OpenStream(ctx context.Context, req *protocol.InferenceRequest) (*TokenStream, error) {
+	c.fillID(req)
+	req.Stream = true
 	buf := strandbuf.NewBuffer(256)
-	req.Encode(buf)
+	encodeRequest(buf, req)
+	if err := c.checkSize(protocol.OpInferenceRequest, buf.Bytes()); err != nil {
+		return nil, err
+	}
 
-	if err := c.transport.Send(ctx, protocol.OpInferenceRequest, buf.Bytes()); err != nil {
-		return nil, fmt.Errorf("strandapi client: send stream request: %w", err)
+	if err := c.send(ctx, protocol.OpInferenceRequest, buf.Bytes()); err != nil {
+		return nil, sendFailed("stream request", err)
 	}
+	return c.readStream(ctx, req.ID), nil
+}
 
+// readStream starts reading stream frames for request id from the transport
+// into a new TokenStream until the stream ends, fails or ctx is done.
+func (c *Client) readStream(ctx context.Context, id [16]byte) *TokenStream {
 	ch := make(chan *protocol.TokenStreamChunk, 64)
+	partials := make(chan *protocol.PartialResponse, 1)
+	ts := &TokenStream{C: ch, Partials: partials, partials: partials, done: make(chan struct{})}
+	ts.order.window = c.reorderWindow
+	// deliver hands released chunks to the consumer, reporting false if ctx
+	// ended first.
+	deliver := func(chunks []*protocol.TokenStreamChunk) bool {
+		for _, chunk := range chunks {
+			select {
+			case ch <- chunk:
+			case <-ctx.Done():
+				ts.err = ctx.Err()
+				return false
+			}
+		}
+		return true
+	}
 	go func() {
+		// Runs last, once C is closed, so a slow cancel does not hold up
+		// the consumer.
+		defer func() {
+			if ctx.Err() != nil && errors.Is(ts.err, ctx.Err()) {
+				c.cancelAbandoned(id)
+			}
+		}()
+		defer close(ts.done)
 		defer close(ch)
+		defer close(partials)
 		for {
 			opcode, payload, err := c.transport.Recv(ctx)
 			if err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					ts.err = ctxErr
+				} else {
+					deliver(ts.order.flush())
+					ts.err = fmt.Errorf("strandapi client: recv stream: %w", err)
+				}
 				return
 			}
 			switch opcode {
 			case protocol.OpTokenStreamStart:
 				// Stream has started; continue reading chunks.
+				if err := checkStreamStart(payload, id); err != nil {
+					ts.err = err
+					return
+				}
 				continue
 			case protocol.OpTokenStreamChunk:
 				chunk := &protocol.TokenStreamChunk{}
 				reader := strandbuf.NewReader(payload)
 				if err := chunk.Decode(reader); err != nil {
+					ts.err = fmt.Errorf("strandapi client: decode token chunk: %w", err)
 					return
 				}
-				select {
-				case ch <- chunk:
-				case <-ctx.Done():
+				if !deliver(ts.order.add(chunk)) {
 					return
 				}
+			case protocol.OpTokenStreamBatch:
+				batch := &protocol.TokenStreamBatch{}
+				if err := batch.Decode(strandbuf.NewReader(payload)); err != nil {
+					ts.err = fmt.Errorf("strandapi client: decode token batch: %w", err)
+					return
+				}
+				for i := range batch.Chunks {
+					if !deliver(ts.order.add(&batch.Chunks[i])) {
+						return
+					}
+				}
+			case protocol.OpPartialResponse:
+				partial := &protocol.PartialResponse{}
+				if err := partial.Decode(strandbuf.NewReader(payload)); err != nil {
+					ts.err = fmt.Errorf("strandapi client: decode partial response: %w", err)
+					return
+				}
+				ts.addPartial(partial)
 			case protocol.OpTokenStreamEnd:
+				if !deliver(ts.order.flush()) {
+					return
+				}
+				if len(payload) > 0 {
+					summary := &protocol.StreamSummary{}
+					if err := summary.Decode(strandbuf.NewReader(payload)); err == nil {
+						ts.summary = summary
+					}
+				}
 				return
 			case protocol.OpError:
+				deliver(ts.order.flush())
+				ts.err = newServerError(payload)
 				return
 			default:
 				// Unexpected opcode -- ignore and keep reading.
@@ -121,14 +436,210 @@ func (c *Client) StreamTokens(ctx context.Context, req *protocol.InferenceReques
 		}
 	}()
 
-	return ch, nil
+	return ts
+}
+
+// abandonedCancelTimeout bounds the OpCancel a stream reader sends when its
+// context ends mid-stream.
+const abandonedCancelTimeout = time.Second
+
+// cancelAbandoned tells the server to stop the stream for request id after
+// the local reader gave up on it, so the node stops generating tokens nobody
+// will read. It is best effort: the reader's own context is already done, so
+// the cancel gets a short one of its own, and a failure is ignored.
+func (c *Client) cancelAbandoned(id [16]byte) {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), abandonedCancelTimeout)
+	defer cancel()
+	_ = c.CancelStream(ctx, id)
+}
+
+// StreamTokens sends a streaming inference request and returns a channel that
+// yields TokenStreamChunk messages as they arrive. The channel is closed when
+// the stream ends (OpTokenStreamEnd) or an error occurs, including ctx
+// ending: the reader then stops at once, even if nobody is receiving from
+// the channel, and asks the server to stop the stream. Use OpenStream to
+// learn why the stream ended (TokenStream.Err), to learn of lost chunks
+// (TokenStream.Gaps) and to observe the final StreamSummary.
+func (c *Client) StreamTokens(ctx context.Context, req *protocol.InferenceRequest) (<-chan *protocol.TokenStreamChunk, error) {
+	ts, err := c.OpenStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return ts.C, nil
+}
+
+// Heartbeat sends an OpHeartbeat, carrying health if it is non-nil, and waits
+// for the reply. It returns the peer's NodeHealth, or nil if the peer sent an
+// empty heartbeat.
+func (c *Client) Heartbeat(ctx context.Context, health *protocol.NodeHealth) (*protocol.NodeHealth, error) {
+	var payload []byte
+	if health != nil {
+		buf := strandbuf.NewBuffer(12)
+		health.Encode(buf)
+		payload = buf.Bytes()
+	}
+	if err := c.send(ctx, protocol.OpHeartbeat, payload); err != nil {
+		return nil, sendFailed("heartbeat", err)
+	}
+	opcode, reply, err := c.transport.Recv(ctx)
+	if err != nil {
+		return nil, recvFailed(ctx, "heartbeat", err)
+	}
+	if opcode == protocol.OpError {
+		return nil, newServerError(reply)
+	}
+	if opcode != protocol.OpHeartbeat {
+		return nil, fmt.Errorf("strandapi client: unexpected opcode 0x%02x, want 0x%02x", opcode, protocol.OpHeartbeat)
+	}
+	peer, err := protocol.ParseHeartbeat(reply)
+	if err != nil {
+		return nil, fmt.Errorf("strandapi client: %w", err)
+	}
+	return peer, nil
+}
+
+// CancelStream asks the server to stop generating for the in-flight request
+// with the given ID by sending an OpCancel frame. A stream whose context
+// ends before the stream does sends one itself once its reader has stopped;
+// call CancelStream to stop a stream that is still being read.
+func (c *Client) CancelStream(ctx context.Context, requestID [16]byte) error {
+	msg := &protocol.Cancel{RequestID: requestID}
+	buf := strandbuf.NewBuffer(16)
+	msg.Encode(buf)
+	if err := c.send(ctx, protocol.OpCancel, buf.Bytes()); err != nil {
+		return sendFailed("cancel", err)
+	}
+	return nil
+}
+
+// ToolFunc executes a tool on behalf of the model. It receives the raw
+// ToolInvoke arguments and returns the result payload sent back in the
+// ToolResult; a non-nil error is reported to the server with ErrInternal.
+type ToolFunc func(ctx context.Context, args []byte) ([]byte, error)
+
+// InferWithTools sends an inference request and drives the resulting stream
+// to completion, answering every OpToolInvoke frame by calling the matching
+// entry in tools and sending an OpToolResult. Invocations of unregistered
+// tools are answered with ErrNotFound. Like OpenStream it sets req.Stream. It
+// returns the assembled response, with usage and finish reason taken from
+// the stream summary if sent, once the server ends the stream (or replies
+// with a complete InferenceResponse).
+func (c *Client) InferWithTools(ctx context.Context, req *protocol.InferenceRequest, tools map[string]ToolFunc) (*protocol.InferenceResponse, error) {
+	c.fillID(req)
+	req.Stream = true
+	buf := strandbuf.NewBuffer(256)
+	encodeRequest(buf, req)
+	if err := c.checkSize(protocol.OpInferenceRequest, buf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	if err := c.send(ctx, protocol.OpInferenceRequest, buf.Bytes()); err != nil {
+		return nil, sendFailed("inference request", err)
+	}
+
+	resp := &protocol.InferenceResponse{ID: req.ID, FinishReason: "stop"}
+	var text strings.Builder
+	for {
+		opcode, payload, err := c.transport.Recv(ctx)
+		if err != nil {
+			return nil, recvFailed(ctx, "inference response", err)
+		}
+		switch opcode {
+		case protocol.OpTokenStreamStart:
+			if err := checkStreamStart(payload, req.ID); err != nil {
+				return nil, err
+			}
+			continue
+		case protocol.OpTokenStreamChunk:
+			chunk := &protocol.TokenStreamChunk{}
+			if err := chunk.Decode(strandbuf.NewReader(payload)); err != nil {
+				return nil, fmt.Errorf("strandapi client: decode token chunk: %w", err)
+			}
+			text.WriteString(chunk.Token)
+			resp.CompletionTokens++
+		case protocol.OpTokenStreamBatch:
+			batch := &protocol.TokenStreamBatch{}
+			if err := batch.Decode(strandbuf.NewReader(payload)); err != nil {
+				return nil, fmt.Errorf("strandapi client: decode token batch: %w", err)
+			}
+			for _, chunk := range batch.Chunks {
+				text.WriteString(chunk.Token)
+				resp.CompletionTokens++
+			}
+		case protocol.OpToolInvoke:
+			invoke := &protocol.ToolInvoke{}
+			if err := invoke.Decode(strandbuf.NewReader(payload)); err != nil {
+				return nil, fmt.Errorf("strandapi client: decode tool invoke: %w", err)
+			}
+			if err := c.answerToolInvoke(ctx, invoke, tools); err != nil {
+				return nil, err
+			}
+		case protocol.OpTokenStreamEnd:
+			resp.Text = text.String()
+			if len(payload) > 0 {
+				summary := &protocol.StreamSummary{}
+				if err := summary.Decode(strandbuf.NewReader(payload)); err == nil {
+					applySummary(resp, summary)
+				}
+			}
+			return resp, nil
+		case protocol.OpInferenceResponse:
+			final := &protocol.InferenceResponse{}
+			if err := final.Decode(strandbuf.NewReader(payload)); err != nil {
+				return nil, fmt.Errorf("strandapi client: decode inference response: %w", err)
+			}
+			if err := checkResponseID(final, req.ID); err != nil {
+				return nil, err
+			}
+			return final, nil
+		case protocol.OpError:
+			return nil, newServerError(payload)
+		default:
+			// Unexpected opcode -- ignore and keep reading.
+			continue
+		}
+	}
+}
+
+// answerToolInvoke runs the requested tool and sends its ToolResult.
+func (c *Client) answerToolInvoke(ctx context.Context, invoke *protocol.ToolInvoke, tools map[string]ToolFunc) error {
+	result := &protocol.ToolResult{RequestID: invoke.RequestID, ErrorCode: protocol.ErrOK}
+	fn, ok := tools[invoke.ToolName]
+	if !ok {
+		result.ErrorCode = protocol.ErrNotFound
+		result.ResultPayload = []byte(fmt.Sprintf("unknown tool %q", invoke.ToolName))
+	} else if out, err := fn(ctx, invoke.Arguments); err != nil {
+		result.ErrorCode = protocol.ErrInternal
+		result.ResultPayload = []byte(err.Error())
+	} else {
+		result.ResultPayload = out
+	}
+
+	buf := strandbuf.NewBuffer(64 + len(result.ResultPayload))
+	result.Encode(buf)
+	if err := c.checkSize(protocol.OpToolResult, buf.Bytes()); err != nil {
+		return err
+	}
+	if err := c.send(ctx, protocol.OpToolResult, buf.Bytes()); err != nil {
+		return sendFailed("tool result", err)
+	}
+	return nil
 }
 
 // RawSend transmits a single StrandAPI frame with the given opcode and payload.
 // Use this for protocol messages not covered by the typed helpers (e.g. agent
 // delegation, tool invocation, health checks).
 func (c *Client) RawSend(ctx context.Context, opcode byte, payload []byte) error {
-	return c.transport.Send(ctx, opcode, payload)
+	if err := c.checkSize(opcode, payload); err != nil {
+		return err
+	}
+	return c.send(ctx, opcode, payload)
 }
 
 // RawRecv blocks until a complete StrandAPI frame arrives and returns the raw
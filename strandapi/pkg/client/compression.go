@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+
+	"github.com/strand-protocol/strand/strandapi/pkg/protocol"
+	"github.com/strand-protocol/strand/strandapi/pkg/strandbuf"
+)
+
+// compressMinSize is the smallest payload worth compressing; shorter ones
+// rarely shrink enough to pay for the OpCompressed wrapper.
+const compressMinSize = 256
+
+// WithCompression offers the given codecs, most preferred first, in the
+// OpHello handshake. Once Hello completes the client compresses outgoing
+// payloads with the first of them the server advertised, and sends raw
+// frames if the server advertised none. Payloads under 256 bytes, or that
+// do not shrink, are always sent raw. Without Hello nothing is compressed.
+func WithCompression(cs ...protocol.Compressor) Option {
+	return func(c *Client) {
+		c.compressors = append(c.compressors, cs...)
+	}
+}
+
+// Compression returns the codec outgoing payloads are compressed with:
+// CodecRaw until Hello has agreed on one.
+func (c *Client) Compression() protocol.Codec {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.compressor == nil {
+		return protocol.CodecRaw
+	}
+	return c.compressor.Codec()
+}
+
+// offeredCodecs returns the codecs of the configured compressors.
+func (c *Client) offeredCodecs() []protocol.Codec {
+	var codecs []protocol.Codec
+	for _, comp := range c.compressors {
+		codecs = append(codecs, comp.Codec())
+	}
+	return codecs
+}
+
+// negotiateCompression picks the compressor to use given the codecs the
+// server advertised.
+func (c *Client) negotiateCompression(supported []protocol.Codec) {
+	codec := protocol.NegotiateCodec(c.offeredCodecs(), supported)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.compressor = nil
+	for _, comp := range c.compressors {
+		if codec != protocol.CodecRaw && comp.Codec() == codec {
+			c.compressor = comp
+			return
+		}
+	}
+}
+
+// send transmits a frame, wrapping it in OpCompressed when a codec has been
+// negotiated and compression makes the payload smaller.
+func (c *Client) send(ctx context.Context, opcode byte, payload []byte) error {
+	c.mu.Lock()
+	comp := c.compressor
+	c.mu.Unlock()
+	if comp == nil || len(payload) < compressMinSize {
+		return c.transport.Send(ctx, opcode, payload)
+	}
+	packed, err := comp.Compress(payload)
+	if err != nil || len(packed) >= len(payload) {
+		return c.transport.Send(ctx, opcode, payload)
+	}
+	msg := &protocol.Compressed{Codec: comp.Codec(), Opcode: opcode, Payload: packed}
+	buf := strandbuf.NewBuffer(len(packed) + 8)
+	msg.Encode(buf)
+	return c.transport.Send(ctx, protocol.OpCompressed, buf.Bytes())
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/strand-protocol/strand/strandapi/pkg/protocol"
+	"github.com/strand-protocol/strand/strandapi/pkg/strandbuf"
+)
+
+// Sentinel errors identifying where a client call failed, so callers can
+// decide whether a retry is safe. Match them with errors.Is.
+var (
+	// ErrDialFailed means no transport could be set up; nothing was sent.
+	ErrDialFailed = errors.New("strandapi client: dial failed")
+	// ErrSendFailed means the request could not be handed to the transport,
+	// so it most likely never reached the server.
+	ErrSendFailed = errors.New("strandapi client: send failed")
+	// ErrRecvTimeout means the request was sent but no response arrived
+	// before the context deadline; the server may still be processing it.
+	ErrRecvTimeout = errors.New("strandapi client: timed out waiting for response")
+	// ErrServer means the server received the request and answered with an
+	// OpError. The concrete error is a *ServerError carrying the remote code.
+	ErrServer = errors.New("strandapi client: server error")
+	// ErrResponseMismatch means a response arrived whose ID does not match
+	// the request, e.g. a reply crossed over from another caller sharing the
+	// transport. The response is discarded.
+	ErrResponseMismatch = errors.New("strandapi client: response ID does not match request")
+	// ErrSequenceGap means a token stream skipped one or more SeqNum values,
+	// so chunks were lost in transit and the assembled text is incomplete.
+	// The concrete error is a *SequenceGapError.
+	ErrSequenceGap = errors.New("strandapi client: token stream sequence gap")
+)
+
+// ServerError is returned when the server answers with an OpError. It matches
+// ErrServer under errors.Is.
+type ServerError struct {
+	// Code is the protocol error code (protocol.Err*), or protocol.ErrUnknown
+	// if the server did not identify one.
+	Code uint16
+	// Message is the error detail sent by the server, without the code name.
+	Message string
+}
+
+func (e *ServerError) Error() string {
+	if e.Code == protocol.ErrUnknown {
+		return "strandapi client: server error: " + e.Message
+	}
+	em := protocol.ErrorMessage{Code: e.Code, Message: e.Message}
+	return "strandapi client: server error: " + em.Error()
+}
+
+// Is reports whether target is ErrServer.
+func (e *ServerError) Is(target error) bool {
+	return target == ErrServer
+}
+
+// newServerError builds a ServerError from an OpError payload.
+func newServerError(payload []byte) *ServerError {
+	em := protocol.ParseErrorMessage(payload)
+	return &ServerError{Code: em.Code, Message: em.Message}
+}
+
+// checkStreamStart verifies that an OpTokenStreamStart payload names request
+// id. An empty payload, sent by servers that do not echo the ID, is accepted.
+func checkStreamStart(payload []byte, id [16]byte) error {
+	if len(payload) == 0 {
+		return nil
+	}
+	start := &protocol.StreamStart{}
+	if err := start.Decode(strandbuf.NewReader(payload)); err != nil {
+		return fmt.Errorf("strandapi client: decode stream start: %w", err)
+	}
+	if start.RequestID != id {
+		return fmt.Errorf("%w: stream start for %x, want %x", ErrResponseMismatch, start.RequestID, id)
+	}
+	return nil
+}
+
+// SequenceGapError records a jump in TokenStreamChunk.SeqNum: the chunks from
+// Expected up to, but not including, Got never arrived. It matches
+// ErrSequenceGap under errors.Is.
+type SequenceGapError struct {
+	Expected uint32
+	Got      uint32
+}
+
+func (e *SequenceGapError) Error() string {
+	return fmt.Sprintf("strandapi client: token stream sequence gap: expected chunk %d, got %d (%d missing)",
+		e.Expected, e.Got, e.Got-e.Expected)
+}
+
+// Is reports whether target is ErrSequenceGap.
+func (e *SequenceGapError) Is(target error) bool {
+	return target == ErrSequenceGap
+}
+
+// checkResponseID returns ErrResponseMismatch unless resp answers the request
+// with the given ID.
+func checkResponseID(resp *protocol.InferenceResponse, id [16]byte) error {
+	if resp.ID != id {
+		return fmt.Errorf("%w: got %x, want %x", ErrResponseMismatch, resp.ID, id)
+	}
+	return nil
+}
+
+// callError is a failed step of a client call. It matches both kind (one of
+// the sentinels above) and the underlying transport error.
+type callError struct {
+	op   string
+	kind error
+	err  error
+}
+
+func (e *callError) Error() string {
+	return "strandapi client: " + e.op + ": " + e.err.Error()
+}
+
+func (e *callError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// sendFailed wraps a transport Send error for the named step.
+func sendFailed(op string, err error) error {
+	return &callError{op: "send " + op, kind: ErrSendFailed, err: err}
+}
+
+// recvFailed wraps a transport Recv error for the named step. Errors caused by
+// the context deadline, or a transport read timeout, match ErrRecvTimeout.
+func recvFailed(ctx context.Context, op string, err error) error {
+	var netErr net.Error
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) {
+		return &callError{op: "recv " + op, kind: ErrRecvTimeout, err: err}
+	}
+	return fmt.Errorf("strandapi client: recv %s: %w", op, err)
+}
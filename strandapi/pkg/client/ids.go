@@ -0,0 +1,57 @@
+package client
+
+import (
+	"crypto/rand"
+
+	"github.com/strand-protocol/strand/strandapi/pkg/protocol"
+	"github.com/strand-protocol/strand/strandapi/pkg/strandbuf"
+)
+
+// WithIDGenerator sets the function that supplies an ID for every inference
+// request sent with a zero ID. The default draws 16 random bytes from
+// crypto/rand. Requests whose ID the caller set are sent unchanged.
+// IDs reserved by the message versioning scheme (protocol.ReservedID) are
+// allowed; such requests are sent version-stamped.
+func WithIDGenerator(gen func() [16]byte) Option {
+	return func(c *Client) {
+		c.idGen = gen
+	}
+}
+
+// RandomID returns 16 bytes from crypto/rand, the default request ID. It
+// never returns a protocol.ReservedID, so requests with a random ID can be
+// sent unstamped to any server.
+func RandomID() [16]byte {
+	var id [16]byte
+	for {
+		if _, err := rand.Read(id[:]); err != nil {
+			panic("strandapi client: read random request ID: " + err.Error())
+		}
+		if !protocol.ReservedID(id) {
+			return id
+		}
+	}
+}
+
+// fillID gives req an ID from the configured generator if it has none, so
+// the caller can correlate the response and the server can deduplicate it.
+func (c *Client) fillID(req *protocol.InferenceRequest) {
+	if req.ID != ([16]byte{}) {
+		return
+	}
+	gen := c.idGen
+	if gen == nil {
+		gen = RandomID
+	}
+	req.ID = gen()
+}
+
+// encodeRequest writes req to buf. A request whose ID could be mistaken for
+// a version stamp is sent version-stamped so the server reads the ID intact.
+func encodeRequest(buf *strandbuf.Buffer, req *protocol.InferenceRequest) {
+	if protocol.ReservedID(req.ID) {
+		req.EncodeV2(buf)
+		return
+	}
+	req.Encode(buf)
+}
@@ -0,0 +1,87 @@
+package client
+
+import "github.com/strand-protocol/strand/strandapi/pkg/protocol"
+
+// WithReorderWindow lets token streams tolerate chunks that arrive out of
+// order, as they may over best-effort UDP. Up to n chunks that arrive ahead
+// of the next expected SeqNum are held back and released in SeqNum order
+// once the missing ones arrive. A chunk still missing when more than n are
+// held, or when the stream ends, is reported as a gap and skipped. The
+// default, 0, delivers chunks as they arrive. Either way SeqNum numbering
+// starts at 0, and duplicates and chunks arriving after their gap was
+// reported are dropped.
+func WithReorderWindow(n int) Option {
+	return func(c *Client) {
+		c.reorderWindow = max(n, 0)
+	}
+}
+
+// reorderBuffer releases token chunks in SeqNum order, holding at most
+// window chunks that arrived early, and records the SeqNum values it had to
+// skip. With a zero window it releases every chunk immediately and only
+// records gaps.
+type reorderBuffer struct {
+	window int
+	// next is the SeqNum expected next, starting from 0.
+	next    uint32
+	pending map[uint32]*protocol.TokenStreamChunk
+	gaps    []SequenceGapError
+}
+
+// add accepts an arriving chunk and returns the chunks now ready for the
+// consumer, in order. A chunk older than the next expected SeqNum — already
+// delivered, or arriving after its gap was reported — or a duplicate of a
+// held one is dropped, so the consumer sees each SeqNum at most once.
+func (b *reorderBuffer) add(chunk *protocol.TokenStreamChunk) []*protocol.TokenStreamChunk {
+	if chunk.SeqNum < b.next {
+		return nil
+	}
+	if b.pending == nil {
+		b.pending = make(map[uint32]*protocol.TokenStreamChunk)
+	}
+	if _, dup := b.pending[chunk.SeqNum]; dup {
+		return nil
+	}
+	b.pending[chunk.SeqNum] = chunk
+	return b.release(b.window)
+}
+
+// flush releases every held chunk at the end of the stream, recording gaps
+// for any still missing.
+func (b *reorderBuffer) flush() []*protocol.TokenStreamChunk {
+	return b.release(0)
+}
+
+// release returns the chunks that can be delivered while no more than keep
+// remain held, skipping over missing SeqNums as needed.
+func (b *reorderBuffer) release(keep int) []*protocol.TokenStreamChunk {
+	var out []*protocol.TokenStreamChunk
+	for len(b.pending) > 0 {
+		if chunk, ok := b.pending[b.next]; ok {
+			delete(b.pending, b.next)
+			out = append(out, chunk)
+			b.next++
+			continue
+		}
+		if len(b.pending) <= keep {
+			break
+		}
+		// The window is full: give up on the missing chunks.
+		lowest := b.lowest()
+		b.gaps = append(b.gaps, SequenceGapError{Expected: b.next, Got: lowest})
+		b.next = lowest
+	}
+	return out
+}
+
+// lowest returns the smallest held SeqNum. b.pending must not be empty.
+func (b *reorderBuffer) lowest() uint32 {
+	first := true
+	var low uint32
+	for seq := range b.pending {
+		if first || seq < low {
+			low, first = seq, false
+		}
+	}
+	return low
+}
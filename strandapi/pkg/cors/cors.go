@@ -0,0 +1,170 @@
+// Package cors implements origin-allowlist CORS handling for HTTP front ends
+// to StrandAPI, such as the httpbridge example. Origins are matched against
+// exact origins or single-wildcard subdomain patterns; a bare "*" is never
+// accepted or emitted.
+package cors
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Headers sent on every response to an allowed origin.
+const (
+	AllowedMethods = "GET, POST, OPTIONS"
+	AllowedHeaders = "Content-Type, Authorization, X-Request-ID"
+)
+
+// Policy is a parsed origin allowlist. The zero value allows no origins.
+type Policy struct {
+	exact     map[string]bool
+	wildcards []wildcard
+}
+
+// wildcard is a "scheme://*.suffix[:port]" pattern.
+type wildcard struct {
+	scheme string
+	suffix string // ".example.com"
+	port   string
+}
+
+// New parses origin patterns into a Policy. A pattern is either an exact
+// origin ("https://app.example.com", "http://localhost:9000") or a wildcard
+// subdomain pattern ("https://*.example.com") in which "*" stands for one or
+// more DNS labels. A wildcard matches subdomains only, not the bare domain,
+// and requires the same scheme and port. Patterns are case-insensitive.
+func New(patterns ...string) (*Policy, error) {
+	p := &Policy{exact: make(map[string]bool)}
+	for _, raw := range patterns {
+		if err := p.add(raw); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// ParseList parses a comma-separated pattern list, the format of the
+// STRANDAPI_CORS_ORIGINS environment variable. Blank entries are skipped.
+func ParseList(list string) (*Policy, error) {
+	var patterns []string
+	for _, o := range strings.Split(list, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			patterns = append(patterns, o)
+		}
+	}
+	return New(patterns...)
+}
+
+func (p *Policy) add(raw string) error {
+	u, err := parseOrigin(raw)
+	if err != nil {
+		return fmt.Errorf("cors: invalid origin pattern %q: %w", raw, err)
+	}
+	host := u.Hostname()
+	if !strings.Contains(host, "*") {
+		if !validHost(host) {
+			return fmt.Errorf("cors: invalid origin pattern %q: bad host", raw)
+		}
+		p.exact[u.Scheme+"://"+u.Host] = true
+		return nil
+	}
+	suffix, ok := strings.CutPrefix(host, "*.")
+	if !ok || strings.Contains(suffix, "*") {
+		return fmt.Errorf("cors: invalid origin pattern %q: wildcard must be the whole leftmost label", raw)
+	}
+	// Refuse patterns that would cover a whole top-level domain.
+	if !strings.Contains(suffix, ".") || !validHost(suffix) {
+		return fmt.Errorf("cors: invalid origin pattern %q: wildcard needs a domain with at least two labels", raw)
+	}
+	p.wildcards = append(p.wildcards, wildcard{scheme: u.Scheme, suffix: "." + suffix, port: u.Port()})
+	return nil
+}
+
+// Allowed reports whether origin, the value of a request's Origin header,
+// matches the policy.
+func (p *Policy) Allowed(origin string) bool {
+	if p == nil || origin == "" {
+		return false
+	}
+	u, err := parseOrigin(origin)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if !validHost(host) {
+		return false
+	}
+	if p.exact[u.Scheme+"://"+u.Host] {
+		return true
+	}
+	for _, w := range p.wildcards {
+		if u.Scheme == w.scheme && u.Port() == w.port &&
+			len(host) > len(w.suffix) && strings.HasSuffix(host, w.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler wraps next with CORS handling. Requests from an allowed origin get
+// the origin echoed in Access-Control-Allow-Origin along with the allowed
+// methods and headers; other origins get no CORS headers at all, so the
+// browser blocks the response. OPTIONS preflight requests are answered with
+// 204 No Content without reaching next.
+func (p *Policy) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		if origin := r.Header.Get("Origin"); p.Allowed(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Set("Access-Control-Allow-Methods", AllowedMethods)
+			w.Header().Set("Access-Control-Allow-Headers", AllowedHeaders)
+		}
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// parseOrigin parses s as a lower-cased "scheme://host[:port]" origin,
+// rejecting anything with a path, query, fragment or user info.
+func parseOrigin(s string) (*url.URL, error) {
+	u, err := url.Parse(strings.ToLower(s))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("want scheme://host[:port]")
+	}
+	if u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.Opaque != "" {
+		return nil, fmt.Errorf("want a bare origin without path, query or user info")
+	}
+	return u, nil
+}
+
+// validHost reports whether host is an IP address or a sequence of non-empty
+// labels of letters, digits and hyphens.
+func validHost(host string) bool {
+	if host == "" {
+		return false
+	}
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPolicyAllowed(t *testing.T) {
+	p, err := New("http://localhost:9000", "https://*.example.com", "https://*.dev.example.org:8443")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"http://localhost:9000", true},
+		{"HTTP://LocalHost:9000", true},
+		{"http://localhost:9001", false},
+		{"https://localhost:9000", false},
+		{"https://app.example.com", true},
+		{"https://a.b.example.com", true},
+		{"https://example.com", false},         // the wildcard covers subdomains only
+		{"http://app.example.com", false},      // scheme must match
+		{"https://app.example.com:444", false}, // port must match
+		{"https://evilexample.com", false},
+		{"https://example.com.evil.net", false},
+		{"https://app.example.com.", false},
+		{"https://app.example.com/path", false},
+		{"https://user@app.example.com", false},
+		{"https://x.dev.example.org:8443", true},
+		{"https://x.dev.example.org", false},
+		{"null", false},
+		{"", false},
+	}
+	for _, tc := range tests {
+		if got := p.Allowed(tc.origin); got != tc.want {
+			t.Errorf("Allowed(%q) = %v, want %v", tc.origin, got, tc.want)
+		}
+	}
+}
+
+func TestNewRejectsUnsafePatterns(t *testing.T) {
+	for _, pattern := range []string{
+		"*",
+		"https://*",
+		"https://*.com",
+		"https://foo*.example.com",
+		"https://*.*.example.com",
+		"https://app.*.example.com",
+		"example.com",
+		"https://example.com/path",
+	} {
+		if _, err := New(pattern); err == nil {
+			t.Errorf("New(%q) succeeded, want an error", pattern)
+		}
+	}
+}
+
+func TestParseList(t *testing.T) {
+	p, err := ParseList(" https://a.example.com , ,https://*.example.net")
+	if err != nil {
+		t.Fatalf("ParseList: %v", err)
+	}
+	if !p.Allowed("https://a.example.com") || !p.Allowed("https://b.example.net") {
+		t.Error("ParseList did not keep every listed pattern")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	p, err := New("https://app.example.com", "https://*.example.net")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	h := p.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	tests := []struct {
+		name, method, origin string
+		wantAllowed          bool
+		wantStatus           int
+	}{
+		{"exact", http.MethodGet, "https://app.example.com", true, http.StatusTeapot},
+		{"subdomain wildcard", http.MethodPost, "https://eu.api.example.net", true, http.StatusTeapot},
+		{"not listed", http.MethodGet, "https://evil.example.org", false, http.StatusTeapot},
+		{"no origin", http.MethodGet, "", false, http.StatusTeapot},
+		{"preflight", http.MethodOptions, "https://app.example.com", true, http.StatusNoContent},
+		{"preflight not listed", http.MethodOptions, "https://evil.example.org", false, http.StatusNoContent},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/v1/models", nil)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tc.wantStatus)
+			}
+			corsHeaders := []string{
+				"Access-Control-Allow-Origin",
+				"Access-Control-Allow-Credentials",
+				"Access-Control-Allow-Methods",
+				"Access-Control-Allow-Headers",
+			}
+			for _, name := range corsHeaders {
+				got := rec.Header().Get(name)
+				if tc.wantAllowed && got == "" {
+					t.Errorf("%s missing for allowed origin", name)
+				}
+				if !tc.wantAllowed && got != "" {
+					t.Errorf("%s = %q for disallowed origin, want none", name, got)
+				}
+			}
+			if tc.wantAllowed && rec.Header().Get("Access-Control-Allow-Origin") != tc.origin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", rec.Header().Get("Access-Control-Allow-Origin"), tc.origin)
+			}
+		})
+	}
+}
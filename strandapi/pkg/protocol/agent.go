@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/strand-protocol/strand/strandapi/pkg/strandbuf"
 )
@@ -18,7 +19,7 @@ const maxCapabilities = 128
 //
 //	[uint32] SessionID
 //	[uint32] capability count
-//	[string] Capabilities[0..n-1]  (each length-prefixed)
+//	[string] Capabilities[0..n-1]  (each length-prefixed, sorted)
 //	[uint8]  Version
 type AgentNegotiate struct {
 	SessionID    uint32   // Identifies the delegation session across messages
@@ -27,10 +28,15 @@ type AgentNegotiate struct {
 }
 
 // Encode serialises AgentNegotiate into buf using StrandBuf wire format.
+// Capabilities are written in sorted order so the same logical set always
+// produces identical bytes; m.Capabilities itself is left untouched.
 func (m *AgentNegotiate) Encode(buf *strandbuf.Buffer) {
+	caps := append([]string(nil), m.Capabilities...)
+	sort.Strings(caps)
+
 	buf.WriteUint32(m.SessionID)
-	buf.WriteList(uint32(len(m.Capabilities)))
-	for _, c := range m.Capabilities {
+	buf.WriteList(uint32(len(caps)))
+	for _, c := range caps {
 		buf.WriteString(c)
 	}
 	buf.WriteUint8(m.Version)
@@ -39,6 +45,7 @@ func (m *AgentNegotiate) Encode(buf *strandbuf.Buffer) {
 // Decode reads an AgentNegotiate from r. Returns an error if the data is
 // incomplete or malformed.
 func (m *AgentNegotiate) Decode(r *strandbuf.Reader) error {
+	limitStrings(r)
 	var err error
 	m.SessionID, err = r.ReadUint32()
 	if err != nil {
@@ -73,13 +80,24 @@ func (m *AgentNegotiate) Decode(r *strandbuf.Reader) error {
 //	[16 bytes] TargetNodeID  (raw 128-bit node identifier)
 //	[bytes]    TaskPayload   (length-prefixed opaque task data)
 //	[uint32]   TimeoutMS     (0 = no timeout)
+//	[map]      Metadata      (sorted by key; absent in frames from older peers)
 type AgentDelegate struct {
-	SessionID    uint32   // Delegation session identifier
-	TargetNodeID [16]byte // 128-bit StrandLink node ID of the target agent
-	TaskPayload  []byte   // Opaque task encoding (caller-defined serialisation)
-	TimeoutMS    uint32   // Deadline in milliseconds; 0 means no deadline
+	SessionID    uint32            // Delegation session identifier
+	TargetNodeID [16]byte          // 128-bit StrandLink node ID of the target agent
+	TaskPayload  []byte            // Opaque task encoding (caller-defined serialisation)
+	TimeoutMS    uint32            // Deadline in milliseconds; 0 means no deadline
+	Metadata     map[string]string // Custom key-value metadata (task type, credentials)
 }
 
+// Well-known AgentDelegate metadata keys.
+const (
+	// DelegateMetaTaskType names the capability the delegated task requires.
+	DelegateMetaTaskType = "task_type"
+	// DelegateMetaMIC carries the delegator's encoded Model Identity
+	// Certificate, used by servers that enforce capability-scoped delegation.
+	DelegateMetaMIC = "mic"
+)
+
 // Encode serialises AgentDelegate into buf using StrandBuf wire format.
 func (m *AgentDelegate) Encode(buf *strandbuf.Buffer) {
 	buf.WriteUint32(m.SessionID)
@@ -88,10 +106,23 @@ func (m *AgentDelegate) Encode(buf *strandbuf.Buffer) {
 	}
 	buf.WriteBytes(m.TaskPayload)
 	buf.WriteUint32(m.TimeoutMS)
+	// Keys are written in sorted order so the same delegation always encodes
+	// to the same bytes.
+	keys := make([]string, 0, len(m.Metadata))
+	for k := range m.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	buf.WriteMapLen(uint32(len(keys)))
+	for _, k := range keys {
+		buf.WriteString(k)
+		buf.WriteString(m.Metadata[k])
+	}
 }
 
 // Decode reads an AgentDelegate from r.
 func (m *AgentDelegate) Decode(r *strandbuf.Reader) error {
+	limitStrings(r)
 	var err error
 	m.SessionID, err = r.ReadUint32()
 	if err != nil {
@@ -111,6 +142,14 @@ func (m *AgentDelegate) Decode(r *strandbuf.Reader) error {
 	m.TaskPayload = make([]byte, len(payload))
 	copy(m.TaskPayload, payload)
 	m.TimeoutMS, err = r.ReadUint32()
+	if err != nil {
+		return err
+	}
+	// Metadata is a trailing extension; frames from older peers end here.
+	if r.Remaining() == 0 {
+		return nil
+	}
+	m.Metadata, err = decodeMetadata(r)
 	return err
 }
 
@@ -140,6 +179,7 @@ func (m *AgentResult) Encode(buf *strandbuf.Buffer) {
 
 // Decode reads an AgentResult from r.
 func (m *AgentResult) Decode(r *strandbuf.Reader) error {
+	limitStrings(r)
 	var err error
 	m.SessionID, err = r.ReadUint32()
 	if err != nil {
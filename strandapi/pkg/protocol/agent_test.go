@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 
 	"github.com/strand-protocol/strand/strandapi/pkg/strandbuf"
@@ -14,7 +15,7 @@ import (
 func TestAgentNegotiateRoundTrip(t *testing.T) {
 	orig := &AgentNegotiate{
 		SessionID:    0xDEADBEEF,
-		Capabilities: []string{"inference", "tensor-transfer", "stream"},
+		Capabilities: []string{"inference", "stream", "tensor-transfer"},
 		Version:      1,
 	}
 
@@ -43,6 +44,49 @@ func TestAgentNegotiateRoundTrip(t *testing.T) {
 	}
 }
 
+func TestAgentNegotiateDeterministicEncoding(t *testing.T) {
+	// Build the same logical capability set from a map twice; map iteration
+	// order is randomised, so the slices will usually differ in order.
+	set := map[string]bool{"text_gen": true, "code_gen": true, "vision": true, "tool_use": true, "embedding": true}
+	encode := func() []byte {
+		caps := make([]string, 0, len(set))
+		for c := range set {
+			caps = append(caps, c)
+		}
+		m := &AgentNegotiate{SessionID: 3, Capabilities: caps, Version: 1}
+		buf := strandbuf.NewBuffer(128)
+		m.Encode(buf)
+		return buf.Bytes()
+	}
+
+	first := encode()
+	for i := 0; i < 10; i++ {
+		if got := encode(); !bytes.Equal(first, got) {
+			t.Fatalf("encoding %d differs:\n  first: %x\n  got:   %x", i, first, got)
+		}
+	}
+
+	decoded := &AgentNegotiate{}
+	if err := decoded.Decode(strandbuf.NewReader(first)); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	want := []string{"code_gen", "embedding", "text_gen", "tool_use", "vision"}
+	for i, c := range want {
+		if decoded.Capabilities[i] != c {
+			t.Errorf("Capabilities[%d]: %q != %q", i, decoded.Capabilities[i], c)
+		}
+	}
+}
+
+func TestAgentNegotiateEncodeDoesNotMutate(t *testing.T) {
+	caps := []string{"vision", "code_gen"}
+	m := &AgentNegotiate{Capabilities: caps}
+	m.Encode(strandbuf.NewBuffer(64))
+	if caps[0] != "vision" || caps[1] != "code_gen" {
+		t.Errorf("Encode reordered caller slice: %v", caps)
+	}
+}
+
 func TestAgentNegotiateEmptyCapabilities(t *testing.T) {
 	orig := &AgentNegotiate{
 		SessionID:    1,
@@ -154,6 +198,94 @@ func TestAgentDelegateEmptyPayload(t *testing.T) {
 	}
 }
 
+func TestAgentDelegateMetadataRoundTrip(t *testing.T) {
+	orig := &AgentDelegate{
+		SessionID:   11,
+		TaskPayload: []byte("task"),
+		Metadata: map[string]string{
+			DelegateMetaTaskType: "code_gen",
+			DelegateMetaMIC:      "opaque-credential",
+		},
+	}
+
+	buf := strandbuf.NewBuffer(128)
+	orig.Encode(buf)
+
+	decoded := &AgentDelegate{}
+	if err := decoded.Decode(strandbuf.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(decoded.Metadata) != 2 {
+		t.Fatalf("Metadata len = %d, want 2", len(decoded.Metadata))
+	}
+	if decoded.Metadata[DelegateMetaTaskType] != "code_gen" {
+		t.Errorf("task_type = %q, want %q", decoded.Metadata[DelegateMetaTaskType], "code_gen")
+	}
+	if decoded.Metadata[DelegateMetaMIC] != "opaque-credential" {
+		t.Errorf("mic = %q, want %q", decoded.Metadata[DelegateMetaMIC], "opaque-credential")
+	}
+}
+
+func TestAgentDelegateEncodeDeterministic(t *testing.T) {
+	msg := &AgentDelegate{SessionID: 3, TaskPayload: []byte("task"), Metadata: map[string]string{}}
+	for _, k := range []string{"zeta", "alpha", "mic", "task_type", "beta", "gamma", "delta", "omega"} {
+		msg.Metadata[k] = k + "-value"
+	}
+
+	first := strandbuf.NewBuffer(256)
+	msg.Encode(first)
+	for i := 0; i < 20; i++ {
+		again := strandbuf.NewBuffer(256)
+		msg.Encode(again)
+		if !bytes.Equal(first.Bytes(), again.Bytes()) {
+			t.Fatalf("encoding %d differs from the first", i)
+		}
+	}
+}
+
+func TestAgentDelegateDuplicateMetadataKey(t *testing.T) {
+	buf := strandbuf.NewBuffer(64)
+	buf.WriteUint32(5)
+	for i := 0; i < 16; i++ {
+		buf.WriteUint8(0)
+	}
+	buf.WriteBytes([]byte("task"))
+	buf.WriteUint32(250)
+	buf.WriteMapLen(2)
+	buf.WriteString(DelegateMetaMIC)
+	buf.WriteString("first")
+	buf.WriteString(DelegateMetaMIC)
+	buf.WriteString("second")
+
+	err := (&AgentDelegate{}).Decode(strandbuf.NewReader(buf.Bytes()))
+	if !errors.Is(err, ErrDuplicateMetadataKey) {
+		t.Errorf("Decode: err = %v, want ErrDuplicateMetadataKey", err)
+	}
+}
+
+func TestAgentDelegateDecodeWithoutMetadata(t *testing.T) {
+	// Frames from peers that predate the metadata extension end after
+	// TimeoutMS and must still decode.
+	buf := strandbuf.NewBuffer(64)
+	buf.WriteUint32(5)
+	for i := 0; i < 16; i++ {
+		buf.WriteUint8(0)
+	}
+	buf.WriteBytes([]byte("legacy"))
+	buf.WriteUint32(250)
+
+	decoded := &AgentDelegate{}
+	if err := decoded.Decode(strandbuf.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if decoded.TimeoutMS != 250 {
+		t.Errorf("TimeoutMS: %d != 250", decoded.TimeoutMS)
+	}
+	if decoded.Metadata != nil {
+		t.Errorf("expected nil Metadata, got %v", decoded.Metadata)
+	}
+}
+
 func TestAgentDelegateViaFrame(t *testing.T) {
 	orig := &AgentDelegate{
 		SessionID:    99,
@@ -0,0 +1,56 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Chat roles accepted by RenderChat.
+const (
+	ChatRoleSystem    = "system"
+	ChatRoleUser      = "user"
+	ChatRoleAssistant = "assistant"
+	ChatRoleTool      = "tool"
+)
+
+// DefaultChatTemplate renders each message on its own line prefixed with its
+// role, e.g. "system: Be brief.\nuser: Hi\n".
+const DefaultChatTemplate = "{role}: {content}\n"
+
+// ChatMessage is one turn of a chat conversation.
+type ChatMessage struct {
+	Role    string
+	Content string
+}
+
+// RenderChat flattens messages into a single prompt string. The template is
+// applied to every message in order, with {role} replaced by the message's
+// role and {content} by its text; an empty template uses
+// DefaultChatTemplate. All turns are kept, including system and assistant
+// messages. It returns an error for an empty conversation, an unknown role,
+// or a template without a {content} placeholder.
+func RenderChat(messages []ChatMessage, template string) (string, error) {
+	if template == "" {
+		template = DefaultChatTemplate
+	}
+	if !strings.Contains(template, "{content}") {
+		return "", errors.New("strandapi: chat template has no {content} placeholder")
+	}
+	if len(messages) == 0 {
+		return "", errors.New("strandapi: no chat messages to render")
+	}
+
+	var out strings.Builder
+	for i, m := range messages {
+		switch m.Role {
+		case ChatRoleSystem, ChatRoleUser, ChatRoleAssistant, ChatRoleTool:
+		default:
+			return "", fmt.Errorf("strandapi: chat message %d has unknown role %q", i, m.Role)
+		}
+		// A single Replacer pass keeps placeholders inside message content
+		// from being expanded.
+		strings.NewReplacer("{role}", m.Role, "{content}", m.Content).WriteString(&out, template)
+	}
+	return out.String(), nil
+}
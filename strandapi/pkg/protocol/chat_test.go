@@ -0,0 +1,62 @@
+package protocol
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderChatMultiTurn(t *testing.T) {
+	messages := []ChatMessage{
+		{Role: ChatRoleSystem, Content: "You are terse."},
+		{Role: ChatRoleUser, Content: "What is 2+2?"},
+		{Role: ChatRoleAssistant, Content: "4."},
+		{Role: ChatRoleUser, Content: "And 3+3?"},
+	}
+
+	got, err := RenderChat(messages, "")
+	if err != nil {
+		t.Fatalf("RenderChat: %v", err)
+	}
+	want := "system: You are terse.\nuser: What is 2+2?\nassistant: 4.\nuser: And 3+3?\n"
+	if got != want {
+		t.Errorf("RenderChat = %q, want %q", got, want)
+	}
+
+	chatml, err := RenderChat(messages, "<|im_start|>{role}\n{content}<|im_end|>\n")
+	if err != nil {
+		t.Fatalf("RenderChat chatml: %v", err)
+	}
+	for _, m := range messages {
+		turn := "<|im_start|>" + m.Role + "\n" + m.Content + "<|im_end|>"
+		if !strings.Contains(chatml, turn) {
+			t.Errorf("rendered prompt %q is missing turn %q", chatml, turn)
+		}
+	}
+}
+
+func TestRenderChatPlaceholdersInContent(t *testing.T) {
+	got, err := RenderChat([]ChatMessage{{Role: ChatRoleUser, Content: "say {role}"}}, "")
+	if err != nil {
+		t.Fatalf("RenderChat: %v", err)
+	}
+	if got != "user: say {role}\n" {
+		t.Errorf("RenderChat = %q, placeholders in content must not expand", got)
+	}
+}
+
+func TestRenderChatErrors(t *testing.T) {
+	user := []ChatMessage{{Role: ChatRoleUser, Content: "hi"}}
+	cases := map[string]struct {
+		messages []ChatMessage
+		template string
+	}{
+		"no messages":      {nil, ""},
+		"unknown role":     {[]ChatMessage{{Role: "narrator", Content: "x"}}, ""},
+		"template without": {user, "{role}: "},
+	}
+	for name, tc := range cases {
+		if _, err := RenderChat(tc.messages, tc.template); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
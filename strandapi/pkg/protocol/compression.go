@@ -0,0 +1,152 @@
+package protocol
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/strand-protocol/strand/strandapi/pkg/strandbuf"
+)
+
+// Codec identifies a payload compression algorithm in the OpHello handshake
+// and in Compressed frames.
+type Codec uint8
+
+// Codec values. CodecRaw (no compression) is always supported. Only
+// CodecDeflate has a built-in implementation; other codecs are supplied by
+// the application as a Compressor.
+const (
+	CodecRaw     Codec = 0x00
+	CodecDeflate Codec = 0x01
+	CodecZstd    Codec = 0x02
+	CodecLZ4     Codec = 0x03
+)
+
+// maxHelloCodecs caps the codec list a Hello may carry.
+const maxHelloCodecs = 16
+
+// ErrUnsupportedCodec is returned when a Compressed frame names a codec the
+// receiver has no Compressor for.
+var ErrUnsupportedCodec = errors.New("strandapi: unsupported compression codec")
+
+// String returns the codec's lowercase name.
+func (c Codec) String() string {
+	switch c {
+	case CodecRaw:
+		return "raw"
+	case CodecDeflate:
+		return "deflate"
+	case CodecZstd:
+		return "zstd"
+	case CodecLZ4:
+		return "lz4"
+	default:
+		return fmt.Sprintf("codec(0x%02x)", uint8(c))
+	}
+}
+
+// Compressor implements one Codec.
+type Compressor interface {
+	// Codec returns the identifier advertised in Hello.
+	Codec() Codec
+	// Compress returns the compressed form of p.
+	Compress(p []byte) ([]byte, error)
+	// Decompress returns the original form of p. It fails rather than
+	// produce more than limit bytes, so a small frame cannot expand into an
+	// arbitrarily large allocation. A limit of 0 means MaxPayloadSize.
+	Decompress(p []byte, limit uint32) ([]byte, error)
+}
+
+// Deflate is the built-in CodecDeflate Compressor, using compress/flate at
+// the default compression level.
+var Deflate Compressor = deflateCompressor{}
+
+type deflateCompressor struct{}
+
+func (deflateCompressor) Codec() Codec { return CodecDeflate }
+
+func (deflateCompressor) Compress(p []byte) ([]byte, error) {
+	var out bytes.Buffer
+	w, err := flate.NewWriter(&out, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(p); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func (deflateCompressor) Decompress(p []byte, limit uint32) ([]byte, error) {
+	if limit == 0 {
+		limit = MaxPayloadSize
+	}
+	r := flate.NewReader(bytes.NewReader(p))
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, fmt.Errorf("strandapi: deflate: %w", err)
+	}
+	if uint32(len(out)) > limit {
+		return nil, ErrPayloadTooLarge
+	}
+	return out, nil
+}
+
+// NegotiateCodec returns the first codec in preferred that the peer listed
+// in supported, or CodecRaw if there is none.
+func NegotiateCodec(preferred, supported []Codec) Codec {
+	for _, c := range preferred {
+		if c == CodecRaw {
+			continue
+		}
+		for _, s := range supported {
+			if c == s {
+				return c
+			}
+		}
+	}
+	return CodecRaw
+}
+
+// Compressed wraps a frame whose payload was compressed with a codec the
+// receiver advertised in its Hello. The receiver decompresses Payload and
+// handles the result as a frame with opcode Opcode.
+//
+// Wire layout (StrandBuf):
+//
+//	[uint8] Codec
+//	[uint8] Opcode (of the wrapped frame)
+//	[bytes] Payload (compressed)
+type Compressed struct {
+	Codec   Codec
+	Opcode  byte
+	Payload []byte
+}
+
+// Encode serialises the Compressed frame into buf.
+func (m *Compressed) Encode(buf *strandbuf.Buffer) {
+	buf.WriteUint8(uint8(m.Codec))
+	buf.WriteUint8(m.Opcode)
+	buf.WriteBytes(m.Payload)
+}
+
+// Decode reads a Compressed frame from r. Payload aliases r's buffer.
+func (m *Compressed) Decode(r *strandbuf.Reader) error {
+	codec, err := r.ReadUint8()
+	if err != nil {
+		return err
+	}
+	m.Codec = Codec(codec)
+	m.Opcode, err = r.ReadUint8()
+	if err != nil {
+		return err
+	}
+	m.Payload, err = r.ReadBytes()
+	return err
+}
@@ -1,5 +1,7 @@
 package protocol
 
+import "strings"
+
 // StrandAPI error codes (0x0000–0x00FF).
 //
 // These codes cover the same semantics as the spec (CLAUDE.md §7) but use a
@@ -9,36 +11,40 @@ package protocol
 // capability, trust) for clearer extensibility. Both sets are self-consistent
 // and cover all 13 required error semantics.
 const (
-	ErrOK             uint16 = 0x0000 // Success / no error
-	ErrUnknown        uint16 = 0x0001 // Unspecified error
-	ErrTimeout        uint16 = 0x0002 // Request timed out
-	ErrNotFound       uint16 = 0x0003 // Requested resource not found
-	ErrAlreadyExists  uint16 = 0x0004 // Resource already exists
-	ErrInternal       uint16 = 0x0005 // Internal server error
-	ErrInvalidRequest uint16 = 0x0006 // Malformed or invalid request
-	ErrCapabilities   uint16 = 0x0007 // Node lacks required capabilities
-	ErrContextTooLong uint16 = 0x0008 // Context window limit exceeded
-	ErrModelUnavail   uint16 = 0x0009 // Requested model is not available
-	ErrRateLimited    uint16 = 0x000A // Request rate limit exceeded
-	ErrTrustViolation uint16 = 0x000B // StrandTrust attestation failure
-	ErrCancelled      uint16 = 0x000C // Request was cancelled by client
+	ErrOK               uint16 = 0x0000 // Success / no error
+	ErrUnknown          uint16 = 0x0001 // Unspecified error
+	ErrTimeout          uint16 = 0x0002 // Request timed out
+	ErrNotFound         uint16 = 0x0003 // Requested resource not found
+	ErrAlreadyExists    uint16 = 0x0004 // Resource already exists
+	ErrInternal         uint16 = 0x0005 // Internal server error
+	ErrInvalidRequest   uint16 = 0x0006 // Malformed or invalid request
+	ErrCapabilities     uint16 = 0x0007 // Node lacks required capabilities
+	ErrContextTooLong   uint16 = 0x0008 // Context window limit exceeded
+	ErrModelUnavail     uint16 = 0x0009 // Requested model is not available
+	ErrRateLimited      uint16 = 0x000A // Request rate limit exceeded
+	ErrTrustViolation   uint16 = 0x000B // StrandTrust attestation failure
+	ErrCancelled        uint16 = 0x000C // Request was cancelled by client
+	ErrDeadlineExceeded uint16 = 0x000D // Request deadline passed before completion
+	ErrUnsupported      uint16 = 0x000E // Opcode or feature not supported by the peer
 )
 
 // ErrCodeNames maps error codes to human-readable identifiers for logging.
 var ErrCodeNames = map[uint16]string{
-	ErrOK:             "OK",
-	ErrUnknown:        "UNKNOWN",
-	ErrTimeout:        "TIMEOUT",
-	ErrNotFound:       "NOT_FOUND",
-	ErrAlreadyExists:  "ALREADY_EXISTS",
-	ErrInternal:       "INTERNAL_ERROR",
-	ErrInvalidRequest: "INVALID_REQUEST",
-	ErrCapabilities:   "CAPABILITIES_MISMATCH",
-	ErrContextTooLong: "CONTEXT_TOO_LONG",
-	ErrModelUnavail:   "MODEL_UNAVAILABLE",
-	ErrRateLimited:    "RATE_LIMITED",
-	ErrTrustViolation: "TRUST_VIOLATION",
-	ErrCancelled:      "CANCELLED",
+	ErrOK:               "OK",
+	ErrUnknown:          "UNKNOWN",
+	ErrTimeout:          "TIMEOUT",
+	ErrNotFound:         "NOT_FOUND",
+	ErrAlreadyExists:    "ALREADY_EXISTS",
+	ErrInternal:         "INTERNAL_ERROR",
+	ErrInvalidRequest:   "INVALID_REQUEST",
+	ErrCapabilities:     "CAPABILITIES_MISMATCH",
+	ErrContextTooLong:   "CONTEXT_TOO_LONG",
+	ErrModelUnavail:     "MODEL_UNAVAILABLE",
+	ErrRateLimited:      "RATE_LIMITED",
+	ErrTrustViolation:   "TRUST_VIOLATION",
+	ErrCancelled:        "CANCELLED",
+	ErrDeadlineExceeded: "DEADLINE_EXCEEDED",
+	ErrUnsupported:      "UNSUPPORTED",
 }
 
 // ErrorMessage is a structured error response included in OpError frames.
@@ -47,3 +53,35 @@ type ErrorMessage struct {
 	Code    uint16 `json:"code"`
 	Message string `json:"message"`
 }
+
+// Error returns the message in its wire form, "NAME: message", so handlers can
+// return an *ErrorMessage to choose the code the server reports.
+func (e *ErrorMessage) Error() string {
+	name, ok := ErrCodeNames[e.Code]
+	if !ok {
+		name = ErrCodeNames[ErrUnknown]
+	}
+	return name + ": " + e.Message
+}
+
+// Payload encodes e as an OpError payload: the code's ErrCodeNames identifier,
+// a colon and the message. Codes without a name are sent as UNKNOWN. The
+// text form keeps OpError readable by peers that predate error codes.
+func (e *ErrorMessage) Payload() []byte {
+	return []byte(e.Error())
+}
+
+// ParseErrorMessage decodes an OpError payload. A payload that does not start
+// with a known code name, as sent by older peers, yields ErrUnknown with the
+// whole payload as the message.
+func ParseErrorMessage(payload []byte) *ErrorMessage {
+	msg := string(payload)
+	if name, rest, ok := strings.Cut(msg, ": "); ok {
+		for code, codeName := range ErrCodeNames {
+			if codeName == name {
+				return &ErrorMessage{Code: code, Message: rest}
+			}
+		}
+	}
+	return &ErrorMessage{Code: ErrUnknown, Message: msg}
+}
@@ -7,8 +7,10 @@ import (
 	"io"
 )
 
-// Maximum payload size (16 MiB) to prevent allocation of absurd buffers.
-const maxPayloadSize = 16 << 20
+// MaxPayloadSize is the largest payload (16 MiB) ReadFrame accepts, which
+// prevents allocation of absurd buffers. Use ReadFrameWithLimit to accept
+// larger frames, such as big tensor transfers.
+const MaxPayloadSize = 16 << 20
 
 var (
 	// ErrPayloadTooLarge is returned when a frame's declared length exceeds
@@ -45,8 +47,17 @@ func WriteFrame(w io.Writer, opcode byte, payload []byte) error {
 }
 
 // ReadFrame reads a single StrandAPI frame from r, returning the opcode and
-// payload. Returns io.EOF when the reader is exhausted cleanly.
+// payload. Returns io.EOF when the reader is exhausted cleanly, and
+// ErrPayloadTooLarge for frames declaring more than MaxPayloadSize bytes.
 func ReadFrame(r io.Reader) (opcode byte, payload []byte, err error) {
+	return ReadFrameWithLimit(r, MaxPayloadSize)
+}
+
+// ReadFrameWithLimit is like ReadFrame but rejects, with ErrPayloadTooLarge,
+// frames whose declared payload exceeds maxBytes instead of MaxPayloadSize.
+// The payload buffer is allocated up front from the declared length, so only
+// raise the limit for peers trusted to send frames of that size.
+func ReadFrameWithLimit(r io.Reader, maxBytes uint32) (opcode byte, payload []byte, err error) {
 	var hdr [5]byte
 	if _, err = io.ReadFull(r, hdr[:]); err != nil {
 		return 0, nil, err
@@ -54,7 +65,7 @@ func ReadFrame(r io.Reader) (opcode byte, payload []byte, err error) {
 	length := binary.LittleEndian.Uint32(hdr[0:4])
 	opcode = hdr[4]
 
-	if length > maxPayloadSize {
+	if length > maxBytes {
 		return 0, nil, ErrPayloadTooLarge
 	}
 
@@ -1,8 +1,13 @@
 package protocol
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"math/bits"
 
+	"github.com/strand-protocol/strand/strandapi/pkg/sad"
 	"github.com/strand-protocol/strand/strandapi/pkg/strandbuf"
 )
 
@@ -11,8 +16,83 @@ import (
 const (
 	maxMetadataEntries = 256
 	maxShapeDimensions = 8
+	maxBatchChunks     = 1024
+	// maxStringLen is the default cap on any single string field, applied by
+	// decoders unless the caller configured the Reader with SetMaxString.
+	maxStringLen = 4 << 20
 )
 
+// limitStrings applies the default string cap to r if the caller has not set
+// one of its own.
+func limitStrings(r *strandbuf.Reader) {
+	if r.MaxString() == 0 {
+		r.SetMaxString(maxStringLen)
+	}
+}
+
+// ErrDuplicateMetadataKey reports a metadata map that repeats a key. Encoders
+// never produce one, so a repeat is taken as a crafted message rather than
+// silently keeping one of the values.
+var ErrDuplicateMetadataKey = errors.New("strandapi: duplicate metadata key")
+
+// decodeMetadata reads a map<string,string> metadata field, capped at
+// maxMetadataEntries to prevent allocation-bomb DoS. A key that appears more
+// than once fails with ErrDuplicateMetadataKey.
+func decodeMetadata(r *strandbuf.Reader) (map[string]string, error) {
+	count, err := r.ReadMapLen()
+	if err != nil {
+		return nil, err
+	}
+	if count > maxMetadataEntries {
+		return nil, fmt.Errorf("strandapi: metadata count %d exceeds max %d", count, maxMetadataEntries)
+	}
+	md := make(map[string]string, count)
+	for i := uint32(0); i < count; i++ {
+		k, err := r.ReadString()
+		if err != nil {
+			return nil, err
+		}
+		v, err := r.ReadString()
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := md[k]; dup {
+			return nil, fmt.Errorf("%w %q", ErrDuplicateMetadataKey, k)
+		}
+		md[k] = v
+	}
+	return md, nil
+}
+
+// InferenceMetaStream is the InferenceRequest metadata key a client sets to
+// "true" when it wants a token stream rather than a single response. Servers
+// that pick the response mode per request may honour it.
+const InferenceMetaStream = "stream"
+
+// InferenceMetaRequestID is the InferenceRequest metadata key carrying the
+// caller's correlation ID, such as the HTTP bridge's X-Request-ID, so server
+// logs can be matched with the request that caused them.
+const InferenceMetaRequestID = "request_id"
+
+// RequestIDFromString derives a 128-bit InferenceRequest ID from a textual
+// correlation ID. A 32-digit hex string decodes to those 16 bytes; any other
+// non-empty value maps to the first 16 bytes of its SHA-256 digest, so the
+// same string always yields the same ID. An empty string yields the zero ID.
+func RequestIDFromString(s string) [16]byte {
+	var id [16]byte
+	if s == "" {
+		return id
+	}
+	if len(s) == 2*len(id) {
+		if _, err := hex.Decode(id[:], []byte(s)); err == nil {
+			return id
+		}
+	}
+	sum := sha256.Sum256([]byte(s))
+	copy(id[:], sum[:])
+	return id
+}
+
 // InferenceRequest is the primary message sent by a client to request model
 // inference. It carries a 128-bit request ID, an optional SAD-encoded model
 // selector, the prompt text, generation parameters, and arbitrary metadata.
@@ -23,6 +103,23 @@ type InferenceRequest struct {
 	MaxTokens   uint32            // Maximum tokens to generate
 	Temperature float32           // Sampling temperature
 	Metadata    map[string]string // Custom key-value metadata
+	// DeadlineUnixMs, when non-zero, is the wall-clock time (Unix
+	// milliseconds) by which the server must finish generating; past it the
+	// server aborts and replies with ErrDeadlineExceeded. It is a trailing
+	// field, written only when set, so older decoders ignore it.
+	DeadlineUnixMs uint64
+	// Stream asks for the response as a token stream; when false the server
+	// replies with a single InferenceResponse even if it only has a
+	// StreamHandler. It is a trailing field after DeadlineUnixMs.
+	Stream bool
+	// PromptCacheKey names a prompt prefix cached on the server for this
+	// peer. With PromptCachePrefixLen > 0 the server caches the first
+	// PromptCachePrefixLen bytes of Prompt under the key; with 0 it prepends
+	// the prefix cached earlier, so a long shared system prompt is sent only
+	// once; a key the server does not hold is answered with ErrNotFound.
+	// Both are trailing fields after Stream.
+	PromptCacheKey       string
+	PromptCachePrefixLen uint32
 }
 
 // Encode serialises the InferenceRequest into buf using StrandBuf wire format.
@@ -47,11 +144,44 @@ func (m *InferenceRequest) Encode(buf *strandbuf.Buffer) {
 		buf.WriteString(k)
 		buf.WriteString(v)
 	}
+	// Trailing fields are written up to the last one set, so peers that
+	// predate them decode the same bytes as before.
+	cached := m.PromptCacheKey != "" || m.PromptCachePrefixLen != 0
+	// DeadlineUnixMs: optional trailing uint64
+	if m.DeadlineUnixMs != 0 || m.Stream || cached {
+		buf.WriteUint64(m.DeadlineUnixMs)
+	}
+	// Stream: optional trailing uint8 after the deadline
+	if m.Stream || cached {
+		var flag uint8
+		if m.Stream {
+			flag = 1
+		}
+		buf.WriteUint8(flag)
+	}
+	// PromptCacheKey, PromptCachePrefixLen: optional trailing string and
+	// uint32 after Stream
+	if cached {
+		buf.WriteString(m.PromptCacheKey)
+		buf.WriteUint32(m.PromptCachePrefixLen)
+	}
 }
 
-// Decode reads an InferenceRequest from r. Returns an error if the data is
-// incomplete or malformed.
+// EncodeV2 serialises the InferenceRequest with a MessageV2 version stamp
+// (see the versioning scheme in version.go). Decode accepts either form.
+func (m *InferenceRequest) EncodeV2(buf *strandbuf.Buffer) {
+	writeVersionStamp(buf, MessageV2)
+	m.Encode(buf)
+}
+
+// Decode reads an InferenceRequest from r, accepting both v1 and
+// version-stamped v2 blobs. Returns an error if the data is incomplete or
+// malformed, including metadata that repeats a key (ErrDuplicateMetadataKey).
 func (m *InferenceRequest) Decode(r *strandbuf.Reader) error {
+	limitStrings(r)
+	if _, err := readVersionStamp(r); err != nil {
+		return err
+	}
 	// ID
 	for i := 0; i < 16; i++ {
 		b, err := r.ReadUint8()
@@ -82,29 +212,67 @@ func (m *InferenceRequest) Decode(r *strandbuf.Reader) error {
 	if err != nil {
 		return err
 	}
-	// Metadata — cap to prevent allocation-bomb DoS.
-	count, err := r.ReadMapLen()
-	if err != nil {
+	// Metadata
+	if m.Metadata, err = decodeMetadata(r); err != nil {
 		return err
 	}
-	if count > maxMetadataEntries {
-		return fmt.Errorf("strandapi: metadata count %d exceeds max %d", count, maxMetadataEntries)
-	}
-	m.Metadata = make(map[string]string, count)
-	for i := uint32(0); i < count; i++ {
-		k, err := r.ReadString()
+	// DeadlineUnixMs — absent in blobs from peers that predate it. Any
+	// trailing bytes start it, so a truncated deadline is an error rather
+	// than being read as the fields after it.
+	m.DeadlineUnixMs = 0
+	if r.Remaining() > 0 {
+		m.DeadlineUnixMs, err = r.ReadUint64()
 		if err != nil {
 			return err
 		}
-		v, err := r.ReadString()
+	}
+	// Stream — absent unless set.
+	m.Stream = false
+	if r.Remaining() >= 1 {
+		flag, err := r.ReadUint8()
 		if err != nil {
 			return err
 		}
-		m.Metadata[k] = v
+		m.Stream = flag != 0
+	}
+	// PromptCacheKey, PromptCachePrefixLen — absent unless set.
+	m.PromptCacheKey, m.PromptCachePrefixLen = "", 0
+	if r.Remaining() > 0 {
+		if m.PromptCacheKey, err = r.ReadString(); err != nil {
+			return err
+		}
+		if m.PromptCachePrefixLen, err = r.ReadUint32(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// ErrNoModelSAD is returned by GetModelSAD when the request carries no
+// model selector.
+var ErrNoModelSAD = errors.New("strandapi: request has no model SAD")
+
+// SetModelSAD encodes s and stores the bytes in ModelSAD, so callers can
+// pass a *sad.SAD rather than its wire form.
+func (m *InferenceRequest) SetModelSAD(s *sad.SAD) {
+	buf := strandbuf.NewBuffer(32)
+	s.Encode(buf)
+	m.ModelSAD = buf.Bytes()
+}
+
+// GetModelSAD decodes ModelSAD. It returns ErrNoModelSAD if ModelSAD is
+// empty.
+func (m *InferenceRequest) GetModelSAD() (*sad.SAD, error) {
+	if len(m.ModelSAD) == 0 {
+		return nil, ErrNoModelSAD
+	}
+	s := &sad.SAD{}
+	if err := s.Decode(strandbuf.NewReader(m.ModelSAD)); err != nil {
+		return nil, fmt.Errorf("strandapi: decode model SAD: %w", err)
+	}
+	return s, nil
+}
+
 // InferenceResponse is the complete (non-streaming) response to an
 // InferenceRequest.
 type InferenceResponse struct {
@@ -126,8 +294,20 @@ func (m *InferenceResponse) Encode(buf *strandbuf.Buffer) {
 	buf.WriteUint32(m.CompletionTokens)
 }
 
-// Decode reads an InferenceResponse from r.
+// EncodeV2 serialises the InferenceResponse with a MessageV2 version stamp
+// (see the versioning scheme in version.go). Decode accepts either form.
+func (m *InferenceResponse) EncodeV2(buf *strandbuf.Buffer) {
+	writeVersionStamp(buf, MessageV2)
+	m.Encode(buf)
+}
+
+// Decode reads an InferenceResponse from r, accepting both v1 and
+// version-stamped v2 blobs.
 func (m *InferenceResponse) Decode(r *strandbuf.Reader) error {
+	limitStrings(r)
+	if _, err := readVersionStamp(r); err != nil {
+		return err
+	}
 	for i := 0; i < 16; i++ {
 		b, err := r.ReadUint8()
 		if err != nil {
@@ -159,7 +339,7 @@ func (m *InferenceResponse) Decode(r *strandbuf.Reader) error {
 // delivered during streaming inference.
 type TokenStreamChunk struct {
 	RequestID [16]byte // Links back to the originating request
-	SeqNum    uint32   // Sequence number for client-side reassembly
+	SeqNum    uint32   // Sequence number, from 0, for client-side reassembly
 	Token     string   // The generated token text
 	Logprob   float32  // Log probability of this token
 }
@@ -176,6 +356,7 @@ func (m *TokenStreamChunk) Encode(buf *strandbuf.Buffer) {
 
 // Decode reads a TokenStreamChunk from r.
 func (m *TokenStreamChunk) Decode(r *strandbuf.Reader) error {
+	limitStrings(r)
 	for i := 0; i < 16; i++ {
 		b, err := r.ReadUint8()
 		if err != nil {
@@ -199,6 +380,116 @@ func (m *TokenStreamChunk) Decode(r *strandbuf.Reader) error {
 	return nil
 }
 
+// TokenStreamBatch carries several consecutive TokenStreamChunks in one
+// OpTokenStreamBatch frame, so small tokens do not each cost a datagram.
+// Chunks are in stream order and keep their own SeqNum.
+type TokenStreamBatch struct {
+	Chunks []TokenStreamChunk
+}
+
+// Encode serialises the TokenStreamBatch into buf.
+func (m *TokenStreamBatch) Encode(buf *strandbuf.Buffer) {
+	buf.WriteList(uint32(len(m.Chunks)))
+	for i := range m.Chunks {
+		m.Chunks[i].Encode(buf)
+	}
+}
+
+// Decode reads a TokenStreamBatch from r.
+func (m *TokenStreamBatch) Decode(r *strandbuf.Reader) error {
+	limitStrings(r)
+	count, err := r.ReadList()
+	if err != nil {
+		return err
+	}
+	// Cap to prevent allocation-bomb DoS.
+	if count > maxBatchChunks {
+		return fmt.Errorf("strandapi: batch chunk count %d exceeds max %d", count, maxBatchChunks)
+	}
+	m.Chunks = make([]TokenStreamChunk, count)
+	for i := range m.Chunks {
+		if err := m.Chunks[i].Decode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StreamSummary is the payload of an OpTokenStreamEnd frame. It reports the
+// same usage figures as a blocking InferenceResponse so streaming clients do
+// not need a second request. Peers that predate it send an empty payload.
+type StreamSummary struct {
+	PromptTokens     uint32 // Tokens consumed by the prompt
+	CompletionTokens uint32 // Tokens generated
+	FinishReason     string // "stop", "length", etc.
+}
+
+// Encode serialises the StreamSummary into buf.
+func (m *StreamSummary) Encode(buf *strandbuf.Buffer) {
+	buf.WriteUint32(m.PromptTokens)
+	buf.WriteUint32(m.CompletionTokens)
+	buf.WriteString(m.FinishReason)
+}
+
+// Decode reads a StreamSummary from r.
+func (m *StreamSummary) Decode(r *strandbuf.Reader) error {
+	limitStrings(r)
+	var err error
+	m.PromptTokens, err = r.ReadUint32()
+	if err != nil {
+		return err
+	}
+	m.CompletionTokens, err = r.ReadUint32()
+	if err != nil {
+		return err
+	}
+	m.FinishReason, err = r.ReadString()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// PartialResponse is an interim snapshot of the full response text so far,
+// sent in an OpPartialResponse frame during a token stream. Unlike token
+// chunks, which append, each revision replaces the previous one, so models
+// that revise their output (for example speculative decoding corrections)
+// can show progress that may still change. A higher Revision supersedes a
+// lower one; revisions arriving out of order can be discarded.
+type PartialResponse struct {
+	RequestID [16]byte // Links back to the originating request
+	Revision  uint32   // Increases with every snapshot of the stream
+	Text      string   // Complete interim text, not a delta
+}
+
+// Encode serialises the PartialResponse into buf.
+func (m *PartialResponse) Encode(buf *strandbuf.Buffer) {
+	for i := 0; i < 16; i++ {
+		buf.WriteUint8(m.RequestID[i])
+	}
+	buf.WriteUint32(m.Revision)
+	buf.WriteString(m.Text)
+}
+
+// Decode reads a PartialResponse from r.
+func (m *PartialResponse) Decode(r *strandbuf.Reader) error {
+	limitStrings(r)
+	for i := 0; i < 16; i++ {
+		b, err := r.ReadUint8()
+		if err != nil {
+			return err
+		}
+		m.RequestID[i] = b
+	}
+	var err error
+	m.Revision, err = r.ReadUint32()
+	if err != nil {
+		return err
+	}
+	m.Text, err = r.ReadString()
+	return err
+}
+
 // TensorTransfer carries bulk tensor data (model weights, activations,
 // gradients, embeddings) between endpoints.
 type TensorTransfer struct {
@@ -208,6 +499,79 @@ type TensorTransfer struct {
 	Data  []byte   // Raw tensor data
 }
 
+// Tensor data type codes for TensorTransfer.DType, matching StrandLink's
+// tensor_dtype header field.
+const (
+	DTypeNone     uint8 = 0x00 // Untyped bytes; Data length is not checked
+	DTypeFloat16  uint8 = 0x01
+	DTypeBFloat16 uint8 = 0x02
+	DTypeFloat32  uint8 = 0x03
+	DTypeFloat64  uint8 = 0x04
+	DTypeInt8     uint8 = 0x05
+	DTypeInt4     uint8 = 0x06 // Two elements per byte
+	DTypeUint8    uint8 = 0x07
+	DTypeFP8E4M3  uint8 = 0x08
+	DTypeFP8E5M2  uint8 = 0x09
+)
+
+// ErrTensorMismatch is returned by TensorTransfer.Validate when Data does not
+// hold exactly the elements described by Shape and DType.
+var ErrTensorMismatch = errors.New("strandapi: tensor data does not match shape and dtype")
+
+// dtypeBits returns the element width in bits of a DType code, or false for
+// an unknown code.
+func dtypeBits(dtype uint8) (uint64, bool) {
+	switch dtype {
+	case DTypeFloat16, DTypeBFloat16:
+		return 16, true
+	case DTypeFloat32:
+		return 32, true
+	case DTypeFloat64:
+		return 64, true
+	case DTypeInt8, DTypeUint8, DTypeFP8E4M3, DTypeFP8E5M2:
+		return 8, true
+	case DTypeInt4:
+		return 4, true
+	}
+	return 0, false
+}
+
+// Validate checks that DType is a known code and that len(Data) is exactly
+// the product of Shape times the element size, rounded up to whole bytes for
+// DTypeInt4. An empty Shape is a scalar. DTypeNone tensors are opaque and
+// their length is not checked. Errors wrap ErrTensorMismatch.
+func (m *TensorTransfer) Validate() error {
+	if len(m.Shape) > maxShapeDimensions {
+		return fmt.Errorf("%w: %d dimensions exceeds max %d", ErrTensorMismatch, len(m.Shape), maxShapeDimensions)
+	}
+	width, ok := dtypeBits(m.DType)
+	if !ok && m.DType != DTypeNone {
+		return fmt.Errorf("%w: unknown dtype 0x%02x", ErrTensorMismatch, m.DType)
+	}
+	// Saturate the element count just past what Data could hold so the
+	// product cannot overflow; a zero dimension still brings it back to 0.
+	limit := uint64(len(m.Data)) * 2
+	elems := uint64(1)
+	for _, d := range m.Shape {
+		hi, lo := bits.Mul64(elems, uint64(d))
+		if hi != 0 || lo > limit {
+			lo = limit + 1
+		}
+		elems = lo
+	}
+	if m.DType == DTypeNone {
+		return nil
+	}
+	if elems > limit {
+		return fmt.Errorf("%w: shape %v needs more than the %d bytes of data", ErrTensorMismatch, m.Shape, len(m.Data))
+	}
+	want := (elems*width + 7) / 8
+	if uint64(len(m.Data)) != want {
+		return fmt.Errorf("%w: shape %v of dtype 0x%02x needs %d bytes, got %d", ErrTensorMismatch, m.Shape, m.DType, want, len(m.Data))
+	}
+	return nil
+}
+
 // Encode serialises the TensorTransfer into buf.
 func (m *TensorTransfer) Encode(buf *strandbuf.Buffer) {
 	for i := 0; i < 16; i++ {
@@ -260,3 +624,12 @@ func (m *TensorTransfer) Decode(r *strandbuf.Reader) error {
 	m.Data = dataCopy
 	return nil
 }
+
+// DecodeStrict is Decode followed by Validate, for receivers that hand the
+// tensor to code that trusts Data to match Shape and DType.
+func (m *TensorTransfer) DecodeStrict(r *strandbuf.Reader) error {
+	if err := m.Decode(r); err != nil {
+		return err
+	}
+	return m.Validate()
+}
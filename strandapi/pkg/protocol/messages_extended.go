@@ -81,10 +81,12 @@ func (m *ContextAck) Decode(r *strandbuf.Reader) error {
 //	[16 bytes] RequestID
 //	[string]   ToolName    (length-prefixed tool identifier)
 //	[bytes]    Arguments   (length-prefixed JSON or opaque arguments)
+//	[bytes]    ArgsSchema  (optional; JSON schema for Arguments, see ValidateToolArgs)
 type ToolInvoke struct {
-	RequestID [16]byte
-	ToolName  string
-	Arguments []byte
+	RequestID  [16]byte
+	ToolName   string
+	Arguments  []byte
+	ArgsSchema []byte
 }
 
 func (m *ToolInvoke) Encode(buf *strandbuf.Buffer) {
@@ -93,9 +95,13 @@ func (m *ToolInvoke) Encode(buf *strandbuf.Buffer) {
 	}
 	buf.WriteString(m.ToolName)
 	buf.WriteBytes(m.Arguments)
+	if len(m.ArgsSchema) > 0 {
+		buf.WriteBytes(m.ArgsSchema)
+	}
 }
 
 func (m *ToolInvoke) Decode(r *strandbuf.Reader) error {
+	limitStrings(r)
 	for i := 0; i < 16; i++ {
 		b, err := r.ReadUint8()
 		if err != nil {
@@ -114,6 +120,16 @@ func (m *ToolInvoke) Decode(r *strandbuf.Reader) error {
 	}
 	m.Arguments = make([]byte, len(args))
 	copy(m.Arguments, args)
+	// ArgsSchema is a trailing extension and may be absent.
+	if r.Remaining() == 0 {
+		return nil
+	}
+	schema, err := r.ReadBytes()
+	if err != nil {
+		return err
+	}
+	m.ArgsSchema = make([]byte, len(schema))
+	copy(m.ArgsSchema, schema)
 	return nil
 }
 
@@ -245,3 +261,193 @@ func (m *Cancel) Decode(r *strandbuf.Reader) error {
 	}
 	return nil
 }
+
+// StreamStart is the payload of OpTokenStreamStart. It echoes the ID of the
+// request being answered so a client sharing a transport between requests can
+// tell which stream has begun. Servers predating it send an empty payload.
+type StreamStart struct {
+	RequestID [16]byte
+}
+
+func (m *StreamStart) Encode(buf *strandbuf.Buffer) {
+	for i := 0; i < 16; i++ {
+		buf.WriteUint8(m.RequestID[i])
+	}
+}
+
+func (m *StreamStart) Decode(r *strandbuf.Reader) error {
+	for i := 0; i < 16; i++ {
+		b, err := r.ReadUint8()
+		if err != nil {
+			return err
+		}
+		m.RequestID[i] = b
+	}
+	return nil
+}
+
+// NodeHealth is the optional payload of OpHeartbeat, with which a node
+// piggybacks its current load on liveness probes so routers can prefer the
+// least-loaded candidate. An empty heartbeat payload carries no health.
+type NodeHealth struct {
+	Load          float32 // Utilisation, 0 (idle) to 1 (saturated)
+	ActiveStreams uint32  // Token streams currently being generated
+	QueueDepth    uint32  // Requests waiting for a free slot
+}
+
+func (m *NodeHealth) Encode(buf *strandbuf.Buffer) {
+	buf.WriteFloat32(m.Load)
+	buf.WriteUint32(m.ActiveStreams)
+	buf.WriteUint32(m.QueueDepth)
+}
+
+func (m *NodeHealth) Decode(r *strandbuf.Reader) error {
+	var err error
+	if m.Load, err = r.ReadFloat32(); err != nil {
+		return err
+	}
+	if m.ActiveStreams, err = r.ReadUint32(); err != nil {
+		return err
+	}
+	m.QueueDepth, err = r.ReadUint32()
+	return err
+}
+
+// ParseHeartbeat decodes an OpHeartbeat payload. It returns nil, nil for an
+// empty payload, the form sent by peers that report no health.
+func ParseHeartbeat(payload []byte) (*NodeHealth, error) {
+	if len(payload) == 0 {
+		return nil, nil
+	}
+	h := &NodeHealth{}
+	if err := h.Decode(strandbuf.NewReader(payload)); err != nil {
+		return nil, fmt.Errorf("strandapi: decode heartbeat health: %w", err)
+	}
+	return h, nil
+}
+
+// HelloVersion is the handshake version sent in Hello.Version.
+const HelloVersion uint8 = 1
+
+// DefaultMaxMessageSize is the largest payload a peer accepts when it has not
+// been configured with a smaller limit. It matches the framing layer's cap.
+const DefaultMaxMessageSize uint32 = MaxPayloadSize
+
+// Hello opens a session. The client proposes the largest payload it is
+// willing to send or receive; the server replies with a Hello carrying the
+// negotiated limit, the smaller of both sides' maxima. A MaxMessageSize of 0
+// means "no preference" and defers to the peer.
+//
+// Codecs lists the compression codecs the sender can decompress, in order of
+// preference. It is a trailing extension: peers that predate it send none,
+// which means raw frames only.
+//
+// Wire layout (StrandBuf):
+//
+//	[uint8]  Version
+//	[uint32] MaxMessageSize (bytes)
+//	[list]   Codecs ([uint8] each), optional
+type Hello struct {
+	Version        uint8
+	MaxMessageSize uint32
+	Codecs         []Codec
+}
+
+func (m *Hello) Encode(buf *strandbuf.Buffer) {
+	buf.WriteUint8(m.Version)
+	buf.WriteUint32(m.MaxMessageSize)
+	if len(m.Codecs) == 0 {
+		return
+	}
+	buf.WriteList(uint32(len(m.Codecs)))
+	for _, c := range m.Codecs {
+		buf.WriteUint8(uint8(c))
+	}
+}
+
+func (m *Hello) Decode(r *strandbuf.Reader) error {
+	var err error
+	m.Version, err = r.ReadUint8()
+	if err != nil {
+		return err
+	}
+	m.MaxMessageSize, err = r.ReadUint32()
+	if err != nil {
+		return err
+	}
+	m.Codecs = nil
+	if r.Remaining() == 0 {
+		return nil
+	}
+	count, err := r.ReadList()
+	if err != nil {
+		return err
+	}
+	if count > maxHelloCodecs {
+		return fmt.Errorf("strandapi: hello codec count %d exceeds max %d", count, maxHelloCodecs)
+	}
+	m.Codecs = make([]Codec, count)
+	for i := range m.Codecs {
+		c, err := r.ReadUint8()
+		if err != nil {
+			return err
+		}
+		m.Codecs[i] = Codec(c)
+	}
+	return nil
+}
+
+// NegotiateMaxMessageSize returns the limit both peers can honour given their
+// proposals, treating 0 as "no preference".
+func NegotiateMaxMessageSize(a, b uint32) uint32 {
+	switch {
+	case a == 0:
+		return b
+	case b == 0 || a < b:
+		return a
+	default:
+		return b
+	}
+}
+
+// maxCapabilitySADs caps the number of descriptors a CapabilitiesResponse may
+// carry.
+const maxCapabilitySADs = 1024
+
+// CapabilitiesResponse answers an OpCapabilitiesQuery (whose payload is
+// empty) with the Semantic Address Descriptors of the models the node
+// serves, so a client can discover them without asking the control plane.
+// Each entry is a SAD in its binary encoding; decode it with sad.SAD.Decode.
+//
+// Wire layout (StrandBuf):
+//
+//	[list] SADs ([bytes] each)
+type CapabilitiesResponse struct {
+	SADs [][]byte
+}
+
+func (m *CapabilitiesResponse) Encode(buf *strandbuf.Buffer) {
+	buf.WriteList(uint32(len(m.SADs)))
+	for _, s := range m.SADs {
+		buf.WriteBytes(s)
+	}
+}
+
+func (m *CapabilitiesResponse) Decode(r *strandbuf.Reader) error {
+	count, err := r.ReadList()
+	if err != nil {
+		return err
+	}
+	if count > maxCapabilitySADs {
+		return fmt.Errorf("strandapi: capabilities SAD count %d exceeds max %d", count, maxCapabilitySADs)
+	}
+	m.SADs = make([][]byte, count)
+	for i := range m.SADs {
+		s, err := r.ReadBytes()
+		if err != nil {
+			return err
+		}
+		m.SADs[i] = append([]byte(nil), s...)
+	}
+	return nil
+}
@@ -30,29 +30,42 @@ const (
 	OpHealthCheck  byte = 0x10 // HEALTH_CHECK    — lightweight node probe
 	OpHealthStatus byte = 0x11 // HEALTH_STATUS   — health probe response
 	OpCancel       byte = 0x12 // CANCEL          — cancel an in-flight request
+	OpHello        byte = 0x13 // HELLO           — session handshake, negotiates limits
+
+	OpTokenStreamBatch     byte = 0x14 // TOKEN_STREAM_BATCH    — several TokenStreamChunks in one frame
+	OpCompressed           byte = 0x15 // COMPRESSED            — another frame with a compressed payload
+	OpPartialResponse      byte = 0x16 // PARTIAL_RESPONSE      — replaceable snapshot of a streaming response
+	OpCapabilitiesQuery    byte = 0x17 // CAPABILITIES_QUERY    — ask a node which models it serves
+	OpCapabilitiesResponse byte = 0x18 // CAPABILITIES_RESPONSE — models a node serves
 
 	OpError byte = 0xFF
 )
 
 // OpcodeNames maps opcodes to human-readable names for logging and diagnostics.
 var OpcodeNames = map[byte]string{
-	OpInferenceRequest:  "INFERENCE_REQUEST",
-	OpInferenceResponse: "INFERENCE_RESPONSE",
-	OpTokenStreamStart:  "TOKEN_STREAM_START",
-	OpTokenStreamChunk:  "TOKEN_STREAM_CHUNK",
-	OpTokenStreamEnd:    "TOKEN_STREAM_END",
-	OpTensorTransfer:    "TENSOR_TRANSFER",
-	OpAgentNegotiation:  "AGENT_NEGOTIATION",
-	OpHeartbeat:         "HEARTBEAT",
-	OpAgentNegotiate:    "AGENT_NEGOTIATE",
-	OpAgentDelegate:     "AGENT_DELEGATE",
-	OpAgentResult:       "AGENT_RESULT",
-	OpContextShare:      "CONTEXT_SHARE",
-	OpContextAck:        "CONTEXT_ACK",
-	OpToolInvoke:        "TOOL_INVOKE",
-	OpToolResult:        "TOOL_RESULT",
-	OpHealthCheck:       "HEALTH_CHECK",
-	OpHealthStatus:      "HEALTH_STATUS",
-	OpCancel:            "CANCEL",
-	OpError:             "ERROR",
+	OpInferenceRequest:     "INFERENCE_REQUEST",
+	OpInferenceResponse:    "INFERENCE_RESPONSE",
+	OpTokenStreamStart:     "TOKEN_STREAM_START",
+	OpTokenStreamChunk:     "TOKEN_STREAM_CHUNK",
+	OpTokenStreamEnd:       "TOKEN_STREAM_END",
+	OpTensorTransfer:       "TENSOR_TRANSFER",
+	OpAgentNegotiation:     "AGENT_NEGOTIATION",
+	OpHeartbeat:            "HEARTBEAT",
+	OpAgentNegotiate:       "AGENT_NEGOTIATE",
+	OpAgentDelegate:        "AGENT_DELEGATE",
+	OpAgentResult:          "AGENT_RESULT",
+	OpContextShare:         "CONTEXT_SHARE",
+	OpContextAck:           "CONTEXT_ACK",
+	OpToolInvoke:           "TOOL_INVOKE",
+	OpToolResult:           "TOOL_RESULT",
+	OpHealthCheck:          "HEALTH_CHECK",
+	OpHealthStatus:         "HEALTH_STATUS",
+	OpCancel:               "CANCEL",
+	OpHello:                "HELLO",
+	OpTokenStreamBatch:     "TOKEN_STREAM_BATCH",
+	OpCompressed:           "COMPRESSED",
+	OpPartialResponse:      "PARTIAL_RESPONSE",
+	OpCapabilitiesQuery:    "CAPABILITIES_QUERY",
+	OpCapabilitiesResponse: "CAPABILITIES_RESPONSE",
+	OpError:                "ERROR",
 }
@@ -0,0 +1,174 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// toolSchema is the subset of JSON Schema understood by ValidateToolArgs:
+// type, properties, required, additionalProperties (boolean form), items and
+// enum. Unknown keywords are ignored.
+type toolSchema struct {
+	Type                 string                 `json:"type"`
+	Properties           map[string]*toolSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Items                *toolSchema            `json:"items"`
+	Enum                 []json.RawMessage      `json:"enum"`
+}
+
+// ValidateToolArgs checks invoke.Arguments against a JSON schema so tool
+// handlers can reject malformed arguments before executing. If schema is nil
+// the schema carried in invoke.ArgsSchema is used; if neither is present only
+// well-formedness of the JSON is checked.
+func ValidateToolArgs(invoke *ToolInvoke, schema []byte) error {
+	if schema == nil {
+		schema = invoke.ArgsSchema
+	}
+
+	var args interface{}
+	dec := json.NewDecoder(bytes.NewReader(invoke.Arguments))
+	dec.UseNumber()
+	if err := dec.Decode(&args); err != nil {
+		return fmt.Errorf("strandapi: tool %q: arguments are not valid JSON: %w", invoke.ToolName, err)
+	}
+	if dec.More() {
+		return fmt.Errorf("strandapi: tool %q: trailing data after arguments", invoke.ToolName)
+	}
+
+	if len(schema) == 0 {
+		return nil
+	}
+	var s toolSchema
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return fmt.Errorf("strandapi: tool %q: invalid argument schema: %w", invoke.ToolName, err)
+	}
+	if err := s.validate("$", args); err != nil {
+		return fmt.Errorf("strandapi: tool %q: %w", invoke.ToolName, err)
+	}
+	return nil
+}
+
+// validate checks v against s. path identifies v in error messages.
+func (s *toolSchema) validate(path string, v interface{}) error {
+	if len(s.Enum) > 0 {
+		if err := s.checkEnum(path, v); err != nil {
+			return err
+		}
+	}
+
+	switch s.Type {
+	case "":
+		// No type constraint.
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return typeError(path, s.Type, v)
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+		for name, val := range obj {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%s: unexpected property %q", path, name)
+				}
+				continue
+			}
+			if err := prop.validate(path+"."+name, val); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return typeError(path, s.Type, v)
+		}
+		if s.Items != nil {
+			for i, item := range arr {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return typeError(path, s.Type, v)
+		}
+	case "number":
+		if _, ok := v.(json.Number); !ok {
+			return typeError(path, s.Type, v)
+		}
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return typeError(path, s.Type, v)
+		}
+		f, err := n.Float64()
+		if err != nil || f != math.Trunc(f) {
+			return fmt.Errorf("%s: expected integer, got %s", path, n)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return typeError(path, s.Type, v)
+		}
+	case "null":
+		if v != nil {
+			return typeError(path, s.Type, v)
+		}
+	default:
+		return fmt.Errorf("%s: unsupported schema type %q", path, s.Type)
+	}
+	return nil
+}
+
+// checkEnum reports an error unless v equals one of the enum values.
+func (s *toolSchema) checkEnum(path string, v interface{}) error {
+	got, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, raw := range s.Enum {
+		var want interface{}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&want); err != nil {
+			continue
+		}
+		w, _ := json.Marshal(want)
+		if bytes.Equal(got, w) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: value %s is not one of the allowed values", path, got)
+}
+
+// typeError describes a JSON type mismatch.
+func typeError(path, want string, v interface{}) error {
+	return fmt.Errorf("%s: expected %s, got %s", path, want, jsonTypeName(v))
+}
+
+// jsonTypeName returns the JSON type name of a decoded value.
+func jsonTypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,110 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/strand-protocol/strand/strandapi/pkg/strandbuf"
+)
+
+const calculatorSchema = `{
+	"type": "object",
+	"properties": {
+		"expression": {"type": "string"},
+		"precision":  {"type": "integer"},
+		"mode":       {"type": "string", "enum": ["exact", "approx"]},
+		"operands":   {"type": "array", "items": {"type": "number"}}
+	},
+	"required": ["expression"],
+	"additionalProperties": false
+}`
+
+func TestValidateToolArgsAccepts(t *testing.T) {
+	good := []string{
+		`{"expression": "42 * 137"}`,
+		`{"expression": "1 + 1", "precision": 2, "mode": "exact"}`,
+		`{"expression": "sum", "operands": [1, 2.5, -3]}`,
+	}
+	for _, args := range good {
+		invoke := &ToolInvoke{ToolName: "calculator", Arguments: []byte(args)}
+		if err := ValidateToolArgs(invoke, []byte(calculatorSchema)); err != nil {
+			t.Errorf("ValidateToolArgs(%s): unexpected error: %v", args, err)
+		}
+	}
+}
+
+func TestValidateToolArgsRejects(t *testing.T) {
+	bad := map[string]string{
+		"not json":         `{"expression": `,
+		"trailing data":    `{"expression": "1"} {}`,
+		"not an object":    `["42 * 137"]`,
+		"missing required": `{"precision": 2}`,
+		"wrong type":       `{"expression": 42}`,
+		"non-integer":      `{"expression": "1", "precision": 1.5}`,
+		"enum mismatch":    `{"expression": "1", "mode": "fuzzy"}`,
+		"bad array item":   `{"expression": "1", "operands": [1, "two"]}`,
+		"unexpected field": `{"expression": "1", "shell": "rm -rf /"}`,
+		"null for string":  `{"expression": null}`,
+	}
+	for name, args := range bad {
+		invoke := &ToolInvoke{ToolName: "calculator", Arguments: []byte(args)}
+		if err := ValidateToolArgs(invoke, []byte(calculatorSchema)); err == nil {
+			t.Errorf("%s: ValidateToolArgs(%s) = nil, want error", name, args)
+		}
+	}
+}
+
+func TestValidateToolArgsUsesEmbeddedSchema(t *testing.T) {
+	invoke := &ToolInvoke{
+		ToolName:   "calculator",
+		Arguments:  []byte(`{"precision": 2}`),
+		ArgsSchema: []byte(calculatorSchema),
+	}
+	if err := ValidateToolArgs(invoke, nil); err == nil {
+		t.Error("expected embedded schema to reject missing required property")
+	}
+
+	// Without any schema only JSON well-formedness is checked.
+	invoke.ArgsSchema = nil
+	if err := ValidateToolArgs(invoke, nil); err != nil {
+		t.Errorf("schema-less validation: unexpected error: %v", err)
+	}
+}
+
+func TestValidateToolArgsInvalidSchema(t *testing.T) {
+	invoke := &ToolInvoke{ToolName: "calculator", Arguments: []byte(`{}`)}
+	if err := ValidateToolArgs(invoke, []byte(`{"type": `)); err == nil {
+		t.Error("expected error for malformed schema")
+	}
+}
+
+func TestToolInvokeArgsSchemaRoundTrip(t *testing.T) {
+	orig := &ToolInvoke{
+		RequestID:  [16]byte{7},
+		ToolName:   "calculator",
+		Arguments:  []byte(`{"expression": "2 * 2"}`),
+		ArgsSchema: []byte(calculatorSchema),
+	}
+	buf := strandbuf.NewBuffer(256)
+	orig.Encode(buf)
+
+	decoded := &ToolInvoke{}
+	if err := decoded.Decode(strandbuf.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !bytes.Equal(decoded.ArgsSchema, orig.ArgsSchema) {
+		t.Errorf("ArgsSchema mismatch after round-trip")
+	}
+
+	// An invoke without a schema decodes with a nil ArgsSchema.
+	plain := &ToolInvoke{ToolName: "calculator", Arguments: []byte(`{}`)}
+	buf = strandbuf.NewBuffer(64)
+	plain.Encode(buf)
+	decoded = &ToolInvoke{}
+	if err := decoded.Decode(strandbuf.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Decode plain: %v", err)
+	}
+	if decoded.ArgsSchema != nil {
+		t.Errorf("ArgsSchema = %q, want nil", decoded.ArgsSchema)
+	}
+}
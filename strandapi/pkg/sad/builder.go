@@ -2,13 +2,21 @@ package sad
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/strand-protocol/strand/strandapi/pkg/strandbuf"
 )
 
+// maxLatencySLA is the largest latency target, in milliseconds, a SAD may
+// request (one hour).
+const maxLatencySLA = 60 * 60 * 1000
+
 // Builder provides a fluent interface for constructing SAD descriptors.
+// Setters validate their arguments and record any problem; Build and BuildSAD
+// report every recorded problem at once.
 type Builder struct {
-	sad SAD
+	sad  SAD
+	errs []error
 }
 
 // NewSADBuilder returns a new Builder with sensible defaults.
@@ -20,52 +28,93 @@ func NewSADBuilder() *Builder {
 	}
 }
 
+// errorf records a validation problem.
+func (b *Builder) errorf(format string, args ...any) {
+	b.errs = append(b.errs, fmt.Errorf("sad: "+format, args...))
+}
+
 // ModelType sets the model type string (e.g. "llm", "embedding").
 func (b *Builder) ModelType(t string) *Builder {
 	b.sad.ModelType = t
 	return b
 }
 
-// Capabilities sets the capability bitmask directly.
+// Capabilities sets the capability bitmask directly. Bits outside the
+// defined capability flags are reported as an error.
 func (b *Builder) Capabilities(c uint32) *Builder {
+	if unknown := c &^ knownCapabilities; unknown != 0 {
+		b.errorf("unknown capability bits 0x%x", unknown)
+	}
 	b.sad.Capabilities = c
 	return b
 }
 
-// WithCapability sets one or more capability flags using bitwise OR.
+// WithCapability sets one or more capability flags using bitwise OR. Bits
+// outside the defined capability flags are reported as an error.
 func (b *Builder) WithCapability(flags uint32) *Builder {
+	if unknown := flags &^ knownCapabilities; unknown != 0 {
+		b.errorf("unknown capability bits 0x%x", unknown)
+	}
 	b.sad.Capabilities |= flags
 	return b
 }
 
 // ContextWindow sets the required minimum context window size (in tokens).
+// It must be positive.
 func (b *Builder) ContextWindow(tokens uint32) *Builder {
+	if tokens == 0 {
+		b.errorf("context window must be positive")
+	}
 	b.sad.ContextWindow = tokens
 	return b
 }
 
-// LatencySLA sets the target latency in milliseconds.
+// LatencySLA sets the target latency in milliseconds. It must be between 1
+// and 3600000 (one hour).
 func (b *Builder) LatencySLA(ms uint32) *Builder {
+	if ms == 0 || ms > maxLatencySLA {
+		b.errorf("latency SLA %dms out of range (1-%d)", ms, maxLatencySLA)
+	}
 	b.sad.LatencySLA = ms
 	return b
 }
 
+// Err returns every problem recorded so far, joined with errors.Join, or nil.
+func (b *Builder) Err() error {
+	errs := b.errs
+	if b.sad.ModelType == "" {
+		errs = append(errs[:len(errs):len(errs)], errors.New("sad: model type is required"))
+	}
+	return errors.Join(errs...)
+}
+
 // Build encodes the SAD into its binary wire representation and returns the
-// bytes. Returns an error if the descriptor is incomplete.
+// bytes. Returns an error listing every problem if the descriptor is
+// incomplete or a setter was given an invalid value.
 func (b *Builder) Build() ([]byte, error) {
-	if b.sad.ModelType == "" {
-		return nil, errors.New("sad: model type is required")
+	if err := b.Err(); err != nil {
+		return nil, err
 	}
 	buf := strandbuf.NewBuffer(64)
 	b.sad.Encode(buf)
 	return buf.Bytes(), nil
 }
 
+// MustBuild is like Build but panics on error. It is intended for tests and
+// examples with fixed, known-good descriptors.
+func (b *Builder) MustBuild() []byte {
+	data, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
 // BuildSAD returns the SAD struct directly (useful when you want the typed
 // value rather than the wire bytes).
 func (b *Builder) BuildSAD() (*SAD, error) {
-	if b.sad.ModelType == "" {
-		return nil, errors.New("sad: model type is required")
+	if err := b.Err(); err != nil {
+		return nil, err
 	}
 	s := b.sad // copy
 	return &s, nil
@@ -0,0 +1,72 @@
+package sad
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/strand-protocol/strand/strandapi/pkg/strandbuf"
+)
+
+func TestBuilderValid(t *testing.T) {
+	data, err := NewSADBuilder().
+		ModelType("llm").
+		WithCapability(TextGen | ToolUse).
+		ContextWindow(128000).
+		LatencySLA(250).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	var got SAD
+	if err := got.Decode(strandbuf.NewReader(data)); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	want := SAD{ModelType: "llm", Capabilities: TextGen | ToolUse, ContextWindow: 128000, LatencySLA: 250, Version: 1}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestBuilderReportsAllErrors(t *testing.T) {
+	b := NewSADBuilder().
+		WithCapability(TextGen | 1<<20).
+		ContextWindow(0).
+		LatencySLA(0)
+
+	_, err := b.Build()
+	if err == nil {
+		t.Fatal("Build: expected error")
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		"unknown capability bits 0x100000",
+		"context window must be positive",
+		"latency SLA 0ms out of range",
+		"model type is required",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error %q does not mention %q", msg, want)
+		}
+	}
+	if _, err := b.BuildSAD(); err == nil {
+		t.Error("BuildSAD: expected error")
+	}
+
+	// Fixing the model type leaves the setter errors in place.
+	if _, err := b.ModelType("llm").Build(); err == nil || strings.Contains(err.Error(), "model type") {
+		t.Errorf("after ModelType: err = %v, want setter errors only", err)
+	}
+}
+
+func TestBuilderMustBuild(t *testing.T) {
+	if data := NewSADBuilder().ModelType("embedding").MustBuild(); len(data) == 0 {
+		t.Fatal("MustBuild returned no bytes")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("MustBuild did not panic on an invalid descriptor")
+		}
+	}()
+	NewSADBuilder().LatencySLA(maxLatencySLA + 1).MustBuild()
+}
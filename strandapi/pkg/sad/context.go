@@ -0,0 +1,50 @@
+package sad
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Context window suffixes accepted by ParseContextSuffix. They are decimal,
+// matching how model context sizes are advertised (128k = 128000 tokens).
+const (
+	contextKilo = 1000
+	contextMega = 1000 * 1000
+)
+
+// ParseContextSuffix parses a context window size such as "128k", "1m" or
+// "4096" into a token count. The k and m suffixes (either case) multiply by
+// one thousand and one million respectively.
+func ParseContextSuffix(s string) (uint32, error) {
+	digits, mult := s, uint64(1)
+	switch {
+	case strings.HasSuffix(s, "k"), strings.HasSuffix(s, "K"):
+		digits, mult = s[:len(s)-1], contextKilo
+	case strings.HasSuffix(s, "m"), strings.HasSuffix(s, "M"):
+		digits, mult = s[:len(s)-1], contextMega
+	}
+	n, err := strconv.ParseUint(digits, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("sad: invalid context window %q: want a number with an optional k or m suffix", s)
+	}
+	if n*mult > math.MaxUint32 {
+		return 0, fmt.Errorf("sad: context window %q overflows uint32", s)
+	}
+	return uint32(n * mult), nil
+}
+
+// FormatContext renders a token count in the shortest form ParseContextSuffix
+// accepts: 1000000 becomes "1m", 128000 becomes "128k" and 4096 stays "4096".
+func FormatContext(n uint32) string {
+	switch {
+	case n == 0:
+		return "0"
+	case n%contextMega == 0:
+		return strconv.FormatUint(uint64(n/contextMega), 10) + "m"
+	case n%contextKilo == 0:
+		return strconv.FormatUint(uint64(n/contextKilo), 10) + "k"
+	}
+	return strconv.FormatUint(uint64(n), 10)
+}
@@ -0,0 +1,70 @@
+package sad
+
+import "testing"
+
+func TestContextSuffixRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want uint32
+	}{
+		{"128k", 128000},
+		{"8k", 8000},
+		{"1m", 1000000},
+		{"4096", 4096},
+	} {
+		got, err := ParseContextSuffix(tc.in)
+		if err != nil {
+			t.Fatalf("ParseContextSuffix(%q): %v", tc.in, err)
+		}
+		if got != tc.want {
+			t.Errorf("ParseContextSuffix(%q) = %d, want %d", tc.in, got, tc.want)
+		}
+		if s := FormatContext(got); s != tc.in {
+			t.Errorf("FormatContext(%d) = %q, want %q", got, s, tc.in)
+		}
+	}
+}
+
+func TestContextSuffixUpperCase(t *testing.T) {
+	if got, err := ParseContextSuffix("32K"); err != nil || got != 32000 {
+		t.Errorf("ParseContextSuffix(\"32K\") = %d, %v; want 32000", got, err)
+	}
+}
+
+func TestContextSuffixRejectsInvalid(t *testing.T) {
+	for _, in := range []string{"12x", "", "k", "-8k", "1.5k", "5000m"} {
+		if _, err := ParseContextSuffix(in); err == nil {
+			t.Errorf("ParseContextSuffix(%q): expected error", in)
+		}
+	}
+}
+
+func TestDescriptorRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want SAD
+	}{
+		{"sad:llm:128k", SAD{ModelType: "llm", ContextWindow: 128000, Version: 1}},
+		{"sad:embedding:8k", SAD{ModelType: "embedding", ContextWindow: 8000, Version: 1}},
+		{"sad:llm:1m:200ms", SAD{ModelType: "llm", ContextWindow: 1000000, LatencySLA: 200, Version: 1}},
+	} {
+		got, err := ParseDescriptor(tc.in)
+		if err != nil {
+			t.Fatalf("ParseDescriptor(%q): %v", tc.in, err)
+		}
+		if *got != tc.want {
+			t.Errorf("ParseDescriptor(%q) = %+v, want %+v", tc.in, *got, tc.want)
+		}
+		if s := got.String(); s != tc.in {
+			t.Errorf("String() = %q, want %q", s, tc.in)
+		}
+	}
+}
+
+func TestDescriptorRejectsInvalid(t *testing.T) {
+	for _, in := range []string{"", "sad", "sad:llm", "sad::8k", "nex:llm:8k", "sad:llm:12x", "sad:llm:8k:fast", "sad:llm:8k:1ms:x"} {
+		if _, err := ParseDescriptor(in); err == nil {
+			t.Errorf("ParseDescriptor(%q): expected error", in)
+		}
+	}
+}
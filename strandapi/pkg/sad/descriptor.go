@@ -0,0 +1,46 @@
+package sad
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// descriptorPrefix starts the string form of a SAD.
+const descriptorPrefix = "sad"
+
+// String renders s in the descriptor form read by ParseDescriptor:
+// "sad:<model_type>:<context_window>[:<latency_sla>ms]", for example
+// "sad:llm:128k" or "sad:llm:1m:200ms". The context window uses
+// FormatContext and the latency SLA is omitted when zero. Capabilities and
+// the format version are not part of the string form.
+func (s *SAD) String() string {
+	parts := []string{descriptorPrefix, s.ModelType, FormatContext(s.ContextWindow)}
+	if s.LatencySLA != 0 {
+		parts = append(parts, strconv.FormatUint(uint64(s.LatencySLA), 10)+"ms")
+	}
+	return strings.Join(parts, ":")
+}
+
+// ParseDescriptor parses the string form written by SAD.String. The context
+// window accepts the suffixes of ParseContextSuffix and the latency SLA may
+// omit its "ms" unit. The returned SAD has Version 1 and no capabilities.
+func ParseDescriptor(s string) (*SAD, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 3 || len(parts) > 4 || parts[0] != descriptorPrefix || parts[1] == "" {
+		return nil, fmt.Errorf("sad: invalid descriptor %q: want sad:<model_type>:<context_window>[:<latency_sla>ms]", s)
+	}
+	tokens, err := ParseContextSuffix(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	out := &SAD{ModelType: parts[1], ContextWindow: tokens, Version: 1}
+	if len(parts) == 4 {
+		ms, err := strconv.ParseUint(strings.TrimSuffix(parts[3], "ms"), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("sad: invalid latency SLA %q in descriptor %q", parts[3], s)
+		}
+		out.LatencySLA = uint32(ms)
+	}
+	return out, nil
+}
@@ -0,0 +1,65 @@
+package sad
+
+import (
+	"fmt"
+	"strings"
+)
+
+// capabilityNames lists the snake_case name of each capability flag, the form
+// used in agent negotiation and in HTTP query parameters.
+var capabilityNames = []struct {
+	flag uint32
+	name string
+}{
+	{TextGen, "text_gen"},
+	{CodeGen, "code_gen"},
+	{Embedding, "embedding"},
+	{ImageGen, "image_gen"},
+	{AudioGen, "audio_gen"},
+	{ToolUse, "tool_use"},
+	{Vision, "vision"},
+}
+
+// ParseCapability returns the flag named by name, such as "code_gen" for
+// CodeGen. Names are case-insensitive.
+func ParseCapability(name string) (uint32, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, c := range capabilityNames {
+		if c.name == name {
+			return c.flag, nil
+		}
+	}
+	return 0, fmt.Errorf("sad: unknown capability %q", name)
+}
+
+// CapabilityNames returns the names of the flags set in caps, in bit order.
+// Undefined bits are omitted.
+func CapabilityNames(caps uint32) []string {
+	var names []string
+	for _, c := range capabilityNames {
+		if caps&c.flag != 0 {
+			names = append(names, c.name)
+		}
+	}
+	return names
+}
+
+// Matches reports whether the SAD s satisfies query: s has every capability
+// set in query, the same ModelType, a ContextWindow at least as large, and a
+// LatencySLA no slower. Zero-valued query fields match anything; a query
+// LatencySLA is not met by an s that promises none.
+func Matches(s, query *SAD) bool {
+	if s.Capabilities&query.Capabilities != query.Capabilities {
+		return false
+	}
+	if query.ModelType != "" && s.ModelType != query.ModelType {
+		return false
+	}
+	if s.ContextWindow < query.ContextWindow {
+		return false
+	}
+	if query.LatencySLA != 0 && (s.LatencySLA == 0 || s.LatencySLA > query.LatencySLA) {
+		return false
+	}
+	return true
+}
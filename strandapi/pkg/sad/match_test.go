@@ -0,0 +1,56 @@
+package sad
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCapability(t *testing.T) {
+	for _, c := range capabilityNames {
+		got, err := ParseCapability(c.name)
+		if err != nil || got != c.flag {
+			t.Errorf("ParseCapability(%q) = %#x, %v; want %#x", c.name, got, err, c.flag)
+		}
+	}
+	if got, err := ParseCapability(" Code_Gen "); err != nil || got != CodeGen {
+		t.Errorf("ParseCapability is not case-insensitive: %#x, %v", got, err)
+	}
+	if _, err := ParseCapability("telepathy"); err == nil {
+		t.Error("ParseCapability(\"telepathy\") succeeded")
+	}
+}
+
+func TestCapabilityNames(t *testing.T) {
+	got := CapabilityNames(Vision | TextGen | 1<<31)
+	if want := []string{"text_gen", "vision"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("CapabilityNames = %v, want %v", got, want)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	model := &SAD{ModelType: "llm", Capabilities: TextGen | CodeGen, ContextWindow: 32768, LatencySLA: 200}
+	tests := []struct {
+		name  string
+		query SAD
+		want  bool
+	}{
+		{"empty query", SAD{}, true},
+		{"subset of capabilities", SAD{Capabilities: CodeGen}, true},
+		{"all capabilities", SAD{Capabilities: TextGen | CodeGen}, true},
+		{"missing capability", SAD{Capabilities: CodeGen | Vision}, false},
+		{"model type", SAD{ModelType: "llm"}, true},
+		{"other model type", SAD{ModelType: "embedding"}, false},
+		{"context fits", SAD{ContextWindow: 32768}, true},
+		{"context too large", SAD{ContextWindow: 65536}, false},
+		{"latency met", SAD{LatencySLA: 500}, true},
+		{"latency missed", SAD{LatencySLA: 100}, false},
+	}
+	for _, tc := range tests {
+		if got := Matches(model, &tc.query); got != tc.want {
+			t.Errorf("%s: Matches = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+	if Matches(&SAD{Capabilities: TextGen}, &SAD{LatencySLA: 100}) {
+		t.Error("a SAD without a latency SLA matched a latency requirement")
+	}
+}
@@ -5,6 +5,8 @@
 package sad
 
 import (
+	"hash/fnv"
+
 	"github.com/strand-protocol/strand/strandapi/pkg/strandbuf"
 )
 
@@ -19,6 +21,9 @@ const (
 	Vision   uint32 = 1 << 6 // Image/visual understanding
 )
 
+// knownCapabilities is the union of all defined capability flags.
+const knownCapabilities = TextGen | CodeGen | Embedding | ImageGen | AudioGen | ToolUse | Vision
+
 // SAD is the in-memory representation of a Semantic Address Descriptor.
 type SAD struct {
 	ModelType     string // e.g. "llm", "embedding", "diffusion"
@@ -64,3 +69,28 @@ func (s *SAD) Decode(r *strandbuf.Reader) error {
 	}
 	return nil
 }
+
+// Equal reports whether s and other describe the same model: every field
+// matches. Two nil SADs are equal; a nil and a non-nil one are not.
+func (s *SAD) Equal(other *SAD) bool {
+	if s == nil || other == nil {
+		return s == other
+	}
+	return *s == *other
+}
+
+// Hash returns a 64-bit FNV-1a hash of the SAD's binary encoding. Equal SADs
+// hash identically, across processes and releases that keep the encoding,
+// so the hash can key caches and deduplicate descriptors. A nil SAD hashes
+// as the zero SAD.
+func (s *SAD) Hash() uint64 {
+	var v SAD
+	if s != nil {
+		v = *s
+	}
+	buf := strandbuf.NewBuffer(32)
+	v.Encode(buf)
+	h := fnv.New64a()
+	h.Write(buf.Bytes())
+	return h.Sum64()
+}
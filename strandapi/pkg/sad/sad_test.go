@@ -0,0 +1,42 @@
+package sad
+
+import "testing"
+
+func TestEqualAndHash(t *testing.T) {
+	a := &SAD{ModelType: "llm", Capabilities: TextGen | CodeGen, ContextWindow: 32768, LatencySLA: 200, Version: 1}
+	b := *a
+
+	if !a.Equal(&b) || a.Hash() != b.Hash() {
+		t.Errorf("identical SADs: Equal = %v, hashes %x and %x", a.Equal(&b), a.Hash(), b.Hash())
+	}
+
+	variants := map[string]SAD{
+		"capability bit": {ModelType: "llm", Capabilities: TextGen, ContextWindow: 32768, LatencySLA: 200, Version: 1},
+		"context window": {ModelType: "llm", Capabilities: TextGen | CodeGen, ContextWindow: 32769, LatencySLA: 200, Version: 1},
+		"latency":        {ModelType: "llm", Capabilities: TextGen | CodeGen, ContextWindow: 32768, LatencySLA: 100, Version: 1},
+		"model type":     {ModelType: "llm2", Capabilities: TextGen | CodeGen, ContextWindow: 32768, LatencySLA: 200, Version: 1},
+		"version":        {ModelType: "llm", Capabilities: TextGen | CodeGen, ContextWindow: 32768, LatencySLA: 200, Version: 2},
+	}
+	for name, v := range variants {
+		if a.Equal(&v) {
+			t.Errorf("%s: Equal reported a differing SAD equal", name)
+		}
+		if a.Hash() == v.Hash() {
+			t.Errorf("%s: differing SADs share hash %x", name, a.Hash())
+		}
+	}
+
+	var nilSAD *SAD
+	if !nilSAD.Equal(nil) || nilSAD.Equal(a) || a.Equal(nil) {
+		t.Error("Equal mishandles nil SADs")
+	}
+	if nilSAD.Hash() != (&SAD{}).Hash() {
+		t.Error("a nil SAD does not hash as the zero SAD")
+	}
+
+	// SADs work as map keys through their hash.
+	seen := map[uint64]*SAD{a.Hash(): a}
+	if got := seen[b.Hash()]; !got.Equal(&b) {
+		t.Errorf("lookup by hash found %+v", got)
+	}
+}
@@ -0,0 +1,128 @@
+package sad
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// Default resolution weights from the spec. Cost and trust are not modeled
+// in the SAD yet and always score 1.0.
+const (
+	weightCapability    = 0.30
+	weightLatency       = 0.25
+	weightCost          = 0.20
+	weightContextWindow = 0.15
+	weightTrust         = 0.10
+)
+
+// weightHealth bounds the penalty Rank applies for reported load. It is
+// kept small so health only orders candidates whose descriptors score about
+// the same and never outweighs a missing capability.
+const weightHealth = 0.02
+
+// queueDepthHalf is the queue depth that earns half of the queue penalty.
+const queueDepthHalf = 8
+
+// Score returns how well the SAD s serves query, between 0 and 1, using the
+// spec's weighted multi-constraint scoring: the fraction of requested
+// capabilities s has, how well its LatencySLA meets the requested one, and
+// how far its ContextWindow exceeds the requested minimum. It returns 0 if s
+// has a smaller ContextWindow than query requires, which is a hard
+// constraint.
+func Score(s, query *SAD) float64 {
+	if query.ContextWindow > 0 && s.ContextWindow < query.ContextWindow {
+		return 0
+	}
+
+	capScore := 1.0
+	if query.Capabilities != 0 {
+		matched := bits.OnesCount32(s.Capabilities & query.Capabilities)
+		capScore = float64(matched) / float64(bits.OnesCount32(query.Capabilities))
+	}
+
+	latScore := 1.0
+	if query.LatencySLA > 0 && s.LatencySLA > 0 {
+		ratio := float64(s.LatencySLA) / float64(query.LatencySLA)
+		if ratio > 1.0 {
+			latScore = math.Max(0.0, 1.0-(ratio-1.0)*0.5)
+		}
+	}
+
+	ctxScore := 1.0
+	if query.ContextWindow > 0 {
+		ratio := float64(s.ContextWindow) / float64(query.ContextWindow)
+		ctxScore = math.Min(ratio, 2.0) / 2.0
+	}
+
+	return weightCapability*capScore + weightLatency*latScore + weightCost +
+		weightContextWindow*ctxScore + weightTrust
+}
+
+// Health is the part of a node's reported health that Rank uses. Callers
+// fill it from the NodeHealth the node sent in its heartbeats.
+type Health struct {
+	Load       float32 // Utilisation, 0 (idle) to 1 (saturated)
+	QueueDepth uint32  // Requests waiting for a free slot
+}
+
+// HealthProvider reports the most recent health a node sent in its
+// heartbeats. Health returns false for a node that has not reported any.
+type HealthProvider interface {
+	Health(id string) (Health, bool)
+}
+
+// HealthFunc adapts a function to a HealthProvider.
+type HealthFunc func(id string) (Health, bool)
+
+// Health calls f(id).
+func (f HealthFunc) Health(id string) (Health, bool) { return f(id) }
+
+// Candidate is a node that may serve a request.
+type Candidate struct {
+	ID  string
+	SAD *SAD
+}
+
+// Ranked is a Candidate with its resolution score.
+type Ranked struct {
+	Candidate
+	Score float64
+}
+
+// Rank scores each candidate against query with Score and returns those
+// that satisfy the hard constraints, best first. If hp is non-nil, a node's
+// reported Load and QueueDepth lower its score slightly, so among otherwise
+// equal candidates the least loaded ranks first. Nodes with no reported
+// health are not penalised. Candidates with equal scores keep their input
+// order.
+func Rank(query *SAD, candidates []Candidate, hp HealthProvider) []Ranked {
+	var ranked []Ranked
+	for _, c := range candidates {
+		score := Score(c.SAD, query)
+		if score == 0 {
+			continue
+		}
+		if hp != nil {
+			if h, ok := hp.Health(c.ID); ok {
+				score -= weightHealth * loadPenalty(h)
+			}
+		}
+		ranked = append(ranked, Ranked{Candidate: c, Score: score})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+	return ranked
+}
+
+// loadPenalty maps reported health to [0, 1]: half from Load, clamped to
+// [0, 1], and half from QueueDepth, which approaches 1 as the queue grows.
+func loadPenalty(h Health) float64 {
+	load := math.Min(math.Max(float64(h.Load), 0), 1)
+	if math.IsNaN(load) {
+		load = 0
+	}
+	queue := float64(h.QueueDepth) / (float64(h.QueueDepth) + queueDepthHalf)
+	return 0.5*load + 0.5*queue
+}
@@ -0,0 +1,68 @@
+package sad
+
+import "testing"
+
+func TestScore(t *testing.T) {
+	query := &SAD{Capabilities: TextGen | CodeGen, ContextWindow: 8192, LatencySLA: 200}
+	full := &SAD{Capabilities: TextGen | CodeGen, ContextWindow: 16384, LatencySLA: 100}
+	half := &SAD{Capabilities: TextGen, ContextWindow: 16384, LatencySLA: 100}
+	small := &SAD{Capabilities: TextGen | CodeGen, ContextWindow: 4096, LatencySLA: 100}
+
+	if got := Score(full, query); got < 0.999 || got > 1.001 {
+		t.Errorf("Score(full) = %v, want 1", got)
+	}
+	if Score(half, query) >= Score(full, query) {
+		t.Error("a node missing a capability scored no lower than one with all of them")
+	}
+	if got := Score(small, query); got != 0 {
+		t.Errorf("Score below the minimum context window = %v, want 0", got)
+	}
+}
+
+func TestRankPrefersLeastLoaded(t *testing.T) {
+	desc := &SAD{Capabilities: TextGen | CodeGen, ContextWindow: 32000, LatencySLA: 100}
+	candidates := []Candidate{
+		{ID: "busy", SAD: desc},
+		{ID: "idle", SAD: desc},
+	}
+	health := map[string]Health{
+		"busy": {Load: 0.9, QueueDepth: 30},
+		"idle": {Load: 0.1},
+	}
+	hp := HealthFunc(func(id string) (Health, bool) {
+		h, ok := health[id]
+		return h, ok
+	})
+	query := &SAD{Capabilities: TextGen | CodeGen}
+
+	ranked := Rank(query, candidates, hp)
+	if len(ranked) != 2 {
+		t.Fatalf("Rank returned %d candidates, want 2", len(ranked))
+	}
+	if ranked[0].ID != "idle" {
+		t.Errorf("Rank put %q first, want the less-loaded \"idle\"", ranked[0].ID)
+	}
+
+	// Without health the candidates tie and keep their input order.
+	if ranked := Rank(query, candidates, nil); ranked[0].ID != "busy" {
+		t.Errorf("Rank without health put %q first, want \"busy\"", ranked[0].ID)
+	}
+}
+
+func TestRankLoadIsSoft(t *testing.T) {
+	candidates := []Candidate{
+		{ID: "partial", SAD: &SAD{Capabilities: TextGen}},
+		{ID: "loaded", SAD: &SAD{Capabilities: TextGen | CodeGen}},
+	}
+	hp := HealthFunc(func(id string) (Health, bool) {
+		if id == "loaded" {
+			return Health{Load: 1, QueueDepth: 1 << 20}, true
+		}
+		return Health{}, false
+	})
+
+	ranked := Rank(&SAD{Capabilities: TextGen | CodeGen}, candidates, hp)
+	if len(ranked) != 2 || ranked[0].ID != "loaded" {
+		t.Errorf("load outweighed a missing capability: %+v", ranked)
+	}
+}
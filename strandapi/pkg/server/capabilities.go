@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"log"
+
+	"github.com/strand-protocol/strand/strandapi/pkg/protocol"
+	"github.com/strand-protocol/strand/strandapi/pkg/sad"
+	"github.com/strand-protocol/strand/strandapi/pkg/strandbuf"
+)
+
+// WithServedSADs sets the Semantic Address Descriptors of the models this
+// node serves. The server returns them, in order, to every
+// OpCapabilitiesQuery; without this option it answers with an empty list.
+// Nil entries are skipped.
+func WithServedSADs(sads []*sad.SAD) ServerOption {
+	return func(s *Server) {
+		s.servedSADs = nil
+		for _, d := range sads {
+			if d == nil {
+				continue
+			}
+			buf := strandbuf.NewBuffer(32)
+			d.Encode(buf)
+			s.servedSADs = append(s.servedSADs, buf.Bytes())
+		}
+	}
+}
+
+// handleCapabilitiesQuery answers an OpCapabilitiesQuery with the served
+// SADs.
+func (s *Server) handleCapabilitiesQuery(ctx context.Context, w FrameWriter) {
+	resp := &protocol.CapabilitiesResponse{SADs: s.servedSADs}
+	buf := strandbuf.NewBuffer(64)
+	resp.Encode(buf)
+	if err := w.Send(ctx, protocol.OpCapabilitiesResponse, buf.Bytes()); err != nil {
+		log.Printf("strandapi server: send capabilities response error: %v", err)
+	}
+}
@@ -11,17 +11,31 @@ import (
 
 // Handler is implemented by types that handle non-streaming inference
 // requests. A handler receives a decoded InferenceRequest and returns a
-// complete InferenceResponse or an error.
+// complete InferenceResponse or an error. Usage fields and FinishReason left
+// zero are filled in by the server, counting words as tokens. As for every
+// handler, ctx carries the sender's PeerInfo; see PeerAddr and SessionID.
 type Handler interface {
 	HandleInference(ctx context.Context, req *protocol.InferenceRequest) (*protocol.InferenceResponse, error)
 }
 
 // TokenSender is provided to a StreamHandler so it can emit tokens one at a
 // time. Each call to Send transmits a single TokenStreamChunk to the client.
+// Send blocks while the stream's send queue is full (see WithTokenSendBuffer)
+// and returns the transport error once a write has failed.
 type TokenSender interface {
 	Send(chunk *protocol.TokenStreamChunk) error
 }
 
+// PartialSender is implemented by the TokenSender the server passes to a
+// StreamHandler. SendPartial transmits a PartialResponse, a snapshot of the
+// whole response so far that replaces the previous one, in order with the
+// token chunks. Handlers type-assert for it:
+//
+//	if ps, ok := sender.(server.PartialSender); ok { ... }
+type PartialSender interface {
+	SendPartial(partial *protocol.PartialResponse) error
+}
+
 // StreamHandler is implemented by types that handle streaming inference
 // requests. The handler receives the request and a TokenSender. It should
 // call sender.Send for each generated token and return nil on success.
@@ -36,3 +50,15 @@ type HandlerFunc func(ctx context.Context, req *protocol.InferenceRequest) (*pro
 func (f HandlerFunc) HandleInference(ctx context.Context, req *protocol.InferenceRequest) (*protocol.InferenceResponse, error) {
 	return f(ctx, req)
 }
+
+// FrameWriter sends frames back to the peer whose frame is being handled,
+// on the logical stream the frame arrived on, even while other peers are
+// served on the same listener. On a transport that does not report senders
+// (transport.PeerStreamTransport) it sends on the transport itself.
+type FrameWriter interface {
+	Send(ctx context.Context, opcode byte, payload []byte) error
+}
+
+// FrameHandler processes a single inbound frame with the given payload,
+// writing any replies to w. Register one per opcode with Server.Handle.
+type FrameHandler func(ctx context.Context, payload []byte, w FrameWriter)
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// OpcodeMetrics summarises the frames of one opcode the server handled.
+type OpcodeMetrics struct {
+	// Count is the number of frames dispatched to a handler.
+	Count uint64
+	// TotalLatency is the combined time their handlers ran, and MaxLatency
+	// the longest single run.
+	TotalLatency time.Duration
+	MaxLatency   time.Duration
+}
+
+// MeanLatency returns the average handler run time, or 0 if Count is 0.
+func (m OpcodeMetrics) MeanLatency() time.Duration {
+	if m.Count == 0 {
+		return 0
+	}
+	return m.TotalLatency / time.Duration(m.Count)
+}
+
+// Metrics is a snapshot of the server's frame handling counters.
+type Metrics struct {
+	// Opcodes holds an entry for every opcode that reached a handler. The
+	// frame wrapped by an OpCompressed frame is counted under its own opcode
+	// as well, and its run time is included in the OpCompressed latency.
+	Opcodes map[byte]OpcodeMetrics
+	// Unhandled counts frames with no registered handler and Rejected those
+	// refused for exceeding the maximum message size.
+	Unhandled uint64
+	Rejected  uint64
+	// Dropped counts inbound datagrams the transport rejected before they
+	// became frames (see transport.IsFrameError).
+	Dropped uint64
+}
+
+// metrics accumulates the counters reported by Server.Metrics.
+type metrics struct {
+	mu        sync.Mutex
+	opcodes   map[byte]OpcodeMetrics
+	unhandled uint64
+	rejected  uint64
+	dropped   uint64
+}
+
+func (m *metrics) observe(opcode byte, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.opcodes == nil {
+		m.opcodes = make(map[byte]OpcodeMetrics)
+	}
+	om := m.opcodes[opcode]
+	om.Count++
+	om.TotalLatency += d
+	if d > om.MaxLatency {
+		om.MaxLatency = d
+	}
+	m.opcodes[opcode] = om
+}
+
+func (m *metrics) addUnhandled() {
+	m.mu.Lock()
+	m.unhandled++
+	m.mu.Unlock()
+}
+
+func (m *metrics) addRejected() {
+	m.mu.Lock()
+	m.rejected++
+	m.mu.Unlock()
+}
+
+func (m *metrics) addDropped() {
+	m.mu.Lock()
+	m.dropped++
+	m.mu.Unlock()
+}
+
+func (m *metrics) snapshot() Metrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := Metrics{
+		Opcodes:   make(map[byte]OpcodeMetrics, len(m.opcodes)),
+		Unhandled: m.unhandled,
+		Rejected:  m.rejected,
+		Dropped:   m.dropped,
+	}
+	for op, om := range m.opcodes {
+		out.Opcodes[op] = om
+	}
+	return out
+}
+
+// Metrics returns per-opcode frame counts and handler latencies since the
+// server was created. A frame is recorded when its handler returns; for a
+// streaming request that is once the stream has ended.
+func (s *Server) Metrics() Metrics {
+	return s.metrics.snapshot()
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+
+	"github.com/strand-protocol/strand/strandapi/pkg/protocol"
+)
+
+// maxTrackedPeers bounds how many peers' handshake results the server keeps
+// for PeerInfo.Hello. Beyond it an arbitrary older entry is forgotten.
+const maxTrackedPeers = 4096
+
+// PeerInfo describes the client that sent the frame being handled. The
+// server attaches it to the context passed to every handler.
+type PeerInfo struct {
+	// Addr identifies the peer, normally its network address. It is empty
+	// on transports that do not implement transport.PeerTransport.
+	Addr string
+	// SessionID is the logical stream the frame arrived on, or 0 when the
+	// transport carries none.
+	SessionID uint32
+	// Hello is the handshake reply this server sent the peer: its negotiated
+	// message size and the codecs the server accepts. It is nil if the peer
+	// has not completed OpHello.
+	Hello *protocol.Hello
+}
+
+type peerInfoKey struct{}
+
+func withPeerInfo(ctx context.Context, p PeerInfo) context.Context {
+	return context.WithValue(ctx, peerInfoKey{}, p)
+}
+
+// PeerFromContext returns the PeerInfo the server attached to a handler's
+// context, and false for a context that did not come from the server.
+func PeerFromContext(ctx context.Context) (PeerInfo, bool) {
+	p, ok := ctx.Value(peerInfoKey{}).(PeerInfo)
+	return p, ok
+}
+
+// PeerAddr returns the address of the peer whose frame is being handled, or
+// "" if unknown.
+func PeerAddr(ctx context.Context) string {
+	p, _ := PeerFromContext(ctx)
+	return p.Addr
+}
+
+// SessionID returns the logical stream ID of the frame being handled, or 0
+// if it has none.
+func SessionID(ctx context.Context) uint32 {
+	p, _ := PeerFromContext(ctx)
+	return p.SessionID
+}
+
+// rememberHello records the handshake reply sent to peer.
+func (s *Server) rememberHello(peer string, hello *protocol.Hello) {
+	s.peerMu.Lock()
+	defer s.peerMu.Unlock()
+	if s.peerHellos == nil {
+		s.peerHellos = make(map[string]*protocol.Hello)
+	}
+	if _, ok := s.peerHellos[peer]; !ok && len(s.peerHellos) >= maxTrackedPeers {
+		for p := range s.peerHellos {
+			delete(s.peerHellos, p)
+			break
+		}
+	}
+	s.peerHellos[peer] = hello
+}
+
+// peerInfo builds the PeerInfo for a frame from peer on stream sessionID.
+func (s *Server) peerInfo(peer string, sessionID uint32) PeerInfo {
+	s.peerMu.Lock()
+	hello := s.peerHellos[peer]
+	s.peerMu.Unlock()
+	return PeerInfo{Addr: peer, SessionID: sessionID, Hello: hello}
+}
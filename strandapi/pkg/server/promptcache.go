@@ -0,0 +1,87 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/strand-protocol/strand/strandapi/pkg/protocol"
+)
+
+// defaultPromptCacheEntries is how many cached prompt prefixes a server keeps
+// unless WithPromptCache says otherwise.
+const defaultPromptCacheEntries = 256
+
+// WithPromptCache sets how many prompt prefixes the server caches for
+// InferenceRequest.PromptCacheKey, across all peers (default 256). Beyond it
+// an arbitrary older prefix is forgotten, and a request referencing it is
+// answered with ErrNotFound so the client resends the prefix. n <= 0
+// disables the cache: prefixes are not stored and every reference misses.
+func WithPromptCache(n int) ServerOption {
+	return func(s *Server) {
+		s.promptCache.max = max(n, 0)
+	}
+}
+
+// promptCacheKey identifies a cached prefix. Keys are scoped to the peer
+// that cached them, so one client cannot read another's prompts.
+type promptCacheKey struct {
+	peer string
+	key  string
+}
+
+// promptCache holds prompt prefixes by peer and PromptCacheKey.
+type promptCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[promptCacheKey]string
+}
+
+// resolve applies req's prompt cache fields from peer: it caches the prefix
+// req carries, or completes req.Prompt with the prefix cached under its key.
+// The returned error is reported to the client.
+func (c *promptCache) resolve(peer string, req *protocol.InferenceRequest) *protocol.ErrorMessage {
+	if req.PromptCacheKey == "" {
+		return nil
+	}
+	k := promptCacheKey{peer: peer, key: req.PromptCacheKey}
+	if n := int(req.PromptCachePrefixLen); n > 0 {
+		if n > len(req.Prompt) {
+			return &protocol.ErrorMessage{
+				Code:    protocol.ErrInvalidRequest,
+				Message: fmt.Sprintf("prompt cache prefix of %d bytes exceeds the %d-byte prompt", n, len(req.Prompt)),
+			}
+		}
+		c.store(k, req.Prompt[:n])
+		return nil
+	}
+
+	c.mu.Lock()
+	prefix, ok := c.entries[k]
+	c.mu.Unlock()
+	if !ok {
+		return &protocol.ErrorMessage{
+			Code:    protocol.ErrNotFound,
+			Message: fmt.Sprintf("prompt cache key %q is not cached", req.PromptCacheKey),
+		}
+	}
+	req.Prompt = prefix + req.Prompt
+	return nil
+}
+
+func (c *promptCache) store(k promptCacheKey, prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.max == 0 {
+		return
+	}
+	if c.entries == nil {
+		c.entries = make(map[promptCacheKey]string)
+	}
+	if _, ok := c.entries[k]; !ok && len(c.entries) >= c.max {
+		for old := range c.entries {
+			delete(c.entries, old)
+			break
+		}
+	}
+	c.entries[k] = prefix
+}
@@ -2,8 +2,12 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"runtime/debug"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,6 +24,10 @@ const defaultShutdownTimeout = 5 * time.Second
 type ServerOption func(*Server)
 
 // WithStreamHandler registers a StreamHandler for token-streaming inference.
+// It serves requests with InferenceRequest.Stream set (or the
+// InferenceMetaStream metadata flag). Other requests go to the synchronous
+// Handler if one is registered; otherwise the server runs the stream handler,
+// collects its tokens and replies with a single InferenceResponse.
 func WithStreamHandler(sh StreamHandler) ServerOption {
 	return func(s *Server) {
 		s.streamHandler = sh
@@ -30,12 +38,40 @@ func WithStreamHandler(sh StreamHandler) ServerOption {
 // peer delegates a task to this server the handler is invoked with the decoded
 // AgentDelegate and must return an AgentResult (or an error). On error the
 // server sends back an AgentResult with ErrInternal and the error string.
+// A non-zero AgentDelegate.TimeoutMS bounds the handler's context; if it
+// expires the server replies with ErrTimeout without waiting for the handler.
 func WithAgentHandler(fn func(ctx context.Context, msg *protocol.AgentDelegate) (*protocol.AgentResult, error)) ServerOption {
 	return func(s *Server) {
 		s.agentHandler = fn
 	}
 }
 
+// WithAgentCapabilities sets the capability identifiers this server offers to
+// peer agents. AGENT_NEGOTIATE replies carry the intersection of these and the
+// capabilities the peer requested.
+func WithAgentCapabilities(caps []string) ServerOption {
+	return func(s *Server) {
+		s.agentCapabilities = append([]string(nil), caps...)
+	}
+}
+
+// MICVerifier validates an encoded Model Identity Certificate presented by a
+// delegating peer and returns the capabilities it grants. Implementations
+// typically decode the certificate and check it against the Strand Trust CA;
+// a non-nil error rejects the delegation.
+type MICVerifier func(ctx context.Context, mic string) ([]string, error)
+
+// WithMICVerifier enables capability-scoped authorization for OpAgentDelegate
+// frames. When set, every delegation must carry a MIC in its metadata
+// (protocol.DelegateMetaMIC) that v accepts and whose capabilities include the
+// requested task type (protocol.DelegateMetaTaskType); otherwise the server
+// replies with ErrCapabilities without invoking the agent handler.
+func WithMICVerifier(v MICVerifier) ServerOption {
+	return func(s *Server) {
+		s.micVerifier = v
+	}
+}
+
 // WithShutdownTimeout configures how long Stop waits for in-flight frame
 // handlers to finish before forcibly closing the transport.
 func WithShutdownTimeout(d time.Duration) ServerOption {
@@ -44,6 +80,149 @@ func WithShutdownTimeout(d time.Duration) ServerOption {
 	}
 }
 
+// defaultTokenSendBuffer is the default high-water mark for queued token
+// chunks per stream (see WithTokenSendBuffer).
+const defaultTokenSendBuffer = 32
+
+// WithTokenSendBuffer sets how many encoded token chunks a stream may queue
+// ahead of the transport. Chunks are written by a dedicated goroutine; once n
+// are pending, TokenSender.Send blocks until the transport catches up rather
+// than dropping tokens. Values below 1 are treated as 1.
+func WithTokenSendBuffer(n int) ServerOption {
+	return func(s *Server) {
+		if n < 1 {
+			n = 1
+		}
+		s.tokenSendBuffer = n
+	}
+}
+
+// maxTokenBatch bounds WithTokenBatching's maxTokens so a batch frame stays
+// well inside a single datagram for typical token sizes.
+const maxTokenBatch = 256
+
+// WithTokenBatching coalesces streamed tokens: chunks the handler sends within
+// maxDelay of the first queued one are written as a single OpTokenStreamBatch
+// frame of up to maxTokens chunks instead of one frame each. Order and SeqNum
+// are preserved, and a lone chunk is still sent as OpTokenStreamChunk. The
+// client unbatches transparently. maxTokens <= 1 (the default) disables
+// batching; values above 256 are capped.
+func WithTokenBatching(maxTokens int, maxDelay time.Duration) ServerOption {
+	return func(s *Server) {
+		if maxTokens > maxTokenBatch {
+			maxTokens = maxTokenBatch
+		}
+		s.tokenBatchMax = maxTokens
+		s.tokenBatchDelay = maxDelay
+	}
+}
+
+// WithMaxMessageSize sets the largest payload, in bytes, this server accepts.
+// It is advertised to clients in the OpHello handshake, which settles on the
+// smaller of the two sides' limits, and inbound frames above it are rejected
+// with an OpError. The default is protocol.DefaultMaxMessageSize. On a
+// transport that bounds frame size (transport.MaxPayloadTransport) the
+// advertised limit is capped to that bound.
+func WithMaxMessageSize(n uint32) ServerOption {
+	return func(s *Server) {
+		s.maxMessageSize = n
+	}
+}
+
+// WithMaxPromptBytes rejects inference requests whose prompt is longer than n
+// bytes with ErrInvalidRequest, before any handler runs. It bounds prompt size
+// independently of WithMaxMessageSize, which also covers metadata and other
+// frame types. n <= 0 (the default) disables the check.
+func WithMaxPromptBytes(n int) ServerOption {
+	return func(s *Server) {
+		if n < 0 {
+			n = 0
+		}
+		s.maxPromptBytes = n
+	}
+}
+
+// WithCompression lets clients send frames compressed with any of the given
+// codecs. They are advertised in the OpHello reply, and clients only
+// compress for a server that listed the codec; without this option the
+// server advertises none and receives raw frames only. Replies are not
+// compressed.
+func WithCompression(cs ...protocol.Compressor) ServerOption {
+	return func(s *Server) {
+		for _, c := range cs {
+			if _, dup := s.compressors[c.Codec()]; dup || c.Codec() == protocol.CodecRaw {
+				continue
+			}
+			if s.compressors == nil {
+				s.compressors = make(map[protocol.Codec]protocol.Compressor)
+			}
+			s.compressors[c.Codec()] = c
+			s.codecs = append(s.codecs, c.Codec())
+		}
+	}
+}
+
+// WithStrictOpcodes makes the server answer a frame whose opcode has no
+// handler with an OpError carrying ErrUnsupported, instead of logging and
+// dropping it, so clients learn of protocol mismatches immediately.
+func WithStrictOpcodes() ServerOption {
+	return func(s *Server) {
+		s.strictOpcodes = true
+	}
+}
+
+// WithOverflowQueue holds up to size frames that arrive while the server is
+// at its concurrency limit, instead of dropping them at once, and dispatches
+// them in arrival order as handlers finish. Only frames that find the queue
+// full are dropped, inference requests being answered with ErrRateLimited as
+// before. It absorbs short bursts at the price of queueing delay; frames
+// still queued at shutdown are discarded. size <= 0 (the default) disables
+// the queue.
+func WithOverflowQueue(size int) ServerOption {
+	return func(s *Server) {
+		if size < 0 {
+			size = 0
+		}
+		s.overflowSize = size
+	}
+}
+
+// WithPerPeerConcurrency caps how many frames from a single peer may be in
+// flight at once, so one aggressive client cannot take the whole
+// concurrency budget and starve the others. Frames over a peer's cap are
+// dropped, like frames over the global limit; dropped inference requests are
+// answered with ErrRateLimited. Peers are identified through
+// transport.PeerTransport; on transports that do not implement it every
+// frame counts against one shared peer. n <= 0 (the default) disables the
+// per-peer cap.
+func WithPerPeerConcurrency(n int) ServerOption {
+	return func(s *Server) {
+		if n < 0 {
+			n = 0
+		}
+		s.perPeerLimit = n
+	}
+}
+
+// WithHealthReporter makes the server answer every OpHeartbeat with a
+// protocol.NodeHealth payload from fn, so peers probing it learn its current
+// load. Without it heartbeat replies are empty.
+func WithHealthReporter(fn func() protocol.NodeHealth) ServerOption {
+	return func(s *Server) {
+		s.healthReporter = fn
+	}
+}
+
+// WithHealthObserver calls fn with the NodeHealth carried by each inbound
+// OpHeartbeat. Empty heartbeats carry none and do not call fn; malformed
+// health payloads are logged and ignored, and the heartbeat is still
+// answered.
+func WithHealthObserver(fn func(ctx context.Context, health protocol.NodeHealth)) ServerOption {
+	return func(s *Server) {
+		s.healthObserver = fn
+	}
+}
+
 // maxConcurrentFrames limits the number of goroutines processing frames
 // simultaneously, preventing goroutine exhaustion under burst traffic.
 const maxConcurrentFrames = 1000
@@ -55,14 +234,68 @@ type Server struct {
 	streamHandler StreamHandler
 	// agentHandler handles OpAgentDelegate frames (optional).
 	agentHandler func(ctx context.Context, msg *protocol.AgentDelegate) (*protocol.AgentResult, error)
-	transport        *transport.OverlayTransport
+	// agentCapabilities are advertised during AGENT_NEGOTIATE (optional).
+	agentCapabilities []string
+	// micVerifier, when set, gates OpAgentDelegate on a valid MIC (optional).
+	micVerifier MICVerifier
+	transport        transport.Transport
 	mu               sync.Mutex
 	done             chan struct{}
 	shutdownTimeout  time.Duration
+	// inflight maps each peer's request IDs to the cancel funcs of their
+	// handler contexts, by registration token, so an OpCancel frame from
+	// that peer can stop generation for that request.
+	inflightMu  sync.Mutex
+	inflight    map[inflightKey]map[uint64]context.CancelFunc
+	inflightSeq uint64
+	// tokenSendBuffer is the per-stream chunk queue depth.
+	tokenSendBuffer int
+	// tokenBatchMax and tokenBatchDelay configure WithTokenBatching.
+	tokenBatchMax   int
+	tokenBatchDelay time.Duration
+	// maxMessageSize is the inbound payload limit offered in OpHello.
+	maxMessageSize uint32
+	// maxPromptBytes caps InferenceRequest.Prompt (0 = no cap).
+	maxPromptBytes int
+	// promptCache holds prefixes for InferenceRequest.PromptCacheKey.
+	promptCache promptCache
+	// compressors decode OpCompressed frames; codecs lists them in the order
+	// advertised in OpHello (see WithCompression).
+	compressors map[protocol.Codec]protocol.Compressor
+	codecs      []protocol.Codec
+	// strictOpcodes answers unhandled opcodes with ErrUnsupported.
+	strictOpcodes bool
+	// servedSADs are the encoded descriptors returned for
+	// OpCapabilitiesQuery (see WithServedSADs).
+	servedSADs [][]byte
+	// healthReporter fills heartbeat replies; healthObserver receives the
+	// health carried by inbound heartbeats (both optional).
+	healthReporter func() protocol.NodeHealth
+	healthObserver func(ctx context.Context, health protocol.NodeHealth)
+	// handlers maps opcodes to their FrameHandler (see Handle).
+	handlersMu sync.RWMutex
+	handlers   map[byte]FrameHandler
 	// sem bounds the number of in-flight frame handler goroutines.
 	sem chan struct{}
+	// overflowSize is the capacity of the queue holding frames that arrive
+	// while sem is full (see WithOverflowQueue); 0 drops them at once.
+	overflowSize int
+	// perPeerLimit caps in-flight frames per peer (0 = no cap); peerInflight
+	// counts them by peer identifier.
+	perPeerLimit int
+	peerMu       sync.Mutex
+	peerInflight map[string]int
+	// peerHellos holds the OpHello reply sent to each peer, reported to
+	// handlers in PeerInfo; guarded by peerMu.
+	peerHellos map[string]*protocol.Hello
 	// wg tracks in-flight frame handlers so Stop can drain gracefully.
 	wg sync.WaitGroup
+	// loops tracks the Serve loops and overflow drainers, the only callers
+	// of wg.Add, so Stop can wait for them to exit before waiting on wg.
+	// Serve joins it under mu only while done is open.
+	loops sync.WaitGroup
+	// metrics counts handled frames per opcode (see Metrics).
+	metrics metrics
 }
 
 // New creates a Server with the given inference handler and options.
@@ -72,7 +305,14 @@ func New(handler Handler, opts ...ServerOption) *Server {
 		done:            make(chan struct{}),
 		sem:             make(chan struct{}, maxConcurrentFrames),
 		shutdownTimeout: defaultShutdownTimeout,
+		inflight:        make(map[inflightKey]map[uint64]context.CancelFunc),
+		tokenSendBuffer: defaultTokenSendBuffer,
+		maxMessageSize:  protocol.DefaultMaxMessageSize,
+		handlers:        make(map[byte]FrameHandler),
+		peerInflight:    make(map[string]int),
+		promptCache:     promptCache{max: defaultPromptCacheEntries},
 	}
+	s.registerBuiltinHandlers()
 	for _, opt := range opts {
 		opt(s)
 	}
@@ -86,7 +326,27 @@ func (s *Server) ListenAndServe(addr string) error {
 	if err != nil {
 		return fmt.Errorf("strandapi server: listen: %w", err)
 	}
+	return s.Serve(t)
+}
+
+// Serve processes incoming StrandAPI frames on an already-established
+// transport until the server is stopped or a fatal error occurs. Datagrams
+// the transport rejects one by one (transport.IsFrameError) are counted in
+// Metrics.Dropped and skipped. The server takes ownership of t and closes it
+// on shutdown.
+func (s *Server) Serve(t transport.Transport) error {
+	s.mu.Lock()
+	select {
+	case <-s.done:
+		s.mu.Unlock()
+		t.Close()
+		return nil // already stopped
+	default:
+	}
 	s.transport = t
+	s.loops.Add(1)
+	s.mu.Unlock()
+	defer s.loops.Done()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -97,33 +357,194 @@ func (s *Server) ListenAndServe(addr string) error {
 		t.Close()
 	}()
 
-	for {
+	recv := func(ctx context.Context) (inboundFrame, error) {
 		opcode, payload, err := t.Recv(ctx)
+		return inboundFrame{opcode: opcode, payload: payload}, err
+	}
+	switch pt := t.(type) {
+	case transport.PeerStreamTransport:
+		recv = func(ctx context.Context) (inboundFrame, error) {
+			from, streamID, opcode, payload, err := pt.RecvStreamFrom(ctx)
+			f := inboundFrame{from: from, sessionID: streamID, opcode: opcode, payload: payload}
+			if err == nil && from != nil {
+				f.peer = from.String()
+			}
+			return f, err
+		}
+	case transport.PeerTransport:
+		recv = func(ctx context.Context) (inboundFrame, error) {
+			peer, opcode, payload, err := pt.RecvFrom(ctx)
+			return inboundFrame{peer: peer, opcode: opcode, payload: payload}, err
+		}
+	}
+
+	var overflow chan inboundFrame
+	if s.overflowSize > 0 {
+		overflow = make(chan inboundFrame, s.overflowSize)
+		s.loops.Add(1)
+		go func() {
+			defer s.loops.Done()
+			s.drainOverflow(ctx, overflow)
+		}()
+	}
+
+	for {
+		f, err := recv(ctx)
 		if err != nil {
 			select {
 			case <-s.done:
 				return nil // graceful shutdown
 			default:
-				log.Printf("strandapi server: recv error: %v", err)
-				return err
 			}
+			if transport.IsFrameError(err) {
+				// One bad datagram must not stop the listener.
+				s.metrics.addDropped()
+				continue
+			}
+			log.Printf("strandapi server: recv error: %v", err)
+			return err
+		}
+		if !s.acquirePeer(f.peer) {
+			log.Printf("strandapi server: peer %q at concurrency limit, dropping frame opcode=0x%02x", f.peer, f.opcode)
+			s.rejectOverloaded(ctx, s.replyWriter(f), f.opcode, "peer at concurrency limit")
+			continue
 		}
 		// Dispatch in a goroutine bounded by the semaphore to prevent
-		// goroutine exhaustion under burst traffic.
+		// goroutine exhaustion under burst traffic. While frames wait in the
+		// overflow queue, new ones join it rather than overtake them.
+		if len(overflow) == 0 {
+			select {
+			case s.sem <- struct{}{}:
+				s.dispatch(ctx, f)
+				continue
+			default:
+			}
+		}
+		if overflow != nil {
+			select {
+			case overflow <- f:
+				continue
+			default:
+			}
+		}
+		s.releasePeer(f.peer)
+		log.Printf("strandapi server: overloaded, dropping frame opcode=0x%02x", f.opcode)
+		s.rejectOverloaded(ctx, s.replyWriter(f), f.opcode, "server overloaded")
+	}
+}
+
+// inboundFrame is a received frame awaiting dispatch.
+type inboundFrame struct {
+	peer      string
+	from      net.Addr // sender's address on a PeerStreamTransport, else nil
+	sessionID uint32
+	opcode    byte
+	payload   []byte
+}
+
+// replyWriter returns the FrameWriter for replies to f: on a
+// PeerStreamTransport it answers f's sender on the stream f arrived on, so a
+// listener serves many peers at once; otherwise it sends on the transport,
+// which then has a single peer.
+func (s *Server) replyWriter(f inboundFrame) FrameWriter {
+	if pt, ok := s.transport.(transport.PeerStreamTransport); ok && f.from != nil {
+		return &peerWriter{t: pt, to: f.from, streamID: f.sessionID}
+	}
+	return s.transport
+}
+
+// peerWriter is a FrameWriter bound to one peer and stream.
+type peerWriter struct {
+	t        transport.PeerStreamTransport
+	to       net.Addr
+	streamID uint32
+}
+
+func (w *peerWriter) Send(ctx context.Context, opcode byte, payload []byte) error {
+	return w.t.SendStreamTo(ctx, w.to, w.streamID, opcode, payload)
+}
+
+// dispatch runs the handler for f in a new goroutine. The caller must hold a
+// semaphore slot, which the goroutine releases along with f's peer slot.
+func (s *Server) dispatch(ctx context.Context, f inboundFrame) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() { <-s.sem }()
+		defer s.releasePeer(f.peer)
+		s.handleFrame(withPeerInfo(ctx, s.peerInfo(f.peer, f.sessionID)), s.replyWriter(f), f.opcode, f.payload)
+	}()
+}
+
+// drainOverflow dispatches queued frames in order as semaphore slots free
+// up, until ctx is done. Frames still queued then are discarded.
+func (s *Server) drainOverflow(ctx context.Context, overflow chan inboundFrame) {
+	for {
 		select {
-		case s.sem <- struct{}{}:
-			s.wg.Add(1)
-			go func(op byte, pl []byte) {
-				defer s.wg.Done()
-				defer func() { <-s.sem }()
-				s.handleFrame(ctx, op, pl)
-			}(opcode, payload)
-		default:
-			log.Printf("strandapi server: overloaded, dropping frame opcode=0x%02x", opcode)
+		case f := <-overflow:
+			select {
+			case s.sem <- struct{}{}:
+				s.dispatch(ctx, f)
+			case <-ctx.Done():
+				s.releasePeer(f.peer)
+				return
+			}
+		case <-ctx.Done():
+			return
 		}
 	}
 }
 
+// rejectOverloaded tells the client that a dropped inference request will not
+// be answered, so it can back off instead of waiting for its timeout. Other
+// dropped frames expect no reply and are dropped silently.
+func (s *Server) rejectOverloaded(ctx context.Context, w FrameWriter, opcode byte, reason string) {
+	if opcode == protocol.OpInferenceRequest {
+		s.sendError(ctx, w, protocol.ErrRateLimited, reason)
+	}
+}
+
+// acquirePeer reserves an in-flight slot for peer, reporting false if the
+// peer is already at its WithPerPeerConcurrency cap.
+func (s *Server) acquirePeer(peer string) bool {
+	if s.perPeerLimit <= 0 {
+		return true
+	}
+	s.peerMu.Lock()
+	defer s.peerMu.Unlock()
+	if s.peerInflight[peer] >= s.perPeerLimit {
+		return false
+	}
+	s.peerInflight[peer]++
+	return true
+}
+
+// releasePeer returns a slot taken by acquirePeer.
+func (s *Server) releasePeer(peer string) {
+	if s.perPeerLimit <= 0 {
+		return
+	}
+	s.peerMu.Lock()
+	defer s.peerMu.Unlock()
+	if s.peerInflight[peer]--; s.peerInflight[peer] <= 0 {
+		delete(s.peerInflight, peer)
+	}
+}
+
+// Stats reports the traffic counters of the transport the server is serving
+// on. It returns false before Serve is called or when the transport does not
+// implement transport.StatsTransport.
+func (s *Server) Stats() (transport.TransportStats, bool) {
+	s.mu.Lock()
+	t := s.transport
+	s.mu.Unlock()
+	st, ok := t.(transport.StatsTransport)
+	if !ok {
+		return transport.TransportStats{}, false
+	}
+	return st.TransportStats(), true
+}
+
 // Stop signals the server to shut down gracefully. It stops accepting new
 // frames and waits up to ShutdownTimeout for in-flight handlers to finish.
 func (s *Server) Stop() {
@@ -138,9 +559,12 @@ func (s *Server) Stop() {
 	s.mu.Unlock()
 
 	// Wait for in-flight frame handlers to complete, bounded by the
-	// configured shutdown timeout so we don't block indefinitely.
+	// configured shutdown timeout so we don't block indefinitely. The Serve
+	// loops stop dispatching first, so no handler is added once wg.Wait
+	// begins.
 	done := make(chan struct{})
 	go func() {
+		s.loops.Wait()
 		s.wg.Wait()
 		close(done)
 	}()
@@ -152,78 +576,463 @@ func (s *Server) Stop() {
 	}
 }
 
-// handleFrame dispatches a single StrandAPI frame to the appropriate handler.
-func (s *Server) handleFrame(ctx context.Context, opcode byte, payload []byte) {
-	switch opcode {
-	case protocol.OpInferenceRequest:
-		s.handleInference(ctx, payload)
-	case protocol.OpHeartbeat:
-		s.handleHeartbeat(ctx)
-	case protocol.OpAgentNegotiate:
-		s.handleAgentNegotiate(ctx, payload)
-	case protocol.OpAgentDelegate:
-		s.handleAgentDelegate(ctx, payload)
-	default:
+// Handle registers fn for frames with the given opcode, replacing any
+// existing handler, including the built-in ones for inference, heartbeat,
+// hello, agent delegation, cancellation and capability queries. A nil fn
+// removes the handler so the opcode is logged as unhandled. Handle is safe to
+// call while serving.
+func (s *Server) Handle(opcode byte, fn FrameHandler) {
+	s.handlersMu.Lock()
+	defer s.handlersMu.Unlock()
+	if fn == nil {
+		delete(s.handlers, opcode)
+		return
+	}
+	s.handlers[opcode] = fn
+}
+
+// registerBuiltinHandlers installs the default opcode handlers.
+func (s *Server) registerBuiltinHandlers() {
+	s.handlers[protocol.OpHello] = func(ctx context.Context, payload []byte, w FrameWriter) {
+		s.handleHello(ctx, w, payload)
+	}
+	s.handlers[protocol.OpInferenceRequest] = func(ctx context.Context, payload []byte, w FrameWriter) {
+		s.handleInference(ctx, w, payload)
+	}
+	s.handlers[protocol.OpHeartbeat] = func(ctx context.Context, payload []byte, w FrameWriter) {
+		s.handleHeartbeat(ctx, w, payload)
+	}
+	s.handlers[protocol.OpAgentNegotiate] = func(ctx context.Context, payload []byte, w FrameWriter) {
+		s.handleAgentNegotiate(ctx, w, payload)
+	}
+	s.handlers[protocol.OpAgentDelegate] = func(ctx context.Context, payload []byte, w FrameWriter) {
+		s.handleAgentDelegate(ctx, w, payload)
+	}
+	s.handlers[protocol.OpCancel] = func(ctx context.Context, payload []byte, _ FrameWriter) {
+		s.handleCancel(ctx, payload)
+	}
+	s.handlers[protocol.OpCompressed] = func(ctx context.Context, payload []byte, w FrameWriter) {
+		s.handleCompressed(ctx, w, payload)
+	}
+	s.handlers[protocol.OpCapabilitiesQuery] = func(ctx context.Context, _ []byte, w FrameWriter) {
+		s.handleCapabilitiesQuery(ctx, w)
+	}
+}
+
+// handleFrame dispatches a single StrandAPI frame to the handler registered
+// for its opcode, recording it in the server's Metrics.
+func (s *Server) handleFrame(ctx context.Context, w FrameWriter, opcode byte, payload []byte) {
+	if s.maxMessageSize > 0 && uint32(len(payload)) > s.maxMessageSize {
+		s.metrics.addRejected()
+		s.sendError(ctx, w, protocol.ErrInvalidRequest, fmt.Sprintf("message of %d bytes exceeds maximum of %d", len(payload), s.maxMessageSize))
+		return
+	}
+	s.handlersMu.RLock()
+	fn, ok := s.handlers[opcode]
+	s.handlersMu.RUnlock()
+	if !ok {
+		s.metrics.addUnhandled()
+		if s.strictOpcodes {
+			s.sendError(ctx, w, protocol.ErrUnsupported, fmt.Sprintf("unsupported opcode 0x%02x", opcode))
+			return
+		}
 		log.Printf("strandapi server: unhandled opcode 0x%02x", opcode)
+		return
+	}
+	start := time.Now()
+	defer s.recoverPanic(ctx, w, opcode)
+	fn(ctx, payload, w)
+	s.metrics.observe(opcode, time.Since(start))
+}
+
+// handlerPanic carries a panic raised on a handler's own goroutine (see
+// runBeforeDeadline) to the frame's goroutine, with the original stack.
+type handlerPanic struct {
+	value any
+	stack []byte
+}
+
+// recoverPanic, deferred by handleFrame, keeps a panicking handler from
+// taking the server down: it logs the panic with the frame's opcode and
+// answers the client with ErrInternal. The server keeps serving.
+func (s *Server) recoverPanic(ctx context.Context, w FrameWriter, opcode byte) {
+	p := recover()
+	if p == nil {
+		return
 	}
+	stack := debug.Stack()
+	if hp, ok := p.(handlerPanic); ok {
+		p, stack = hp.value, hp.stack
+	}
+	log.Printf("strandapi server: panic handling opcode 0x%02x: %v\n%s", opcode, p, stack)
+	s.sendError(ctx, w, protocol.ErrInternal, "internal error: handler panicked")
 }
 
-func (s *Server) handleInference(ctx context.Context, payload []byte) {
+// handleHello answers a HELLO handshake with the negotiated message size and
+// the codecs this server accepts compressed frames in.
+func (s *Server) handleHello(ctx context.Context, w FrameWriter, payload []byte) {
+	req := &protocol.Hello{}
+	reader := strandbuf.NewReader(payload)
+	if err := req.Decode(reader); err != nil {
+		s.sendError(ctx, w, protocol.ErrInvalidRequest, fmt.Sprintf("decode error: %v", err))
+		return
+	}
+	resp := &protocol.Hello{
+		Version:        protocol.HelloVersion,
+		MaxMessageSize: protocol.NegotiateMaxMessageSize(s.advertisedMaxMessageSize(), req.MaxMessageSize),
+		Codecs:         s.codecs,
+	}
+	s.rememberHello(PeerAddr(ctx), resp)
+	buf := strandbuf.NewBuffer(8)
+	resp.Encode(buf)
+	if err := w.Send(ctx, protocol.OpHello, buf.Bytes()); err != nil {
+		log.Printf("strandapi server: send hello error: %v", err)
+	}
+}
+
+// advertisedMaxMessageSize is the limit offered in OpHello: the configured
+// maximum, capped to the largest payload the transport can carry in a frame.
+func (s *Server) advertisedMaxMessageSize() uint32 {
+	limit := s.maxMessageSize
+	if mt, ok := s.transport.(transport.MaxPayloadTransport); ok {
+		if n := mt.MaxPayload(); n > 0 && (limit == 0 || uint32(n) < limit) {
+			limit = uint32(n)
+		}
+	}
+	return limit
+}
+
+// handleCompressed decompresses an OpCompressed frame and dispatches the
+// wrapped frame as if it had arrived on its own.
+func (s *Server) handleCompressed(ctx context.Context, w FrameWriter, payload []byte) {
+	msg := &protocol.Compressed{}
+	if err := msg.Decode(strandbuf.NewReader(payload)); err != nil {
+		s.sendError(ctx, w, protocol.ErrInvalidRequest, fmt.Sprintf("decode error: %v", err))
+		return
+	}
+	if msg.Opcode == protocol.OpCompressed {
+		s.sendError(ctx, w, protocol.ErrInvalidRequest, "nested compressed frame")
+		return
+	}
+	c, ok := s.compressors[msg.Codec]
+	if !ok {
+		s.sendError(ctx, w, protocol.ErrInvalidRequest, fmt.Sprintf("%v: %v", protocol.ErrUnsupportedCodec, msg.Codec))
+		return
+	}
+	raw, err := c.Decompress(msg.Payload, s.maxMessageSize)
+	if err != nil {
+		s.sendError(ctx, w, protocol.ErrInvalidRequest, fmt.Sprintf("decompress error: %v", err))
+		return
+	}
+	s.handleFrame(ctx, w, msg.Opcode, raw)
+}
+
+func (s *Server) handleInference(ctx context.Context, w FrameWriter, payload []byte) {
 	req := &protocol.InferenceRequest{}
 	reader := strandbuf.NewReader(payload)
 	if err := req.Decode(reader); err != nil {
-		s.sendError(ctx, fmt.Sprintf("decode error: %v", err))
+		s.sendError(ctx, w, protocol.ErrInvalidRequest, fmt.Sprintf("decode error: %v", err))
+		return
+	}
+	if e := s.promptCache.resolve(PeerAddr(ctx), req); e != nil {
+		s.sendError(ctx, w, e.Code, e.Message)
+		return
+	}
+	if s.maxPromptBytes > 0 && len(req.Prompt) > s.maxPromptBytes {
+		s.sendError(ctx, w, protocol.ErrInvalidRequest, fmt.Sprintf("prompt of %d bytes exceeds maximum of %d", len(req.Prompt), s.maxPromptBytes))
 		return
 	}
 
-	// Try stream handler first if registered, otherwise fall back to
-	// synchronous handler.
-	if s.streamHandler != nil {
-		s.handleStreamInference(ctx, req)
+	ctx, cancel, untrack := s.trackRequest(ctx, req.ID)
+	defer untrack()
+	defer cancel()
+
+	// Stream when the client asked for it and a stream handler is
+	// registered. Otherwise answer with a single response, from the
+	// synchronous handler if there is one or else by collecting the stream.
+	streaming := req.Stream || req.Metadata[protocol.InferenceMetaStream] == "true"
+	if s.streamHandler != nil && (streaming || s.handler == nil) {
+		if streaming {
+			s.handleStreamInference(ctx, w, req)
+		} else {
+			s.handleCollectedInference(ctx, w, req)
+		}
 		return
 	}
 
 	if s.handler == nil {
-		s.sendError(ctx, "no handler registered")
+		s.sendError(ctx, w, protocol.ErrCapabilities, "no handler registered")
 		return
 	}
 
-	resp, err := s.handler.HandleInference(ctx, req)
+	hctx, cancelDeadline := requestDeadline(ctx, req)
+	defer cancelDeadline()
+	resp, err := runBeforeDeadline(hctx, func() (*protocol.InferenceResponse, error) {
+		return s.handler.HandleInference(hctx, req)
+	})
+	if err != nil && hctx.Err() == context.DeadlineExceeded {
+		s.sendDeadlineExceeded(ctx, w)
+		return
+	}
 	if err != nil {
-		s.sendError(ctx, err.Error())
+		s.sendHandlerError(ctx, w, err)
 		return
 	}
 
+	fillUsage(req, resp)
 	buf := strandbuf.NewBuffer(256)
-	resp.Encode(buf)
-	if err := s.transport.Send(ctx, protocol.OpInferenceResponse, buf.Bytes()); err != nil {
+	encodeResponse(buf, resp)
+	if err := w.Send(ctx, protocol.OpInferenceResponse, buf.Bytes()); err != nil {
 		log.Printf("strandapi server: send response error: %v", err)
 	}
 }
 
-func (s *Server) handleStreamInference(ctx context.Context, req *protocol.InferenceRequest) {
-	// Send stream start
-	if err := s.transport.Send(ctx, protocol.OpTokenStreamStart, nil); err != nil {
+// encodeResponse writes resp to buf, version-stamped if its ID could be
+// mistaken for a stamp (protocol.ReservedID) so the client reads it intact.
+func encodeResponse(buf *strandbuf.Buffer, resp *protocol.InferenceResponse) {
+	if protocol.ReservedID(resp.ID) {
+		resp.EncodeV2(buf)
+		return
+	}
+	resp.Encode(buf)
+}
+
+// fillUsage sets the usage fields a handler left zero, estimating tokens as
+// whitespace-separated words the way streamed responses are counted, so
+// every response carries usage and a finish reason.
+func fillUsage(req *protocol.InferenceRequest, resp *protocol.InferenceResponse) {
+	if resp.PromptTokens == 0 {
+		resp.PromptTokens = promptTokens(req)
+	}
+	if resp.CompletionTokens == 0 {
+		resp.CompletionTokens = uint32(len(strings.Fields(resp.Text)))
+	}
+	if resp.FinishReason == "" {
+		resp.FinishReason = finishReason(req, resp.CompletionTokens)
+	}
+}
+
+// promptTokens estimates the token count of req's prompt.
+func promptTokens(req *protocol.InferenceRequest) uint32 {
+	return uint32(len(strings.Fields(req.Prompt)))
+}
+
+// finishReason is "length" when completion reached req's MaxTokens and
+// "stop" otherwise.
+func finishReason(req *protocol.InferenceRequest, completion uint32) string {
+	if req.MaxTokens > 0 && completion >= req.MaxTokens {
+		return "length"
+	}
+	return "stop"
+}
+
+// requestDeadline bounds ctx by req.DeadlineUnixMs when the client set one.
+// The returned context is always cancellable.
+func requestDeadline(ctx context.Context, req *protocol.InferenceRequest) (context.Context, context.CancelFunc) {
+	if req.DeadlineUnixMs == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, time.UnixMilli(int64(req.DeadlineUnixMs)))
+}
+
+// runBeforeDeadline calls fn and returns its result, or ctx.Err() as soon as
+// ctx is done without waiting for fn to notice. A panic in fn is re-raised
+// on the calling goroutine, where handleFrame recovers it.
+func runBeforeDeadline[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	type outcome struct {
+		v        T
+		err      error
+		panicked *handlerPanic
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- outcome{panicked: &handlerPanic{value: p, stack: debug.Stack()}}
+			}
+		}()
+		v, err := fn()
+		done <- outcome{v: v, err: err}
+	}()
+	select {
+	case o := <-done:
+		if o.panicked != nil {
+			panic(*o.panicked)
+		}
+		return o.v, o.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// sendDeadlineExceeded reports a request whose DeadlineUnixMs passed before
+// the handler finished.
+func (s *Server) sendDeadlineExceeded(ctx context.Context, w FrameWriter) {
+	s.sendError(ctx, w, protocol.ErrDeadlineExceeded, "request deadline exceeded")
+}
+
+// inflightKey identifies in-flight requests by the peer that sent them and
+// their request ID. IDs are chosen by clients, so the same ID from two peers
+// names two different requests.
+type inflightKey struct {
+	peer string
+	id   [16]byte
+}
+
+// trackRequest derives a cancellable context for the request with the given
+// ID and registers it so a later OpCancel frame from the same peer can abort
+// the handler. The returned untrack removes this registration only, leaving
+// any other in-flight request with the same peer and ID in place.
+func (s *Server) trackRequest(ctx context.Context, id [16]byte) (context.Context, context.CancelFunc, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	key := inflightKey{peer: PeerAddr(ctx), id: id}
+	s.inflightMu.Lock()
+	s.inflightSeq++
+	token := s.inflightSeq
+	if s.inflight[key] == nil {
+		s.inflight[key] = make(map[uint64]context.CancelFunc)
+	}
+	s.inflight[key][token] = cancel
+	s.inflightMu.Unlock()
+	return ctx, cancel, func() { s.untrackRequest(key, token) }
+}
+
+// untrackRequest removes the registration trackRequest made under token.
+func (s *Server) untrackRequest(key inflightKey, token uint64) {
+	s.inflightMu.Lock()
+	defer s.inflightMu.Unlock()
+	delete(s.inflight[key], token)
+	if len(s.inflight[key]) == 0 {
+		delete(s.inflight, key)
+	}
+}
+
+// handleCancel cancels the handler contexts of the sending peer's in-flight
+// requests with the ID named by a CANCEL frame. Unknown or already-completed
+// request IDs are ignored.
+func (s *Server) handleCancel(ctx context.Context, payload []byte) {
+	msg := &protocol.Cancel{}
+	reader := strandbuf.NewReader(payload)
+	if err := msg.Decode(reader); err != nil {
+		log.Printf("strandapi server: cancel decode error: %v", err)
+		return
+	}
+	s.inflightMu.Lock()
+	defer s.inflightMu.Unlock()
+	for _, cancel := range s.inflight[inflightKey{peer: PeerAddr(ctx), id: msg.RequestID}] {
+		cancel()
+	}
+}
+
+func (s *Server) handleStreamInference(ctx context.Context, w FrameWriter, req *protocol.InferenceRequest) {
+	// Send stream start, echoing the request ID.
+	start := strandbuf.NewBuffer(16)
+	(&protocol.StreamStart{RequestID: req.ID}).Encode(start)
+	if err := w.Send(ctx, protocol.OpTokenStreamStart, start.Bytes()); err != nil {
 		log.Printf("strandapi server: send stream start error: %v", err)
 		return
 	}
 
-	sender := &overlayTokenSender{transport: s.transport, ctx: ctx}
-	if err := s.streamHandler.HandleTokenStream(ctx, req, sender); err != nil {
-		s.sendError(ctx, err.Error())
+	hctx, cancelDeadline := requestDeadline(ctx, req)
+	defer cancelDeadline()
+	sender := newOverlayTokenSender(hctx, w, s.tokenSendBuffer)
+	sender.batchMax, sender.batchDelay = s.tokenBatchMax, s.tokenBatchDelay
+	// Stops the writer goroutine should the handler panic; a no-op after the
+	// flush below.
+	defer sender.flush()
+	_, handlerErr := runBeforeDeadline(hctx, func() (struct{}, error) {
+		return struct{}{}, s.streamHandler.HandleTokenStream(hctx, req, sender)
+	})
+	// Drain queued chunks before the terminating frame so it cannot overtake
+	// them.
+	flushErr := sender.flush()
+	if (handlerErr != nil || flushErr != nil) && hctx.Err() == context.DeadlineExceeded {
+		s.sendDeadlineExceeded(ctx, w)
+		return
+	}
+	if flushErr != nil {
+		log.Printf("strandapi server: send token chunk error: %v", flushErr)
+		return
+	}
+	if handlerErr != nil {
+		s.sendHandlerError(ctx, w, handlerErr)
 		return
 	}
 
-	// Send stream end
-	if err := s.transport.Send(ctx, protocol.OpTokenStreamEnd, nil); err != nil {
+	// Send stream end carrying usage for the completed stream.
+	summary := &protocol.StreamSummary{
+		PromptTokens:     promptTokens(req),
+		CompletionTokens: sender.sent,
+		FinishReason:     finishReason(req, sender.sent),
+	}
+	buf := strandbuf.NewBuffer(32)
+	summary.Encode(buf)
+	if err := w.Send(ctx, protocol.OpTokenStreamEnd, buf.Bytes()); err != nil {
 		log.Printf("strandapi server: send stream end error: %v", err)
 	}
 }
 
-// handleAgentNegotiate responds to an AGENT_NEGOTIATE frame by echoing back
-// an AGENT_NEGOTIATE with this server's own capabilities (currently empty —
-// callers may extend this by wrapping the server).
-func (s *Server) handleAgentNegotiate(ctx context.Context, payload []byte) {
+// handleCollectedInference serves a non-streaming request with the stream
+// handler, collecting its tokens into one InferenceResponse.
+func (s *Server) handleCollectedInference(ctx context.Context, w FrameWriter, req *protocol.InferenceRequest) {
+	hctx, cancelDeadline := requestDeadline(ctx, req)
+	defer cancelDeadline()
+	sender := &collectingTokenSender{}
+	_, err := runBeforeDeadline(hctx, func() (struct{}, error) {
+		return struct{}{}, s.streamHandler.HandleTokenStream(hctx, req, sender)
+	})
+	if err != nil && hctx.Err() == context.DeadlineExceeded {
+		s.sendDeadlineExceeded(ctx, w)
+		return
+	}
+	if err != nil {
+		s.sendHandlerError(ctx, w, err)
+		return
+	}
+
+	resp := sender.response(req)
+	buf := strandbuf.NewBuffer(256)
+	encodeResponse(buf, resp)
+	if err := w.Send(ctx, protocol.OpInferenceResponse, buf.Bytes()); err != nil {
+		log.Printf("strandapi server: send response error: %v", err)
+	}
+}
+
+// collectingTokenSender is the TokenSender for a stream handler serving a
+// non-streaming request. It buffers the tokens instead of sending them.
+type collectingTokenSender struct {
+	mu     sync.Mutex
+	text   strings.Builder
+	tokens uint32
+}
+
+func (c *collectingTokenSender) Send(chunk *protocol.TokenStreamChunk) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.text.WriteString(chunk.Token)
+	c.tokens++
+	return nil
+}
+
+// response builds the InferenceResponse for req from the collected tokens,
+// with usage counted as for a stream summary.
+func (c *collectingTokenSender) response(req *protocol.InferenceRequest) *protocol.InferenceResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &protocol.InferenceResponse{
+		ID:               req.ID,
+		Text:             c.text.String(),
+		FinishReason:     finishReason(req, c.tokens),
+		PromptTokens:     promptTokens(req),
+		CompletionTokens: c.tokens,
+	}
+}
+
+// handleAgentNegotiate responds to an AGENT_NEGOTIATE frame with the
+// capabilities common to the peer's request and this server's configured set
+// (see WithAgentCapabilities).
+func (s *Server) handleAgentNegotiate(ctx context.Context, w FrameWriter, payload []byte) {
 	req := &protocol.AgentNegotiate{}
 	reader := strandbuf.NewReader(payload)
 	if err := req.Decode(reader); err != nil {
@@ -233,34 +1042,63 @@ func (s *Server) handleAgentNegotiate(ctx context.Context, payload []byte) {
 	// Echo back with the same SessionID so the peer can correlate the reply.
 	resp := &protocol.AgentNegotiate{
 		SessionID:    req.SessionID,
-		Capabilities: []string{}, // Extend via higher-level server wrappers.
+		Capabilities: intersectCapabilities(req.Capabilities, s.agentCapabilities),
 		Version:      1,
 	}
 	buf := strandbuf.NewBuffer(64)
 	resp.Encode(buf)
-	if err := s.transport.Send(ctx, protocol.OpAgentNegotiate, buf.Bytes()); err != nil {
+	if err := w.Send(ctx, protocol.OpAgentNegotiate, buf.Bytes()); err != nil {
 		log.Printf("strandapi server: send agent negotiate response error: %v", err)
 	}
 }
 
+// intersectCapabilities returns the entries of requested that also appear in
+// offered, preserving the peer's order and dropping duplicates.
+func intersectCapabilities(requested, offered []string) []string {
+	have := make(map[string]bool, len(offered))
+	for _, c := range offered {
+		have[c] = true
+	}
+	common := []string{}
+	for _, c := range requested {
+		if have[c] {
+			common = append(common, c)
+			delete(have, c)
+		}
+	}
+	return common
+}
+
 // handleAgentDelegate dispatches an AGENT_DELEGATE frame to the registered
 // agentHandler. If no handler is registered, it replies with ErrCapabilities.
-func (s *Server) handleAgentDelegate(ctx context.Context, payload []byte) {
+func (s *Server) handleAgentDelegate(ctx context.Context, w FrameWriter, payload []byte) {
 	req := &protocol.AgentDelegate{}
 	reader := strandbuf.NewReader(payload)
 	if err := req.Decode(reader); err != nil {
-		s.sendAgentResult(ctx, req.SessionID, nil, protocol.ErrInvalidRequest, fmt.Sprintf("decode error: %v", err))
+		s.sendAgentResult(ctx, w, req.SessionID, nil, protocol.ErrInvalidRequest, fmt.Sprintf("decode error: %v", err))
 		return
 	}
 
 	if s.agentHandler == nil {
-		s.sendAgentResult(ctx, req.SessionID, nil, protocol.ErrCapabilities, "no agent handler registered")
+		s.sendAgentResult(ctx, w, req.SessionID, nil, protocol.ErrCapabilities, "no agent handler registered")
 		return
 	}
 
-	result, err := s.agentHandler(ctx, req)
+	if s.micVerifier != nil {
+		if err := s.authorizeDelegate(ctx, req); err != nil {
+			s.sendAgentResult(ctx, w, req.SessionID, nil, protocol.ErrCapabilities, err.Error())
+			return
+		}
+	}
+
+	result, err := s.runAgentHandler(ctx, req)
+	if errors.Is(err, context.DeadlineExceeded) {
+		s.sendAgentResult(ctx, w, req.SessionID, nil, protocol.ErrTimeout,
+			fmt.Sprintf("delegated task exceeded timeout of %dms", req.TimeoutMS))
+		return
+	}
 	if err != nil {
-		s.sendAgentResult(ctx, req.SessionID, nil, protocol.ErrInternal, err.Error())
+		s.sendAgentResult(ctx, w, req.SessionID, nil, protocol.ErrInternal, err.Error())
 		return
 	}
 
@@ -271,13 +1109,53 @@ func (s *Server) handleAgentDelegate(ctx context.Context, payload []byte) {
 	}
 	buf := strandbuf.NewBuffer(256)
 	result.Encode(buf)
-	if err := s.transport.Send(ctx, protocol.OpAgentResult, buf.Bytes()); err != nil {
+	if err := w.Send(ctx, protocol.OpAgentResult, buf.Bytes()); err != nil {
 		log.Printf("strandapi server: send agent result error: %v", err)
 	}
 }
 
+// runAgentHandler invokes the agent handler, enforcing req.TimeoutMS when it
+// is non-zero. On expiry the handler's context is cancelled and
+// context.DeadlineExceeded is returned immediately, without waiting for the
+// handler to notice.
+func (s *Server) runAgentHandler(ctx context.Context, req *protocol.AgentDelegate) (*protocol.AgentResult, error) {
+	if req.TimeoutMS == 0 {
+		return s.agentHandler(ctx, req)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(req.TimeoutMS)*time.Millisecond)
+	defer cancel()
+
+	return runBeforeDeadline(ctx, func() (*protocol.AgentResult, error) {
+		return s.agentHandler(ctx, req)
+	})
+}
+
+// authorizeDelegate checks that the delegation carries a MIC accepted by the
+// configured verifier and that the MIC grants the requested task type.
+func (s *Server) authorizeDelegate(ctx context.Context, req *protocol.AgentDelegate) error {
+	taskType := req.Metadata[protocol.DelegateMetaTaskType]
+	if taskType == "" {
+		return fmt.Errorf("delegation missing %q metadata", protocol.DelegateMetaTaskType)
+	}
+	mic := req.Metadata[protocol.DelegateMetaMIC]
+	if mic == "" {
+		return fmt.Errorf("delegation missing %q metadata", protocol.DelegateMetaMIC)
+	}
+	caps, err := s.micVerifier(ctx, mic)
+	if err != nil {
+		return fmt.Errorf("MIC rejected: %v", err)
+	}
+	for _, c := range caps {
+		if c == taskType {
+			return nil
+		}
+	}
+	return fmt.Errorf("MIC does not grant capability %q", taskType)
+}
+
 // sendAgentResult is a helper that encodes and sends an AgentResult frame.
-func (s *Server) sendAgentResult(ctx context.Context, sessionID uint32, payload []byte, code uint16, msg string) {
+func (s *Server) sendAgentResult(ctx context.Context, w FrameWriter, sessionID uint32, payload []byte, code uint16, msg string) {
 	result := &protocol.AgentResult{
 		SessionID:     sessionID,
 		ResultPayload: payload,
@@ -286,28 +1164,224 @@ func (s *Server) sendAgentResult(ctx context.Context, sessionID uint32, payload
 	}
 	buf := strandbuf.NewBuffer(128)
 	result.Encode(buf)
-	if err := s.transport.Send(ctx, protocol.OpAgentResult, buf.Bytes()); err != nil {
+	if err := w.Send(ctx, protocol.OpAgentResult, buf.Bytes()); err != nil {
 		log.Printf("strandapi server: send agent result error: %v", err)
 	}
 }
 
-func (s *Server) handleHeartbeat(ctx context.Context) {
-	// Reply with a heartbeat.
-	_ = s.transport.Send(ctx, protocol.OpHeartbeat, nil)
+func (s *Server) handleHeartbeat(ctx context.Context, w FrameWriter, payload []byte) {
+	health, err := protocol.ParseHeartbeat(payload)
+	if err != nil {
+		log.Printf("strandapi server: %v", err)
+	} else if health != nil && s.healthObserver != nil {
+		s.healthObserver(ctx, *health)
+	}
+
+	// Reply with a heartbeat, carrying our own health if we report it.
+	var reply []byte
+	if s.healthReporter != nil {
+		h := s.healthReporter()
+		buf := strandbuf.NewBuffer(12)
+		h.Encode(buf)
+		reply = buf.Bytes()
+	}
+	_ = w.Send(ctx, protocol.OpHeartbeat, reply)
+}
+
+func (s *Server) sendError(ctx context.Context, w FrameWriter, code uint16, msg string) {
+	e := &protocol.ErrorMessage{Code: code, Message: msg}
+	_ = w.Send(ctx, protocol.OpError, e.Payload())
 }
 
-func (s *Server) sendError(ctx context.Context, msg string) {
-	_ = s.transport.Send(ctx, protocol.OpError, []byte(msg))
+// sendHandlerError reports an error returned by an inference handler. A
+// handler may return a *protocol.ErrorMessage to pick the code; otherwise
+// cancellation maps to ErrCancelled, a context deadline to ErrTimeout and
+// anything else to ErrInternal.
+func (s *Server) sendHandlerError(ctx context.Context, w FrameWriter, err error) {
+	var em *protocol.ErrorMessage
+	switch {
+	case errors.As(err, &em):
+		s.sendError(ctx, w, em.Code, em.Message)
+	case errors.Is(err, context.Canceled):
+		s.sendError(ctx, w, protocol.ErrCancelled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		s.sendError(ctx, w, protocol.ErrTimeout, err.Error())
+	default:
+		s.sendError(ctx, w, protocol.ErrInternal, err.Error())
+	}
 }
 
-// overlayTokenSender implements TokenSender over an OverlayTransport.
+// overlayTokenSender implements TokenSender and PartialSender over the
+// FrameWriter of the request's frame. Send queues a copy of the chunk for a writer goroutine,
+// blocking once the queue is full so a fast handler is paced by the
+// transport. The first transport error stops the writer and is returned by
+// every later Send.
 type overlayTokenSender struct {
-	transport *transport.OverlayTransport
-	ctx       context.Context
+	w     FrameWriter
+	ctx   context.Context
+	queue chan streamFrame
+	done  chan struct{} // closed when the writer goroutine exits
+
+	// batchMax > 1 enables coalescing of chunks queued within batchDelay
+	// into OpTokenStreamBatch frames (WithTokenBatching). Set before the
+	// first Send.
+	batchMax   int
+	batchDelay time.Duration
+
+	// closeMu guards queue against a Send racing with flush.
+	closeMu sync.RWMutex
+	closed  bool
+
+	mu   sync.Mutex
+	err  error
+	sent uint32 // chunks successfully sent, reported in the StreamSummary
+}
+
+// newOverlayTokenSender starts a sender whose queue holds up to depth chunks.
+func newOverlayTokenSender(ctx context.Context, w FrameWriter, depth int) *overlayTokenSender {
+	s := &overlayTokenSender{
+		w:     w,
+		ctx:   ctx,
+		queue: make(chan streamFrame, depth),
+		done:  make(chan struct{}),
+	}
+	go s.writeLoop()
+	return s
+}
+
+// streamFrame is a queued token chunk or, if partial is set, a partial
+// response.
+type streamFrame struct {
+	chunk   protocol.TokenStreamChunk
+	partial *protocol.PartialResponse
 }
 
 func (s *overlayTokenSender) Send(chunk *protocol.TokenStreamChunk) error {
-	buf := strandbuf.NewBuffer(128)
-	chunk.Encode(buf)
-	return s.transport.Send(s.ctx, protocol.OpTokenStreamChunk, buf.Bytes())
+	return s.enqueue(streamFrame{chunk: *chunk})
+}
+
+// SendPartial queues a copy of partial behind the chunks already sent.
+func (s *overlayTokenSender) SendPartial(partial *protocol.PartialResponse) error {
+	p := *partial
+	return s.enqueue(streamFrame{partial: &p})
+}
+
+func (s *overlayTokenSender) enqueue(f streamFrame) error {
+	if err := s.failure(); err != nil {
+		return err
+	}
+
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+	if s.closed {
+		return errors.New("strandapi server: token stream already ended")
+	}
+	select {
+	case s.queue <- f:
+		return nil
+	case <-s.done:
+		return s.failure()
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// writeLoop writes queued frames until the queue is closed or a write fails.
+func (s *overlayTokenSender) writeLoop() {
+	defer close(s.done)
+	for f := range s.queue {
+		if f.partial == nil {
+			batch := []protocol.TokenStreamChunk{f.chunk}
+			var next *streamFrame
+			if s.batchMax > 1 {
+				batch, next = s.collect(batch)
+			}
+			if !s.record(s.write(batch), len(batch)) {
+				return
+			}
+			if next == nil {
+				continue
+			}
+			f = *next
+		}
+		if !s.record(s.writePartial(f.partial), 0) {
+			return
+		}
+	}
+}
+
+// record notes the outcome of a write of n chunks, reporting false if it
+// failed and the writer must stop.
+func (s *overlayTokenSender) record(err error, n int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.err = err
+		return false
+	}
+	s.sent += uint32(n)
+	return true
+}
+
+// collect extends batch with chunks queued within batchDelay, up to batchMax.
+// A partial response ends the batch early and is returned as next, to be
+// written after it.
+func (s *overlayTokenSender) collect(batch []protocol.TokenStreamChunk) ([]protocol.TokenStreamChunk, *streamFrame) {
+	timer := time.NewTimer(s.batchDelay)
+	defer timer.Stop()
+	for len(batch) < s.batchMax {
+		select {
+		case f, ok := <-s.queue:
+			if !ok {
+				return batch, nil
+			}
+			if f.partial != nil {
+				return batch, &f
+			}
+			batch = append(batch, f.chunk)
+		case <-timer.C:
+			return batch, nil
+		}
+	}
+	return batch, nil
+}
+
+// writePartial sends p as an OpPartialResponse frame.
+func (s *overlayTokenSender) writePartial(p *protocol.PartialResponse) error {
+	buf := strandbuf.NewBuffer(32 + len(p.Text))
+	p.Encode(buf)
+	return s.w.Send(s.ctx, protocol.OpPartialResponse, buf.Bytes())
+}
+
+// write sends batch as one OpTokenStreamChunk frame, or as an
+// OpTokenStreamBatch frame when it holds more than one chunk.
+func (s *overlayTokenSender) write(batch []protocol.TokenStreamChunk) error {
+	buf := strandbuf.NewBuffer(128 * len(batch))
+	if len(batch) == 1 {
+		batch[0].Encode(buf)
+		return s.w.Send(s.ctx, protocol.OpTokenStreamChunk, buf.Bytes())
+	}
+	msg := &protocol.TokenStreamBatch{Chunks: batch}
+	msg.Encode(buf)
+	return s.w.Send(s.ctx, protocol.OpTokenStreamBatch, buf.Bytes())
+}
+
+// failure returns the error that stopped the writer, if any.
+func (s *overlayTokenSender) failure() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// flush stops accepting chunks, waits for the queue to drain and returns the
+// first transport error. Send calls after flush fail.
+func (s *overlayTokenSender) flush() error {
+	s.closeMu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.closeMu.Unlock()
+	<-s.done
+	return s.failure()
 }
@@ -3,18 +3,33 @@ package strandbuf
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
+	"io"
 	"math"
 )
 
 var (
 	// ErrShortBuffer is returned when the Reader has fewer bytes than required.
 	ErrShortBuffer = errors.New("strandbuf: insufficient data in buffer")
+	// ErrStringTooLong is returned when a string's declared length exceeds the
+	// limit set with SetMaxString.
+	ErrStringTooLong = errors.New("strandbuf: string exceeds maximum length")
 )
 
+// fillChunk bounds how much a stream Reader allocates ahead of the data
+// actually received, so a bogus length prefix cannot force a huge allocation.
+const fillChunk = 64 << 10
+
 // Reader provides sequential, zero-copy decoding of StrandBuf-encoded data.
 type Reader struct {
 	data   []byte
 	offset int
+	// maxString caps the declared length ReadString accepts; 0 is unlimited.
+	maxString int
+	// src, when non-nil, supplies further data on demand (NewStreamReader).
+	src io.Reader
+	// base counts the bytes already discarded from the front of data.
+	base int
 }
 
 // NewReader wraps an existing byte slice for decoding.
@@ -22,20 +37,133 @@ func NewReader(data []byte) *Reader {
 	return &Reader{data: data}
 }
 
-// Remaining returns the number of unread bytes.
+// NewStreamReader returns a Reader that pulls data from src as decoding needs
+// it, so a message can be decoded straight off a connection without buffering
+// it first. It reads exactly the bytes each field requires, retrying short
+// reads, and never reads past the last field decoded. Data ending mid-field
+// yields ErrShortBuffer; other src errors are returned unchanged.
+//
+// Remaining reads src to EOF, so wrap src in an io.LimitReader of the frame
+// length when decoding messages with optional trailing fields.
+func NewStreamReader(src io.Reader) *Reader {
+	return &Reader{src: src}
+}
+
+// Reset makes r read data from the start, as if it had just been returned by
+// NewReader(data): the position, any stream source and the SetMaxString
+// limit are cleared. It lets a pooled Reader decode many frames without
+// allocating a new one for each.
+func (r *Reader) Reset(data []byte) {
+	*r = Reader{data: data}
+}
+
+// Remaining returns the number of unread bytes. For a stream Reader this
+// first reads the rest of the source.
 func (r *Reader) Remaining() int {
+	if r.src != nil {
+		r.drain()
+	}
 	return len(r.data) - r.offset
 }
 
 // Offset returns the current read position.
 func (r *Reader) Offset() int {
-	return r.offset
+	return r.base + r.offset
+}
+
+// fill reads from src until at least n unread bytes are buffered. Slices
+// returned earlier alias data, so the unread tail moves to a new array rather
+// than being compacted in place.
+func (r *Reader) fill(n int) error {
+	if r.src == nil {
+		return ErrShortBuffer
+	}
+	unread := len(r.data) - r.offset
+	buf := make([]byte, unread, max(unread, min(n, fillChunk)))
+	copy(buf, r.data[r.offset:])
+	r.base += r.offset
+	r.offset = 0
+	defer func() { r.data = buf }()
+
+	for len(buf) < n {
+		chunk := min(n-len(buf), fillChunk)
+		if cap(buf)-len(buf) < chunk {
+			grown := make([]byte, len(buf), len(buf)+max(chunk, len(buf)))
+			copy(grown, buf)
+			buf = grown
+		}
+		got, err := io.ReadFull(r.src, buf[len(buf):len(buf)+chunk])
+		buf = buf[:len(buf)+got]
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrShortBuffer
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drain buffers everything left in src and turns r into a plain byte Reader
+// once src reports EOF.
+func (r *Reader) drain() {
+	rest, err := io.ReadAll(r.src)
+	if len(rest) > 0 {
+		buf := make([]byte, 0, len(r.data)-r.offset+len(rest))
+		buf = append(buf, r.data[r.offset:]...)
+		r.base += r.offset
+		r.offset = 0
+		r.data = append(buf, rest...)
+	}
+	if err == nil {
+		r.src = nil
+	}
+}
+
+// SetMaxString caps the declared length, in bytes, that ReadString and
+// ReadStringView accept. Longer strings fail with ErrStringTooLong before any
+// data is consumed past the length prefix. n <= 0 removes the limit.
+func (r *Reader) SetMaxString(n int) {
+	if n < 0 {
+		n = 0
+	}
+	r.maxString = n
+}
+
+// MaxString returns the limit set with SetMaxString, or 0 if there is none.
+func (r *Reader) MaxString() int {
+	return r.maxString
+}
+
+// Peek returns the next n bytes without consuming them. The slice aliases the
+// Reader's underlying buffer.
+func (r *Reader) Peek(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, ErrShortBuffer
+	}
+	if r.offset+n > len(r.data) {
+		if err := r.fill(n); err != nil {
+			return nil, err
+		}
+	}
+	return r.data[r.offset : r.offset+n], nil
+}
+
+// Skip advances past the next n bytes.
+func (r *Reader) Skip(n int) error {
+	if n < 0 {
+		return ErrShortBuffer
+	}
+	_, err := r.need(n)
+	return err
 }
 
 // need checks that at least n bytes remain and returns the current offset.
 func (r *Reader) need(n int) (int, error) {
 	if r.offset+n > len(r.data) {
-		return 0, ErrShortBuffer
+		if err := r.fill(n); err != nil {
+			return 0, err
+		}
 	}
 	off := r.offset
 	r.offset += n
@@ -99,15 +227,36 @@ func (r *Reader) ReadFloat64() (float64, error) {
 // ReadString reads a length-prefixed UTF-8 string. The returned string holds
 // its own copy of the data (safe after the Reader is discarded).
 func (r *Reader) ReadString() (string, error) {
-	length, err := r.ReadUint32()
+	b, err := r.readStringBytes()
 	if err != nil {
 		return "", err
 	}
+	return string(b), nil
+}
+
+// ReadStringView reads a length-prefixed UTF-8 string without copying it. The
+// returned slice aliases the Reader's underlying buffer and is only valid
+// while that buffer is alive and unmodified; use it on hot decode paths that
+// immediately compare or hash the value, and ReadString to keep it.
+func (r *Reader) ReadStringView() ([]byte, error) {
+	return r.readStringBytes()
+}
+
+// readStringBytes reads a length-prefixed string as a sub-slice of the
+// buffer, enforcing the SetMaxString limit.
+func (r *Reader) readStringBytes() ([]byte, error) {
+	length, err := r.ReadUint32()
+	if err != nil {
+		return nil, err
+	}
+	if r.maxString > 0 && uint64(length) > uint64(r.maxString) {
+		return nil, fmt.Errorf("%w: declared %d bytes, limit %d", ErrStringTooLong, length, r.maxString)
+	}
 	off, err := r.need(int(length))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return string(r.data[off : off+int(length)]), nil
+	return r.data[off : off+int(length)], nil
 }
 
 // ReadBytes reads a length-prefixed byte slice. The returned slice is a
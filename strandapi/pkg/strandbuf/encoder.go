@@ -49,6 +49,22 @@ func (b *Buffer) grow(n int) int {
 	return off
 }
 
+// Reserve appends n zero bytes and returns their offset, so a value known
+// only after the following data is written (typically a length prefix) can be
+// filled in later with PatchUint32.
+func (b *Buffer) Reserve(n int) int {
+	off := b.grow(n)
+	clear(b.data[off : off+n])
+	return off
+}
+
+// PatchUint32 overwrites 4 bytes at offset with v in little-endian order. The
+// offset is usually one returned by Reserve(4). It panics if the range is
+// outside the bytes written so far.
+func (b *Buffer) PatchUint32(offset int, v uint32) {
+	binary.LittleEndian.PutUint32(b.data[offset:], v)
+}
+
 // WriteUint8 appends a single byte.
 func (b *Buffer) WriteUint8(v uint8) {
 	off := b.grow(1)
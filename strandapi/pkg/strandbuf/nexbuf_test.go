@@ -1,8 +1,12 @@
 package strandbuf
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"math"
 	"testing"
+	"testing/iotest"
 )
 
 func TestUint8RoundTrip(t *testing.T) {
@@ -137,6 +141,35 @@ func TestStringRoundTrip(t *testing.T) {
 	}
 }
 
+func TestReadStringMaxLength(t *testing.T) {
+	buf := NewBuffer(64)
+	buf.WriteString("short")
+	buf.WriteString("this one is too long")
+
+	r := NewReader(buf.Bytes())
+	r.SetMaxString(8)
+	if got, err := r.ReadString(); err != nil || got != "short" {
+		t.Fatalf("ReadString = %q, %v; want \"short\"", got, err)
+	}
+	if _, err := r.ReadString(); !errors.Is(err, ErrStringTooLong) {
+		t.Fatalf("ReadString over limit: err = %v, want ErrStringTooLong", err)
+	}
+
+	// The view variant honours the same limit.
+	r = NewReader(buf.Bytes())
+	r.SetMaxString(4)
+	if _, err := r.ReadStringView(); !errors.Is(err, ErrStringTooLong) {
+		t.Fatalf("ReadStringView over limit: err = %v, want ErrStringTooLong", err)
+	}
+
+	// Raw bytes are not strings and are not capped.
+	r = NewReader(buf.Bytes())
+	r.SetMaxString(1)
+	if _, err := r.ReadBytes(); err != nil {
+		t.Fatalf("ReadBytes with string limit: %v", err)
+	}
+}
+
 func TestBytesRoundTrip(t *testing.T) {
 	buf := NewBuffer(64)
 	values := [][]byte{{}, {0x00}, {0xDE, 0xAD, 0xBE, 0xEF}, make([]byte, 256)}
@@ -382,3 +415,226 @@ func TestReadBytesZeroCopy(t *testing.T) {
 		t.Errorf("ReadBytes did not return a zero-copy sub-slice")
 	}
 }
+
+func TestReservePatchLengthPrefix(t *testing.T) {
+	buf := NewBuffer(8)
+	buf.WriteUint8(0x7E) // leading tag before the framed body
+
+	lenOff := buf.Reserve(4)
+	bodyStart := buf.Len()
+	buf.WriteString("hello")
+	buf.WriteUint64(42)
+	buf.PatchUint32(lenOff, uint32(buf.Len()-bodyStart))
+
+	r := NewReader(buf.Bytes())
+	tag, err := r.ReadUint8()
+	if err != nil || tag != 0x7E {
+		t.Fatalf("tag = %#x, %v; want 0x7e", tag, err)
+	}
+	body, err := r.ReadBytes()
+	if err != nil {
+		t.Fatalf("ReadBytes: %v", err)
+	}
+	if r.Remaining() != 0 {
+		t.Errorf("Remaining = %d after framed body, want 0", r.Remaining())
+	}
+
+	br := NewReader(body)
+	s, err := br.ReadString()
+	if err != nil || s != "hello" {
+		t.Fatalf("ReadString = %q, %v; want hello", s, err)
+	}
+	v, err := br.ReadUint64()
+	if err != nil || v != 42 {
+		t.Fatalf("ReadUint64 = %d, %v; want 42", v, err)
+	}
+}
+
+func TestReserveZeroesReusedCapacity(t *testing.T) {
+	buf := NewBuffer(8)
+	buf.WriteUint64(math.MaxUint64)
+	buf.Reset()
+
+	off := buf.Reserve(4)
+	if off != 0 {
+		t.Fatalf("Reserve offset = %d, want 0", off)
+	}
+	for i, b := range buf.Bytes() {
+		if b != 0 {
+			t.Errorf("reserved byte %d = %#x, want 0", i, b)
+		}
+	}
+}
+
+func TestPatchUint32OutOfRangePanics(t *testing.T) {
+	buf := NewBuffer(8)
+	buf.Reserve(2)
+	defer func() {
+		if recover() == nil {
+			t.Error("PatchUint32 past the written bytes did not panic")
+		}
+	}()
+	buf.PatchUint32(0, 1)
+}
+
+func TestReadStringViewAliasesBuffer(t *testing.T) {
+	buf := NewBuffer(32)
+	buf.WriteString("model")
+	buf.WriteString("gpt")
+	data := buf.Bytes()
+
+	r := NewReader(data)
+	v, err := r.ReadStringView()
+	if err != nil {
+		t.Fatalf("ReadStringView: %v", err)
+	}
+	if string(v) != "model" {
+		t.Fatalf("ReadStringView = %q, want model", v)
+	}
+	// The view points into data: the string body starts after the 4-byte
+	// length prefix.
+	if &v[0] != &data[4] {
+		t.Error("ReadStringView did not return a view of the underlying buffer")
+	}
+	data[4] = 'M'
+	if string(v) != "Model" {
+		t.Errorf("view = %q after mutating buffer, want Model", v)
+	}
+
+	// Reading continues after the viewed string.
+	s, err := r.ReadString()
+	if err != nil || s != "gpt" {
+		t.Fatalf("ReadString after view = %q, %v; want gpt", s, err)
+	}
+
+	if _, err := NewReader([]byte{5, 0, 0, 0, 'a'}).ReadStringView(); err != ErrShortBuffer {
+		t.Errorf("short view: got %v, want ErrShortBuffer", err)
+	}
+}
+
+func TestPeekAndSkip(t *testing.T) {
+	r := NewReader([]byte{1, 2, 3, 4})
+	p, err := r.Peek(2)
+	if err != nil || len(p) != 2 || p[0] != 1 || p[1] != 2 {
+		t.Fatalf("Peek(2) = %v, %v", p, err)
+	}
+	if r.Offset() != 0 {
+		t.Fatalf("Peek advanced offset to %d", r.Offset())
+	}
+	if err := r.Skip(3); err != nil {
+		t.Fatalf("Skip(3): %v", err)
+	}
+	if v, err := r.ReadUint8(); err != nil || v != 4 {
+		t.Fatalf("ReadUint8 after Skip = %d, %v; want 4", v, err)
+	}
+	if _, err := r.Peek(1); err != ErrShortBuffer {
+		t.Fatalf("Peek past end: err = %v, want ErrShortBuffer", err)
+	}
+	if err := r.Skip(1); err != ErrShortBuffer {
+		t.Fatalf("Skip past end: err = %v, want ErrShortBuffer", err)
+	}
+}
+
+func TestStreamReader(t *testing.T) {
+	buf := NewBuffer(32)
+	buf.WriteUint32(7)
+	buf.WriteString("hello")
+	buf.WriteBytes([]byte{1, 2, 3})
+	buf.WriteUint8(9) // belongs to the next message
+
+	src := bytes.NewReader(buf.Bytes())
+	r := NewStreamReader(iotest.OneByteReader(src))
+	if v, err := r.ReadUint32(); err != nil || v != 7 {
+		t.Fatalf("ReadUint32 = %d, %v; want 7", v, err)
+	}
+	s, err := r.ReadStringView()
+	if err != nil || string(s) != "hello" {
+		t.Fatalf("ReadStringView = %q, %v; want hello", s, err)
+	}
+	b, err := r.ReadBytes()
+	if err != nil || !bytes.Equal(b, []byte{1, 2, 3}) {
+		t.Fatalf("ReadBytes = %v, %v", b, err)
+	}
+	// Earlier zero-copy results stay valid as the reader refills.
+	if string(s) != "hello" {
+		t.Errorf("string view changed to %q after later reads", s)
+	}
+	if r.Offset() != buf.Len()-1 {
+		t.Errorf("Offset = %d, want %d", r.Offset(), buf.Len()-1)
+	}
+	if src.Len() != 1 {
+		t.Errorf("stream reader consumed %d bytes past the decoded fields", 1-src.Len())
+	}
+}
+
+func TestStreamReaderShortAndFailingSource(t *testing.T) {
+	r := NewStreamReader(bytes.NewReader([]byte{5, 0, 0, 0, 'a', 'b'}))
+	if _, err := r.ReadString(); err != ErrShortBuffer {
+		t.Errorf("truncated stream: err = %v, want ErrShortBuffer", err)
+	}
+
+	boom := errors.New("connection reset")
+	r = NewStreamReader(io.MultiReader(bytes.NewReader([]byte{1, 0}), iotest.ErrReader(boom)))
+	if _, err := r.ReadUint32(); err != boom {
+		t.Errorf("failing source: err = %v, want %v", err, boom)
+	}
+
+	// A bogus length prefix fails on the data, not on a 4 GiB allocation.
+	r = NewStreamReader(bytes.NewReader([]byte{0xFF, 0xFF, 0xFF, 0xFF, 'x'}))
+	if _, err := r.ReadBytes(); err != ErrShortBuffer {
+		t.Errorf("huge length prefix: err = %v, want ErrShortBuffer", err)
+	}
+}
+
+func TestStreamReaderRemaining(t *testing.T) {
+	r := NewStreamReader(iotest.OneByteReader(bytes.NewReader([]byte{1, 2, 3, 4})))
+	if _, err := r.Peek(1); err != nil {
+		t.Fatalf("Peek: %v", err)
+	}
+	if n := r.Remaining(); n != 4 {
+		t.Errorf("Remaining = %d, want 4", n)
+	}
+	if v, err := r.ReadUint32(); err != nil || v != 0x04030201 {
+		t.Errorf("ReadUint32 = %#x, %v", v, err)
+	}
+	if n := r.Remaining(); n != 0 {
+		t.Errorf("Remaining after read = %d, want 0", n)
+	}
+}
+
+func TestReaderReset(t *testing.T) {
+	first := NewBuffer(16)
+	first.WriteString("first")
+	first.WriteUint32(7)
+	second := NewBuffer(16)
+	second.WriteString("second")
+
+	r := NewStreamReader(bytes.NewReader(first.Bytes()))
+	r.SetMaxString(3)
+	if _, err := r.ReadString(); !errors.Is(err, ErrStringTooLong) {
+		t.Fatalf("ReadString with limit 3: err = %v, want ErrStringTooLong", err)
+	}
+
+	// Reuse the partly read stream reader on a byte slice.
+	r.Reset(second.Bytes())
+	if r.Offset() != 0 || r.MaxString() != 0 {
+		t.Errorf("after Reset: Offset = %d, MaxString = %d, want 0, 0", r.Offset(), r.MaxString())
+	}
+	if s, err := r.ReadString(); err != nil || s != "second" {
+		t.Fatalf("ReadString after Reset = %q, %v", s, err)
+	}
+	if _, err := r.ReadUint8(); !errors.Is(err, ErrShortBuffer) {
+		t.Errorf("read past the reset data: err = %v, want ErrShortBuffer", err)
+	}
+
+	r.Reset(first.Bytes())
+	if s, err := r.ReadString(); err != nil || s != "first" {
+		t.Fatalf("ReadString after second Reset = %q, %v", s, err)
+	}
+	if v, err := r.ReadUint32(); err != nil || v != 7 {
+		t.Errorf("ReadUint32 = %d, %v, want 7", v, err)
+	}
+	if n := r.Remaining(); n != 0 {
+		t.Errorf("Remaining = %d, want 0", n)
+	}
+}
@@ -0,0 +1,116 @@
+package transport
+
+import (
+	"context"
+	"net"
+)
+
+// PeerStreamTransport is a transport that reports which peer and logical
+// stream each frame belongs to and can address frames to any peer.
+// OverlayTransport implements it.
+type PeerStreamTransport interface {
+	SendStreamTo(ctx context.Context, to net.Addr, streamID uint32, opcode byte, payload []byte) error
+	RecvStreamFrom(ctx context.Context) (from net.Addr, streamID uint32, opcode byte, payload []byte, err error)
+	Close() error
+}
+
+// Demux fans frames from one PeerStreamTransport out to per-peer, per-stream
+// PeerStreams. It is the listener-side counterpart of Session and shares its
+// reader: a single background goroutine owns the socket and is the only
+// caller of RecvStreamFrom, so application code never reads the transport
+// directly and many consumers can wait for frames concurrently. Frames from a
+// (peer, stream) pair not seen before create a PeerStream that is handed out
+// by Accept.
+//
+// Delivery never drops frames, so a stalled consumer stalls the reader: one
+// PeerStream that stops reading blocks delivery to every other once its
+// queue of 64 frames is full, and new peer streams wait for Accept once 16
+// are pending.
+type Demux struct {
+	t PeerStreamTransport
+	f *fanout[demuxKey, *PeerStream]
+}
+
+// demuxKey identifies a PeerStream by peer address and stream ID.
+type demuxKey struct {
+	peer   string
+	stream uint32
+}
+
+// NewDemux starts fanning out frames read from t. The demux takes ownership
+// of t and closes it when the demux is closed.
+func NewDemux(t PeerStreamTransport) *Demux {
+	d := &Demux{t: t}
+	d.f = newFanout(
+		func(ctx context.Context) (demuxKey, net.Addr, frame, error) {
+			from, id, opcode, payload, err := t.RecvStreamFrom(ctx)
+			if err != nil {
+				return demuxKey{}, nil, frame{}, err
+			}
+			return demuxKey{peer: from.String(), stream: id}, from, frame{opcode: opcode, payload: payload}, nil
+		},
+		func(key demuxKey, peer net.Addr, q *queue) *PeerStream {
+			return &PeerStream{key: key, peer: peer, demux: d, q: q}
+		},
+		t.Close,
+	)
+	return d
+}
+
+// Open returns the PeerStream for the given peer and stream ID, creating it
+// if needed, so the local side can start a conversation.
+func (d *Demux) Open(peer net.Addr, streamID uint32) (*PeerStream, error) {
+	return d.f.open(demuxKey{peer: peer.String(), stream: streamID}, peer)
+}
+
+// Accept blocks until a frame arrives from a peer and stream that have not
+// been opened locally, and returns the new PeerStream.
+func (d *Demux) Accept(ctx context.Context) (*PeerStream, error) {
+	return d.f.acceptStream(ctx)
+}
+
+// Err returns the error that terminated the demux reader, if any.
+func (d *Demux) Err() error { return d.f.Err() }
+
+// Close shuts down the demux, all of its PeerStreams, and the underlying
+// transport.
+func (d *Demux) Close() error { return d.f.close() }
+
+// PeerStream is one logical stream with one peer of a Demux. It implements
+// Transport: Send replies to that peer on that stream, and Recv returns only
+// frames the peer sent on it.
+type PeerStream struct {
+	key   demuxKey
+	peer  net.Addr
+	demux *Demux
+	q     *queue
+}
+
+var _ Transport = (*PeerStream)(nil)
+
+// Peer returns the address of the remote peer.
+func (ps *PeerStream) Peer() net.Addr { return ps.peer }
+
+// ID returns the logical stream identifier.
+func (ps *PeerStream) ID() uint32 { return ps.key.stream }
+
+// Send transmits a frame to the peer on this stream.
+func (ps *PeerStream) Send(ctx context.Context, opcode byte, payload []byte) error {
+	if err := ps.q.checkOpen(); err != nil {
+		return err
+	}
+	return ps.demux.t.SendStreamTo(ctx, ps.peer, ps.key.stream, opcode, payload)
+}
+
+// Recv blocks until the peer sends a frame on this stream.
+func (ps *PeerStream) Recv(ctx context.Context) (byte, []byte, error) {
+	return ps.q.next(ctx)
+}
+
+// Close detaches the stream from its demux. It does not close the demux or
+// notify the peer; frames that arrive from the same peer and stream
+// afterwards are dropped unless it is opened again.
+func (ps *PeerStream) Close() error {
+	ps.q.close()
+	return nil
+}
@@ -0,0 +1,200 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDemuxFansOutPerPeerStream(t *testing.T) {
+	listener, err := ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	demux := NewDemux(listener)
+	defer demux.Close()
+
+	const (
+		peers     = 6
+		streams   = 4
+		perStream = 30
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Each accepted PeerStream checks that every frame it receives belongs to
+	// it and in order, then echoes the frame back.
+	var (
+		mu       sync.Mutex
+		received = map[string]int{} // payload prefix -> frames seen
+		serveWG  sync.WaitGroup
+	)
+	serve := func(ps *PeerStream) {
+		defer serveWG.Done()
+		var prefix string
+		for i := 0; i < perStream; i++ {
+			op, payload, err := ps.Recv(ctx)
+			if err != nil {
+				t.Errorf("peer %s stream %d Recv %d: %v", ps.Peer(), ps.ID(), i, err)
+				return
+			}
+			var p, s, seq int
+			if _, err := fmt.Sscanf(string(payload), "p%d/s%d/%d", &p, &s, &seq); err != nil {
+				t.Errorf("peer %s stream %d: bad payload %q", ps.Peer(), ps.ID(), payload)
+				return
+			}
+			if i == 0 {
+				prefix = fmt.Sprintf("p%d/s%d", p, s)
+			}
+			if got := fmt.Sprintf("p%d/s%d", p, s); got != prefix || uint32(s) != ps.ID() || seq != i || op != byte(s) {
+				t.Errorf("peer %s stream %d frame %d = %q (opcode 0x%02x): misrouted or out of order",
+					ps.Peer(), ps.ID(), i, payload, op)
+				return
+			}
+			if err := ps.Send(ctx, op, payload); err != nil {
+				t.Errorf("peer %s stream %d Send: %v", ps.Peer(), ps.ID(), err)
+				return
+			}
+		}
+		mu.Lock()
+		received[prefix] += perStream
+		mu.Unlock()
+	}
+	go func() {
+		for {
+			ps, err := demux.Accept(ctx)
+			if err != nil {
+				return
+			}
+			serveWG.Add(1)
+			go serve(ps)
+		}
+	}()
+
+	// Each peer drives several streams concurrently in lockstep with the
+	// echoes, so every stream has at most one frame in flight.
+	var clientWG sync.WaitGroup
+	for p := 0; p < peers; p++ {
+		dialer, err := DialOverlay(listener.LocalAddr().String())
+		if err != nil {
+			t.Fatalf("DialOverlay: %v", err)
+		}
+		sess := NewSession(dialer)
+		defer sess.Close()
+		for s := 1; s <= streams; s++ {
+			st, err := sess.Open(uint32(s))
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			clientWG.Add(1)
+			go func(p, s int, st *Stream) {
+				defer clientWG.Done()
+				for i := 0; i < perStream; i++ {
+					want := fmt.Sprintf("p%d/s%d/%d", p, s, i)
+					if err := st.Send(ctx, byte(s), []byte(want)); err != nil {
+						t.Errorf("%s Send: %v", want, err)
+						return
+					}
+					_, payload, err := st.Recv(ctx)
+					if err != nil {
+						t.Errorf("%s echo Recv: %v", want, err)
+						return
+					}
+					if string(payload) != want {
+						t.Errorf("echo = %q, want %q", payload, want)
+						return
+					}
+				}
+			}(p, s, st)
+		}
+	}
+	clientWG.Wait()
+	serveWG.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != peers*streams {
+		t.Errorf("served %d peer streams, want %d", len(received), peers*streams)
+	}
+	for prefix, n := range received {
+		if n != perStream {
+			t.Errorf("%s: %d frames, want %d", prefix, n, perStream)
+		}
+	}
+}
+
+func TestDemuxCloseUnblocksStreams(t *testing.T) {
+	listener, err := ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	demux := NewDemux(listener)
+
+	ps, err := demux.Open(listener.LocalAddr(), 3)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		_, _, err := ps.Recv(context.Background())
+		errCh <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	demux.Close()
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Error("expected error from Recv after demux close")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Recv did not unblock after demux close")
+	}
+	if _, err := demux.Accept(context.Background()); err == nil {
+		t.Error("expected error from Accept on a closed demux")
+	}
+	if _, err := demux.Open(listener.LocalAddr(), 4); err == nil {
+		t.Error("expected error opening a stream on a closed demux")
+	}
+}
+
+func TestDemuxSkipsRejectedDatagrams(t *testing.T) {
+	listener, err := ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	demux := NewDemux(listener)
+	defer demux.Close()
+
+	raw, err := net.Dial("udp", listener.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("net.Dial: %v", err)
+	}
+	defer raw.Close()
+	if _, err := raw.Write([]byte("garbage")); err != nil {
+		t.Fatalf("write garbage: %v", err)
+	}
+
+	dialer, err := DialOverlay(listener.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("DialOverlay: %v", err)
+	}
+	defer dialer.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := dialer.SendStream(ctx, 7, 0x01, []byte("after")); err != nil {
+		t.Fatalf("SendStream: %v", err)
+	}
+
+	ps, err := demux.Accept(ctx)
+	if err != nil {
+		t.Fatalf("Accept after a rejected datagram: %v", err)
+	}
+	if _, payload, err := ps.Recv(ctx); err != nil || string(payload) != "after" {
+		t.Fatalf("Recv = %q, %v; want %q", payload, err, "after")
+	}
+}
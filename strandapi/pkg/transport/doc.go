@@ -10,6 +10,22 @@
 //   - Custom 8-byte overlay frame header (2B magic + 1B version + 1B flags + 4B length)
 //   - UDP send/recv with context cancellation and deadline support
 //   - Magic byte and version validation
+//   - Optional 4-byte stream ID header extension (FlagStreamID), with Session
+//     multiplexing many logical Streams over one socket
+//   - Optional 8-byte frame trace ID header extension (FlagTraceID), logged on
+//     both ends when SetTraceLogger is enabled
+//   - Optional trailing ed25519 frame signature (FlagSignature), added with
+//     WithFrameSigning and enforced with WithFrameVerification
+//   - Binding to a named network interface (WithInterface)
+//   - Kernel socket buffer sizing (WithReadBuffer, WithWriteBuffer, Stats)
+//   - Half-close with a FIN frame (FlagFIN): CloseSend, PeerSendClosed, and
+//     per peer on a listener, CloseSendTo and PeerSendClosedFrom
+//   - Higher-layer header flag bits (FrameFlags): SendFlags, RecvFlags
+//   - Framing over any caller-supplied net.PacketConn (NewOverlayFromConn)
+//   - Per-peer, per-stream fan-out from a single reader goroutine (Demux)
+//   - Frame, byte and drop counters (TransportStats)
+//   - Seeded drop and reorder injection for tests (WithLossSimulation)
+//   - Token-bucket pacing of outgoing bytes (WithSendPacing)
 //
 // Per the full spec (CLAUDE.md §3.6), the overlay path should also implement:
 //   - StrandLink 64-byte frame header encode/decode
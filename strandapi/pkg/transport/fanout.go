@@ -0,0 +1,265 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// streamRecvQueue is the number of frames buffered per logical stream before
+// the reader blocks waiting for that stream's consumer.
+const streamRecvQueue = 64
+
+// acceptQueue is the number of peer-initiated streams buffered until Accept
+// is called. Beyond this the reader blocks waiting for Accept.
+const acceptQueue = 16
+
+// maxClosedStreams bounds how many closed stream keys a fanout remembers;
+// beyond it an arbitrary one is forgotten.
+const maxClosedStreams = 1024
+
+// frame is a single demultiplexed StrandAPI frame.
+type frame struct {
+	opcode  byte
+	payload []byte
+}
+
+// fanout is the single-reader core of Session and Demux. One background
+// goroutine owns the transport and is the only caller of recv; it delivers
+// each frame to the stream for the frame's key, creating streams for keys
+// not seen before and handing them out through accept.
+//
+// Delivery never drops frames for open streams, so a stalled consumer stalls
+// the reader: a stream that stops reading blocks delivery to every other
+// once its queue of streamRecvQueue frames is full, and new streams wait for
+// Accept once acceptQueue are pending. Frames that arrive late for a stream
+// already closed are dropped rather than reopening it, until the stream is
+// opened again locally.
+type fanout[K comparable, S any] struct {
+	recv      func(ctx context.Context) (K, net.Addr, frame, error)
+	newStream func(key K, peer net.Addr, q *queue) S
+	closeT    func() error
+	cancel    context.CancelFunc
+	done      chan struct{}
+
+	mu      sync.Mutex
+	streams map[K]fanoutEntry[S]
+	// closedKeys holds recently closed streams, up to maxClosedStreams.
+	closedKeys map[K]struct{}
+	accept     chan S
+	closed     bool
+	err        error
+}
+
+// fanoutEntry is a stream with the queue the reader delivers its frames to.
+type fanoutEntry[S any] struct {
+	stream S
+	q      *queue
+}
+
+// newFanout starts the reader. newStream wraps a new stream's queue in the
+// caller's stream type; closeT closes the transport recv reads from.
+func newFanout[K comparable, S any](
+	recv func(ctx context.Context) (K, net.Addr, frame, error),
+	newStream func(key K, peer net.Addr, q *queue) S,
+	closeT func() error,
+) *fanout[K, S] {
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &fanout[K, S]{
+		recv:       recv,
+		newStream:  newStream,
+		closeT:     closeT,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+		streams:    make(map[K]fanoutEntry[S]),
+		closedKeys: make(map[K]struct{}),
+		accept:     make(chan S, acceptQueue),
+	}
+	go f.readLoop(ctx)
+	return f
+}
+
+// open returns the stream for key, creating it if needed. Opening a closed
+// stream again accepts frames for it once more.
+func (f *fanout[K, S]) open(key K, peer net.Addr) (S, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		var zero S
+		return zero, ErrTransportClosed
+	}
+	delete(f.closedKeys, key)
+	e, _ := f.entryLocked(key, peer)
+	return e.stream, nil
+}
+
+// acceptStream blocks until the reader creates a stream for a frame whose key
+// has not been opened locally.
+func (f *fanout[K, S]) acceptStream(ctx context.Context) (S, error) {
+	var zero S
+	select {
+	case st := <-f.accept:
+		return st, nil
+	case <-f.done:
+		return zero, f.Err()
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// Err returns the error that terminated the reader, if any.
+func (f *fanout[K, S]) Err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	if f.closed {
+		return ErrTransportClosed
+	}
+	return nil
+}
+
+// close stops the reader and closes the transport.
+func (f *fanout[K, S]) close() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	f.closed = true
+	f.mu.Unlock()
+
+	f.cancel()
+	err := f.closeT()
+	<-f.done
+	return err
+}
+
+// entryLocked returns the entry for key, creating it if absent. The second
+// result reports whether it was newly created. f.mu must be held.
+func (f *fanout[K, S]) entryLocked(key K, peer net.Addr) (fanoutEntry[S], bool) {
+	if e, ok := f.streams[key]; ok {
+		return e, false
+	}
+	q := &queue{
+		recv:   make(chan frame, streamRecvQueue),
+		closed: make(chan struct{}),
+		done:   f.done,
+		err:    f.Err,
+		remove: func() { f.remove(key) },
+	}
+	e := fanoutEntry[S]{stream: f.newStream(key, peer, q), q: q}
+	f.streams[key] = e
+	return e, true
+}
+
+// readLoop delivers incoming frames until the transport fails or the fanout
+// is closed. Datagrams the transport rejects one by one (IsFrameError) are
+// skipped.
+func (f *fanout[K, S]) readLoop(ctx context.Context) {
+	defer close(f.done)
+	for {
+		key, peer, fr, err := f.recv(ctx)
+		if err != nil && IsFrameError(err) && ctx.Err() == nil {
+			continue
+		}
+		if err != nil {
+			f.mu.Lock()
+			if !f.closed {
+				f.err = err
+			}
+			f.mu.Unlock()
+			return
+		}
+
+		f.mu.Lock()
+		if _, ok := f.closedKeys[key]; ok {
+			// A late frame for a closed stream.
+			f.mu.Unlock()
+			continue
+		}
+		e, created := f.entryLocked(key, peer)
+		f.mu.Unlock()
+		if created {
+			select {
+			case f.accept <- e.stream:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case e.q.recv <- fr:
+		case <-e.q.closed:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// remove detaches a closed stream and remembers its key so late frames for
+// it are dropped.
+func (f *fanout[K, S]) remove(key K) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.streams, key)
+	if len(f.closedKeys) >= maxClosedStreams {
+		for k := range f.closedKeys {
+			delete(f.closedKeys, k)
+			break
+		}
+	}
+	f.closedKeys[key] = struct{}{}
+}
+
+// queue is the receiving half of one fanout stream.
+type queue struct {
+	recv   chan frame
+	closed chan struct{}
+	once   sync.Once
+	done   <-chan struct{} // closed when the reader exits
+	err    func() error    // the reader's terminal error
+	remove func()          // detaches the stream from its fanout
+}
+
+// checkOpen returns ErrStreamClosed once the stream has been closed.
+func (q *queue) checkOpen() error {
+	select {
+	case <-q.closed:
+		return ErrStreamClosed
+	default:
+		return nil
+	}
+}
+
+// next blocks until a frame for the stream arrives.
+func (q *queue) next(ctx context.Context) (byte, []byte, error) {
+	select {
+	case f := <-q.recv:
+		return f.opcode, f.payload, nil
+	case <-q.closed:
+		return 0, nil, ErrStreamClosed
+	case <-q.done:
+		// Drain anything already delivered before reporting the failure.
+		select {
+		case f := <-q.recv:
+			return f.opcode, f.payload, nil
+		default:
+		}
+		if err := q.err(); err != nil {
+			return 0, nil, err
+		}
+		return 0, nil, ErrStreamClosed
+	case <-ctx.Done():
+		return 0, nil, ctx.Err()
+	}
+}
+
+// close detaches the stream from its fanout. It is idempotent.
+func (q *queue) close() {
+	q.once.Do(func() {
+		close(q.closed)
+		q.remove()
+	})
+}
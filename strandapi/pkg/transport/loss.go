@@ -0,0 +1,117 @@
+package transport
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// reorderHold is the longest a datagram delayed by loss simulation waits for
+// a later datagram to overtake it before it is sent anyway.
+const reorderHold = 5 * time.Millisecond
+
+// WithLossSimulation makes the transport discard each outgoing datagram with
+// probability dropRate and, with probability reorderRate, hold one back so
+// the next datagram sent overtakes it (or for at most 5ms if none follows).
+// Rates are clamped to [0, 1]. A dropped datagram still counts as sent.
+//
+// It exists for testing reliability and ordering logic against a lossy
+// network and must not be used in production. The random sequence is seeded
+// with 1 unless WithLossSeed is given, so a test run is reproducible.
+func WithLossSimulation(dropRate, reorderRate float64) OverlayOption {
+	return func(t *OverlayTransport) {
+		if t.loss == nil {
+			t.loss = &lossSimulator{seed: 1}
+		}
+		t.loss.dropRate = min(max(dropRate, 0), 1)
+		t.loss.reorderRate = min(max(reorderRate, 0), 1)
+	}
+}
+
+// WithLossSeed seeds the random sequence of WithLossSimulation.
+func WithLossSeed(seed int64) OverlayOption {
+	return func(t *OverlayTransport) {
+		if t.loss == nil {
+			t.loss = &lossSimulator{}
+		}
+		t.loss.seed = seed
+	}
+}
+
+// heldDatagram is a datagram delayed by reordering, with the write that
+// sends it.
+type heldDatagram struct {
+	frame []byte
+	write func([]byte) error
+}
+
+// lossSimulator drops and reorders outgoing datagrams (WithLossSimulation).
+type lossSimulator struct {
+	dropRate    float64
+	reorderRate float64
+	seed        int64
+
+	mu   sync.Mutex
+	rng  *rand.Rand
+	held *heldDatagram
+}
+
+// send passes frame to write unless the simulation drops or delays it. A
+// datagram held back earlier is written right after frame.
+func (l *lossSimulator) send(frame []byte, write func([]byte) error) error {
+	l.mu.Lock()
+	if l.rng == nil {
+		l.rng = rand.New(rand.NewSource(l.seed))
+	}
+	if l.rng.Float64() < l.dropRate {
+		l.mu.Unlock()
+		return nil
+	}
+	if l.held == nil && l.rng.Float64() < l.reorderRate {
+		h := &heldDatagram{frame: frame, write: write}
+		l.held = h
+		l.mu.Unlock()
+		time.AfterFunc(reorderHold, func() { l.release(h) })
+		return nil
+	}
+	prev := l.held
+	l.held = nil
+	l.mu.Unlock()
+
+	err := write(frame)
+	if prev != nil {
+		prev.write(prev.frame)
+	}
+	return err
+}
+
+// release sends h if it is still held back.
+func (l *lossSimulator) release(h *heldDatagram) {
+	l.mu.Lock()
+	if l.held != h {
+		l.mu.Unlock()
+		return
+	}
+	l.held = nil
+	l.mu.Unlock()
+	h.write(h.frame)
+}
+
+// writeDatagram writes one encoded frame to remote, through the loss
+// simulation when one is configured.
+func (t *OverlayTransport) writeDatagram(frame []byte, remote net.Addr) error {
+	write := func(f []byte) error {
+		var err error
+		if t.dialed {
+			_, err = t.udp.Write(f)
+		} else {
+			_, err = t.conn.WriteTo(f, remote)
+		}
+		return err
+	}
+	if t.loss != nil {
+		return t.loss.send(frame, write)
+	}
+	return write(frame)
+}
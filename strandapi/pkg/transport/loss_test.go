@@ -0,0 +1,81 @@
+package transport
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+// lossyRun sends n frames from a transport with the given options over an
+// in-memory conn and returns the opcodes that arrived, in arrival order.
+func lossyRun(t *testing.T, n int, opts ...OverlayOption) []byte {
+	t.Helper()
+	connA, connB := newMemPacketConnPair()
+	sender, err := NewOverlayFromConn(connA, append([]OverlayOption{WithRemoteAddr(connB.LocalAddr())}, opts...)...)
+	if err != nil {
+		t.Fatalf("NewOverlayFromConn: %v", err)
+	}
+	defer sender.Close()
+	receiver, err := NewOverlayFromConn(connB)
+	if err != nil {
+		t.Fatalf("NewOverlayFromConn: %v", err)
+	}
+	defer receiver.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	for op := 1; op <= n; op++ {
+		if err := sender.Send(ctx, byte(op), nil); err != nil {
+			t.Fatalf("Send %d: %v", op, err)
+		}
+	}
+
+	// Drain until nothing more arrives. One context serves every Recv so
+	// that no earlier call's cancellation can cut a later one short.
+	rctx, rcancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer rcancel()
+	var got []byte
+	for {
+		op, _, err := receiver.Recv(rctx)
+		if err != nil {
+			return got
+		}
+		got = append(got, op)
+	}
+}
+
+func TestLossSimulationDrop(t *testing.T) {
+	const n = 60
+	got := lossyRun(t, n, WithLossSimulation(0.5, 0), WithLossSeed(42))
+
+	if len(got) < n/4 || len(got) > 3*n/4 {
+		t.Errorf("%d of %d frames arrived at a 50%% drop rate", len(got), n)
+	}
+	if !slices.IsSorted(got) {
+		t.Errorf("drop-only simulation reordered frames: %v", got)
+	}
+	// The same seed drops the same frames.
+	if again := lossyRun(t, n, WithLossSimulation(0.5, 0), WithLossSeed(42)); !slices.Equal(again, got) {
+		t.Errorf("same seed delivered %v, then %v", got, again)
+	}
+}
+
+func TestLossSimulationReorder(t *testing.T) {
+	const n = 40
+	got := lossyRun(t, n, WithLossSimulation(0, 0.5))
+
+	if len(got) != n {
+		t.Fatalf("%d of %d frames arrived with no drop rate", len(got), n)
+	}
+	if slices.IsSorted(got) {
+		t.Errorf("a 50%% reorder rate delivered every frame in order")
+	}
+	sorted := slices.Clone(got)
+	slices.Sort(sorted)
+	for i, op := range sorted {
+		if op != byte(i+1) {
+			t.Fatalf("frames delivered %v, want each of 1..%d once", got, n)
+		}
+	}
+}
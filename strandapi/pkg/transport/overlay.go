@@ -2,11 +2,16 @@ package transport
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"log"
+	"math/rand"
 	"net"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -18,13 +23,109 @@ const (
 	maxUDPPayload         = 65507
 )
 
+// FrameFlags is the overlay header's flags byte. The low four bits are set
+// and consumed by the transport itself; the rest are carried end to end for
+// higher layers, which set them with SendFlags and read them with RecvFlags.
+// The transport attaches no meaning to those, and peers that predate them
+// ignore them.
+type FrameFlags byte
+
+// Transport-level flag bits, managed by the transport.
+const (
+	// FlagStreamID indicates a 4-byte little-endian logical stream ID follows
+	// the fixed header, before the opcode. Frames on stream 0 omit it.
+	FlagStreamID FrameFlags = 0x01
+	// FlagTraceID indicates an 8-byte little-endian frame trace ID follows
+	// the fixed header (after the stream ID extension, if present).
+	FlagTraceID FrameFlags = 0x02
+	// FlagSignature indicates a 64-byte ed25519 signature trails the payload.
+	// It covers every preceding byte of the frame and is not counted in the
+	// length field.
+	FlagSignature FrameFlags = 0x04
+	// FlagFIN marks a transport-level FIN frame, sent by CloseSend to tell the
+	// peer no further frames follow in that direction. It carries a zero
+	// opcode and no payload and is consumed by Recv, never returned.
+	FlagFIN FrameFlags = 0x08
+
+	// transportFlags are the bits SendFlags refuses and RecvFlags hides.
+	transportFlags = FlagStreamID | FlagTraceID | FlagSignature | FlagFIN
+)
+
+// Frame flag bits for higher layers (SendFlags, RecvFlags).
+const (
+	// FlagReliable marks a frame the sender wants acknowledged and
+	// retransmitted if lost.
+	FlagReliable FrameFlags = 0x10
+	// FlagCompressed marks a payload compressed by the sender.
+	FlagCompressed FrameFlags = 0x20
+	// FlagEncrypted marks a payload encrypted by the sender.
+	FlagEncrypted FrameFlags = 0x40
+)
+
+// Has reports whether every bit in bits is set in f.
+func (f FrameFlags) Has(bits FrameFlags) bool {
+	return f&bits == bits
+}
+
+// String lists the named bits set in f, such as "reliable|compressed", with
+// any unnamed bits in hex. It returns "0" when no bit is set.
+func (f FrameFlags) String() string {
+	if f == 0 {
+		return "0"
+	}
+	var names []string
+	for _, b := range []struct {
+		bit  FrameFlags
+		name string
+	}{
+		{FlagStreamID, "stream-id"},
+		{FlagTraceID, "trace-id"},
+		{FlagSignature, "signature"},
+		{FlagFIN, "fin"},
+		{FlagReliable, "reliable"},
+		{FlagCompressed, "compressed"},
+		{FlagEncrypted, "encrypted"},
+	} {
+		if f&b.bit != 0 {
+			names = append(names, b.name)
+			f &^= b.bit
+		}
+	}
+	if f != 0 {
+		names = append(names, fmt.Sprintf("0x%02x", byte(f)))
+	}
+	return strings.Join(names, "|")
+}
+
+const (
+
+	streamIDExtSize = 4
+	traceIDExtSize  = 8
+	signatureSize   = ed25519.SignatureSize
+)
+
 var (
 	ErrInvalidMagic   = errors.New("strandapi overlay: invalid magic bytes")
 	ErrVersionMismatch = errors.New("strandapi overlay: unsupported version")
 	ErrMessageTooLarge = errors.New("strandapi overlay: message exceeds maximum UDP payload")
 	ErrTransportClosed = errors.New("strandapi overlay: transport is closed")
+	ErrTruncated       = errors.New("strandapi overlay: datagram larger than read buffer")
+	ErrUnauthenticated = errors.New("strandapi overlay: frame signature missing or invalid")
+	ErrMalformed       = errors.New("strandapi overlay: malformed frame")
+	ErrSendClosed      = errors.New("strandapi overlay: send side is closed")
+	ErrReservedFlags   = errors.New("strandapi overlay: flag bits reserved for the transport")
 )
 
+// IsFrameError reports whether err rejected a single inbound datagram rather
+// than the transport itself: a truncated, malformed, foreign-version or
+// unauthenticated frame. The datagram is counted in
+// TransportStats.FramesDropped; receivers should skip it and keep reading.
+func IsFrameError(err error) bool {
+	return errors.Is(err, ErrTruncated) || errors.Is(err, ErrMalformed) ||
+		errors.Is(err, ErrInvalidMagic) || errors.Is(err, ErrVersionMismatch) ||
+		errors.Is(err, ErrUnauthenticated)
+}
+
 // OverlayTransport is a pure-Go transport that frames StrandAPI messages over
 // UDP. It requires no CGo, no StrandLink, and no StrandStream -- it exists to
 // provide full StrandAPI functionality with zero native dependencies.
@@ -32,50 +133,477 @@ var (
 // Frame layout on the wire:
 //
 //	[2B magic 0x504C][1B version][1B flags][4B length][1B opcode][payload...]
+//
+// When flags has FlagStreamID set, a [4B stream ID] extension sits between
+// the length and the opcode, followed by an [8B trace ID] extension when
+// FlagTraceID is set. The length field always covers opcode+payload. When
+// FlagSignature is set, a [64B ed25519 signature] follows the payload.
 type OverlayTransport struct {
-	conn   *net.UDPConn
-	remote *net.UDPAddr // non-nil for client (dialled) connections
+	conn net.PacketConn
+	// udp is conn as a UDP socket, or nil when a caller-supplied conn of
+	// another type was passed to NewOverlayFromConn.
+	udp    *net.UDPConn
+	remote net.Addr // peer address; learned from the first Recv for listeners
+	dialed bool     // udp is connected to remote (DialOverlay)
 	mu     sync.Mutex
 	closed bool
+	// sendClosed holds the peers CloseSend has sent a FIN frame to, and
+	// peerSendClosed the peers whose FIN frame has been received, keyed by
+	// address (see peerKeyLocked). Each holds at most maxFINPeers entries.
+	sendClosed     map[string]bool
+	peerSendClosed map[string]bool
+	// readBufSize caps the datagram size Recv accepts; 0 means maxUDPPayload.
+	readBufSize int
+	// traceLog, when set, logs every frame with its trace ID (SetTraceLogger).
+	traceLog *log.Logger
+	// signKey signs every outgoing frame (WithFrameSigning).
+	signKey ed25519.PrivateKey
+	// verifyKey, when set, rejects inbound frames without a valid signature
+	// (WithFrameVerification).
+	verifyKey ed25519.PublicKey
+	// iface names the network interface the socket is bound to (WithInterface).
+	iface string
+	// sockReadBuf and sockWriteBuf are the requested kernel socket buffer
+	// sizes (WithReadBuffer, WithWriteBuffer); 0 keeps the OS default.
+	sockReadBuf  int
+	sockWriteBuf int
+	// loss, when set, drops and reorders outgoing datagrams
+	// (WithLossSimulation).
+	loss *lossSimulator
+	// pacer, when set, limits the outgoing byte rate (WithSendPacing).
+	pacer *sendPacer
+
+	// traffic backs TransportStats.
+	traffic struct {
+		framesSent, framesReceived, bytesSent, bytesReceived, framesDropped atomic.Uint64
+	}
+}
+
+// OverlayStats reports the socket configuration of an OverlayTransport.
+type OverlayStats struct {
+	// ReadBuffer and WriteBuffer are the kernel socket buffer sizes in bytes
+	// as reported by the OS, which may clamp (or, on Linux, double) the
+	// requested value. They are 0 where the OS cannot report them.
+	ReadBuffer  int
+	WriteBuffer int
+}
+
+// OverlayOption configures an OverlayTransport at construction time.
+type OverlayOption func(*OverlayTransport)
+
+// WithInterface binds the transport's socket to the named network interface,
+// so traffic uses that NIC on a multi-homed host (for example, a data-plane
+// interface separate from management). On Linux the socket is bound with
+// SO_BINDTODEVICE, which may require CAP_NET_RAW on older kernels. Elsewhere
+// the socket is bound to the interface's first address instead, which selects
+// the source address but leaves the route to the operating system.
+func WithInterface(name string) OverlayOption {
+	return func(t *OverlayTransport) { t.iface = name }
+}
+
+// WithReadBuffer sets the kernel receive buffer of the transport's socket to
+// n bytes. Raise it for high-throughput streams such as tensor transfer, where
+// the OS default drops datagrams under bursts. The OS may clamp the value (on
+// Linux to net.core.rmem_max); Stats reports the size actually applied.
+func WithReadBuffer(n int) OverlayOption {
+	return func(t *OverlayTransport) { t.sockReadBuf = n }
+}
+
+// WithWriteBuffer sets the kernel send buffer of the transport's socket to n
+// bytes, subject to the same OS clamping as WithReadBuffer.
+func WithWriteBuffer(n int) OverlayOption {
+	return func(t *OverlayTransport) { t.sockWriteBuf = n }
+}
+
+// WithRemoteAddr sets the peer that Send addresses before any frame has been
+// received, for transports made by NewOverlayFromConn or ListenOverlay.
+// DialOverlay always sends to the dialed address.
+func WithRemoteAddr(addr net.Addr) OverlayOption {
+	return func(t *OverlayTransport) { t.remote = addr }
+}
+
+// WithFrameSigning signs every outgoing frame with priv. The signature gives a
+// receiver configured with WithFrameVerification per-frame authenticity
+// without a handshake; it provides no confidentiality and no replay
+// protection.
+func WithFrameSigning(priv ed25519.PrivateKey) OverlayOption {
+	return func(t *OverlayTransport) { t.signKey = priv }
+}
+
+// WithFrameVerification makes Recv reject, with ErrUnauthenticated, any frame
+// that is unsigned or whose signature does not verify under pub. Without it,
+// signatures on inbound frames are ignored.
+func WithFrameVerification(pub ed25519.PublicKey) OverlayOption {
+	return func(t *OverlayTransport) { t.verifyKey = pub }
 }
 
 // DialOverlay connects to a remote StrandAPI overlay endpoint.
-func DialOverlay(addr string) (*OverlayTransport, error) {
+func DialOverlay(addr string, opts ...OverlayOption) (*OverlayTransport, error) {
+	t := &OverlayTransport{dialed: true}
+	for _, o := range opts {
+		o(t)
+	}
 	raddr, err := net.ResolveUDPAddr("udp", addr)
 	if err != nil {
 		return nil, fmt.Errorf("strandapi overlay: resolve %s: %w", addr, err)
 	}
-	conn, err := net.DialUDP("udp", nil, raddr)
+	conn, err := t.dialUDP(raddr)
 	if err != nil {
 		return nil, fmt.Errorf("strandapi overlay: dial %s: %w", addr, err)
 	}
-	return &OverlayTransport{conn: conn, remote: raddr}, nil
+	t.conn, t.udp, t.remote = conn, conn, raddr
+	if err := t.applySocketBuffers(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return t, nil
 }
 
 // ListenOverlay creates a listening overlay transport bound to addr.
-func ListenOverlay(addr string) (*OverlayTransport, error) {
+func ListenOverlay(addr string, opts ...OverlayOption) (*OverlayTransport, error) {
+	t := &OverlayTransport{}
+	for _, o := range opts {
+		o(t)
+	}
 	laddr, err := net.ResolveUDPAddr("udp", addr)
 	if err != nil {
 		return nil, fmt.Errorf("strandapi overlay: resolve %s: %w", addr, err)
 	}
-	conn, err := net.ListenUDP("udp", laddr)
+	conn, err := t.listenUDP(laddr)
 	if err != nil {
 		return nil, fmt.Errorf("strandapi overlay: listen %s: %w", addr, err)
 	}
-	return &OverlayTransport{conn: conn}, nil
+	t.conn, t.udp = conn, conn
+	if err := t.applySocketBuffers(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// NewOverlayFromConn layers overlay framing over a caller-supplied packet
+// conn, such as a QUIC datagram conn or an in-memory fake for tests. Like a
+// listener, the transport replies to the address of the first frame it
+// receives; use WithRemoteAddr to send first. A connected *net.UDPConn is
+// treated as dialed. WithInterface has no effect here, and WithReadBuffer and
+// WithWriteBuffer apply only if conn has SetReadBuffer and SetWriteBuffer
+// methods. Close closes conn.
+func NewOverlayFromConn(conn net.PacketConn, opts ...OverlayOption) (*OverlayTransport, error) {
+	if conn == nil {
+		return nil, errors.New("strandapi overlay: nil packet conn")
+	}
+	t := &OverlayTransport{conn: conn}
+	for _, o := range opts {
+		o(t)
+	}
+	if udp, ok := conn.(*net.UDPConn); ok {
+		t.udp = udp
+		if raddr := udp.RemoteAddr(); raddr != nil {
+			t.dialed, t.remote = true, raddr
+		}
+	}
+	if err := t.applySocketBuffers(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// dialUDP opens the connected socket for DialOverlay, bound to the
+// WithInterface interface if one was given.
+func (t *OverlayTransport) dialUDP(raddr *net.UDPAddr) (*net.UDPConn, error) {
+	if t.iface == "" {
+		return net.DialUDP("udp", nil, raddr)
+	}
+	control, ip, err := interfaceBinding(t.iface)
+	if err != nil {
+		return nil, err
+	}
+	d := net.Dialer{Control: control}
+	if ip != nil {
+		d.LocalAddr = &net.UDPAddr{IP: ip}
+	}
+	c, err := d.Dial("udp", raddr.String())
+	if err != nil {
+		return nil, err
+	}
+	return c.(*net.UDPConn), nil
+}
+
+// listenUDP opens the listening socket for ListenOverlay, bound to the
+// WithInterface interface if one was given.
+func (t *OverlayTransport) listenUDP(laddr *net.UDPAddr) (*net.UDPConn, error) {
+	if t.iface == "" {
+		return net.ListenUDP("udp", laddr)
+	}
+	control, ip, err := interfaceBinding(t.iface)
+	if err != nil {
+		return nil, err
+	}
+	if ip != nil && (laddr.IP == nil || laddr.IP.IsUnspecified()) {
+		laddr = &net.UDPAddr{IP: ip, Port: laddr.Port}
+	}
+	lc := net.ListenConfig{Control: control}
+	pc, err := lc.ListenPacket(context.Background(), "udp", laddr.String())
+	if err != nil {
+		return nil, err
+	}
+	return pc.(*net.UDPConn), nil
+}
+
+// applySocketBuffers applies the WithReadBuffer and WithWriteBuffer sizes to
+// the freshly created socket. Conns without buffer setters are left alone.
+func (t *OverlayTransport) applySocketBuffers() error {
+	if t.sockReadBuf > 0 {
+		if c, ok := t.conn.(interface{ SetReadBuffer(int) error }); ok {
+			if err := c.SetReadBuffer(t.sockReadBuf); err != nil {
+				return fmt.Errorf("strandapi overlay: set read buffer: %w", err)
+			}
+		}
+	}
+	if t.sockWriteBuf > 0 {
+		if c, ok := t.conn.(interface{ SetWriteBuffer(int) error }); ok {
+			if err := c.SetWriteBuffer(t.sockWriteBuf); err != nil {
+				return fmt.Errorf("strandapi overlay: set write buffer: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Stats returns the transport's effective socket configuration. It reports
+// zero buffer sizes for a NewOverlayFromConn transport that is not over UDP.
+func (t *OverlayTransport) Stats() OverlayStats {
+	var st OverlayStats
+	if t.udp != nil {
+		st.ReadBuffer, st.WriteBuffer = socketBufferSizes(t.udp)
+	}
+	return st
+}
+
+// TransportStats returns the frames and bytes the transport has sent and
+// received, and how many inbound datagrams it rejected.
+func (t *OverlayTransport) TransportStats() TransportStats {
+	return TransportStats{
+		FramesSent:     t.traffic.framesSent.Load(),
+		FramesReceived: t.traffic.framesReceived.Load(),
+		BytesSent:      t.traffic.bytesSent.Load(),
+		BytesReceived:  t.traffic.bytesReceived.Load(),
+		FramesDropped:  t.traffic.framesDropped.Load(),
+	}
+}
+
+// SetReadBufferSize sets the largest datagram, in bytes including the overlay
+// header, that Recv will accept. Size it to the negotiated maximum message
+// size plus header overhead to avoid allocating a full 64 KiB per read.
+// Datagrams that do not fit are reported as ErrTruncated rather than parsed.
+// Values <= 0 or above the UDP maximum restore the default (65507 bytes).
+func (t *OverlayTransport) SetReadBufferSize(n int) {
+	if n <= 0 || n > maxUDPPayload {
+		n = 0
+	}
+	t.mu.Lock()
+	t.readBufSize = n
+	t.mu.Unlock()
+}
+
+// MaxPayload returns the largest payload, in bytes, that one frame can carry
+// in either direction: the UDP maximum, or the SetReadBufferSize limit when
+// smaller, less the header with both extensions, the opcode and a signature.
+// It implements MaxPayloadTransport.
+func (t *OverlayTransport) MaxPayload() int {
+	t.mu.Lock()
+	size := t.readBufSize
+	t.mu.Unlock()
+	if size == 0 {
+		size = maxUDPPayload
+	}
+	return size - (overlayHdrSize + streamIDExtSize + traceIDExtSize + 1 + signatureSize)
+}
+
+// SetTraceLogger enables per-frame trace logging: every frame sent or
+// received is logged to l with its opcode, size and trace ID, and frames sent
+// without an explicit trace ID are assigned a random one so both ends of the
+// wire log the same value. A nil l disables logging.
+func (t *OverlayTransport) SetTraceLogger(l *log.Logger) {
+	t.mu.Lock()
+	t.traceLog = l
+	t.mu.Unlock()
 }
 
 // Send transmits a single StrandAPI frame over the overlay.
 func (t *OverlayTransport) Send(ctx context.Context, opcode byte, payload []byte) error {
+	return t.sendFrame(ctx, nil, 0, 0, 0, opcode, payload)
+}
+
+// SendFlags transmits a single StrandAPI frame with the given FrameFlags set
+// in its header, for the peer to read with RecvFlags. Flags managed by the
+// transport (FlagStreamID, FlagTraceID, FlagSignature, FlagFIN) cannot be
+// set this way; passing any fails with ErrReservedFlags.
+func (t *OverlayTransport) SendFlags(ctx context.Context, flags FrameFlags, opcode byte, payload []byte) error {
+	if flags&transportFlags != 0 {
+		return fmt.Errorf("%w: %s", ErrReservedFlags, flags&transportFlags)
+	}
+	return t.sendFrame(ctx, nil, flags, 0, 0, opcode, payload)
+}
+
+// SendStream transmits a single StrandAPI frame tagged with the given logical
+// stream ID. Stream 0 is the default stream and is sent without the header
+// extension, so it is wire-compatible with peers that predate multiplexing.
+func (t *OverlayTransport) SendStream(ctx context.Context, streamID uint32, opcode byte, payload []byte) error {
+	return t.sendFrame(ctx, nil, 0, streamID, 0, opcode, payload)
+}
+
+// SendTraced transmits a single StrandAPI frame carrying the given trace ID so
+// it can be correlated across both ends' logs. A zero traceID sends no trace
+// extension unless trace logging is enabled.
+func (t *OverlayTransport) SendTraced(ctx context.Context, traceID uint64, opcode byte, payload []byte) error {
+	return t.sendFrame(ctx, nil, 0, 0, traceID, opcode, payload)
+}
+
+// maxFINPeers bounds the per-peer half-close state a listener keeps; beyond
+// it an arbitrary peer's entry is forgotten.
+const maxFINPeers = 1024
+
+// CloseSend half-closes the transport: it sends a FIN frame so the peer
+// learns that no more frames follow, and makes every later Send fail with
+// ErrSendClosed. Recv keeps working, so a client can finish sending and still
+// read the rest of the response. Calling CloseSend again is a no-op; if the
+// FIN frame cannot be sent, CloseSend returns the error and sending stays
+// open so the caller can retry. Peers that predate FlagFIN see the FIN as a
+// frame with opcode 0x00. A listener half-closes towards the peer learned
+// from Recv; CloseSendTo addresses any other.
+func (t *OverlayTransport) CloseSend() error {
+	return t.CloseSendTo(nil)
+}
+
+// CloseSendTo is CloseSend towards one peer of a listener, such as one
+// reported by RecvStreamFrom: only sends to that peer fail afterwards. A
+// dialed transport always half-closes towards the dialed peer.
+func (t *OverlayTransport) CloseSendTo(to net.Addr) error {
 	t.mu.Lock()
 	if t.closed {
 		t.mu.Unlock()
 		return ErrTransportClosed
 	}
+	key := t.peerKeyLocked(to)
+	if key != "" && t.sendClosed[key] {
+		t.mu.Unlock()
+		return nil
+	}
+	remote := t.remote
+	if to != nil {
+		remote = to
+	}
+	traceLog := t.traceLog
 	t.mu.Unlock()
+	if err := t.writeFrame(context.Background(), remote, traceLog, FlagFIN, 0, 0, 0, nil); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.sendClosed = markPeer(t.sendClosed, t.peerKeyLocked(to))
+	t.mu.Unlock()
+	return nil
+}
+
+// PeerSendClosed reports whether the peer has half-closed its send side with
+// CloseSend. It becomes true once Recv has consumed the peer's FIN frame. On
+// a listener it reports on the peer learned from Recv; PeerSendClosedFrom
+// asks about any other.
+func (t *OverlayTransport) PeerSendClosed() bool {
+	return t.PeerSendClosedFrom(nil)
+}
+
+// PeerSendClosedFrom reports whether the peer at from has half-closed its
+// send side. A dialed transport always reports on the dialed peer.
+func (t *OverlayTransport) PeerSendClosedFrom(from net.Addr) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := t.peerKeyLocked(from)
+	return key != "" && t.peerSendClosed[key]
+}
+
+// peerKeyLocked returns the key of the peer addr names in the half-close
+// state: the dialed peer on a dialed transport, otherwise addr or, when addr
+// is nil, the remote learned from Recv. It returns "" if there is no such
+// peer. t.mu must be held.
+func (t *OverlayTransport) peerKeyLocked(addr net.Addr) string {
+	if t.dialed || addr == nil {
+		addr = t.remote
+	}
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
+
+// markPeer adds key to set, allocating set on first use and forgetting an
+// arbitrary entry once maxFINPeers are held, and returns the set.
+func markPeer(set map[string]bool, key string) map[string]bool {
+	if set == nil {
+		set = make(map[string]bool)
+	}
+	if !set[key] && len(set) >= maxFINPeers {
+		for k := range set {
+			delete(set, k)
+			break
+		}
+	}
+	set[key] = true
+	return set
+}
 
-	// Total wire frame: header + 1B opcode + payload
-	totalLen := overlayHdrSize + 1 + len(payload)
+// SendStreamTo transmits a frame on the given logical stream to a specific
+// peer, such as one reported by RecvStreamFrom, so a listener can answer many
+// peers. A dialed transport always sends to the dialed peer. It implements
+// PeerStreamTransport.
+func (t *OverlayTransport) SendStreamTo(ctx context.Context, to net.Addr, streamID uint32, opcode byte, payload []byte) error {
+	return t.sendFrame(ctx, to, 0, streamID, 0, opcode, payload)
+}
+
+// sendFrame writes a frame with the given higher-layer flags to to, or to the
+// transport's peer when to is nil.
+func (t *OverlayTransport) sendFrame(ctx context.Context, to net.Addr, flags FrameFlags, streamID uint32, traceID uint64, opcode byte, payload []byte) error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return ErrTransportClosed
+	}
+	if key := t.peerKeyLocked(to); key != "" && t.sendClosed[key] {
+		t.mu.Unlock()
+		return ErrSendClosed
+	}
+	remote := t.remote
+	if to != nil {
+		remote = to
+	}
+	traceLog := t.traceLog
+	t.mu.Unlock()
+	return t.writeFrame(ctx, remote, traceLog, flags, streamID, traceID, opcode, payload)
+}
+
+// writeFrame encodes and writes one frame with the given extra header flags.
+func (t *OverlayTransport) writeFrame(ctx context.Context, remote net.Addr, traceLog *log.Logger, flags FrameFlags, streamID uint32, traceID uint64, opcode byte, payload []byte) error {
+	if traceID == 0 && traceLog != nil {
+		traceID = rand.Uint64() | 1 // never 0, which means "untraced"
+	}
+
+	hdrSize := overlayHdrSize
+	if streamID != 0 {
+		hdrSize += streamIDExtSize
+		flags |= FlagStreamID
+	}
+	if traceID != 0 {
+		hdrSize += traceIDExtSize
+		flags |= FlagTraceID
+	}
+	sigLen := 0
+	if t.signKey != nil {
+		sigLen = signatureSize
+		flags |= FlagSignature
+	}
+
+	// Total wire frame: header + 1B opcode + payload [+ signature]
+	totalLen := hdrSize + 1 + len(payload) + sigLen
 	if totalLen > maxUDPPayload {
 		return ErrMessageTooLarge
 	}
@@ -85,14 +613,28 @@ func (t *OverlayTransport) Send(ctx context.Context, opcode byte, payload []byte
 	binary.BigEndian.PutUint16(frame[0:2], OverlayMagic)
 	// Version
 	frame[2] = OverlayVersion
-	// Flags (reserved)
-	frame[3] = 0
+	// Flags
+	frame[3] = byte(flags)
 	// Length of (opcode + payload)
 	binary.LittleEndian.PutUint32(frame[4:8], uint32(1+len(payload)))
+	// Header extensions
+	off := overlayHdrSize
+	if flags&FlagStreamID != 0 {
+		binary.LittleEndian.PutUint32(frame[off:], streamID)
+		off += streamIDExtSize
+	}
+	if flags&FlagTraceID != 0 {
+		binary.LittleEndian.PutUint64(frame[off:], traceID)
+	}
 	// Opcode
-	frame[8] = opcode
+	frame[hdrSize] = opcode
 	// Payload
-	copy(frame[9:], payload)
+	copy(frame[hdrSize+1:], payload)
+	// Signature over everything before it
+	if sigLen > 0 {
+		body := totalLen - sigLen
+		copy(frame[body:], ed25519.Sign(t.signKey, frame[:body]))
+	}
 
 	// Respect context deadline.
 	if deadline, ok := ctx.Deadline(); ok {
@@ -101,35 +643,132 @@ func (t *OverlayTransport) Send(ctx context.Context, opcode byte, payload []byte
 		}
 	}
 
-	_, err := t.conn.Write(frame)
+	if !t.dialed && remote == nil {
+		// Listener-mode transports reply to the peer learned from Recv.
+		return fmt.Errorf("strandapi overlay: no remote peer to send to")
+	}
+	if t.pacer != nil {
+		if err := t.pacer.wait(ctx, len(frame)); err != nil {
+			return err
+		}
+	}
+	err := t.writeDatagram(frame, remote)
+	if traceLog != nil {
+		traceLog.Printf("strandapi overlay: send trace=%016x stream=%d opcode=0x%02x len=%d err=%v", traceID, streamID, opcode, len(payload), err)
+	}
+	if err == nil {
+		t.traffic.framesSent.Add(1)
+		t.traffic.bytesSent.Add(uint64(len(frame)))
+	}
 	return err
 }
 
-// Recv blocks until a complete StrandAPI overlay frame arrives.
+// Recv blocks until a complete StrandAPI overlay frame arrives. The logical
+// stream ID, if any, is discarded; use RecvStream to observe it.
 func (t *OverlayTransport) Recv(ctx context.Context) (byte, []byte, error) {
+	_, _, opcode, payload, err := t.recvFrame(ctx)
+	return opcode, payload, err
+}
+
+// RecvFlags blocks until a complete StrandAPI overlay frame arrives and
+// returns the FrameFlags its sender set with SendFlags along with the opcode
+// and payload. Transport-managed bits are cleared, so a frame sent with
+// plain Send reports 0.
+func (t *OverlayTransport) RecvFlags(ctx context.Context) (FrameFlags, byte, []byte, error) {
+	_, flags, _, _, opcode, payload, err := t.recvFrameFrom(ctx)
+	return flags &^ transportFlags, opcode, payload, err
+}
+
+// RecvStream blocks until a complete StrandAPI overlay frame arrives and
+// returns its logical stream ID (0 when the frame carries no extension)
+// along with the opcode and payload.
+func (t *OverlayTransport) RecvStream(ctx context.Context) (uint32, byte, []byte, error) {
+	streamID, _, opcode, payload, err := t.recvFrame(ctx)
+	return streamID, opcode, payload, err
+}
+
+// RecvTraced blocks until a complete StrandAPI overlay frame arrives and
+// returns its trace ID (0 when the sender attached none) along with the
+// opcode and payload.
+func (t *OverlayTransport) RecvTraced(ctx context.Context) (uint64, byte, []byte, error) {
+	_, traceID, opcode, payload, err := t.recvFrame(ctx)
+	return traceID, opcode, payload, err
+}
+
+// RecvFrom blocks until a complete StrandAPI overlay frame arrives and
+// returns the sender's address alongside the opcode and payload. It
+// implements PeerTransport.
+func (t *OverlayTransport) RecvFrom(ctx context.Context) (string, byte, []byte, error) {
+	from, _, _, _, opcode, payload, err := t.recvFrameFrom(ctx)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	return from.String(), opcode, payload, nil
+}
+
+// RecvStreamFrom blocks until a complete StrandAPI overlay frame arrives and
+// returns the sender's address and logical stream ID along with the opcode
+// and payload. It implements PeerStreamTransport.
+func (t *OverlayTransport) RecvStreamFrom(ctx context.Context) (net.Addr, uint32, byte, []byte, error) {
+	from, _, streamID, _, opcode, payload, err := t.recvFrameFrom(ctx)
+	return from, streamID, opcode, payload, err
+}
+
+func (t *OverlayTransport) recvFrame(ctx context.Context) (streamID uint32, traceID uint64, opcode byte, payload []byte, err error) {
+	_, _, streamID, traceID, opcode, payload, err = t.recvFrameFrom(ctx)
+	return streamID, traceID, opcode, payload, err
+}
+
+// recvFrameFrom is recvFrame that also reports the sender's address and the
+// header flags. FIN frames are recorded for PeerSendClosed and skipped.
+func (t *OverlayTransport) recvFrameFrom(ctx context.Context) (from net.Addr, flags FrameFlags, streamID uint32, traceID uint64, opcode byte, payload []byte, err error) {
+	for {
+		from, flags, streamID, traceID, opcode, payload, err = t.readFrame(ctx)
+		if err != nil || flags&FlagFIN == 0 {
+			return from, flags, streamID, traceID, opcode, payload, err
+		}
+		t.mu.Lock()
+		if key := t.peerKeyLocked(from); key != "" {
+			t.peerSendClosed = markPeer(t.peerSendClosed, key)
+		}
+		t.mu.Unlock()
+	}
+}
+
+// readFrame reads and validates a single datagram, returning its header flags
+// alongside the decoded frame.
+func (t *OverlayTransport) readFrame(ctx context.Context) (from net.Addr, flags FrameFlags, streamID uint32, traceID uint64, opcode byte, payload []byte, err error) {
 	t.mu.Lock()
 	if t.closed {
 		t.mu.Unlock()
-		return 0, nil, ErrTransportClosed
+		return nil, 0, 0, 0, 0, nil, ErrTransportClosed
 	}
+	bufSize := t.readBufSize
+	traceLog := t.traceLog
 	t.mu.Unlock()
+	if bufSize == 0 {
+		bufSize = maxUDPPayload
+	}
 
 	// Return immediately if the context is already done.
-	if err := ctx.Err(); err != nil {
-		return 0, nil, err
+	if err = ctx.Err(); err != nil {
+		return nil, 0, 0, 0, 0, nil, err
 	}
 
-	buf := make([]byte, maxUDPPayload)
+	// One spare byte detects truncation portably: the OS silently drops the
+	// tail of a datagram that does not fit, so a completely filled buffer
+	// means the datagram was larger than bufSize.
+	buf := make([]byte, bufSize+1)
 
 	// Respect context deadline.
 	if deadline, ok := ctx.Deadline(); ok {
-		if err := t.conn.SetReadDeadline(deadline); err != nil {
-			return 0, nil, err
+		if err = t.conn.SetReadDeadline(deadline); err != nil {
+			return nil, 0, 0, 0, 0, nil, err
 		}
 	}
 
 	// Monitor context cancellation. When ctx is cancelled (with or without a
-	// deadline), set an expired read deadline so ReadFromUDP unblocks promptly.
+	// deadline), set an expired read deadline so ReadFrom unblocks promptly.
 	// The goroutine exits cleanly when the read finishes normally.
 	readDone := make(chan struct{})
 	defer close(readDone)
@@ -141,42 +780,88 @@ func (t *OverlayTransport) Recv(ctx context.Context) (byte, []byte, error) {
 		}
 	}()
 
-	n, remoteAddr, err := t.conn.ReadFromUDP(buf)
+	n, remoteAddr, err := t.conn.ReadFrom(buf)
 	if err != nil {
-		return 0, nil, err
+		return nil, 0, 0, 0, 0, nil, err
 	}
-	if n < overlayHdrSize+1 {
-		return 0, nil, fmt.Errorf("strandapi overlay: frame too short (%d bytes)", n)
+	// A datagram arrived: count it as received or, if it fails validation
+	// below, as dropped.
+	defer func() {
+		if err != nil {
+			t.traffic.framesDropped.Add(1)
+			return
+		}
+		t.traffic.framesReceived.Add(1)
+		t.traffic.bytesReceived.Add(uint64(n))
+	}()
+	if n > bufSize {
+		return nil, 0, 0, 0, 0, nil, ErrTruncated
 	}
-
-	// Save the remote address for listener-mode transports so that
-	// subsequent Send calls know where to reply.
-	if t.remote == nil && remoteAddr != nil {
-		t.remote = remoteAddr
+	if n < overlayHdrSize+1 {
+		return nil, 0, 0, 0, 0, nil, fmt.Errorf("%w: too short (%d bytes)", ErrMalformed, n)
 	}
 
 	// Validate magic
 	magic := binary.BigEndian.Uint16(buf[0:2])
 	if magic != OverlayMagic {
-		return 0, nil, ErrInvalidMagic
+		return nil, 0, 0, 0, 0, nil, ErrInvalidMagic
 	}
 
 	// Validate version
 	if buf[2] != OverlayVersion {
-		return 0, nil, ErrVersionMismatch
+		return nil, 0, 0, 0, 0, nil, ErrVersionMismatch
+	}
+
+	// Parse the optional stream ID and trace ID extensions.
+	flags = FrameFlags(buf[3])
+	hdrSize := overlayHdrSize
+	if flags&FlagStreamID != 0 {
+		hdrSize += streamIDExtSize
+		if n < hdrSize+1 {
+			return nil, 0, 0, 0, 0, nil, fmt.Errorf("%w: too short for stream ID (%d bytes)", ErrMalformed, n)
+		}
+		streamID = binary.LittleEndian.Uint32(buf[hdrSize-streamIDExtSize:])
+	}
+	if flags&FlagTraceID != 0 {
+		hdrSize += traceIDExtSize
+		if n < hdrSize+1 {
+			return nil, 0, 0, 0, 0, nil, fmt.Errorf("%w: too short for trace ID (%d bytes)", ErrMalformed, n)
+		}
+		traceID = binary.LittleEndian.Uint64(buf[hdrSize-traceIDExtSize:])
 	}
 
 	// Parse length
 	length := binary.LittleEndian.Uint32(buf[4:8])
-	if overlayHdrSize+int(length) > n {
-		return 0, nil, fmt.Errorf("strandapi overlay: declared length %d exceeds received %d", length, n-overlayHdrSize)
+	if length == 0 || hdrSize+int(length) > n {
+		return nil, 0, 0, 0, 0, nil, fmt.Errorf("%w: declared length %d exceeds received %d", ErrMalformed, length, n-hdrSize)
+	}
+
+	// Verify the trailing signature, if required.
+	if t.verifyKey != nil {
+		body := hdrSize + int(length)
+		if flags&FlagSignature == 0 || n < body+signatureSize ||
+			!ed25519.Verify(t.verifyKey, buf[:body], buf[body:body+signatureSize]) {
+			return nil, 0, 0, 0, 0, nil, ErrUnauthenticated
+		}
+	}
+
+	// Save the remote address for listener-mode transports so that
+	// subsequent Send calls know where to reply. Only a fully verified frame
+	// may claim it, or a stray or forged datagram would steal the replies.
+	t.mu.Lock()
+	if t.remote == nil && remoteAddr != nil {
+		t.remote = remoteAddr
 	}
+	t.mu.Unlock()
 
-	opcode := buf[8]
-	payload := make([]byte, length-1)
-	copy(payload, buf[9:9+length-1])
+	opcode = buf[hdrSize]
+	payload = make([]byte, length-1)
+	copy(payload, buf[hdrSize+1:hdrSize+int(length)])
 
-	return opcode, payload, nil
+	if traceLog != nil {
+		traceLog.Printf("strandapi overlay: recv trace=%016x stream=%d opcode=0x%02x len=%d", traceID, streamID, opcode, len(payload))
+	}
+	return remoteAddr, flags, streamID, traceID, opcode, payload, nil
 }
 
 // Close shuts down the overlay transport.
@@ -3,8 +3,18 @@ package transport
 import (
 	"bytes"
 	"context"
+	"crypto/ed25519"
 	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -327,3 +337,790 @@ func TestOverlayLargePayload(t *testing.T) {
 		t.Errorf("large payload mismatch (got %d bytes, want %d)", len(gotPayload), len(payload))
 	}
 }
+
+func TestOverlayMaxPayload(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	listener, err := ListenOverlay("127.0.0.1:0", WithFrameVerification(pub))
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	defer listener.Close()
+	client, err := DialOverlay(listener.LocalAddr().String(), WithFrameSigning(priv))
+	if err != nil {
+		t.Fatalf("DialOverlay: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// The largest payload fits even with every header extension and a
+	// signature.
+	max := client.MaxPayload()
+	if err := client.SendTraced(ctx, 42, 0x01, make([]byte, max)); err != nil {
+		t.Fatalf("Send MaxPayload bytes: %v", err)
+	}
+	if _, payload, err := listener.Recv(ctx); err != nil || len(payload) != max {
+		t.Fatalf("Recv: len=%d err=%v, want len=%d", len(payload), err, max)
+	}
+
+	listener.SetReadBufferSize(1024)
+	if got := listener.MaxPayload(); got >= 1024 || got <= 0 {
+		t.Errorf("MaxPayload with 1024-byte read buffer = %d", got)
+	}
+}
+
+func TestOverlayRecvTruncated(t *testing.T) {
+	listener, err := ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	defer listener.Close()
+	listener.SetReadBufferSize(64)
+
+	client, err := DialOverlay(listener.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("DialOverlay: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// 8B header + 1B opcode + 200B payload does not fit in 64 bytes.
+	if err := client.Send(ctx, 0x01, make([]byte, 200)); err != nil {
+		t.Fatalf("Send oversized: %v", err)
+	}
+	if _, _, err := listener.Recv(ctx); err != ErrTruncated {
+		t.Fatalf("Recv oversized: got %v, want ErrTruncated", err)
+	}
+
+	// A frame that exactly fills the buffer is still accepted, and the
+	// transport remains usable after a truncated datagram.
+	exact := make([]byte, 64-overlayHdrSize-1)
+	exact[0] = 0xAB
+	if err := client.Send(ctx, 0x02, exact); err != nil {
+		t.Fatalf("Send exact: %v", err)
+	}
+	opcode, payload, err := listener.Recv(ctx)
+	if err != nil {
+		t.Fatalf("Recv exact: %v", err)
+	}
+	if opcode != 0x02 || len(payload) != len(exact) || payload[0] != 0xAB {
+		t.Errorf("Recv exact: opcode=0x%02x len=%d, want 0x02 len=%d", opcode, len(payload), len(exact))
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for use as a log.Logger sink.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestOverlayTraceIDRoundTrip(t *testing.T) {
+	listener, err := ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	defer listener.Close()
+
+	client, err := DialOverlay(listener.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("DialOverlay: %v", err)
+	}
+	defer client.Close()
+
+	var sendLog, recvLog syncBuffer
+	client.SetTraceLogger(log.New(&sendLog, "", 0))
+	listener.SetTraceLogger(log.New(&recvLog, "", 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	const traceID uint64 = 0x0123456789ABCDEF
+	if err := client.SendTraced(ctx, traceID, 0x01, []byte("traced")); err != nil {
+		t.Fatalf("SendTraced: %v", err)
+	}
+	gotID, opcode, payload, err := listener.RecvTraced(ctx)
+	if err != nil {
+		t.Fatalf("RecvTraced: %v", err)
+	}
+	if gotID != traceID || opcode != 0x01 || string(payload) != "traced" {
+		t.Errorf("RecvTraced = %016x 0x%02x %q, want %016x 0x01 %q", gotID, opcode, payload, traceID, "traced")
+	}
+
+	want := "trace=0123456789abcdef"
+	if !strings.Contains(sendLog.String(), want) {
+		t.Errorf("sender log %q does not contain %q", sendLog.String(), want)
+	}
+	if !strings.Contains(recvLog.String(), want) {
+		t.Errorf("receiver log %q does not contain %q", recvLog.String(), want)
+	}
+
+	// With trace logging on, plain Send frames get a trace ID assigned.
+	if err := client.Send(ctx, 0x02, nil); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	autoID, _, _, err := listener.RecvTraced(ctx)
+	if err != nil {
+		t.Fatalf("RecvTraced auto: %v", err)
+	}
+	if autoID == 0 {
+		t.Fatal("Send with trace logging enabled did not attach a trace ID")
+	}
+	if want := fmt.Sprintf("trace=%016x", autoID); !strings.Contains(recvLog.String(), want) || !strings.Contains(sendLog.String(), want) {
+		t.Errorf("auto-assigned trace %s missing from one side's log", want)
+	}
+}
+
+func TestOverlayUntracedFrame(t *testing.T) {
+	listener, err := ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	defer listener.Close()
+
+	client, err := DialOverlay(listener.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("DialOverlay: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// A traced frame on a non-zero stream carries both extensions.
+	if err := client.sendFrame(ctx, nil, 0, 9, 77, 0x03, []byte("both")); err != nil {
+		t.Fatalf("sendFrame: %v", err)
+	}
+	streamID, traceID, opcode, payload, err := listener.recvFrame(ctx)
+	if err != nil {
+		t.Fatalf("recvFrame: %v", err)
+	}
+	if streamID != 9 || traceID != 77 || opcode != 0x03 || string(payload) != "both" {
+		t.Errorf("recvFrame = stream %d trace %d 0x%02x %q", streamID, traceID, opcode, payload)
+	}
+
+	// Without trace logging, Send attaches no trace ID.
+	if err := client.Send(ctx, 0x04, []byte("plain")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	traceID, _, _, err = listener.RecvTraced(ctx)
+	if err != nil {
+		t.Fatalf("RecvTraced: %v", err)
+	}
+	if traceID != 0 {
+		t.Errorf("untraced frame has trace ID %d, want 0", traceID)
+	}
+}
+
+func TestOverlayFrameFlags(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	listener, err := ListenOverlay("127.0.0.1:0", WithFrameVerification(pub))
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	defer listener.Close()
+
+	// Signing and tracing add transport flags on the wire, which RecvFlags
+	// must not report.
+	client, err := DialOverlay(listener.LocalAddr().String(), WithFrameSigning(priv))
+	if err != nil {
+		t.Fatalf("DialOverlay: %v", err)
+	}
+	defer client.Close()
+	client.SetTraceLogger(log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	want := FlagReliable | FlagCompressed | FlagEncrypted | 0x80
+	if err := client.SendFlags(ctx, want, 0x05, []byte("flagged")); err != nil {
+		t.Fatalf("SendFlags: %v", err)
+	}
+	flags, opcode, payload, err := listener.RecvFlags(ctx)
+	if err != nil {
+		t.Fatalf("RecvFlags: %v", err)
+	}
+	if flags != want || opcode != 0x05 || string(payload) != "flagged" {
+		t.Errorf("RecvFlags = %s 0x%02x %q, want %s 0x05 %q", flags, opcode, payload, want, "flagged")
+	}
+	if !flags.Has(FlagReliable|FlagEncrypted) || flags.Has(FlagSignature) {
+		t.Errorf("Has on %s: reliable|encrypted = %v, signature = %v", flags, flags.Has(FlagReliable|FlagEncrypted), flags.Has(FlagSignature))
+	}
+	if got := want.String(); got != "reliable|compressed|encrypted|0x80" {
+		t.Errorf("String() = %q", got)
+	}
+
+	// A plain Send carries no higher-layer flags.
+	if err := client.Send(ctx, 0x06, nil); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if flags, _, _, err := listener.RecvFlags(ctx); err != nil || flags != 0 {
+		t.Errorf("RecvFlags after Send = %s, %v; want 0, nil", flags, err)
+	}
+
+	// Transport-managed bits cannot be set by callers.
+	if err := client.SendFlags(ctx, FlagReliable|FlagFIN, 0x07, nil); !errors.Is(err, ErrReservedFlags) {
+		t.Errorf("SendFlags with FlagFIN: err = %v, want ErrReservedFlags", err)
+	}
+}
+
+func TestOverlayFrameSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	listener, err := ListenOverlay("127.0.0.1:0", WithFrameVerification(pub))
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	defer listener.Close()
+
+	sender, err := DialOverlay(listener.LocalAddr().String(), WithFrameSigning(priv))
+	if err != nil {
+		t.Fatalf("DialOverlay: %v", err)
+	}
+	defer sender.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	payload := []byte("signed frame")
+	if err := sender.SendStream(ctx, 3, 0x01, payload); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	streamID, op, got, err := listener.RecvStream(ctx)
+	if err != nil {
+		t.Fatalf("Recv signed frame: %v", err)
+	}
+	if streamID != 3 || op != 0x01 || !bytes.Equal(got, payload) {
+		t.Errorf("got stream=%d op=0x%02x payload=%q, want stream=3 op=0x01 payload=%q", streamID, op, got, payload)
+	}
+}
+
+func TestOverlayFrameSignatureTampered(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	listener, err := ListenOverlay("127.0.0.1:0", WithFrameVerification(pub))
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	defer listener.Close()
+
+	// Capture a genuine signed frame on a raw socket.
+	tap, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP: %v", err)
+	}
+	defer tap.Close()
+	sender, err := DialOverlay(tap.LocalAddr().String(), WithFrameSigning(priv))
+	if err != nil {
+		t.Fatalf("DialOverlay: %v", err)
+	}
+	defer sender.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := sender.Send(ctx, 0x01, []byte("pay 10 credits")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	buf := make([]byte, 512)
+	_ = tap.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := tap.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("tap read: %v", err)
+	}
+	frame := buf[:n]
+	if FrameFlags(frame[3])&FlagSignature == 0 {
+		t.Fatal("signed frame lacks FlagSignature")
+	}
+
+	conn, err := net.DialUDP("udp", nil, listener.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP: %v", err)
+	}
+	defer conn.Close()
+
+	// Tamper with one payload byte; the signature no longer verifies.
+	tampered := append([]byte(nil), frame...)
+	tampered[overlayHdrSize+5] ^= 0xFF
+	conn.Write(tampered)
+	if _, _, err := listener.Recv(ctx); err != ErrUnauthenticated {
+		t.Errorf("tampered frame: err = %v, want ErrUnauthenticated", err)
+	}
+
+	// Stripping the signature entirely is rejected too.
+	unsigned := append([]byte(nil), frame[:n-ed25519.SignatureSize]...)
+	unsigned[3] &^= byte(FlagSignature)
+	conn.Write(unsigned)
+	if _, _, err := listener.Recv(ctx); err != ErrUnauthenticated {
+		t.Errorf("unsigned frame: err = %v, want ErrUnauthenticated", err)
+	}
+
+	// The untouched frame still verifies.
+	conn.Write(frame)
+	if _, payload, err := listener.Recv(ctx); err != nil || string(payload) != "pay 10 credits" {
+		t.Errorf("genuine frame: payload %q err %v", payload, err)
+	}
+}
+
+func TestOverlayWithInterface(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("no loopback interface named lo")
+	}
+	listener, err := ListenOverlay("127.0.0.1:0", WithInterface("lo"))
+	if err != nil {
+		if errors.Is(err, syscall.EPERM) {
+			t.Skipf("binding to an interface not permitted: %v", err)
+		}
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	defer listener.Close()
+
+	sender, err := DialOverlay(listener.LocalAddr().String(), WithInterface("lo"))
+	if err != nil {
+		t.Fatalf("DialOverlay: %v", err)
+	}
+	defer sender.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := sender.Send(ctx, 0x01, []byte("via lo")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	op, payload, err := listener.Recv(ctx)
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if op != 0x01 || string(payload) != "via lo" {
+		t.Errorf("got 0x%02x %q, want 0x01 %q", op, payload, "via lo")
+	}
+
+	if _, err := ListenOverlay("127.0.0.1:0", WithInterface("no-such-nic0")); err == nil {
+		t.Error("ListenOverlay with unknown interface: expected error")
+	}
+}
+
+func TestOverlaySocketBuffers(t *testing.T) {
+	const want = 4 << 20
+	tr, err := ListenOverlay("127.0.0.1:0", WithReadBuffer(want), WithWriteBuffer(want))
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	defer tr.Close()
+
+	base, err := ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	defer base.Close()
+
+	st, def := tr.Stats(), base.Stats()
+	if st.ReadBuffer == 0 && def.ReadBuffer == 0 {
+		t.Skip("socket buffer sizes not reported on this platform")
+	}
+	// The OS may clamp the request, but never below its own default.
+	if st.ReadBuffer < def.ReadBuffer || st.ReadBuffer == 0 {
+		t.Errorf("ReadBuffer = %d, default %d", st.ReadBuffer, def.ReadBuffer)
+	}
+	if st.WriteBuffer < def.WriteBuffer || st.WriteBuffer == 0 {
+		t.Errorf("WriteBuffer = %d, default %d", st.WriteBuffer, def.WriteBuffer)
+	}
+	if max := sysctlInt("/proc/sys/net/core/rmem_max"); max > 0 {
+		// Linux applies min(want, rmem_max) and reports it doubled.
+		if exp := 2 * min(want, max); st.ReadBuffer != exp {
+			t.Errorf("ReadBuffer = %d, want %d", st.ReadBuffer, exp)
+		}
+	}
+}
+
+// sysctlInt reads an integer sysctl from /proc, returning 0 if unavailable.
+func sysctlInt(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return n
+}
+
+func TestOverlayCloseSend(t *testing.T) {
+	listener, err := ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	defer listener.Close()
+
+	sender, err := DialOverlay(listener.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("DialOverlay: %v", err)
+	}
+	defer sender.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := sender.Send(ctx, 0x01, []byte("last request")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if err := sender.CloseSend(); err != nil {
+		t.Fatalf("CloseSend: %v", err)
+	}
+	if err := sender.CloseSend(); err != nil {
+		t.Errorf("second CloseSend: %v, want nil", err)
+	}
+	if err := sender.Send(ctx, 0x01, []byte("too late")); !errors.Is(err, ErrSendClosed) {
+		t.Errorf("Send after CloseSend: err = %v, want ErrSendClosed", err)
+	}
+
+	if op, payload, err := listener.Recv(ctx); err != nil || op != 0x01 || string(payload) != "last request" {
+		t.Fatalf("listener Recv = 0x%02x %q %v", op, payload, err)
+	}
+	if listener.PeerSendClosed() {
+		t.Error("PeerSendClosed before the FIN frame was read")
+	}
+	// The FIN frame is consumed rather than returned, so this Recv times out.
+	finCtx, finCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer finCancel()
+	if op, _, err := listener.Recv(finCtx); err == nil {
+		t.Fatalf("listener Recv returned opcode 0x%02x, want the FIN frame to be consumed", op)
+	}
+	if !listener.PeerSendClosed() {
+		t.Error("PeerSendClosed = false after the peer's FIN frame")
+	}
+
+	// The half-closed side still receives.
+	if err := listener.Send(ctx, 0x02, []byte("response")); err != nil {
+		t.Fatalf("listener Send: %v", err)
+	}
+	if op, payload, err := sender.Recv(ctx); err != nil || op != 0x02 || string(payload) != "response" {
+		t.Fatalf("sender Recv after CloseSend = 0x%02x %q %v", op, payload, err)
+	}
+}
+
+func TestOverlayCloseSendFailureKeepsSendOpen(t *testing.T) {
+	listener, err := ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	defer listener.Close()
+
+	// With no peer learned yet the FIN cannot be sent.
+	if err := listener.CloseSend(); err == nil {
+		t.Fatal("CloseSend with no peer: expected error")
+	}
+
+	dialer, err := DialOverlay(listener.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("DialOverlay: %v", err)
+	}
+	defer dialer.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := dialer.Send(ctx, 0x01, []byte("hello")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if _, _, err := listener.Recv(ctx); err != nil {
+		t.Fatalf("listener Recv: %v", err)
+	}
+
+	// The failed CloseSend left sending open, and a retry now succeeds.
+	if err := listener.Send(ctx, 0x02, []byte("reply")); err != nil {
+		t.Fatalf("Send after a failed CloseSend: %v", err)
+	}
+	if err := listener.CloseSend(); err != nil {
+		t.Fatalf("CloseSend retry: %v", err)
+	}
+	if _, payload, err := dialer.Recv(ctx); err != nil || string(payload) != "reply" {
+		t.Fatalf("dialer Recv = %q, %v; want %q", payload, err, "reply")
+	}
+	finCtx, finCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer finCancel()
+	dialer.Recv(finCtx)
+	if !dialer.PeerSendClosed() {
+		t.Error("PeerSendClosed = false after the retried FIN")
+	}
+}
+
+func TestOverlayCloseSendIsPerPeer(t *testing.T) {
+	listener, err := ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	defer listener.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	addrs := map[string]net.Addr{}
+	for _, name := range []string{"a", "b"} {
+		d, err := DialOverlay(listener.LocalAddr().String())
+		if err != nil {
+			t.Fatalf("DialOverlay: %v", err)
+		}
+		defer d.Close()
+		if err := d.Send(ctx, 0x01, []byte(name)); err != nil {
+			t.Fatalf("%s Send: %v", name, err)
+		}
+		from, _, _, payload, err := listener.RecvStreamFrom(ctx)
+		if err != nil || string(payload) != name {
+			t.Fatalf("RecvStreamFrom = %q, %v; want %q", payload, err, name)
+		}
+		addrs[name] = from
+		if name == "a" {
+			if err := d.CloseSend(); err != nil {
+				t.Fatalf("a CloseSend: %v", err)
+			}
+		}
+	}
+
+	// a's FIN is normally consumed while reading b's frame; this drains it
+	// in case b's frame overtook it.
+	finCtx, finCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer finCancel()
+	listener.Recv(finCtx)
+	if !listener.PeerSendClosedFrom(addrs["a"]) || listener.PeerSendClosedFrom(addrs["b"]) {
+		t.Errorf("PeerSendClosedFrom a=%v b=%v, want true and false",
+			listener.PeerSendClosedFrom(addrs["a"]), listener.PeerSendClosedFrom(addrs["b"]))
+	}
+
+	if err := listener.CloseSendTo(addrs["a"]); err != nil {
+		t.Fatalf("CloseSendTo(a): %v", err)
+	}
+	if err := listener.SendStreamTo(ctx, addrs["a"], 0, 0x02, nil); !errors.Is(err, ErrSendClosed) {
+		t.Errorf("SendStreamTo(a) after CloseSendTo(a): err = %v, want ErrSendClosed", err)
+	}
+	if err := listener.SendStreamTo(ctx, addrs["b"], 0, 0x02, nil); err != nil {
+		t.Errorf("SendStreamTo(b) after CloseSendTo(a): %v", err)
+	}
+}
+
+// memAddr is the address of a memPacketConn endpoint.
+type memAddr string
+
+func (a memAddr) Network() string { return "mem" }
+func (a memAddr) String() string  { return string(a) }
+
+type memDatagram struct {
+	from memAddr
+	data []byte
+}
+
+// memPacketConn is an in-memory net.PacketConn. Every datagram written is
+// delivered to peer unless drop reports true for its zero-based index.
+type memPacketConn struct {
+	addr memAddr
+	in   chan memDatagram
+	peer *memPacketConn
+	drop func(i int) bool
+
+	mu       sync.Mutex
+	sent     int
+	deadline time.Time
+	wake     chan struct{} // closed and replaced when the read deadline changes
+	done     chan struct{}
+	closed   bool
+}
+
+// newMemPacketConnPair returns two connected endpoints, "a" and "b".
+func newMemPacketConnPair() (*memPacketConn, *memPacketConn) {
+	a := &memPacketConn{addr: "a", in: make(chan memDatagram, 64), wake: make(chan struct{}), done: make(chan struct{})}
+	b := &memPacketConn{addr: "b", in: make(chan memDatagram, 64), wake: make(chan struct{}), done: make(chan struct{})}
+	a.peer, b.peer = b, a
+	return a, b
+}
+
+func (c *memPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	for {
+		c.mu.Lock()
+		deadline, wake := c.deadline, c.wake
+		c.mu.Unlock()
+		timer := time.NewTimer(time.Hour)
+		if !deadline.IsZero() {
+			timer.Reset(time.Until(deadline))
+		}
+		select {
+		case dg := <-c.in:
+			timer.Stop()
+			return copy(p, dg.data), dg.from, nil
+		case <-c.done:
+			timer.Stop()
+			return 0, nil, net.ErrClosed
+		case <-timer.C:
+			if !deadline.IsZero() {
+				return 0, nil, os.ErrDeadlineExceeded
+			}
+		case <-wake:
+			timer.Stop()
+		}
+	}
+}
+
+func (c *memPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if addr.String() != string(c.peer.addr) {
+		return 0, fmt.Errorf("mem: no route to %s", addr)
+	}
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, net.ErrClosed
+	}
+	i := c.sent
+	c.sent++
+	c.mu.Unlock()
+	if c.drop != nil && c.drop(i) {
+		return len(p), nil
+	}
+	c.peer.in <- memDatagram{from: c.addr, data: bytes.Clone(p)}
+	return len(p), nil
+}
+
+func (c *memPacketConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.done)
+	}
+	return nil
+}
+
+func (c *memPacketConn) LocalAddr() net.Addr { return c.addr }
+
+func (c *memPacketConn) SetDeadline(t time.Time) error { return c.SetReadDeadline(t) }
+
+func (c *memPacketConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadline = t
+	close(c.wake)
+	c.wake = make(chan struct{})
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *memPacketConn) SetWriteDeadline(time.Time) error { return nil }
+
+func TestOverlayFromConnWithLoss(t *testing.T) {
+	connA, connB := newMemPacketConnPair()
+	connA.drop = func(i int) bool { return i%3 == 2 } // lose every third datagram
+
+	sender, err := NewOverlayFromConn(connA, WithRemoteAddr(connB.LocalAddr()))
+	if err != nil {
+		t.Fatalf("NewOverlayFromConn: %v", err)
+	}
+	defer sender.Close()
+	receiver, err := NewOverlayFromConn(connB)
+	if err != nil {
+		t.Fatalf("NewOverlayFromConn: %v", err)
+	}
+	defer receiver.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	for op := byte(1); op <= 9; op++ {
+		if err := sender.Send(ctx, op, []byte{op, op}); err != nil {
+			t.Fatalf("Send 0x%02x: %v", op, err)
+		}
+	}
+	for _, want := range []byte{1, 2, 4, 5, 7, 8} {
+		from, op, payload, err := receiver.RecvFrom(ctx)
+		if err != nil {
+			t.Fatalf("RecvFrom: %v", err)
+		}
+		if op != want || !bytes.Equal(payload, []byte{want, want}) || from != "a" {
+			t.Fatalf("RecvFrom = %s 0x%02x %v, want a 0x%02x", from, op, payload, want)
+		}
+	}
+
+	// Nothing else arrives; the read deadline unblocks the fake conn.
+	shortCtx, shortCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer shortCancel()
+	if op, _, err := receiver.Recv(shortCtx); err == nil {
+		t.Fatalf("Recv returned opcode 0x%02x, want a timeout after the lost frames", op)
+	}
+
+	// The receiver learned the sender's address and can reply.
+	if err := receiver.Send(ctx, 0x10, []byte("ack")); err != nil {
+		t.Fatalf("receiver Send: %v", err)
+	}
+	if op, payload, err := sender.Recv(ctx); err != nil || op != 0x10 || string(payload) != "ack" {
+		t.Fatalf("sender Recv = 0x%02x %q %v", op, payload, err)
+	}
+	if st := sender.Stats(); st != (OverlayStats{}) {
+		t.Errorf("Stats over a non-UDP conn = %+v, want zero", st)
+	}
+
+	if err := sender.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := connA.WriteTo([]byte("x"), connB.LocalAddr()); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("underlying conn WriteTo after Close: err = %v, want net.ErrClosed", err)
+	}
+}
+
+func TestOverlayTransportStats(t *testing.T) {
+	listener, err := ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	defer listener.Close()
+	sender, err := DialOverlay(listener.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("DialOverlay: %v", err)
+	}
+	defer sender.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	payload := []byte("counted")
+	for i := 0; i < 3; i++ {
+		if err := sender.Send(ctx, 0x01, payload); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+		if _, _, err := listener.Recv(ctx); err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+	}
+
+	// A datagram with the wrong magic is counted as dropped.
+	raw, err := net.DialUDP("udp", nil, listener.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP: %v", err)
+	}
+	defer raw.Close()
+	raw.Write([]byte{0, 0, OverlayVersion, 0, 2, 0, 0, 0, 0x01, 0x42})
+	if _, _, err := listener.Recv(ctx); err != ErrInvalidMagic {
+		t.Fatalf("Recv = %v, want ErrInvalidMagic", err)
+	}
+
+	frameLen := uint64(overlayHdrSize + 1 + len(payload))
+	if got, want := sender.TransportStats(), (TransportStats{FramesSent: 3, BytesSent: 3 * frameLen}); got != want {
+		t.Errorf("sender stats = %+v, want %+v", got, want)
+	}
+	if got, want := listener.TransportStats(), (TransportStats{FramesReceived: 3, BytesReceived: 3 * frameLen, FramesDropped: 1}); got != want {
+		t.Errorf("listener stats = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,78 @@
+package transport
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pacingBurst is how much sending time a send pacer lets accumulate while
+// the transport is idle: a paced transport may burst up to this long's worth
+// of bytes before being held to its rate.
+const pacingBurst = 10 * time.Millisecond
+
+// WithSendPacing limits the transport's outgoing traffic to bytesPerSec,
+// counting whole frames on the wire. A send that would exceed the rate
+// blocks until the link has room for it or the send's context is done, so
+// a long stream of large frames (tensor chunks, for example) leaves at a
+// steady rate instead of overrunning socket buffers along the path. Up to
+// 10ms worth of traffic may go out at once after an idle period. A rate of 0
+// or less disables pacing.
+func WithSendPacing(bytesPerSec int) OverlayOption {
+	return func(t *OverlayTransport) {
+		if bytesPerSec <= 0 {
+			t.pacer = nil
+			return
+		}
+		t.pacer = newSendPacer(float64(bytesPerSec))
+	}
+}
+
+// sendPacer is a token bucket over bytes (WithSendPacing). A frame larger
+// than the bucket is let through once the bucket is full and leaves it in
+// debt, so frames of any size are paced rather than refused.
+type sendPacer struct {
+	rate  float64 // bytes per second
+	burst float64 // bucket capacity in bytes
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newSendPacer(bytesPerSec float64) *sendPacer {
+	burst := bytesPerSec * pacingBurst.Seconds()
+	return &sendPacer{rate: bytesPerSec, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// wait reserves n bytes of sending capacity and blocks until the reservation
+// is due. Concurrent senders are paced together, in the order they reserve.
+// If ctx ends first the reservation is returned so it does not delay later
+// sends.
+func (p *sendPacer) wait(ctx context.Context, n int) error {
+	p.mu.Lock()
+	now := time.Now()
+	p.tokens = min(p.burst, p.tokens+now.Sub(p.last).Seconds()*p.rate)
+	p.last = now
+	p.tokens -= float64(n)
+	var delay time.Duration
+	if p.tokens < 0 {
+		delay = time.Duration(-p.tokens / p.rate * float64(time.Second))
+	}
+	p.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		p.mu.Lock()
+		p.tokens += float64(n)
+		p.mu.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,47 @@
+package transport
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSendPacing(t *testing.T) {
+	const (
+		rate      = 256 << 10 // bytes per second
+		frames    = 32
+		chunkSize = 4000
+	)
+	connA, connB := newMemPacketConnPair()
+	sender, err := NewOverlayFromConn(connA, WithRemoteAddr(connB.LocalAddr()), WithSendPacing(rate))
+	if err != nil {
+		t.Fatalf("NewOverlayFromConn: %v", err)
+	}
+	defer sender.Close()
+	defer connB.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	chunk := make([]byte, chunkSize)
+	start := time.Now()
+	for i := 0; i < frames; i++ {
+		if err := sender.Send(ctx, 0x01, chunk); err != nil {
+			t.Fatalf("Send %d: %v", i, err)
+		}
+	}
+	elapsed := time.Since(start)
+
+	// Everything beyond the initial burst allowance leaves at the rate.
+	sent := float64(sender.TransportStats().BytesSent)
+	want := time.Duration((sent - rate*pacingBurst.Seconds()) / rate * float64(time.Second))
+	if elapsed < want*9/10 || elapsed > want+250*time.Millisecond {
+		t.Errorf("sending %.0f bytes at %d B/s took %v, want about %v", sent, rate, elapsed, want)
+	}
+
+	// A send that cannot go out before its context ends fails with it.
+	short, cancelShort := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancelShort()
+	if err := sender.Send(short, 0x01, make([]byte, 32000)); err != context.DeadlineExceeded {
+		t.Errorf("paced Send past its deadline: err = %v, want context.DeadlineExceeded", err)
+	}
+}
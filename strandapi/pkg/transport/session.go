@@ -0,0 +1,98 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// ErrStreamClosed is returned by Stream operations after the stream or its
+// parent Session has been closed.
+var ErrStreamClosed = errors.New("strandapi session: stream is closed")
+
+// StreamTransport is a transport that can tag frames with a logical stream
+// ID. OverlayTransport implements it.
+type StreamTransport interface {
+	SendStream(ctx context.Context, streamID uint32, opcode byte, payload []byte) error
+	RecvStream(ctx context.Context) (streamID uint32, opcode byte, payload []byte, err error)
+	Close() error
+}
+
+// Session multiplexes multiple logical streams over a single StreamTransport.
+// A background reader demultiplexes received frames by stream ID and delivers
+// them to the matching Stream; frames for a stream ID that has not been
+// opened locally create a new stream that is handed out by Accept. Like
+// Demux, it never drops frames, so a peer-initiated stream waits for Accept.
+type Session struct {
+	t StreamTransport
+	f *fanout[uint32, *Stream]
+}
+
+// NewSession starts multiplexing over t. The session takes ownership of t and
+// closes it when the session is closed.
+func NewSession(t StreamTransport) *Session {
+	s := &Session{t: t}
+	s.f = newFanout(
+		func(ctx context.Context) (uint32, net.Addr, frame, error) {
+			id, opcode, payload, err := t.RecvStream(ctx)
+			return id, nil, frame{opcode: opcode, payload: payload}, err
+		},
+		func(id uint32, _ net.Addr, q *queue) *Stream {
+			return &Stream{id: id, session: s, q: q}
+		},
+		t.Close,
+	)
+	return s
+}
+
+// Open returns the local Stream for the given ID, creating it if needed.
+func (s *Session) Open(id uint32) (*Stream, error) {
+	return s.f.open(id, nil)
+}
+
+// Accept blocks until the peer sends a frame on a stream that has not been
+// opened locally, and returns that stream.
+func (s *Session) Accept(ctx context.Context) (*Stream, error) {
+	return s.f.acceptStream(ctx)
+}
+
+// Err returns the error that terminated the session reader, if any.
+func (s *Session) Err() error { return s.f.Err() }
+
+// Close shuts down the session, all of its streams, and the underlying
+// transport.
+func (s *Session) Close() error { return s.f.close() }
+
+// Stream is one logical stream within a Session. It implements Transport, so
+// it can back a client or server just like a dedicated socket.
+type Stream struct {
+	id      uint32
+	session *Session
+	q       *queue
+}
+
+var _ Transport = (*Stream)(nil)
+
+// ID returns the logical stream identifier.
+func (st *Stream) ID() uint32 { return st.id }
+
+// Send transmits a frame on this stream.
+func (st *Stream) Send(ctx context.Context, opcode byte, payload []byte) error {
+	if err := st.q.checkOpen(); err != nil {
+		return err
+	}
+	return st.session.t.SendStream(ctx, st.id, opcode, payload)
+}
+
+// Recv blocks until a frame addressed to this stream arrives.
+func (st *Stream) Recv(ctx context.Context) (byte, []byte, error) {
+	return st.q.next(ctx)
+}
+
+// Close detaches the stream from its session. It does not close the session
+// or notify the peer; frames that arrive for the stream afterwards are
+// dropped unless it is opened again.
+func (st *Stream) Close() error {
+	st.q.close()
+	return nil
+}
@@ -0,0 +1,291 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestOverlayStreamIDRoundTrip(t *testing.T) {
+	listener, err := ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	defer listener.Close()
+
+	sender, err := DialOverlay(listener.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("DialOverlay: %v", err)
+	}
+	defer sender.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := sender.SendStream(ctx, 0xDEADBEEF, 0x05, []byte("tagged")); err != nil {
+		t.Fatalf("SendStream: %v", err)
+	}
+	id, op, payload, err := listener.RecvStream(ctx)
+	if err != nil {
+		t.Fatalf("RecvStream: %v", err)
+	}
+	if id != 0xDEADBEEF {
+		t.Errorf("stream ID = 0x%08x, want 0xDEADBEEF", id)
+	}
+	if op != 0x05 {
+		t.Errorf("opcode = 0x%02x, want 0x05", op)
+	}
+	if string(payload) != "tagged" {
+		t.Errorf("payload = %q, want %q", payload, "tagged")
+	}
+}
+
+func TestOverlayStreamZeroOmitsExtension(t *testing.T) {
+	// A frame on stream 0 must be byte-identical to a plain Send so older
+	// peers can still parse it.
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP: %v", err)
+	}
+	defer conn.Close()
+
+	sender, err := DialOverlay(conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("DialOverlay: %v", err)
+	}
+	defer sender.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := sender.SendStream(ctx, 0, 0x01, []byte("x")); err != nil {
+		t.Fatalf("SendStream: %v", err)
+	}
+
+	buf := make([]byte, 64)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("ReadFromUDP: %v", err)
+	}
+	if n != overlayHdrSize+2 {
+		t.Errorf("frame size = %d, want %d", n, overlayHdrSize+2)
+	}
+	if buf[3] != 0 {
+		t.Errorf("flags = 0x%02x, want 0", buf[3])
+	}
+}
+
+func TestSessionDemultiplexesStreams(t *testing.T) {
+	listener, err := ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	dialer, err := DialOverlay(listener.LocalAddr().String())
+	if err != nil {
+		listener.Close()
+		t.Fatalf("DialOverlay: %v", err)
+	}
+
+	clientSess := NewSession(dialer)
+	defer clientSess.Close()
+	serverSess := NewSession(listener)
+	defer serverSess.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	a, err := clientSess.Open(1)
+	if err != nil {
+		t.Fatalf("Open(1): %v", err)
+	}
+	b, err := clientSess.Open(2)
+	if err != nil {
+		t.Fatalf("Open(2): %v", err)
+	}
+
+	// Interleave frames on the two logical streams.
+	const perStream = 5
+	for i := 0; i < perStream; i++ {
+		if err := a.Send(ctx, 0x01, []byte(fmt.Sprintf("a-%d", i))); err != nil {
+			t.Fatalf("a.Send: %v", err)
+		}
+		if err := b.Send(ctx, 0x02, []byte(fmt.Sprintf("b-%d", i))); err != nil {
+			t.Fatalf("b.Send: %v", err)
+		}
+	}
+
+	// The server side sees two peer-initiated streams.
+	accepted := map[uint32]*Stream{}
+	for len(accepted) < 2 {
+		st, err := serverSess.Accept(ctx)
+		if err != nil {
+			t.Fatalf("Accept: %v", err)
+		}
+		accepted[st.ID()] = st
+	}
+
+	for id, prefix := range map[uint32]string{1: "a", 2: "b"} {
+		st, ok := accepted[id]
+		if !ok {
+			t.Fatalf("stream %d was not accepted", id)
+		}
+		for i := 0; i < perStream; i++ {
+			op, payload, err := st.Recv(ctx)
+			if err != nil {
+				t.Fatalf("stream %d Recv %d: %v", id, i, err)
+			}
+			want := fmt.Sprintf("%s-%d", prefix, i)
+			if string(payload) != want {
+				t.Errorf("stream %d frame %d = %q, want %q", id, i, payload, want)
+			}
+			if op != byte(id) {
+				t.Errorf("stream %d opcode = 0x%02x, want 0x%02x", id, op, byte(id))
+			}
+		}
+	}
+
+	// Replies are routed back to the matching client-side stream.
+	if err := accepted[2].Send(ctx, 0x12, []byte("reply-b")); err != nil {
+		t.Fatalf("reply b: %v", err)
+	}
+	if err := accepted[1].Send(ctx, 0x11, []byte("reply-a")); err != nil {
+		t.Fatalf("reply a: %v", err)
+	}
+	if _, payload, err := a.Recv(ctx); err != nil || string(payload) != "reply-a" {
+		t.Errorf("a.Recv = %q, %v; want %q", payload, err, "reply-a")
+	}
+	if _, payload, err := b.Recv(ctx); err != nil || string(payload) != "reply-b" {
+		t.Errorf("b.Recv = %q, %v; want %q", payload, err, "reply-b")
+	}
+}
+
+func TestSessionCloseUnblocksStreams(t *testing.T) {
+	listener, err := ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	sess := NewSession(listener)
+
+	st, err := sess.Open(7)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, _, err := st.Recv(context.Background())
+		errCh <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	sess.Close()
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Error("expected error from Recv after session close")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Recv did not unblock after session close")
+	}
+
+	if _, err := sess.Open(8); err == nil {
+		t.Error("expected error opening a stream on a closed session")
+	}
+}
+
+func TestSessionSkipsRejectedDatagrams(t *testing.T) {
+	listener, err := ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	sess := NewSession(listener)
+	defer sess.Close()
+
+	raw, err := net.Dial("udp", listener.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("net.Dial: %v", err)
+	}
+	defer raw.Close()
+	if _, err := raw.Write([]byte("garbage")); err != nil {
+		t.Fatalf("write garbage: %v", err)
+	}
+
+	dialer, err := DialOverlay(listener.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("DialOverlay: %v", err)
+	}
+	defer dialer.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := dialer.SendStream(ctx, 7, 0x01, []byte("after")); err != nil {
+		t.Fatalf("SendStream: %v", err)
+	}
+
+	st, err := sess.Accept(ctx)
+	if err != nil {
+		t.Fatalf("Accept after a rejected datagram: %v", err)
+	}
+	if _, payload, err := st.Recv(ctx); err != nil || string(payload) != "after" {
+		t.Fatalf("Recv = %q, %v; want %q", payload, err, "after")
+	}
+}
+
+func TestSessionDropsLateFramesForClosedStream(t *testing.T) {
+	listener, err := ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	sess := NewSession(listener)
+	defer sess.Close()
+
+	dialer, err := DialOverlay(listener.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("DialOverlay: %v", err)
+	}
+	defer dialer.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := dialer.SendStream(ctx, 4, 0x01, []byte("first")); err != nil {
+		t.Fatalf("SendStream: %v", err)
+	}
+	st, err := sess.Accept(ctx)
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	if _, payload, err := st.Recv(ctx); err != nil || string(payload) != "first" {
+		t.Fatalf("Recv = %q, %v; want %q", payload, err, "first")
+	}
+	st.Close()
+
+	// A late frame for the closed stream must not surface it again; the
+	// next new stream is the one accepted.
+	if err := dialer.SendStream(ctx, 4, 0x01, []byte("late")); err != nil {
+		t.Fatalf("SendStream late: %v", err)
+	}
+	if err := dialer.SendStream(ctx, 5, 0x01, []byte("next")); err != nil {
+		t.Fatalf("SendStream next: %v", err)
+	}
+	next, err := sess.Accept(ctx)
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	if next.ID() != 5 {
+		t.Fatalf("Accept returned stream %d, want 5 with the late frame for 4 dropped", next.ID())
+	}
+
+	// Opening the stream again locally accepts its frames once more.
+	reopened, err := sess.Open(4)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := dialer.SendStream(ctx, 4, 0x01, []byte("again")); err != nil {
+		t.Fatalf("SendStream again: %v", err)
+	}
+	if _, payload, err := reopened.Recv(ctx); err != nil || string(payload) != "again" {
+		t.Fatalf("reopened Recv = %q, %v; want %q", payload, err, "again")
+	}
+}
@@ -0,0 +1,43 @@
+package transport
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// interfaceBinding returns a socket control function that binds the socket to
+// the named interface with SO_BINDTODEVICE.
+func interfaceBinding(name string) (func(network, address string, c syscall.RawConn) error, net.IP, error) {
+	if _, err := net.InterfaceByName(name); err != nil {
+		return nil, nil, fmt.Errorf("interface %q: %w", name, err)
+	}
+	control := func(_, _ string, c syscall.RawConn) error {
+		var serr error
+		if err := c.Control(func(fd uintptr) {
+			serr = syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, name)
+		}); err != nil {
+			return err
+		}
+		if serr != nil {
+			return fmt.Errorf("bind to interface %q: %w", name, serr)
+		}
+		return nil
+	}
+	return control, nil, nil
+}
+
+// socketBufferSizes returns the kernel receive and send buffer sizes of conn.
+// Linux reports twice the requested size to account for bookkeeping
+// overhead.
+func socketBufferSizes(conn *net.UDPConn) (rcv, snd int) {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return 0, 0
+	}
+	_ = rc.Control(func(fd uintptr) {
+		rcv, _ = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+		snd, _ = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_SNDBUF)
+	})
+	return rcv, snd
+}
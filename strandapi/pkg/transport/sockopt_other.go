@@ -0,0 +1,46 @@
+//go:build !linux
+
+package transport
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// interfaceBinding returns the address to bind to for the named interface.
+// Without SO_BINDTODEVICE the closest portable approximation is binding to
+// the interface's first address, preferring IPv4.
+func interfaceBinding(name string) (func(network, address string, c syscall.RawConn) error, net.IP, error) {
+	ifi, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("interface %q: %w", name, err)
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, nil, fmt.Errorf("interface %q: %w", name, err)
+	}
+	var first net.IP
+	for _, a := range addrs {
+		ipn, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ip4 := ipn.IP.To4(); ip4 != nil {
+			return nil, ip4, nil
+		}
+		if first == nil {
+			first = ipn.IP
+		}
+	}
+	if first == nil {
+		return nil, nil, fmt.Errorf("interface %q has no addresses", name)
+	}
+	return nil, first, nil
+}
+
+// socketBufferSizes is not implemented portably; the sizes are reported as
+// unknown.
+func socketBufferSizes(conn *net.UDPConn) (rcv, snd int) {
+	return 0, 0
+}
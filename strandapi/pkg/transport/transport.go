@@ -23,3 +23,39 @@ type Transport interface {
 	// return an error.
 	Close() error
 }
+
+// TransportStats counts the traffic a transport has carried since it was
+// created. Byte counts are whole frames as sent on the wire, headers
+// included.
+type TransportStats struct {
+	FramesSent     uint64
+	FramesReceived uint64
+	BytesSent      uint64
+	BytesReceived  uint64
+	// FramesDropped counts inbound datagrams rejected as malformed,
+	// truncated or failing signature verification.
+	FramesDropped uint64
+}
+
+// StatsTransport is implemented by transports that count their traffic.
+// OverlayTransport implements it.
+type StatsTransport interface {
+	TransportStats() TransportStats
+}
+
+// PeerTransport is implemented by transports that can report which peer sent
+// each frame. Servers use it to account for resources per peer.
+type PeerTransport interface {
+	Transport
+
+	// RecvFrom is like Recv but also returns an opaque identifier for the
+	// sending peer, such as its network address.
+	RecvFrom(ctx context.Context) (peer string, opcode byte, payload []byte, err error)
+}
+
+// MaxPayloadTransport is implemented by transports that bound the payload a
+// single frame can carry. Servers cap the message size they advertise in
+// OpHello to it. OverlayTransport implements it.
+type MaxPayloadTransport interface {
+	MaxPayload() int
+}
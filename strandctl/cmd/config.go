@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/strand-protocol/strand/strandctl/pkg/config"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage strandctl configuration contexts",
+	Long: `Manage the named contexts in the strandctl config file. Each context
+holds a server URL and API key; commands use the current context unless
+--context names another.`,
+}
+
+var configUseContextCmd = &cobra.Command{
+	Use:   "use-context <name>",
+	Short: "Set the current context in the config file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Reload rather than saving cfg, which has the context applied.
+		path := configPath()
+		c, err := config.Load(path)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if err := c.UseContext(args[0]); err != nil {
+			return err
+		}
+		if err := c.Save(path); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Switched to context %q.\n", args[0])
+		return nil
+	},
+}
+
+// contextRow is one line of `config get-contexts`. API keys are never
+// printed.
+type contextRow struct {
+	Current string `json:"current" yaml:"current"`
+	Name    string `json:"name" yaml:"name"`
+	Server  string `json:"server" yaml:"server"`
+}
+
+var configGetContextsCmd = &cobra.Command{
+	Use:   "get-contexts",
+	Short: "List the contexts defined in the config file",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names := make([]string, 0, len(cfg.Contexts))
+		for name := range cfg.Contexts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		rows := make([]contextRow, 0, len(names))
+		for _, name := range names {
+			row := contextRow{Name: name, Server: cfg.Contexts[name].ServerURL}
+			if name == cfg.Context {
+				row.Current = "*"
+			}
+			rows = append(rows, row)
+		}
+		fmt.Fprint(cmd.OutOrStdout(), formatter.Format(rows))
+		return nil
+	},
+}
+
+var configCurrentContextCmd = &cobra.Command{
+	Use:   "current-context",
+	Short: "Show the context and server URL commands will use",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s (%s)\n", cfg.Context, cfg.ServerURL)
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configUseContextCmd)
+	configCmd.AddCommand(configGetContextsCmd)
+	configCmd.AddCommand(configCurrentContextCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -1,10 +1,17 @@
 package cmd
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/strand-protocol/strand/strandctl/pkg/api"
 )
 
 var metricsCmd = &cobra.Command{
@@ -31,45 +38,112 @@ var metricsShowCmd = &cobra.Command{
 	},
 }
 
-var metricsExportFormatFlag string
+var (
+	metricsExportFormatFlag string
+	metricsExportPushFlag   string
+	metricsExportJobFlag    string
+)
+
+// metricFamily describes one exported metric and how to read it from a
+// node's MetricsData.
+type metricFamily struct {
+	name, help, kind string
+	value            func(m *api.MetricsData) string
+}
+
+var metricFamilies = []metricFamily{
+	{"strand_connections", "Number of active connections", "gauge",
+		func(m *api.MetricsData) string { return fmt.Sprintf("%d", m.Connections) }},
+	{"strand_bytes_sent", "Total bytes sent", "counter",
+		func(m *api.MetricsData) string { return fmt.Sprintf("%d", m.BytesSent) }},
+	{"strand_bytes_recv", "Total bytes received", "counter",
+		func(m *api.MetricsData) string { return fmt.Sprintf("%d", m.BytesRecv) }},
+	{"strand_latency_ms", "Latency in milliseconds", "gauge",
+		func(m *api.MetricsData) string { return fmt.Sprintf("%.2f", m.Latency) }},
+}
+
+// Content types for the text exposition formats, as expected by a
+// Prometheus pushgateway.
+const (
+	prometheusContentType  = "text/plain; version=0.0.4; charset=utf-8"
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// writeExposition writes metrics in the Prometheus text format, or in the
+// OpenMetrics format (counter samples suffixed _total, terminated by
+// "# EOF") when openMetrics is set. Each family's HELP and TYPE lines appear
+// once, followed by one sample per node.
+func writeExposition(w io.Writer, metrics []*api.MetricsData, openMetrics bool) {
+	for _, f := range metricFamilies {
+		sample := f.name
+		if openMetrics && f.kind == "counter" {
+			sample += "_total"
+		}
+		fmt.Fprintf(w, "# HELP %s %s\n", f.name, f.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+		for _, m := range metrics {
+			fmt.Fprintf(w, "%s{node_id=%q} %s\n", sample, m.NodeID, f.value(m))
+		}
+	}
+	if openMetrics {
+		fmt.Fprintln(w, "# EOF")
+	}
+}
+
+// pushMetrics POSTs an exposition body to a Prometheus pushgateway under the
+// given job name.
+func pushMetrics(gateway, job, contentType string, body []byte) error {
+	endpoint := strings.TrimSuffix(gateway, "/") + "/metrics/job/" + url.PathEscape(job)
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	resp, err := httpClient.Post(endpoint, contentType, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("pushgateway returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
 
 var metricsExportCmd = &cobra.Command{
 	Use:   "export",
-	Short: "Export metrics in Prometheus or JSON format",
+	Short: "Export metrics in Prometheus, OpenMetrics or JSON format",
+	Long: `Export metrics for every node to stdout, or with --push to a Prometheus
+pushgateway (prometheus and openmetrics formats only).`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Gather metrics from all nodes
 		nodes, err := client.ListNodes()
 		if err != nil {
 			return fmt.Errorf("failed to list nodes: %w", err)
 		}
+		var metrics []*api.MetricsData
+		for _, n := range nodes {
+			m, err := client.GetMetrics(n.ID)
+			if err != nil {
+				continue
+			}
+			metrics = append(metrics, m)
+		}
 
+		var (
+			body        bytes.Buffer
+			contentType string
+		)
 		switch metricsExportFormatFlag {
 		case "prometheus":
-			for _, n := range nodes {
-				m, err := client.GetMetrics(n.ID)
-				if err != nil {
-					continue
-				}
-				fmt.Fprintf(cmd.OutOrStdout(), "# HELP strand_connections Number of active connections\n")
-				fmt.Fprintf(cmd.OutOrStdout(), "# TYPE strand_connections gauge\n")
-				fmt.Fprintf(cmd.OutOrStdout(), "strand_connections{node_id=%q} %d\n", m.NodeID, m.Connections)
-				fmt.Fprintf(cmd.OutOrStdout(), "# HELP strand_bytes_sent Total bytes sent\n")
-				fmt.Fprintf(cmd.OutOrStdout(), "# TYPE strand_bytes_sent counter\n")
-				fmt.Fprintf(cmd.OutOrStdout(), "strand_bytes_sent{node_id=%q} %d\n", m.NodeID, m.BytesSent)
-				fmt.Fprintf(cmd.OutOrStdout(), "# HELP strand_bytes_recv Total bytes received\n")
-				fmt.Fprintf(cmd.OutOrStdout(), "# TYPE strand_bytes_recv counter\n")
-				fmt.Fprintf(cmd.OutOrStdout(), "strand_bytes_recv{node_id=%q} %d\n", m.NodeID, m.BytesRecv)
-				fmt.Fprintf(cmd.OutOrStdout(), "# HELP strand_latency_ms Latency in milliseconds\n")
-				fmt.Fprintf(cmd.OutOrStdout(), "# TYPE strand_latency_ms gauge\n")
-				fmt.Fprintf(cmd.OutOrStdout(), "strand_latency_ms{node_id=%q} %.2f\n", m.NodeID, m.Latency)
-			}
+			writeExposition(&body, metrics, false)
+			contentType = prometheusContentType
+		case "openmetrics":
+			writeExposition(&body, metrics, true)
+			contentType = openMetricsContentType
 		case "json":
-			var allMetrics []any
-			for _, n := range nodes {
-				m, err := client.GetMetrics(n.ID)
-				if err != nil {
-					continue
-				}
+			if metricsExportPushFlag != "" {
+				return fmt.Errorf("--push requires --format prometheus or openmetrics")
+			}
+			allMetrics := make([]any, 0, len(metrics))
+			for _, m := range metrics {
 				allMetrics = append(allMetrics, m)
 			}
 			data, err := json.MarshalIndent(allMetrics, "", "  ")
@@ -77,16 +151,32 @@ var metricsExportCmd = &cobra.Command{
 				return fmt.Errorf("failed to marshal metrics: %w", err)
 			}
 			fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			return nil
 		default:
-			return fmt.Errorf("unsupported export format %q (use prometheus or json)", metricsExportFormatFlag)
+			return fmt.Errorf("unsupported export format %q (use prometheus, openmetrics or json)", metricsExportFormatFlag)
+		}
+
+		if metricsExportPushFlag == "" {
+			_, err := cmd.OutOrStdout().Write(body.Bytes())
+			return err
+		}
+		if dryRun {
+			fmt.Fprintf(cmd.OutOrStdout(), "(dry-run) would push metrics for %d nodes to %s\n", len(metrics), metricsExportPushFlag)
+			return nil
+		}
+		if err := pushMetrics(metricsExportPushFlag, metricsExportJobFlag, contentType, body.Bytes()); err != nil {
+			return err
 		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Pushed metrics for %d nodes to %s\n", len(metrics), metricsExportPushFlag)
 		return nil
 	},
 }
 
 func init() {
 	metricsShowCmd.Flags().StringVar(&metricsShowNodeFlag, "node", "", "node ID to show metrics for (required)")
-	metricsExportCmd.Flags().StringVar(&metricsExportFormatFlag, "format", "prometheus", "export format: prometheus, json")
+	metricsExportCmd.Flags().StringVar(&metricsExportFormatFlag, "format", "prometheus", "export format: prometheus, openmetrics, json")
+	metricsExportCmd.Flags().StringVar(&metricsExportPushFlag, "push", "", "push metrics to this Prometheus pushgateway URL instead of printing them")
+	metricsExportCmd.Flags().StringVar(&metricsExportJobFlag, "job", "strandctl", "pushgateway job name used with --push")
 
 	metricsCmd.AddCommand(metricsShowCmd)
 	metricsCmd.AddCommand(metricsExportCmd)
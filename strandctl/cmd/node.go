@@ -4,7 +4,9 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"reflect"
 	"strings"
+	"time"
 
 	"github.com/strand-protocol/strand/strandctl/pkg/api"
 	"github.com/spf13/cobra"
@@ -16,10 +18,24 @@ var nodeCmd = &cobra.Command{
 	Long:  "List, inspect, and manage nodes in the Strand network.",
 }
 
+var (
+	nodeListWatch    bool
+	nodeListInterval time.Duration
+	nodeListCount    int
+)
+
 var nodeListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all known nodes in the network",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if nodeListWatch {
+			lw := listWatch[api.NodeInfo]{
+				fetch: client.ListNodes,
+				key:   func(n api.NodeInfo) string { return n.ID },
+				equal: nodesEqual,
+			}
+			return lw.run(cmd.Context(), cmd.OutOrStdout(), nodeListInterval, nodeListCount)
+		}
 		nodes, err := client.ListNodes()
 		if err != nil {
 			return fmt.Errorf("failed to list nodes: %w", err)
@@ -75,7 +91,17 @@ var nodeDrainCmd = &cobra.Command{
 	},
 }
 
+// nodesEqual reports whether two snapshots of a node differ in anything but
+// LastSeen, which moves on every heartbeat and would mark every row changed.
+func nodesEqual(a, b api.NodeInfo) bool {
+	a.LastSeen, b.LastSeen = time.Time{}, time.Time{}
+	return reflect.DeepEqual(a, b)
+}
+
 func init() {
+	nodeListCmd.Flags().BoolVarP(&nodeListWatch, "watch", "w", false, "refresh the list every --interval, marking added (+), changed (~) and removed (-) rows")
+	nodeListCmd.Flags().DurationVar(&nodeListInterval, "interval", 2*time.Second, "refresh interval for --watch")
+	nodeListCmd.Flags().IntVar(&nodeListCount, "count", 0, "stop --watch after this many refreshes (0 = until interrupted)")
 	nodeCmd.AddCommand(nodeListCmd)
 	nodeCmd.AddCommand(nodeDescribeCmd)
 	nodeCmd.AddCommand(nodeDrainCmd)
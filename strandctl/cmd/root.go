@@ -15,6 +15,7 @@ var (
 	cfgFile      string
 	outputFormat string
 	serverURL    string
+	contextName  string // --context: config context to use instead of the active one
 	dryRun       bool // --dry-run: print actions without executing them
 	yesFlag      bool // --yes: skip confirmation prompts for destructive operations
 
@@ -36,15 +37,14 @@ NICs, and servers.`,
 	SilenceErrors: true,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		// Load configuration
-		path := cfgFile
-		if path == "" {
-			path = config.DefaultPath()
-		}
 		var err error
-		cfg, err = config.Load(path)
+		cfg, err = config.Load(configPath())
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
+		if err := cfg.ApplyContext(contextName); err != nil {
+			return err
+		}
 
 		// Override config with flags
 		if serverURL != "" {
@@ -54,8 +54,11 @@ NICs, and servers.`,
 			cfg.OutputFormat = outputFormat
 		}
 
-		// Create API client (mock for now)
-		client = &api.MockClient{}
+		// Create API client (mock for now) unless one was injected with
+		// SetClient.
+		if client == nil {
+			client = &api.MockClient{}
+		}
 
 		// Create output formatter
 		formatter = output.NewFormatter(cfg.OutputFormat)
@@ -64,6 +67,14 @@ NICs, and servers.`,
 	},
 }
 
+// configPath returns the config file named by --config, or the default.
+func configPath() string {
+	if cfgFile != "" {
+		return cfgFile
+	}
+	return config.DefaultPath()
+}
+
 // Execute runs the root command.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
@@ -88,7 +99,8 @@ func RootCmd() *cobra.Command {
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ~/.strand/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ~/.strandctl/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&contextName, "context", "", "config context to use (default is the config's current context)")
 	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "", "output format: table, json, yaml (default \"table\")")
 	rootCmd.PersistentFlags().StringVar(&serverURL, "server", "", "Strand API server URL")
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "print actions that would be taken without executing them")
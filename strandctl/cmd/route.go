@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/strand-protocol/strand/strandapi/pkg/sad"
 	"github.com/strand-protocol/strand/strandctl/pkg/api"
 	"github.com/spf13/cobra"
 )
@@ -57,15 +58,19 @@ var routeAddCmd = &cobra.Command{
 			parts = append(parts, routeAddModelType)
 		}
 		if routeAddContextWindow != "" {
-			parts = append(parts, routeAddContextWindow)
+			tokens, err := sad.ParseContextSuffix(routeAddContextWindow)
+			if err != nil {
+				return fmt.Errorf("invalid --context-window: %w", err)
+			}
+			parts = append(parts, sad.FormatContext(tokens))
 		}
 		if routeAddLatencySLA != "" {
 			parts = append(parts, routeAddLatencySLA)
 		}
-		sad := strings.Join(parts, ":")
+		descriptor := strings.Join(parts, ":")
 
 		route := api.RouteInfo{
-			SAD:       sad,
+			SAD:       descriptor,
 			Endpoints: []string{},
 			Weight:    100,
 			TTL:       300,
@@ -73,14 +78,14 @@ var routeAddCmd = &cobra.Command{
 		if err := client.AddRoute(route); err != nil {
 			return fmt.Errorf("failed to add route: %w", err)
 		}
-		fmt.Fprintf(cmd.OutOrStdout(), "Route %q added successfully.\n", sad)
+		fmt.Fprintf(cmd.OutOrStdout(), "Route %q added successfully.\n", descriptor)
 		return nil
 	},
 }
 
 func init() {
 	routeAddCmd.Flags().StringVar(&routeAddModelType, "model-type", "", "model type for the route")
-	routeAddCmd.Flags().StringVar(&routeAddContextWindow, "context-window", "", "context window size")
+	routeAddCmd.Flags().StringVar(&routeAddContextWindow, "context-window", "", "context window size in tokens, e.g. 8192, 128k or 1m")
 	routeAddCmd.Flags().StringVar(&routeAddLatencySLA, "latency-sla", "", "latency SLA target")
 
 	routeCmd.AddCommand(routeListCmd)
@@ -57,6 +57,28 @@ var trustVerifyCmd = &cobra.Command{
 	},
 }
 
+var trustVerifyMICCmd = &cobra.Command{
+	Use:   "verify-mic <mic-id>",
+	Short: "Verify an issued MIC against the control plane CA",
+	Long: `Ask the control plane to verify the MIC with the given ID and print its
+validity, capabilities and expiry. Exits non-zero if the MIC is not valid.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := api.ValidateID(args[0]); err != nil {
+			return fmt.Errorf("invalid mic-id: %w", err)
+		}
+		result, err := client.VerifyMICByID(args[0])
+		if err != nil {
+			return fmt.Errorf("MIC verification failed: %w", err)
+		}
+		fmt.Fprint(cmd.OutOrStdout(), formatter.Format(result))
+		if !result.Valid {
+			return fmt.Errorf("MIC %q is not valid (%s)", args[0], result.Status)
+		}
+		return nil
+	},
+}
+
 var trustListCAsCmd = &cobra.Command{
 	Use:   "list-cas",
 	Short: "List all Certificate Authorities",
@@ -75,6 +97,7 @@ func init() {
 
 	trustCmd.AddCommand(trustIssueMICCmd)
 	trustCmd.AddCommand(trustVerifyCmd)
+	trustCmd.AddCommand(trustVerifyMICCmd)
 	trustCmd.AddCommand(trustListCAsCmd)
 	rootCmd.AddCommand(trustCmd)
 }
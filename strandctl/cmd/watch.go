@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/strand-protocol/strand/strandctl/pkg/output"
+)
+
+// Row markers printed by --watch in front of each table row.
+const (
+	watchUnchanged = " "
+	watchAdded     = "+"
+	watchRemoved   = "-"
+	watchChanged   = "~"
+)
+
+var (
+	watchAddedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	watchRemovedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Strikethrough(true)
+	watchChangedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Bold(true)
+)
+
+// listWatch describes a list command that --watch can refresh and diff.
+type listWatch[T any] struct {
+	fetch func() ([]T, error)
+	key   func(T) string
+	equal func(a, b T) bool
+}
+
+// run fetches and prints the list every interval until ctx is done or count
+// refreshes have been printed (count <= 0 means no limit). From the second
+// refresh on, table rows are marked "+" (added), "~" (changed) or "-"
+// (removed since the previous refresh) and coloured with lipgloss; other
+// output formats are reprinted as-is. A failed fetch is reported and retried
+// on the next tick.
+func (lw listWatch[T]) run(ctx context.Context, w io.Writer, interval time.Duration, count int) error {
+	var prev []T
+	for i := 0; count <= 0 || i < count; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(interval):
+			}
+		}
+		fmt.Fprintf(w, "Every %s: %s\n\n", interval, time.Now().Format(time.TimeOnly))
+		cur, err := lw.fetch()
+		if err != nil {
+			fmt.Fprintf(w, "error: %v\n\n", err)
+			continue
+		}
+		if i == 0 {
+			fmt.Fprint(w, formatter.Format(cur))
+		} else {
+			fmt.Fprint(w, lw.render(prev, cur))
+		}
+		fmt.Fprintln(w)
+		prev = cur
+	}
+	return nil
+}
+
+// render formats cur followed by the rows of prev that disappeared, marking
+// each table row by how it differs from prev.
+func (lw listWatch[T]) render(prev, cur []T) string {
+	old := make(map[string]T, len(prev))
+	for _, item := range prev {
+		old[lw.key(item)] = item
+	}
+	seen := make(map[string]bool, len(cur))
+	rows := append([]T(nil), cur...)
+	marks := make([]string, 0, len(cur))
+	for _, item := range cur {
+		k := lw.key(item)
+		seen[k] = true
+		switch p, ok := old[k]; {
+		case !ok:
+			marks = append(marks, watchAdded)
+		case !lw.equal(p, item):
+			marks = append(marks, watchChanged)
+		default:
+			marks = append(marks, watchUnchanged)
+		}
+	}
+	for _, item := range prev {
+		if !seen[lw.key(item)] {
+			rows = append(rows, item)
+			marks = append(marks, watchRemoved)
+		}
+	}
+
+	text := formatter.Format(rows)
+	if _, ok := formatter.(*output.TableFormatter); !ok || len(rows) == 0 {
+		return text
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	var b strings.Builder
+	b.WriteString("  " + lines[0] + "\n")
+	for i, line := range lines[1:] {
+		row := marks[i] + " " + line
+		switch marks[i] {
+		case watchAdded:
+			row = watchAddedStyle.Render(row)
+		case watchRemoved:
+			row = watchRemovedStyle.Render(row)
+		case watchChanged:
+			row = watchChangedStyle.Render(row)
+		}
+		b.WriteString(row + "\n")
+	}
+	return b.String()
+}
@@ -6,6 +6,7 @@ require (
 	github.com/charmbracelet/bubbletea v1.3.10
 	github.com/charmbracelet/lipgloss v1.1.0
 	github.com/spf13/cobra v1.8.1
+	github.com/strand-protocol/strand/strandapi v0.0.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -33,3 +34,5 @@ require (
 	golang.org/x/text v0.31.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 )
+
+replace github.com/strand-protocol/strand/strandapi => ../strandapi
@@ -15,6 +15,7 @@ type APIClient interface {
 	// Trust / MIC management
 	IssueMIC(nodeID string) (*MICInfo, error)
 	VerifyMIC(data []byte) (*MICInfo, error)
+	VerifyMICByID(id string) (*MICVerification, error)
 	ListCAs() ([]string, error)
 
 	// Diagnostics
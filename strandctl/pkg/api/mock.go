@@ -117,6 +117,16 @@ func (m *MockClient) VerifyMIC(data []byte) (*MICInfo, error) {
 	}, nil
 }
 
+func (m *MockClient) VerifyMICByID(id string) (*MICVerification, error) {
+	return &MICVerification{
+		ID:           id,
+		Valid:        true,
+		Status:       "valid",
+		Capabilities: []string{"llm-inference", "embedding"},
+		ValidUntil:   time.Now().Add(180 * 24 * time.Hour),
+	}, nil
+}
+
 func (m *MockClient) ListCAs() ([]string, error) {
 	return []string{
 		"strand-root-ca",
@@ -29,6 +29,16 @@ type MICInfo struct {
 	Status     string    `json:"status" yaml:"status"`
 }
 
+// MICVerification is the result of verifying an issued MIC by ID against
+// the control plane's CA.
+type MICVerification struct {
+	ID           string    `json:"id" yaml:"id"`
+	Valid        bool      `json:"valid" yaml:"valid"`
+	Status       string    `json:"status" yaml:"status"` // "valid", "revoked", "expired" or "invalid"
+	Capabilities []string  `json:"capabilities" yaml:"capabilities"`
+	ValidUntil   time.Time `json:"valid_until" yaml:"valid_until"`
+}
+
 // FirmwareInfo represents a firmware image.
 type FirmwareInfo struct {
 	ID       string `json:"id" yaml:"id"`
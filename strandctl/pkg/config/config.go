@@ -13,16 +13,76 @@ type Config struct {
 	ServerURL    string `yaml:"server_url" json:"server_url"`
 	AuthToken    string `yaml:"auth_token" json:"auth_token"`
 	OutputFormat string `yaml:"output_format" json:"output_format"`
-	Context      string `yaml:"context" json:"context"`
+	// Context names the active entry of Contexts.
+	Context string `yaml:"context" json:"context"`
+	// Contexts holds named environments, each with its own server and API
+	// key, selected with Context, --context or `strandctl config use-context`.
+	Contexts map[string]ContextConfig `yaml:"contexts,omitempty" json:"contexts,omitempty"`
 }
 
-// DefaultPath returns the default config file path: ~/.strand/config.yaml
+// ContextConfig is one named environment in Config.Contexts.
+type ContextConfig struct {
+	ServerURL string `yaml:"server_url" json:"server_url"`
+	APIKey    string `yaml:"api_key,omitempty" json:"api_key,omitempty"`
+}
+
+// ApplyContext makes the named context's server URL and API key the
+// effective ServerURL and AuthToken. An empty name selects c.Context. Naming
+// a context that is not defined is an error, except for the implicit
+// "default" context, which falls back to the top-level settings.
+func (c *Config) ApplyContext(name string) error {
+	explicit := name != ""
+	if !explicit {
+		name = c.Context
+	}
+	ctx, ok := c.Contexts[name]
+	if !ok {
+		if explicit || (name != "" && name != "default") {
+			return fmt.Errorf("context %q not found in config", name)
+		}
+		return nil
+	}
+	c.Context = name
+	if ctx.ServerURL != "" {
+		c.ServerURL = ctx.ServerURL
+	}
+	if ctx.APIKey != "" {
+		c.AuthToken = ctx.APIKey
+	}
+	return nil
+}
+
+// UseContext sets the active context, which must be defined in Contexts.
+func (c *Config) UseContext(name string) error {
+	if _, ok := c.Contexts[name]; !ok {
+		return fmt.Errorf("context %q not found in config", name)
+	}
+	c.Context = name
+	return nil
+}
+
+// configDir and legacyConfigDir are the directories under $HOME searched for
+// config.yaml; the legacy location is still read if the new one is absent.
+const (
+	configDir       = ".strandctl"
+	legacyConfigDir = ".strand"
+)
+
+// DefaultPath returns the default config file path: ~/.strandctl/config.yaml,
+// or ~/.strand/config.yaml if only that older file exists.
 func DefaultPath() string {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return filepath.Join(".", ".strand", "config.yaml")
+		return filepath.Join(".", configDir, "config.yaml")
+	}
+	path := filepath.Join(home, configDir, "config.yaml")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		legacy := filepath.Join(home, legacyConfigDir, "config.yaml")
+		if _, err := os.Stat(legacy); err == nil {
+			return legacy
+		}
 	}
-	return filepath.Join(home, ".strand", "config.yaml")
+	return path
 }
 
 // Load reads the configuration from the given YAML file path.
@@ -61,3 +121,16 @@ func Load(path string) (*Config, error) {
 
 	return cfg, nil
 }
+
+// Save writes c to path as YAML with owner-only permissions, creating the
+// parent directory if needed.
+func (c *Config) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
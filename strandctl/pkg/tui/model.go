@@ -1,7 +1,7 @@
 // Package tui provides the interactive terminal dashboard for strandctl.
 // It is built on the bubbletea/lipgloss stack and renders three tabs:
 // Nodes, Routes, and Streams. Data is refreshed every 2 seconds by calling
-// the Strand Cloud REST API.
+// the Strand Cloud REST API, backing off exponentially while that fails.
 package tui
 
 import (
@@ -14,6 +14,9 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+
+	"github.com/strand-protocol/strand/strandapi/pkg/sad"
+	"github.com/strand-protocol/strand/strandapi/pkg/strandbuf"
 )
 
 // ---------------------------------------------------------------------------
@@ -72,6 +75,12 @@ var (
 			Foreground(lipgloss.Color("1")).
 			Bold(true).
 			PaddingLeft(1)
+
+	// errorPanelStyle frames the error panel shown while fetches fail.
+	errorPanelStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("1")).
+			Padding(0, 1)
 )
 
 // ---------------------------------------------------------------------------
@@ -92,8 +101,11 @@ const (
 // Tea messages
 // ---------------------------------------------------------------------------
 
-// tickMsg is sent every refreshInterval to trigger a data refresh.
-type tickMsg time.Time
+// tickMsg triggers a scheduled data refresh. gen identifies the schedule
+// that sent it; a tick from a superseded schedule is ignored.
+type tickMsg struct {
+	gen int
+}
 
 // dataMsg carries a freshly fetched dataset.
 type dataMsg struct {
@@ -102,14 +114,19 @@ type dataMsg struct {
 	streams []StreamRow
 }
 
-// errMsg carries a fetch or decode error to display in the status bar.
+// errMsg carries a fetch or decode error to display in the error panel.
 type errMsg error
 
 // ---------------------------------------------------------------------------
 // Model
 // ---------------------------------------------------------------------------
 
-const refreshInterval = 2 * time.Second
+// refreshInterval is the time between refreshes while fetches succeed. Each
+// consecutive failure doubles it, up to maxRefreshInterval.
+const (
+	refreshInterval    = 2 * time.Second
+	maxRefreshInterval = 30 * time.Second
+)
 
 // Model is the top-level bubbletea model for the dashboard.
 type Model struct {
@@ -124,6 +141,14 @@ type Model struct {
 	err       error
 	loading   bool
 	lastFetch time.Time
+	// failures counts consecutive failed fetches; errAt is when the last
+	// one failed.
+	failures int
+	errAt    time.Time
+	// tickGen is the generation of the pending refresh tick. Scheduling a
+	// tick bumps it, so a tick already in flight is dropped instead of
+	// starting a second refresh cycle.
+	tickGen int
 }
 
 // New returns a Model configured to talk to serverURL.
@@ -135,15 +160,28 @@ func New(serverURL string) Model {
 	}
 }
 
-// Init starts the periodic tick and issues the first data fetch.
+// Init issues the first data fetch. Its result schedules the next refresh.
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(tick(), fetchData(m.serverURL))
+	return fetchData(m.serverURL)
 }
 
-// tick schedules a tickMsg after refreshInterval.
-func tick() tea.Cmd {
-	return tea.Tick(refreshInterval, func(t time.Time) tea.Msg {
-		return tickMsg(t)
+// refreshDelay is how long to wait before the next refresh: refreshInterval,
+// doubled for every consecutive failure beyond the first, up to
+// maxRefreshInterval.
+func (m Model) refreshDelay() time.Duration {
+	d := refreshInterval
+	for i := 1; i < m.failures && d < maxRefreshInterval; i++ {
+		d *= 2
+	}
+	return min(d, maxRefreshInterval)
+}
+
+// scheduleRefresh supersedes any pending tick with one after refreshDelay.
+func (m *Model) scheduleRefresh() tea.Cmd {
+	m.tickGen++
+	gen := m.tickGen
+	return tea.Tick(m.refreshDelay(), func(time.Time) tea.Msg {
+		return tickMsg{gen: gen}
 	})
 }
 
@@ -171,31 +209,35 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "3":
 			m.activeTab = tabStreams
 		case "r":
-			// Manual refresh
+			// Manual refresh; the error panel stays up until it succeeds.
 			m.loading = true
-			m.err = nil
 			return m, fetchData(m.serverURL)
 		}
 		return m, nil
 
 	case tickMsg:
+		if msg.gen != m.tickGen {
+			return m, nil
+		}
 		m.loading = true
-		m.err = nil
-		return m, tea.Batch(tick(), fetchData(m.serverURL))
+		return m, fetchData(m.serverURL)
 
 	case dataMsg:
 		m.loading = false
 		m.err = nil
+		m.failures = 0
 		m.nodes = msg.nodes
 		m.routes = msg.routes
 		m.streams = msg.streams
 		m.lastFetch = time.Now()
-		return m, nil
+		return m, m.scheduleRefresh()
 
 	case errMsg:
 		m.loading = false
 		m.err = msg
-		return m, nil
+		m.failures++
+		m.errAt = time.Now()
+		return m, m.scheduleRefresh()
 	}
 
 	return m, nil
@@ -229,8 +271,16 @@ func (m Model) View() string {
 	sb.WriteString(strings.Repeat("─", m.width))
 	sb.WriteString("\n")
 
-	// --- Content area ---
+	// --- Error panel ---
 	contentHeight := m.height - 5 // title(1) + tabs(1) + divider(1) + status(2)
+	if m.err != nil {
+		panel := m.renderErrorPanel()
+		sb.WriteString(panel)
+		sb.WriteString("\n")
+		contentHeight -= lipgloss.Height(panel)
+	}
+
+	// --- Content area ---
 	if contentHeight < 1 {
 		contentHeight = 1
 	}
@@ -263,12 +313,24 @@ func (m Model) renderActiveTab() string {
 	}
 }
 
-// renderStatus renders the bottom status bar line.
-func (m Model) renderStatus() string {
-	if m.err != nil {
-		return errorStyle.Render(fmt.Sprintf("Error: %v", m.err))
+// renderErrorPanel renders the last fetch error with how long it has been
+// failing and when the next automatic attempt is due. The tabs below it keep
+// showing the last data fetched successfully.
+func (m Model) renderErrorPanel() string {
+	failures := "1 failure"
+	if m.failures != 1 {
+		failures = fmt.Sprintf("%d consecutive failures", m.failures)
+	}
+	lines := []string{
+		errorStyle.PaddingLeft(0).Render(fmt.Sprintf("Error: %v", m.err)),
+		fmt.Sprintf("%s, last at %s; next retry in %s", failures, m.errAt.Format("15:04:05"), m.refreshDelay()),
+		dimStyle.Render("press r to retry now"),
 	}
+	return errorPanelStyle.Width(max(m.width-2, 1)).Render(strings.Join(lines, "\n"))
+}
 
+// renderStatus renders the bottom status bar line.
+func (m Model) renderStatus() string {
 	parts := []string{
 		fmt.Sprintf("server: %s", m.serverURL),
 	}
@@ -328,6 +390,7 @@ type nodeAPIResponse struct {
 // routeAPIResponse mirrors the JSON shape returned by GET /v1/routes.
 type routeAPIResponse struct {
 	ID        string `json:"id"`
+	SAD       []byte `json:"sad"`
 	Endpoints []struct {
 		NodeID  string  `json:"node_id"`
 		Address string  `json:"address"`
@@ -409,8 +472,20 @@ func fetchRoutes(serverURL string) ([]RouteRow, error) {
 			nextHop = r.Endpoints[0].NodeID
 			score = fmt.Sprintf("%.2f", r.Endpoints[0].Weight)
 		}
+		dest := r.ID
+		if len(r.SAD) > 0 {
+			// Show the route's descriptor, e.g. "sad:llm:128k", when it
+			// decodes; otherwise fall back to the route ID.
+			var s sad.SAD
+			if err := s.Decode(strandbuf.NewReader(r.SAD)); err == nil {
+				dest = s.String()
+				if names := sad.CapabilityNames(s.Capabilities); len(names) > 0 {
+					cap = names[0]
+				}
+			}
+		}
 		rows = append(rows, RouteRow{
-			Destination: r.ID,
+			Destination: dest,
 			NextHop:     nextHop,
 			Capability:  cap,
 			Score:       score,
@@ -2,8 +2,12 @@ package tests
 
 import (
 	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/strand-protocol/strand/strandctl/cmd"
 	"github.com/strand-protocol/strand/strandctl/pkg/api"
@@ -138,6 +142,24 @@ func TestRouteAddCommand(t *testing.T) {
 	}
 }
 
+func TestRouteAddContextWindow(t *testing.T) {
+	setupTest()
+	addCmd, _, _ := cmd.RootCmd().Find([]string{"route", "add"})
+	defer addCmd.Flags().Set("context-window", "")
+
+	out, err := executeCommand("route", "add", "--model-type", "llm-inference", "--context-window", "128000")
+	if err != nil {
+		t.Fatalf("route add command failed: %v", err)
+	}
+	if !strings.Contains(out, `"sad:llm-inference:128k"`) {
+		t.Errorf("expected normalized context window in SAD, got: %s", out)
+	}
+
+	if _, err := executeCommand("route", "add", "--model-type", "llm-inference", "--context-window", "12x"); err == nil {
+		t.Error("expected error for --context-window 12x")
+	}
+}
+
 func TestFirmwareListCommand(t *testing.T) {
 	setupTest()
 	out, err := executeCommand("firmware", "list")
@@ -249,3 +271,191 @@ func TestYAMLOutputFormat(t *testing.T) {
 		t.Errorf("expected YAML output with 'id:' field, got: %s", out)
 	}
 }
+
+// sequenceClient returns a different node list on each ListNodes call,
+// repeating the last one once the sequence is exhausted.
+type sequenceClient struct {
+	api.MockClient
+	lists [][]api.NodeInfo
+	calls int
+}
+
+func (c *sequenceClient) ListNodes() ([]api.NodeInfo, error) {
+	i := c.calls
+	if i >= len(c.lists) {
+		i = len(c.lists) - 1
+	}
+	c.calls++
+	return c.lists[i], nil
+}
+
+func TestNodeListWatchMarksChanges(t *testing.T) {
+	alpha := api.NodeInfo{ID: "node-alpha-01", Status: "ready"}
+	beta := api.NodeInfo{ID: "node-beta-02", Status: "ready"}
+	betaDraining := api.NodeInfo{ID: "node-beta-02", Status: "draining"}
+	delta := api.NodeInfo{ID: "node-delta-04", Status: "ready"}
+	// LastSeen alone does not count as a change.
+	alphaLater := alpha
+	alphaLater.LastSeen = time.Now()
+
+	cmd.SetClient(&sequenceClient{lists: [][]api.NodeInfo{
+		{alpha, beta},
+		{alphaLater, betaDraining, delta},
+		{alpha, delta},
+	}})
+	cmd.SetFormatter(output.NewFormatter("table"))
+	defer func() {
+		list, _, _ := cmd.RootCmd().Find([]string{"node", "list"})
+		list.Flags().Set("watch", "false")
+		list.Flags().Set("count", "0")
+		list.Flags().Set("interval", "2s")
+	}()
+
+	out, err := executeCommand("node", "list", "-o", "table", "--watch", "--interval", "1ms", "--count", "3")
+	if err != nil {
+		t.Fatalf("node list --watch failed: %v", err)
+	}
+
+	refreshes := strings.Split(out, "Every ")[1:]
+	if len(refreshes) != 3 {
+		t.Fatalf("got %d refreshes, want 3:\n%s", len(refreshes), out)
+	}
+	marked := func(refresh, mark, id string) bool {
+		for _, line := range strings.Split(refresh, "\n") {
+			if strings.HasPrefix(line, mark+" ") && strings.Contains(line, id) {
+				return true
+			}
+		}
+		return false
+	}
+
+	second, third := refreshes[1], refreshes[2]
+	if !marked(second, " ", "node-alpha-01") {
+		t.Errorf("refresh 2: node-alpha-01 should be unchanged:\n%s", second)
+	}
+	if !marked(second, "~", "node-beta-02") {
+		t.Errorf("refresh 2: node-beta-02 should be marked changed:\n%s", second)
+	}
+	if !marked(second, "+", "node-delta-04") {
+		t.Errorf("refresh 2: node-delta-04 should be marked added:\n%s", second)
+	}
+	if !marked(third, "-", "node-beta-02") {
+		t.Errorf("refresh 3: node-beta-02 should be marked removed:\n%s", third)
+	}
+	if !marked(third, " ", "node-delta-04") {
+		t.Errorf("refresh 3: node-delta-04 should be unchanged:\n%s", third)
+	}
+}
+
+// micClient reports the MIC "mic-revoked" as revoked and every other MIC as
+// valid.
+type micClient struct{ api.MockClient }
+
+func (c *micClient) VerifyMICByID(id string) (*api.MICVerification, error) {
+	v := &api.MICVerification{
+		ID:           id,
+		Valid:        true,
+		Status:       "valid",
+		Capabilities: []string{"llm-inference"},
+		ValidUntil:   time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+	if id == "mic-revoked" {
+		v.Valid, v.Status = false, "revoked"
+	}
+	return v, nil
+}
+
+func TestTrustVerifyMIC(t *testing.T) {
+	cmd.SetClient(&micClient{})
+	defer cmd.SetClient(&api.MockClient{})
+
+	out, err := executeCommand("trust", "verify-mic", "-o", "table", "mic-good")
+	if err != nil {
+		t.Fatalf("verify-mic valid MIC failed: %v", err)
+	}
+	for _, want := range []string{"Valid:", "true", "llm-inference", "2030-01-02"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got: %s", want, out)
+		}
+	}
+
+	out, err = executeCommand("trust", "verify-mic", "-o", "json", "mic-revoked")
+	if err == nil {
+		t.Fatal("verify-mic on a revoked MIC should fail")
+	}
+	if !strings.Contains(out, `"valid": false`) || !strings.Contains(out, `"status": "revoked"`) {
+		t.Errorf("expected JSON output reporting the revocation, got: %s", out)
+	}
+	cmd.RootCmd().PersistentFlags().Set("output", "")
+}
+
+func TestMetricsExportPush(t *testing.T) {
+	setupTest()
+	type push struct {
+		path, contentType, body string
+	}
+	pushes := make(chan push, 1)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost {
+			http.Error(w, "want POST", http.StatusMethodNotAllowed)
+			return
+		}
+		pushes <- push{r.URL.Path, r.Header.Get("Content-Type"), string(body)}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer gateway.Close()
+	defer func() {
+		export, _, _ := cmd.RootCmd().Find([]string{"metrics", "export"})
+		export.Flags().Set("push", "")
+		export.Flags().Set("format", "prometheus")
+	}()
+
+	out, err := executeCommand("metrics", "export", "--dry-run=false", "--format", "prometheus", "--push", gateway.URL)
+	if err != nil {
+		t.Fatalf("metrics export --push failed: %v", err)
+	}
+	if !strings.Contains(out, "Pushed metrics for 3 nodes") {
+		t.Errorf("expected push confirmation, got: %s", out)
+	}
+	var got push
+	select {
+	case got = <-pushes:
+	default:
+		t.Fatal("nothing was pushed to the pushgateway")
+	}
+	if got.path != "/metrics/job/strandctl" {
+		t.Errorf("pushed to %q, want /metrics/job/strandctl", got.path)
+	}
+	if !strings.HasPrefix(got.contentType, "text/plain") {
+		t.Errorf("Content-Type = %q, want text/plain exposition format", got.contentType)
+	}
+	for _, want := range []string{
+		"# TYPE strand_connections gauge",
+		`strand_connections{node_id="node-alpha-01"} 42`,
+		`strand_bytes_sent{node_id="node-gamma-03"} 1073741824`,
+	} {
+		if !strings.Contains(got.body, want) {
+			t.Errorf("pushed body missing %q:\n%s", want, got.body)
+		}
+	}
+	if n := strings.Count(got.body, "# HELP strand_connections"); n != 1 {
+		t.Errorf("HELP for strand_connections appears %d times, want 1", n)
+	}
+}
+
+func TestMetricsExportOpenMetrics(t *testing.T) {
+	setupTest()
+	out, err := executeCommand("metrics", "export", "--format", "openmetrics")
+	if err != nil {
+		t.Fatalf("metrics export openmetrics command failed: %v", err)
+	}
+	if !strings.Contains(out, `strand_bytes_sent_total{node_id="node-alpha-01"}`) {
+		t.Errorf("expected OpenMetrics counter samples with _total suffix, got: %s", out)
+	}
+	if !strings.HasSuffix(out, "# EOF\n") {
+		t.Errorf("expected OpenMetrics output to end with # EOF, got: %s", out)
+	}
+	export, _, _ := cmd.RootCmd().Find([]string{"metrics", "export"})
+	export.Flags().Set("format", "prometheus")
+}
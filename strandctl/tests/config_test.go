@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/strand-protocol/strand/strandctl/cmd"
+)
+
+const twoContextConfig = `context: staging
+contexts:
+  staging:
+    server_url: https://staging.strand.example:9100
+    api_key: staging-key
+  prod:
+    server_url: https://prod.strand.example:9100
+    api_key: prod-key
+`
+
+func TestConfigContexts(t *testing.T) {
+	setupTest()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(twoContextConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		flags := cmd.RootCmd().PersistentFlags()
+		flags.Set("config", "")
+		flags.Set("context", "")
+	}()
+
+	out, err := executeCommand("--config", path, "config", "current-context")
+	if err != nil {
+		t.Fatalf("current-context: %v", err)
+	}
+	if !strings.Contains(out, "staging (https://staging.strand.example:9100)") {
+		t.Errorf("active context should be staging, got: %s", out)
+	}
+
+	out, err = executeCommand("--config", path, "--context", "prod", "config", "current-context")
+	if err != nil {
+		t.Fatalf("current-context --context prod: %v", err)
+	}
+	if !strings.Contains(out, "prod (https://prod.strand.example:9100)") {
+		t.Errorf("--context prod should select prod, got: %s", out)
+	}
+
+	if _, err := executeCommand("--config", path, "--context", "missing", "config", "current-context"); err == nil {
+		t.Error("expected error for unknown --context")
+	}
+
+	if _, err := executeCommand("--config", path, "--context", "", "config", "use-context", "prod"); err != nil {
+		t.Fatalf("use-context prod: %v", err)
+	}
+	out, err = executeCommand("--config", path, "config", "current-context")
+	if err != nil {
+		t.Fatalf("current-context after use-context: %v", err)
+	}
+	if !strings.Contains(out, "prod (https://prod.strand.example:9100)") {
+		t.Errorf("use-context prod should persist, got: %s", out)
+	}
+
+	out, err = executeCommand("--config", path, "-o", "table", "config", "get-contexts")
+	if err != nil {
+		t.Fatalf("get-contexts: %v", err)
+	}
+	if !strings.Contains(out, "staging") || strings.Contains(out, "prod-key") {
+		t.Errorf("get-contexts should list contexts without API keys, got: %s", out)
+	}
+
+	// --server still overrides the context's URL.
+	out, err = executeCommand("--config", path, "--server", "http://override:1", "config", "current-context")
+	if err != nil {
+		t.Fatalf("current-context --server: %v", err)
+	}
+	cmd.RootCmd().PersistentFlags().Set("server", "")
+	if !strings.Contains(out, "http://override:1") {
+		t.Errorf("--server should override the context URL, got: %s", out)
+	}
+}
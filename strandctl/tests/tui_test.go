@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/strand-protocol/strand/strandapi/pkg/sad"
+	"github.com/strand-protocol/strand/strandapi/pkg/strandbuf"
+	"github.com/strand-protocol/strand/strandctl/pkg/tui"
+)
+
+// refresh presses r and feeds the fetch result back into m.
+func refresh(t *testing.T, m tea.Model) tea.Model {
+	t.Helper()
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	if cmd == nil {
+		t.Fatal("r: no fetch command")
+	}
+	m, next := m.Update(cmd())
+	if next == nil {
+		t.Fatal("fetch result did not schedule the next refresh")
+	}
+	return m
+}
+
+func TestDashboardErrorBackoff(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer srv.Close()
+
+	var m tea.Model = tui.New(srv.URL)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+
+	for i, want := range []struct{ failures, retry string }{
+		{"1 failure", "2s"},
+		{"2 consecutive failures", "4s"},
+		{"3 consecutive failures", "8s"},
+		{"4 consecutive failures", "16s"},
+		{"5 consecutive failures", "30s"},
+		{"6 consecutive failures", "30s"},
+	} {
+		m = refresh(t, m)
+		view := m.View()
+		if !strings.Contains(view, "unexpected status 503") {
+			t.Fatalf("fetch %d: view does not show the error:\n%s", i+1, view)
+		}
+		if !strings.Contains(view, want.failures+",") || !strings.Contains(view, "next retry in "+want.retry) {
+			t.Errorf("fetch %d: want %q and next retry in %s in:\n%s", i+1, want.failures, want.retry, view)
+		}
+		if !strings.Contains(view, "press r to retry now") {
+			t.Errorf("fetch %d: view has no manual retry hint", i+1)
+		}
+	}
+
+	// A success clears the panel and resets the backoff.
+	failing.Store(false)
+	m = refresh(t, m)
+	if view := m.View(); strings.Contains(view, "Error:") {
+		t.Errorf("view still shows an error after a successful fetch:\n%s", view)
+	}
+	failing.Store(true)
+	m = refresh(t, m)
+	if view := m.View(); !strings.Contains(view, "1 failure,") || !strings.Contains(view, "next retry in 2s") {
+		t.Errorf("backoff not reset after a success:\n%s", view)
+	}
+}
+
+func TestDashboardRoutesShowDescriptor(t *testing.T) {
+	buf := strandbuf.NewBuffer(32)
+	(&sad.SAD{ModelType: "llm", Capabilities: sad.CodeGen, ContextWindow: 128000, Version: 1}).Encode(buf)
+	routes, err := json.Marshal([]map[string]any{{
+		"id":        "route-1",
+		"sad":       buf.Bytes(),
+		"endpoints": []map[string]any{{"node_id": "node-a", "weight": 1}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/routes" {
+			w.Write(routes)
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer srv.Close()
+
+	var m tea.Model = tui.New(srv.URL)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+	m = refresh(t, m)
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+
+	view := m.View()
+	if !strings.Contains(view, "sad:llm:128k") || !strings.Contains(view, "code_gen") {
+		t.Errorf("routes tab does not show the decoded descriptor:\n%s", view)
+	}
+}
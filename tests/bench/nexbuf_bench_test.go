@@ -100,6 +100,57 @@ func BenchmarkStrandBufDecode(b *testing.B) {
 	b.SetBytes(int64(len(encoded)))
 }
 
+// decoder is a message decoded through an interface, as generic dispatch
+// code does. The call makes the Reader escape, so a fresh one per frame is a
+// heap allocation.
+type decoder interface {
+	Decode(r *strandbuf.Reader) error
+}
+
+// decodeAs decodes r into msg without the compiler seeing msg's concrete
+// type.
+//
+//go:noinline
+func decodeAs(msg decoder, r *strandbuf.Reader) error {
+	return msg.Decode(r)
+}
+
+// BenchmarkStrandBufDecodeReset compares decoding each frame through a
+// decoder with a new Reader against reusing one Reader via Reset.
+func BenchmarkStrandBufDecodeReset(b *testing.B) {
+	req := &protocol.InferenceResponse{
+		ID:               [16]byte{1, 2, 3, 4},
+		Text:             "Quantum computing uses qubits.",
+		FinishReason:     "stop",
+		PromptTokens:     8,
+		CompletionTokens: 5,
+	}
+	buf := strandbuf.NewBuffer(256)
+	req.Encode(buf)
+	encoded := bytes.Clone(buf.Bytes())
+
+	b.Run("NewReader", func(b *testing.B) {
+		msg := &protocol.InferenceResponse{}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := decodeAs(msg, strandbuf.NewReader(encoded)); err != nil {
+				b.Fatalf("decode: %v", err)
+			}
+		}
+	})
+	b.Run("Reset", func(b *testing.B) {
+		msg := &protocol.InferenceResponse{}
+		reader := strandbuf.NewReader(nil)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			reader.Reset(encoded)
+			if err := decodeAs(msg, reader); err != nil {
+				b.Fatalf("decode: %v", err)
+			}
+		}
+	})
+}
+
 // BenchmarkStrandBufDecodeResponse benchmarks decoding an InferenceResponse.
 func BenchmarkStrandBufDecodeResponse(b *testing.B) {
 	resp := &protocol.InferenceResponse{
@@ -127,6 +178,69 @@ func BenchmarkStrandBufDecodeResponse(b *testing.B) {
 	b.SetBytes(int64(len(encoded)))
 }
 
+// encodedStringKeys returns n length-prefixed metadata-style keys.
+func encodedStringKeys(n int) []byte {
+	buf := strandbuf.NewBuffer(n * 16)
+	for i := 0; i < n; i++ {
+		buf.WriteString(fmt.Sprintf("x-strand-key-%02d", i))
+	}
+	return buf.Bytes()
+}
+
+// BenchmarkStrandBufReadString decodes keys with ReadString, allocating a
+// string per key.
+func BenchmarkStrandBufReadString(b *testing.B) {
+	const n = 16
+	encoded := encodedStringKeys(n)
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		reader := strandbuf.NewReader(encoded)
+		matches := 0
+		for j := 0; j < n; j++ {
+			s, err := reader.ReadString()
+			if err != nil {
+				b.Fatalf("ReadString: %v", err)
+			}
+			if s == "x-strand-key-07" {
+				matches++
+			}
+		}
+		if matches != 1 {
+			b.Fatalf("matches = %d, want 1", matches)
+		}
+	}
+	b.SetBytes(int64(len(encoded)))
+}
+
+// BenchmarkStrandBufReadStringView decodes the same keys with ReadStringView,
+// comparing against the zero-copy view without allocating.
+func BenchmarkStrandBufReadStringView(b *testing.B) {
+	const n = 16
+	encoded := encodedStringKeys(n)
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		reader := strandbuf.NewReader(encoded)
+		matches := 0
+		for j := 0; j < n; j++ {
+			v, err := reader.ReadStringView()
+			if err != nil {
+				b.Fatalf("ReadStringView: %v", err)
+			}
+			if string(v) == "x-strand-key-07" {
+				matches++
+			}
+		}
+		if matches != 1 {
+			b.Fatalf("matches = %d, want 1", matches)
+		}
+	}
+	b.SetBytes(int64(len(encoded)))
+}
+
 // --------------------------------------------------------------------------
 // Overlay transport roundtrip
 // --------------------------------------------------------------------------
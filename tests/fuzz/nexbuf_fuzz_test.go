@@ -81,7 +81,7 @@ func FuzzFrameRead(f *testing.F) {
 	// Header with zero length.
 	f.Add([]byte{0x00, 0x00, 0x00, 0x00, 0x08})
 
-	// Absurdly large length (should hit maxPayloadSize check).
+	// Absurdly large length (should hit MaxPayloadSize check).
 	f.Add([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0x01})
 
 	f.Fuzz(func(t *testing.T, data []byte) {
@@ -137,6 +137,9 @@ func FuzzInferenceResponseDecode(f *testing.F) {
 
 	f.Add([]byte{})
 
+	// A 16-byte ID followed by a text length far beyond the string cap.
+	f.Add(append(make([]byte, 16), 0xFF, 0xFF, 0xFF, 0x7F, 'x'))
+
 	f.Fuzz(func(t *testing.T, data []byte) {
 		decoded := &protocol.InferenceResponse{}
 		reader := strandbuf.NewReader(data)
@@ -277,3 +280,40 @@ func FuzzTensorTransferDecode(f *testing.F) {
 		_ = decoded.Decode(reader)
 	})
 }
+
+// FuzzTensorTransferDecodeStrict verifies that a tensor accepted by
+// DecodeStrict always passes Validate, so Data can be trusted to match Shape
+// and DType.
+func FuzzTensorTransferDecodeStrict(f *testing.F) {
+	for _, tensor := range []*protocol.TensorTransfer{
+		{DType: protocol.DTypeFloat32, Shape: []uint32{2, 2}, Data: make([]byte, 16)},
+		{DType: protocol.DTypeInt4, Shape: []uint32{3}, Data: make([]byte, 2)},
+		{DType: protocol.DTypeFloat16, Shape: []uint32{4, 4}, Data: []byte{1, 2, 3, 4}},
+		{DType: protocol.DTypeUint8, Shape: []uint32{0xFFFFFFFF, 0xFFFFFFFF, 0}},
+	} {
+		buf := strandbuf.NewBuffer(128)
+		tensor.Encode(buf)
+		f.Add(buf.Bytes())
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decoded := &protocol.TensorTransfer{}
+		if err := decoded.DecodeStrict(strandbuf.NewReader(data)); err != nil {
+			return
+		}
+		if err := decoded.Validate(); err != nil {
+			t.Fatalf("DecodeStrict accepted a tensor that fails Validate: %v", err)
+		}
+		elems := uint64(1)
+		for _, d := range decoded.Shape {
+			elems *= uint64(d)
+		}
+		if decoded.DType == protocol.DTypeInt4 {
+			elems = (elems + 1) / 2
+		}
+		if decoded.DType != protocol.DTypeNone && uint64(len(decoded.Data))%max(elems, 1) != 0 {
+			t.Fatalf("shape %v dtype %d: %d data bytes is not a whole number of elements",
+				decoded.Shape, decoded.DType, len(decoded.Data))
+		}
+	})
+}
@@ -0,0 +1,383 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/strand-protocol/strand/strandapi/pkg/client"
+	"github.com/strand-protocol/strand/strandapi/pkg/protocol"
+	"github.com/strand-protocol/strand/strandapi/pkg/server"
+	"github.com/strand-protocol/strand/strandapi/pkg/transport"
+)
+
+// TestClientErrorDialFailed verifies that an address the overlay transport
+// cannot dial is reported as ErrDialFailed.
+func TestClientErrorDialFailed(t *testing.T) {
+	_, err := client.Dial("127.0.0.1:70000")
+	if !errors.Is(err, client.ErrDialFailed) {
+		t.Fatalf("Dial err = %v, want ErrDialFailed", err)
+	}
+}
+
+// failingSendTransport refuses every Send as if the transport were closed.
+type failingSendTransport struct {
+	transport.Transport
+}
+
+func (failingSendTransport) Send(context.Context, byte, []byte) error {
+	return transport.ErrTransportClosed
+}
+
+// TestClientErrorSendFailed verifies that a request the transport refuses is
+// reported as ErrSendFailed and still wraps the transport error.
+func TestClientErrorSendFailed(t *testing.T) {
+	clientT, _ := newChannelTransportPair()
+	c, err := client.Dial("unused", client.WithTransport(failingSendTransport{clientT}))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = c.Infer(ctx, &protocol.InferenceRequest{Prompt: "hello", Metadata: map[string]string{}})
+	if !errors.Is(err, client.ErrSendFailed) {
+		t.Fatalf("Infer err = %v, want ErrSendFailed", err)
+	}
+	if !errors.Is(err, transport.ErrTransportClosed) {
+		t.Errorf("Infer err = %v, want it to wrap ErrTransportClosed", err)
+	}
+	if errors.Is(err, client.ErrRecvTimeout) || errors.Is(err, client.ErrServer) {
+		t.Errorf("Infer err = %v matches more than one error kind", err)
+	}
+}
+
+// TestClientErrorRecvTimeout verifies that a handler slower than the caller's
+// deadline is reported as ErrRecvTimeout, not as a send failure.
+func TestClientErrorRecvTimeout(t *testing.T) {
+	release := make(chan struct{})
+	slowHandler := server.HandlerFunc(func(ctx context.Context, req *protocol.InferenceRequest) (*protocol.InferenceResponse, error) {
+		<-release
+		return &protocol.InferenceResponse{ID: req.ID, Text: "too late"}, nil
+	})
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, server.New(slowHandler), serverT)
+	defer stop()
+	// Unblock the handler before stop so Stop need not wait it out.
+	defer close(release)
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = c.Infer(ctx, &protocol.InferenceRequest{Prompt: "slow", Metadata: map[string]string{}})
+	if !errors.Is(err, client.ErrRecvTimeout) {
+		t.Fatalf("Infer err = %v, want ErrRecvTimeout", err)
+	}
+	if errors.Is(err, client.ErrSendFailed) {
+		t.Errorf("Infer err = %v, should not match ErrSendFailed", err)
+	}
+}
+
+// TestClientErrorServer verifies that an OpError reply is reported as a
+// *ServerError matching ErrServer, carrying the remote error code.
+func TestClientErrorServer(t *testing.T) {
+	t.Run("handler error", func(t *testing.T) {
+		failing := server.HandlerFunc(func(ctx context.Context, req *protocol.InferenceRequest) (*protocol.InferenceResponse, error) {
+			return nil, errors.New("model exploded")
+		})
+		clientT, serverT := newChannelTransportPair()
+		stop := startServer(t, server.New(failing), serverT)
+		defer stop()
+
+		c, err := client.Dial("unused", client.WithTransport(clientT))
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer c.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err = c.Infer(ctx, &protocol.InferenceRequest{Prompt: "boom", Metadata: map[string]string{}})
+		if !errors.Is(err, client.ErrServer) {
+			t.Fatalf("Infer err = %v, want ErrServer", err)
+		}
+		var srvErr *client.ServerError
+		if !errors.As(err, &srvErr) {
+			t.Fatalf("Infer err = %T, want *client.ServerError", err)
+		}
+		if srvErr.Code != protocol.ErrInternal || srvErr.Message != "model exploded" {
+			t.Errorf("ServerError = {%#04x %q}, want {ErrInternal \"model exploded\"}", srvErr.Code, srvErr.Message)
+		}
+	})
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		slowHandler := server.HandlerFunc(func(ctx context.Context, req *protocol.InferenceRequest) (*protocol.InferenceResponse, error) {
+			<-release
+			return &protocol.InferenceResponse{ID: req.ID}, nil
+		})
+		clientT, serverT := newChannelTransportPair()
+		stop := startServer(t, server.New(slowHandler), serverT)
+		defer stop()
+
+		c, err := client.Dial("unused", client.WithTransport(clientT))
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer c.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err = c.Infer(ctx, &protocol.InferenceRequest{
+			Prompt:         "deadline",
+			Metadata:       map[string]string{},
+			DeadlineUnixMs: uint64(time.Now().Add(50 * time.Millisecond).UnixMilli()),
+		})
+		var srvErr *client.ServerError
+		if !errors.As(err, &srvErr) {
+			t.Fatalf("Infer err = %v, want *client.ServerError", err)
+		}
+		if srvErr.Code != protocol.ErrDeadlineExceeded {
+			t.Errorf("ServerError.Code = %#04x, want ErrDeadlineExceeded", srvErr.Code)
+		}
+		if errors.Is(err, client.ErrRecvTimeout) {
+			t.Errorf("Infer err = %v, a server-side deadline should not match ErrRecvTimeout", err)
+		}
+	})
+}
+
+// TestClientErrorResponseMismatch verifies that a response carrying another
+// request's ID is rejected rather than handed to the caller.
+func TestClientErrorResponseMismatch(t *testing.T) {
+	crossed := server.HandlerFunc(func(ctx context.Context, req *protocol.InferenceRequest) (*protocol.InferenceResponse, error) {
+		id := req.ID
+		id[0] ^= 0xFF
+		return &protocol.InferenceResponse{ID: id, Text: "not yours", FinishReason: "stop"}, nil
+	})
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, server.New(crossed), serverT)
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := c.Infer(ctx, &protocol.InferenceRequest{
+		ID:       [16]byte{1, 2, 3, 4},
+		Prompt:   "hello",
+		Metadata: map[string]string{},
+	})
+	if !errors.Is(err, client.ErrResponseMismatch) {
+		t.Fatalf("Infer = (%v, %v), want ErrResponseMismatch", resp, err)
+	}
+	if resp != nil {
+		t.Errorf("Infer returned response %+v alongside mismatch error", resp)
+	}
+}
+
+// lossyStreamHandler streams four chunks but skips SeqNum 2, as if that chunk
+// were lost in transit.
+type lossyStreamHandler struct{}
+
+func (lossyStreamHandler) HandleTokenStream(_ context.Context, req *protocol.InferenceRequest, sender server.TokenSender) error {
+	for _, seq := range []uint32{0, 1, 3, 4} {
+		if err := sender.Send(&protocol.TokenStreamChunk{RequestID: req.ID, SeqNum: seq, Token: "t"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TestClientErrorSequenceGap verifies that a token stream missing a chunk is
+// reported as ErrSequenceGap once it ends, while the surviving tokens and the
+// summary are still delivered.
+func TestClientErrorSequenceGap(t *testing.T) {
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, server.New(nil, server.WithStreamHandler(lossyStreamHandler{})), serverT)
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ts, err := c.OpenStream(ctx, &protocol.InferenceRequest{Prompt: "lossy", Metadata: map[string]string{}})
+	if err != nil {
+		t.Fatalf("OpenStream: %v", err)
+	}
+	var tokens int
+	for range ts.C {
+		tokens++
+	}
+	if tokens != 4 {
+		t.Errorf("received %d tokens, want 4", tokens)
+	}
+	err = ts.Err()
+	if !errors.Is(err, client.ErrSequenceGap) {
+		t.Fatalf("Err() = %v, want ErrSequenceGap", err)
+	}
+	var gapErr *client.SequenceGapError
+	if !errors.As(err, &gapErr) || gapErr.Expected != 2 || gapErr.Got != 3 {
+		t.Errorf("Err() = %#v, want gap {Expected:2 Got:3}", err)
+	}
+	if gaps := ts.Gaps(); len(gaps) != 1 {
+		t.Errorf("Gaps() = %v, want one gap", gaps)
+	}
+	if ts.Summary() == nil {
+		t.Error("Summary() = nil, want the server's summary despite the gap")
+	}
+
+	// Stream asks the server to stream, so Infer assembles the gapped stream.
+	if _, err := c.Infer(ctx, &protocol.InferenceRequest{Prompt: "lossy", Metadata: map[string]string{}, Stream: true}); !errors.Is(err, client.ErrSequenceGap) {
+		t.Errorf("Infer err = %v, want ErrSequenceGap for an incomplete stream", err)
+	}
+}
+
+// shuffledStreamHandler streams one chunk per SeqNum in seqs, in that order,
+// carrying the SeqNum as its token.
+type shuffledStreamHandler struct{ seqs []uint32 }
+
+func (h shuffledStreamHandler) HandleTokenStream(_ context.Context, req *protocol.InferenceRequest, sender server.TokenSender) error {
+	for _, seq := range h.seqs {
+		if err := sender.Send(&protocol.TokenStreamChunk{RequestID: req.ID, SeqNum: seq, Token: fmt.Sprint(seq)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TestClientReorderWindow verifies that a client with a reorder window
+// delivers chunks that arrive out of order in SeqNum order, still reports a
+// chunk missing for longer than the window as a gap, and delivers each
+// SeqNum at most once.
+func TestClientReorderWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		seqs     []uint32
+		want     string
+		wantGaps []client.SequenceGapError
+	}{
+		{"within window", []uint32{1, 0, 3, 4, 2, 5, 7, 6}, "0 1 2 3 4 5 6 7", nil},
+		{"missing past window", []uint32{0, 2, 3, 4, 5}, "0 2 3 4 5", []client.SequenceGapError{{Expected: 1, Got: 2}}},
+		{"missing at end", []uint32{0, 1, 3}, "0 1 3", []client.SequenceGapError{{Expected: 2, Got: 3}}},
+		{"first chunk lost", []uint32{1, 2, 3, 4}, "1 2 3 4", []client.SequenceGapError{{Expected: 0, Got: 1}}},
+		{"duplicate held chunk", []uint32{0, 2, 2, 1}, "0 1 2", nil},
+		{"duplicate and late chunks", []uint32{0, 2, 3, 4, 1, 2, 5}, "0 2 3 4 5", []client.SequenceGapError{{Expected: 1, Got: 2}}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clientT, serverT := newChannelTransportPair()
+			stop := startServer(t, server.New(nil, server.WithStreamHandler(shuffledStreamHandler{tc.seqs})), serverT)
+			defer stop()
+
+			c, err := client.Dial("unused", client.WithTransport(clientT), client.WithReorderWindow(2))
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer c.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			ts, err := c.OpenStream(ctx, &protocol.InferenceRequest{Prompt: "shuffled", Metadata: map[string]string{}})
+			if err != nil {
+				t.Fatalf("OpenStream: %v", err)
+			}
+			var got []string
+			for chunk := range ts.C {
+				got = append(got, chunk.Token)
+			}
+			if strings.Join(got, " ") != tc.want {
+				t.Errorf("tokens = %v, want %s", got, tc.want)
+			}
+			if gaps := ts.Gaps(); !reflect.DeepEqual(gaps, tc.wantGaps) {
+				t.Errorf("Gaps() = %v, want %v", gaps, tc.wantGaps)
+			}
+			if err := ts.Err(); (err == nil) != (tc.wantGaps == nil) {
+				t.Errorf("Err() = %v, want gap error: %v", err, tc.wantGaps != nil)
+			}
+		})
+	}
+}
+
+// TestClientFillsZeroRequestID verifies that a request sent without an ID
+// gets a random one, that a caller-set ID is sent unchanged, even one that
+// looks like a version stamp, and that WithIDGenerator replaces the default.
+func TestClientFillsZeroRequestID(t *testing.T) {
+	ids := make(chan [16]byte, 8)
+	srv := server.New(server.HandlerFunc(func(ctx context.Context, req *protocol.InferenceRequest) (*protocol.InferenceResponse, error) {
+		ids <- req.ID
+		return &protocol.InferenceResponse{ID: req.ID, Text: "ok"}, nil
+	}))
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	infer := func(c *client.Client, id [16]byte) (sent, received [16]byte) {
+		t.Helper()
+		req := &protocol.InferenceRequest{ID: id, Prompt: "id", Metadata: map[string]string{}}
+		if _, err := c.Infer(ctx, req); err != nil {
+			t.Fatalf("Infer: %v", err)
+		}
+		return req.ID, <-ids
+	}
+
+	first, got := infer(c, [16]byte{})
+	if first == ([16]byte{}) {
+		t.Fatal("zero request ID was not filled in")
+	}
+	if got != first {
+		t.Errorf("server saw ID %x, request carries %x", got, first)
+	}
+	if second, _ := infer(c, [16]byte{}); second == first {
+		t.Errorf("two zero-ID requests were both assigned %x", first)
+	}
+
+	set := [16]byte{0x01, 0x02}
+	if sent, got := infer(c, set); sent != set || got != set {
+		t.Errorf("caller-set ID became %x (server saw %x), want %x", sent, got, set)
+	}
+
+	// An ID that starts like a version stamp still reaches the server, and
+	// comes back in the response, intact.
+	reserved := [16]byte{0xFE, 'S', 'B', 'V', 0x02}
+	if sent, got := infer(c, reserved); sent != reserved || got != reserved {
+		t.Errorf("reserved ID became %x (server saw %x), want %x", sent, got, reserved)
+	}
+
+	fixed := [16]byte{0xAA, 0xBB}
+	custom, err := client.Dial("unused", client.WithTransport(clientT),
+		client.WithIDGenerator(func() [16]byte { return fixed }))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	if sent, got := infer(custom, [16]byte{}); sent != fixed || got != fixed {
+		t.Errorf("generated ID %x (server saw %x), want %x", sent, got, fixed)
+	}
+}
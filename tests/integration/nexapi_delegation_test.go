@@ -0,0 +1,305 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/strand-protocol/strand/strand-cloud/pkg/ca"
+	"github.com/strand-protocol/strand/strand-cloud/pkg/model"
+	"github.com/strand-protocol/strand/strandapi/pkg/client"
+	"github.com/strand-protocol/strand/strandapi/pkg/protocol"
+	"github.com/strand-protocol/strand/strandapi/pkg/server"
+	"github.com/strand-protocol/strand/strandapi/pkg/strandbuf"
+)
+
+// --------------------------------------------------------------------------
+// Helpers
+// --------------------------------------------------------------------------
+
+// newTestCA returns a CA with a freshly generated root key.
+func newTestCA(t *testing.T) *ca.CA {
+	t.Helper()
+	c := ca.NewCA(ca.NewMemoryKeyStore())
+	if err := c.GenerateCA(); err != nil {
+		t.Fatalf("GenerateCA: %v", err)
+	}
+	return c
+}
+
+// issueEncodedMIC issues a MIC with the given capabilities and returns its
+// JSON encoding, the form carried in AgentDelegate metadata.
+func issueEncodedMIC(t *testing.T, authority *ca.CA, id string, caps []string) string {
+	t.Helper()
+	now := time.Now().UTC()
+	mic := &model.MIC{
+		ID:           id,
+		NodeID:       "node-delegator",
+		Capabilities: caps,
+		ValidFrom:    now.Add(-time.Minute),
+		ValidUntil:   now.Add(time.Hour),
+	}
+	if err := authority.IssueMIC(mic); err != nil {
+		t.Fatalf("IssueMIC: %v", err)
+	}
+	b, err := json.Marshal(mic)
+	if err != nil {
+		t.Fatalf("marshal MIC: %v", err)
+	}
+	return string(b)
+}
+
+// caMICVerifier adapts ca.VerifyMIC to the server's MICVerifier hook.
+func caMICVerifier(authority *ca.CA) server.MICVerifier {
+	return func(_ context.Context, encoded string) ([]string, error) {
+		var mic model.MIC
+		if err := json.Unmarshal([]byte(encoded), &mic); err != nil {
+			return nil, fmt.Errorf("decode MIC: %w", err)
+		}
+		ok, err := authority.VerifyMIC(&mic)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("MIC %q failed verification", mic.ID)
+		}
+		return mic.Capabilities, nil
+	}
+}
+
+// delegate sends an AgentDelegate with the given metadata and returns the
+// decoded AgentResult.
+func delegate(t *testing.T, c *client.Client, sessionID uint32, meta map[string]string) *protocol.AgentResult {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	msg := &protocol.AgentDelegate{
+		SessionID:   sessionID,
+		TaskPayload: []byte("write a sort function"),
+		Metadata:    meta,
+	}
+	buf := strandbuf.NewBuffer(256)
+	msg.Encode(buf)
+	if err := c.RawSend(ctx, protocol.OpAgentDelegate, buf.Bytes()); err != nil {
+		t.Fatalf("send delegate: %v", err)
+	}
+
+	opcode, payload, err := c.RawRecv(ctx)
+	if err != nil {
+		t.Fatalf("recv result: %v", err)
+	}
+	if opcode != protocol.OpAgentResult {
+		t.Fatalf("opcode = 0x%02x, want 0x%02x", opcode, protocol.OpAgentResult)
+	}
+	result := &protocol.AgentResult{}
+	if err := result.Decode(strandbuf.NewReader(payload)); err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	return result
+}
+
+// --------------------------------------------------------------------------
+// Tests
+// --------------------------------------------------------------------------
+
+// TestAgentDelegateMICAuthorization verifies that a server configured with a
+// MIC verifier rejects delegations whose MIC lacks the requested task type and
+// accepts those whose MIC grants it.
+func TestAgentDelegateMICAuthorization(t *testing.T) {
+	authority := newTestCA(t)
+
+	var handled int
+	srv := server.New(nil,
+		server.WithAgentHandler(func(_ context.Context, msg *protocol.AgentDelegate) (*protocol.AgentResult, error) {
+			handled++
+			return &protocol.AgentResult{ResultPayload: []byte("done"), ErrorCode: protocol.ErrOK}, nil
+		}),
+		server.WithMICVerifier(caMICVerifier(authority)),
+	)
+
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	// Insufficient MIC: grants text_gen only, task needs code_gen.
+	weak := issueEncodedMIC(t, authority, "mic-weak", []string{"text_gen"})
+	result := delegate(t, c, 1, map[string]string{
+		protocol.DelegateMetaTaskType: "code_gen",
+		protocol.DelegateMetaMIC:      weak,
+	})
+	if result.ErrorCode != protocol.ErrCapabilities {
+		t.Errorf("insufficient MIC: ErrorCode = 0x%04x, want 0x%04x", result.ErrorCode, protocol.ErrCapabilities)
+	}
+
+	// Missing MIC entirely.
+	result = delegate(t, c, 2, map[string]string{protocol.DelegateMetaTaskType: "code_gen"})
+	if result.ErrorCode != protocol.ErrCapabilities {
+		t.Errorf("missing MIC: ErrorCode = 0x%04x, want 0x%04x", result.ErrorCode, protocol.ErrCapabilities)
+	}
+
+	// Sufficient MIC.
+	strong := issueEncodedMIC(t, authority, "mic-strong", []string{"text_gen", "code_gen"})
+	result = delegate(t, c, 3, map[string]string{
+		protocol.DelegateMetaTaskType: "code_gen",
+		protocol.DelegateMetaMIC:      strong,
+	})
+	if result.ErrorCode != protocol.ErrOK {
+		t.Fatalf("sufficient MIC: ErrorCode = 0x%04x (%s), want OK", result.ErrorCode, result.ErrorMsg)
+	}
+	if result.SessionID != 3 {
+		t.Errorf("SessionID = %d, want 3", result.SessionID)
+	}
+	if string(result.ResultPayload) != "done" {
+		t.Errorf("ResultPayload = %q, want %q", result.ResultPayload, "done")
+	}
+
+	// Revoked MIC is rejected even though it grants the capability.
+	authority.RevokeMIC("mic-strong")
+	result = delegate(t, c, 4, map[string]string{
+		protocol.DelegateMetaTaskType: "code_gen",
+		protocol.DelegateMetaMIC:      strong,
+	})
+	if result.ErrorCode != protocol.ErrCapabilities {
+		t.Errorf("revoked MIC: ErrorCode = 0x%04x, want 0x%04x", result.ErrorCode, protocol.ErrCapabilities)
+	}
+
+	if handled != 1 {
+		t.Errorf("agent handler invoked %d times, want 1", handled)
+	}
+}
+
+// TestAgentNegotiateCapabilityIntersection verifies that the negotiate reply
+// contains exactly the capabilities shared by the peer and the server.
+func TestAgentNegotiateCapabilityIntersection(t *testing.T) {
+	srv := server.New(nil, server.WithAgentCapabilities([]string{"text_gen", "code_gen"}))
+
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	req := &protocol.AgentNegotiate{
+		SessionID:    42,
+		Capabilities: []string{"text_gen", "code_gen", "vision"},
+		Version:      1,
+	}
+	buf := strandbuf.NewBuffer(128)
+	req.Encode(buf)
+	if err := c.RawSend(ctx, protocol.OpAgentNegotiate, buf.Bytes()); err != nil {
+		t.Fatalf("send negotiate: %v", err)
+	}
+
+	opcode, payload, err := c.RawRecv(ctx)
+	if err != nil {
+		t.Fatalf("recv negotiate: %v", err)
+	}
+	if opcode != protocol.OpAgentNegotiate {
+		t.Fatalf("opcode = 0x%02x, want 0x%02x", opcode, protocol.OpAgentNegotiate)
+	}
+	resp := &protocol.AgentNegotiate{}
+	if err := resp.Decode(strandbuf.NewReader(payload)); err != nil {
+		t.Fatalf("decode negotiate: %v", err)
+	}
+	if resp.SessionID != 42 {
+		t.Errorf("SessionID = %d, want 42", resp.SessionID)
+	}
+
+	want := map[string]bool{"text_gen": true, "code_gen": true}
+	if len(resp.Capabilities) != len(want) {
+		t.Fatalf("Capabilities = %v, want exactly text_gen and code_gen", resp.Capabilities)
+	}
+	for _, c := range resp.Capabilities {
+		if !want[c] {
+			t.Errorf("unexpected capability %q in response", c)
+		}
+	}
+}
+
+// TestAgentDelegateTimeout verifies that a handler running past the
+// delegation's TimeoutMS yields a prompt ErrTimeout result and that the
+// handler's context is cancelled.
+func TestAgentDelegateTimeout(t *testing.T) {
+	handlerCancelled := make(chan struct{})
+	srv := server.New(nil,
+		server.WithAgentHandler(func(ctx context.Context, msg *protocol.AgentDelegate) (*protocol.AgentResult, error) {
+			select {
+			case <-ctx.Done():
+				close(handlerCancelled)
+				return nil, ctx.Err()
+			case <-time.After(5 * time.Second):
+				return &protocol.AgentResult{ResultPayload: []byte("too late")}, nil
+			}
+		}),
+	)
+
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	msg := &protocol.AgentDelegate{
+		SessionID:   9,
+		TaskPayload: []byte("slow task"),
+		TimeoutMS:   100,
+	}
+	buf := strandbuf.NewBuffer(128)
+	msg.Encode(buf)
+
+	start := time.Now()
+	if err := c.RawSend(ctx, protocol.OpAgentDelegate, buf.Bytes()); err != nil {
+		t.Fatalf("send delegate: %v", err)
+	}
+	opcode, payload, err := c.RawRecv(ctx)
+	if err != nil {
+		t.Fatalf("recv result: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if opcode != protocol.OpAgentResult {
+		t.Fatalf("opcode = 0x%02x, want 0x%02x", opcode, protocol.OpAgentResult)
+	}
+	result := &protocol.AgentResult{}
+	if err := result.Decode(strandbuf.NewReader(payload)); err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	if result.ErrorCode != protocol.ErrTimeout {
+		t.Errorf("ErrorCode = 0x%04x, want 0x%04x", result.ErrorCode, protocol.ErrTimeout)
+	}
+	if result.SessionID != 9 {
+		t.Errorf("SessionID = %d, want 9", result.SessionID)
+	}
+	if elapsed > time.Second {
+		t.Errorf("timeout result took %v, want well under 1s", elapsed)
+	}
+
+	select {
+	case <-handlerCancelled:
+	case <-time.After(time.Second):
+		t.Error("handler context was not cancelled")
+	}
+}
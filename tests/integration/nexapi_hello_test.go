@@ -0,0 +1,250 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/strand-protocol/strand/strandapi/pkg/client"
+	"github.com/strand-protocol/strand/strandapi/pkg/protocol"
+	"github.com/strand-protocol/strand/strandapi/pkg/server"
+	"github.com/strand-protocol/strand/strandapi/pkg/transport"
+)
+
+// countingTransport records how many frames of each opcode were sent.
+type countingTransport struct {
+	transport.Transport
+	sends [256]atomic.Int32
+}
+
+func (t *countingTransport) Send(ctx context.Context, opcode byte, payload []byte) error {
+	t.sends[opcode].Add(1)
+	return t.Transport.Send(ctx, opcode, payload)
+}
+
+// TestStrandAPIHelloNegotiatesMaxMessageSize verifies that the OpHello
+// handshake settles on the smaller limit and that the client then rejects an
+// oversized request with ErrMessageTooLarge without touching the transport.
+func TestStrandAPIHelloNegotiatesMaxMessageSize(t *testing.T) {
+	srv := server.New(&echoHandler{}, server.WithMaxMessageSize(1024))
+
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	counting := &countingTransport{Transport: clientT}
+	c, err := client.Dial("unused", client.WithTransport(counting), client.WithMaxMessageSize(4096))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	hello, err := c.Hello(ctx)
+	if err != nil {
+		t.Fatalf("Hello: %v", err)
+	}
+	if hello.MaxMessageSize != 1024 || c.MaxMessageSize() != 1024 {
+		t.Fatalf("negotiated %d (client reports %d), want 1024", hello.MaxMessageSize, c.MaxMessageSize())
+	}
+
+	// A request within the limit still works.
+	if _, err := c.Infer(ctx, &protocol.InferenceRequest{Prompt: "small", Metadata: map[string]string{}}); err != nil {
+		t.Fatalf("Infer small: %v", err)
+	}
+	before := counting.sends[protocol.OpInferenceRequest].Load()
+
+	big := &protocol.InferenceRequest{Prompt: strings.Repeat("x", 2048), Metadata: map[string]string{}}
+	_, err = c.Infer(ctx, big)
+	if !errors.Is(err, transport.ErrMessageTooLarge) {
+		t.Fatalf("Infer oversized: err = %v, want ErrMessageTooLarge", err)
+	}
+	if _, err := c.OpenStream(ctx, big); !errors.Is(err, transport.ErrMessageTooLarge) {
+		t.Fatalf("OpenStream oversized: err = %v, want ErrMessageTooLarge", err)
+	}
+	if after := counting.sends[protocol.OpInferenceRequest].Load(); after != before {
+		t.Errorf("oversized requests reached the transport (%d sends, want %d)", after, before)
+	}
+}
+
+// TestStrandAPIHelloCapsToDatagramSize verifies that over the UDP overlay
+// the handshake settles on a limit that fits in one datagram, so a prompt too
+// large to send fails up front with ErrMessageTooLarge.
+func TestStrandAPIHelloCapsToDatagramSize(t *testing.T) {
+	serverT, err := transport.ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	srv := server.New(&echoHandler{})
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	c, err := client.Dial(serverT.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	hello, err := c.Hello(ctx)
+	if err != nil {
+		t.Fatalf("Hello: %v", err)
+	}
+	if hello.MaxMessageSize == 0 || hello.MaxMessageSize >= 65507 {
+		t.Fatalf("negotiated %d, want a limit below the UDP maximum", hello.MaxMessageSize)
+	}
+
+	big := &protocol.InferenceRequest{Prompt: strings.Repeat("x", 100_000), Metadata: map[string]string{}}
+	if _, err := c.Infer(ctx, big); !errors.Is(err, transport.ErrMessageTooLarge) {
+		t.Fatalf("Infer 100 KB prompt: err = %v, want ErrMessageTooLarge", err)
+	}
+	resp, err := c.Infer(ctx, &protocol.InferenceRequest{Prompt: "small", Metadata: map[string]string{}})
+	if err != nil {
+		t.Fatalf("Infer small: %v", err)
+	}
+	if resp.Text != "echo: small" {
+		t.Errorf("Infer small: got %q", resp.Text)
+	}
+}
+
+// TestStrandAPIServerRejectsOversizedFrame verifies that a client which skips
+// the handshake still gets a clear error for a frame above the server limit.
+func TestStrandAPIServerRejectsOversizedFrame(t *testing.T) {
+	srv := server.New(&echoHandler{}, server.WithMaxMessageSize(512))
+
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = c.Infer(ctx, &protocol.InferenceRequest{Prompt: strings.Repeat("y", 1024), Metadata: map[string]string{}})
+	if err == nil || !strings.Contains(err.Error(), "exceeds maximum") {
+		t.Fatalf("Infer oversized without hello: err = %v, want server size error", err)
+	}
+}
+
+// fakeZstd stands in for an application-supplied zstd Compressor. It only
+// has to be recognisable: the server under test never sees its output.
+type fakeZstd struct{ calls atomic.Int32 }
+
+func (z *fakeZstd) Codec() protocol.Codec { return protocol.CodecZstd }
+
+func (z *fakeZstd) Compress(p []byte) ([]byte, error) {
+	z.calls.Add(1)
+	return p[:1], nil
+}
+
+func (z *fakeZstd) Decompress(p []byte, _ uint32) ([]byte, error) { return p, nil }
+
+// TestStrandAPIHelloCompressionFallsBackToRaw verifies that a client offering
+// zstd to a server that accepts only raw frames sends them uncompressed.
+func TestStrandAPIHelloCompressionFallsBackToRaw(t *testing.T) {
+	srv := server.New(&echoHandler{})
+
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	zstd := &fakeZstd{}
+	counting := &countingTransport{Transport: clientT}
+	c, err := client.Dial("unused", client.WithTransport(counting), client.WithCompression(zstd))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	hello, err := c.Hello(ctx)
+	if err != nil {
+		t.Fatalf("Hello: %v", err)
+	}
+	if len(hello.Codecs) != 0 {
+		t.Fatalf("raw-only server advertised codecs %v", hello.Codecs)
+	}
+	if got := c.Compression(); got != protocol.CodecRaw {
+		t.Fatalf("Compression = %v, want raw", got)
+	}
+
+	prompt := strings.Repeat("compressible ", 200)
+	resp, err := c.Infer(ctx, &protocol.InferenceRequest{Prompt: prompt, Metadata: map[string]string{}})
+	if err != nil {
+		t.Fatalf("Infer: %v", err)
+	}
+	if resp.Text != "echo: "+prompt {
+		t.Errorf("response text has %d bytes, want %d", len(resp.Text), len("echo: "+prompt))
+	}
+	if n := counting.sends[protocol.OpCompressed].Load(); n != 0 {
+		t.Errorf("client sent %d compressed frames to a raw-only server", n)
+	}
+	if n := counting.sends[protocol.OpInferenceRequest].Load(); n != 1 {
+		t.Errorf("client sent %d raw inference requests, want 1", n)
+	}
+	if n := zstd.calls.Load(); n != 0 {
+		t.Errorf("zstd compressor was called %d times", n)
+	}
+}
+
+// TestStrandAPIHelloCompressionDeflate verifies that when both sides support
+// deflate, large requests travel compressed and are served normally.
+func TestStrandAPIHelloCompressionDeflate(t *testing.T) {
+	srv := server.New(&echoHandler{}, server.WithCompression(protocol.Deflate))
+
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	counting := &countingTransport{Transport: clientT}
+	c, err := client.Dial("unused", client.WithTransport(counting),
+		client.WithCompression(&fakeZstd{}, protocol.Deflate))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := c.Hello(ctx); err != nil {
+		t.Fatalf("Hello: %v", err)
+	}
+	if got := c.Compression(); got != protocol.CodecDeflate {
+		t.Fatalf("Compression = %v, want deflate", got)
+	}
+
+	prompt := strings.Repeat("compressible ", 200)
+	resp, err := c.Infer(ctx, &protocol.InferenceRequest{Prompt: prompt, Metadata: map[string]string{}})
+	if err != nil {
+		t.Fatalf("Infer: %v", err)
+	}
+	if resp.Text != "echo: "+prompt {
+		t.Errorf("response text has %d bytes, want %d", len(resp.Text), len("echo: "+prompt))
+	}
+	if n := counting.sends[protocol.OpCompressed].Load(); n != 1 {
+		t.Errorf("client sent %d compressed frames, want 1", n)
+	}
+
+	// Small payloads are not worth compressing.
+	if _, err := c.Infer(ctx, &protocol.InferenceRequest{Prompt: "short", Metadata: map[string]string{}}); err != nil {
+		t.Fatalf("Infer short: %v", err)
+	}
+	if n := counting.sends[protocol.OpInferenceRequest].Load(); n != 1 {
+		t.Errorf("client sent %d raw inference requests, want 1", n)
+	}
+}
@@ -1,8 +1,15 @@
 package integration
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
+	"errors"
 	"fmt"
+	"net"
+	"runtime"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -11,6 +18,7 @@ import (
 	"github.com/strand-protocol/strand/strandapi/pkg/client"
 	"github.com/strand-protocol/strand/strandapi/pkg/strandbuf"
 	"github.com/strand-protocol/strand/strandapi/pkg/protocol"
+	"github.com/strand-protocol/strand/strandapi/pkg/sad"
 	"github.com/strand-protocol/strand/strandapi/pkg/server"
 	"github.com/strand-protocol/strand/strandapi/pkg/transport"
 )
@@ -396,3 +404,2080 @@ func TestStrandAPILargePrompt(t *testing.T) {
 		t.Errorf("large prompt response length: got %d, want %d", len(resp.Text), len(expected))
 	}
 }
+
+// --------------------------------------------------------------------------
+// Tests against the real server dispatch loop (server.Serve)
+// --------------------------------------------------------------------------
+
+// startServer runs srv on serverT via Serve and returns a function that stops
+// it and waits for the dispatch loop to exit.
+func startServer(t *testing.T, srv *server.Server, serverT transport.Transport) (stop func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := srv.Serve(serverT); err != nil {
+			t.Logf("serve: %v", err)
+		}
+	}()
+	return func() {
+		srv.Stop()
+		<-done
+	}
+}
+
+// blockingStreamHandler emits a single token and then blocks until its
+// context is cancelled, recording that the cancellation was observed.
+type blockingStreamHandler struct {
+	cancelled chan struct{}
+}
+
+func (h *blockingStreamHandler) HandleTokenStream(ctx context.Context, req *protocol.InferenceRequest, sender server.TokenSender) error {
+	if err := sender.Send(&protocol.TokenStreamChunk{RequestID: req.ID, Token: "first"}); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		close(h.cancelled)
+		return ctx.Err()
+	case <-time.After(5 * time.Second):
+		return fmt.Errorf("handler was never cancelled")
+	}
+}
+
+// TestStrandAPIStreamCancellation verifies that a client abandoning a stream
+// and calling CancelStream causes the server-side handler context to be
+// cancelled, so the node stops generating.
+func TestStrandAPIStreamCancellation(t *testing.T) {
+	h := &blockingStreamHandler{cancelled: make(chan struct{})}
+	srv := server.New(nil, server.WithStreamHandler(h))
+
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	req := &protocol.InferenceRequest{
+		ID:       [16]byte{0xCA, 0xFE},
+		Prompt:   "generate forever",
+		Metadata: map[string]string{},
+	}
+
+	streamCtx, streamCancel := context.WithCancel(context.Background())
+	ch, err := c.StreamTokens(streamCtx, req)
+	if err != nil {
+		t.Fatalf("StreamTokens: %v", err)
+	}
+
+	select {
+	case chunk := <-ch:
+		if chunk == nil || chunk.Token != "first" {
+			t.Fatalf("first chunk: got %+v", chunk)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for first chunk")
+	}
+
+	// Simulate the downstream consumer disconnecting.
+	streamCancel()
+	for range ch {
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := c.CancelStream(ctx, req.ID); err != nil {
+		t.Fatalf("CancelStream: %v", err)
+	}
+
+	select {
+	case <-h.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("server handler did not observe cancellation")
+	}
+}
+
+// floodStreamHandler streams tokens as fast as the transport takes them
+// until its context is cancelled, which it records.
+type floodStreamHandler struct {
+	cancelled chan struct{}
+}
+
+func (h *floodStreamHandler) HandleTokenStream(ctx context.Context, req *protocol.InferenceRequest, sender server.TokenSender) error {
+	defer close(h.cancelled)
+	for i := uint32(0); ; i++ {
+		if err := sender.Send(&protocol.TokenStreamChunk{RequestID: req.ID, SeqNum: i, Token: "tok"}); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// TestStrandAPIStreamTokensCancelNoLeak cancels StreamTokens mid-stream,
+// with the consumer no longer receiving, and checks that the channel is
+// closed, the server is told to stop, and no goroutine outlives the client.
+func TestStrandAPIStreamTokensCancelNoLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 5; i++ {
+		h := &floodStreamHandler{cancelled: make(chan struct{})}
+		srv := server.New(nil, server.WithStreamHandler(h))
+		clientT, serverT := newChannelTransportPair()
+		stop := startServer(t, srv, serverT)
+		c, err := client.Dial("unused", client.WithTransport(clientT))
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		ch, err := c.StreamTokens(ctx, &protocol.InferenceRequest{Prompt: "flood"})
+		if err != nil {
+			t.Fatalf("StreamTokens: %v", err)
+		}
+		for n := 0; n < 3; n++ {
+			select {
+			case <-ch:
+			case <-time.After(2 * time.Second):
+				t.Fatal("timed out waiting for a chunk")
+			}
+		}
+		// Stop receiving, then cancel while the reader is blocked handing
+		// over chunks.
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+
+		// The channel closes without the consumer draining the backlog.
+		timeout := time.After(2 * time.Second)
+	drain:
+		for {
+			select {
+			case _, ok := <-ch:
+				if !ok {
+					break drain
+				}
+			case <-timeout:
+				t.Fatal("token channel not closed after cancellation")
+			}
+		}
+		select {
+		case <-h.cancelled:
+		case <-time.After(2 * time.Second):
+			t.Fatal("server handler did not observe the abandoned stream's cancel")
+		}
+		c.Close()
+		stop()
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("goroutines: %d before, %d after cancelled streams\n%s",
+				before, runtime.NumGoroutine(), buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// wordStreamHandler streams the prompt back one word at a time.
+type wordStreamHandler struct{}
+
+func (h *wordStreamHandler) HandleTokenStream(_ context.Context, req *protocol.InferenceRequest, sender server.TokenSender) error {
+	for i, w := range strings.Fields(req.Prompt) {
+		if err := sender.Send(&protocol.TokenStreamChunk{RequestID: req.ID, SeqNum: uint32(i), Token: w}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TestStrandAPIStreamSummary verifies that the stream end frame carries usage
+// and that the summary becomes available after the last token.
+func TestStrandAPIStreamSummary(t *testing.T) {
+	srv := server.New(nil, server.WithStreamHandler(&wordStreamHandler{}))
+
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	tests := []struct {
+		name       string
+		prompt     string
+		maxTokens  uint32
+		wantFinish string
+	}{
+		{"stop", "one two three", 100, "stop"},
+		{"length", "one two three four", 4, "length"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			ts, err := c.OpenStream(ctx, &protocol.InferenceRequest{
+				Prompt:    tc.prompt,
+				MaxTokens: tc.maxTokens,
+				Metadata:  map[string]string{},
+			})
+			if err != nil {
+				t.Fatalf("OpenStream: %v", err)
+			}
+
+			var tokens int
+			for range ts.C {
+				tokens++
+			}
+
+			words := uint32(len(strings.Fields(tc.prompt)))
+			if uint32(tokens) != words {
+				t.Fatalf("received %d tokens, want %d", tokens, words)
+			}
+			summary := ts.Summary()
+			if summary == nil {
+				t.Fatal("Summary() = nil after stream end")
+			}
+			if summary.CompletionTokens != words {
+				t.Errorf("CompletionTokens = %d, want %d", summary.CompletionTokens, words)
+			}
+			if summary.PromptTokens != words {
+				t.Errorf("PromptTokens = %d, want %d", summary.PromptTokens, words)
+			}
+			if summary.FinishReason != tc.wantFinish {
+				t.Errorf("FinishReason = %q, want %q", summary.FinishReason, tc.wantFinish)
+			}
+		})
+	}
+}
+
+// TestStrandAPIStreamFlag verifies that one server with only a stream handler
+// streams requests with Stream set and answers the others with a single
+// response collected from the same handler.
+func TestStrandAPIStreamFlag(t *testing.T) {
+	srv := server.New(nil, server.WithStreamHandler(&wordStreamHandler{}))
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	send := func(stream bool) {
+		t.Helper()
+		req := &protocol.InferenceRequest{ID: [16]byte{1}, Prompt: "one two three", Metadata: map[string]string{}, Stream: stream}
+		buf := strandbuf.NewBuffer(64)
+		req.Encode(buf)
+		if err := clientT.Send(ctx, protocol.OpInferenceRequest, buf.Bytes()); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+
+	send(false)
+	opcode, payload, err := clientT.Recv(ctx)
+	if err != nil {
+		t.Fatalf("recv: %v", err)
+	}
+	if opcode != protocol.OpInferenceResponse {
+		t.Fatalf("reply to a non-streaming request has opcode %#02x, want OpInferenceResponse", opcode)
+	}
+	resp := &protocol.InferenceResponse{}
+	if err := resp.Decode(strandbuf.NewReader(payload)); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.ID != [16]byte{1} || resp.Text != "onetwothree" || resp.CompletionTokens != 3 || resp.PromptTokens != 3 || resp.FinishReason != "stop" {
+		t.Errorf("collected response = %+v, want the three tokens with usage", resp)
+	}
+
+	send(true)
+	var ops []byte
+	for {
+		opcode, _, err := clientT.Recv(ctx)
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		ops = append(ops, opcode)
+		if opcode == protocol.OpTokenStreamEnd {
+			break
+		}
+	}
+	want := []byte{protocol.OpTokenStreamStart, protocol.OpTokenStreamChunk, protocol.OpTokenStreamChunk, protocol.OpTokenStreamChunk, protocol.OpTokenStreamEnd}
+	if !bytes.Equal(ops, want) {
+		t.Errorf("reply to a streaming request = %x, want %x", ops, want)
+	}
+}
+
+// TestStrandAPIStreamErrDistinguishesCancellation verifies that a stream cut
+// off by context cancellation reports a non-nil Err alongside the tokens
+// received so far, while a completed stream reports nil.
+func TestStrandAPIStreamErrDistinguishesCancellation(t *testing.T) {
+	h := &blockingStreamHandler{cancelled: make(chan struct{})}
+	srv := server.New(nil, server.WithStreamHandler(h))
+
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	ts, err := c.OpenStream(ctx, &protocol.InferenceRequest{Prompt: "partial", Metadata: map[string]string{}})
+	if err != nil {
+		t.Fatalf("OpenStream: %v", err)
+	}
+	var partial []string
+	for chunk := range ts.C {
+		partial = append(partial, chunk.Token)
+	}
+
+	if len(partial) != 1 || partial[0] != "first" {
+		t.Errorf("partial tokens = %v, want [first]", partial)
+	}
+	if err := ts.Err(); err != context.DeadlineExceeded {
+		t.Errorf("Err() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if ts.Summary() != nil {
+		t.Error("Summary() should be nil for an interrupted stream")
+	}
+}
+
+// TestStrandAPIStreamErrNilOnCompletion verifies Err is nil after a stream
+// that ended normally.
+func TestStrandAPIStreamErrNilOnCompletion(t *testing.T) {
+	srv := server.New(nil, server.WithStreamHandler(&wordStreamHandler{}))
+
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ts, err := c.OpenStream(ctx, &protocol.InferenceRequest{Prompt: "a b", Metadata: map[string]string{}})
+	if err != nil {
+		t.Fatalf("OpenStream: %v", err)
+	}
+	for range ts.C {
+	}
+	if err := ts.Err(); err != nil {
+		t.Errorf("Err() = %v, want nil", err)
+	}
+}
+
+// gatedTransport holds every token chunk write until gate is closed, and
+// fails chunk writes with failErr when set, simulating a slow or broken link.
+type gatedTransport struct {
+	*channelTransport
+	gate    chan struct{}
+	failErr error
+}
+
+func (t *gatedTransport) Send(ctx context.Context, opcode byte, payload []byte) error {
+	if opcode == protocol.OpTokenStreamChunk {
+		if t.failErr != nil {
+			return t.failErr
+		}
+		select {
+		case <-t.gate:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return t.channelTransport.Send(ctx, opcode, payload)
+}
+
+// countingStreamHandler sends n numbered tokens, recording how many Send
+// calls have returned and the first Send error.
+type countingStreamHandler struct {
+	n        int
+	accepted atomic.Int32
+	err      chan error
+}
+
+func (h *countingStreamHandler) HandleTokenStream(_ context.Context, req *protocol.InferenceRequest, sender server.TokenSender) error {
+	for i := 0; i < h.n; i++ {
+		if err := sender.Send(&protocol.TokenStreamChunk{RequestID: req.ID, SeqNum: uint32(i), Token: fmt.Sprint(i)}); err != nil {
+			h.err <- err
+			return err
+		}
+		h.accepted.Add(1)
+	}
+	h.err <- nil
+	return nil
+}
+
+// TestStrandAPIStreamBackpressure verifies that a handler producing tokens
+// faster than the transport accepts them is blocked at the configured
+// high-water mark and that no tokens are lost once the transport drains.
+func TestStrandAPIStreamBackpressure(t *testing.T) {
+	const depth, total = 4, 50
+	h := &countingStreamHandler{n: total, err: make(chan error, 1)}
+	srv := server.New(nil, server.WithStreamHandler(h), server.WithTokenSendBuffer(depth))
+
+	clientT, serverT := newChannelTransportPair()
+	gated := &gatedTransport{channelTransport: serverT, gate: make(chan struct{})}
+	stop := startServer(t, srv, gated)
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ts, err := c.OpenStream(ctx, &protocol.InferenceRequest{Prompt: "burst", Metadata: map[string]string{}})
+	if err != nil {
+		t.Fatalf("OpenStream: %v", err)
+	}
+
+	// With the transport stalled the writer holds one chunk and the queue
+	// holds depth more; the next Send must block.
+	deadline := time.Now().Add(2 * time.Second)
+	for h.accepted.Load() < depth+1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := h.accepted.Load(); got != depth+1 {
+		t.Fatalf("accepted %d chunks while transport stalled, want %d", got, depth+1)
+	}
+
+	close(gated.gate)
+
+	var got []string
+	for chunk := range ts.C {
+		got = append(got, chunk.Token)
+	}
+	if err := ts.Err(); err != nil {
+		t.Fatalf("stream Err = %v", err)
+	}
+	if len(got) != total {
+		t.Fatalf("received %d tokens, want %d", len(got), total)
+	}
+	for i, tok := range got {
+		if tok != fmt.Sprint(i) {
+			t.Fatalf("token %d = %q, want %q", i, tok, fmt.Sprint(i))
+		}
+	}
+	if s := ts.Summary(); s == nil || s.CompletionTokens != total {
+		t.Errorf("summary = %+v, want CompletionTokens %d", s, total)
+	}
+	if err := <-h.err; err != nil {
+		t.Errorf("handler Send error: %v", err)
+	}
+}
+
+// TestStrandAPIStreamSendErrorSurfaces verifies that a transport failure
+// while writing chunks is returned to the handler from a later Send.
+func TestStrandAPIStreamSendErrorSurfaces(t *testing.T) {
+	h := &countingStreamHandler{n: 100, err: make(chan error, 1)}
+	srv := server.New(nil, server.WithStreamHandler(h), server.WithTokenSendBuffer(2))
+
+	clientT, serverT := newChannelTransportPair()
+	failErr := fmt.Errorf("send buffer full")
+	stop := startServer(t, srv, &gatedTransport{channelTransport: serverT, failErr: failErr})
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := c.OpenStream(ctx, &protocol.InferenceRequest{Prompt: "doomed", Metadata: map[string]string{}}); err != nil {
+		t.Fatalf("OpenStream: %v", err)
+	}
+
+	select {
+	case err := <-h.err:
+		if err != failErr {
+			t.Errorf("handler Send error = %v, want %v", err, failErr)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler never saw the transport error")
+	}
+}
+
+// TestStrandAPICustomFrameHandler verifies that Server.Handle routes a new
+// opcode to a user handler and can override a built-in one.
+func TestStrandAPICustomFrameHandler(t *testing.T) {
+	const opEmbeddingRequest, opEmbeddingResponse byte = 0x40, 0x41
+
+	srv := server.New(&echoHandler{})
+	var invoked atomic.Int32
+	srv.Handle(opEmbeddingRequest, func(ctx context.Context, payload []byte, w server.FrameWriter) {
+		invoked.Add(1)
+		_ = w.Send(ctx, opEmbeddingResponse, []byte(strings.ToUpper(string(payload))))
+	})
+	srv.Handle(protocol.OpHeartbeat, func(ctx context.Context, _ []byte, w server.FrameWriter) {
+		_ = w.Send(ctx, protocol.OpHeartbeat, []byte("custom"))
+	})
+
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := c.RawSend(ctx, opEmbeddingRequest, []byte("embed me")); err != nil {
+		t.Fatalf("RawSend: %v", err)
+	}
+	opcode, payload, err := c.RawRecv(ctx)
+	if err != nil {
+		t.Fatalf("RawRecv: %v", err)
+	}
+	if opcode != opEmbeddingResponse || string(payload) != "EMBED ME" {
+		t.Errorf("got opcode 0x%02x payload %q, want 0x%02x %q", opcode, payload, opEmbeddingResponse, "EMBED ME")
+	}
+	if invoked.Load() != 1 {
+		t.Errorf("custom handler invoked %d times, want 1", invoked.Load())
+	}
+
+	if err := c.RawSend(ctx, protocol.OpHeartbeat, nil); err != nil {
+		t.Fatalf("RawSend heartbeat: %v", err)
+	}
+	if opcode, payload, err = c.RawRecv(ctx); err != nil || opcode != protocol.OpHeartbeat || string(payload) != "custom" {
+		t.Errorf("heartbeat override: got 0x%02x %q %v, want custom reply", opcode, payload, err)
+	}
+
+	// Built-in handlers not overridden keep working.
+	resp, err := c.Infer(ctx, &protocol.InferenceRequest{Prompt: "still here", Metadata: map[string]string{}})
+	if err != nil || resp.Text != "echo: still here" {
+		t.Errorf("Infer after Handle: %+v, %v", resp, err)
+	}
+}
+
+// TestStrandAPICustomFrameHandlerRepliesToSender verifies over a real
+// overlay listener that a custom handler's FrameWriter answers the peer that
+// sent the frame, on the stream it arrived on, while another peer is served
+// concurrently.
+func TestStrandAPICustomFrameHandlerRepliesToSender(t *testing.T) {
+	const opEcho byte = 0x40
+
+	serverT, err := transport.ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	srv := server.New(nil)
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	srv.Handle(opEcho, func(ctx context.Context, payload []byte, w server.FrameWriter) {
+		arrived.Done()
+		arrived.Wait()
+		_ = w.Send(ctx, opEcho, append([]byte("re: "), payload...))
+	})
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	peers := []struct {
+		stream uint32
+		msg    string
+	}{{0, "from a"}, {3, "from b"}}
+	var wg sync.WaitGroup
+	for _, p := range peers {
+		pt, err := transport.DialOverlay(serverT.LocalAddr().String())
+		if err != nil {
+			t.Fatalf("DialOverlay: %v", err)
+		}
+		defer pt.Close()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := pt.SendStream(ctx, p.stream, opEcho, []byte(p.msg)); err != nil {
+				t.Errorf("%s: SendStream: %v", p.msg, err)
+				return
+			}
+			stream, opcode, payload, err := pt.RecvStream(ctx)
+			if err != nil {
+				t.Errorf("%s: RecvStream: %v", p.msg, err)
+				return
+			}
+			if stream != p.stream || opcode != opEcho || string(payload) != "re: "+p.msg {
+				t.Errorf("%s: got stream %d opcode 0x%02x %q, want stream %d %q", p.msg, stream, opcode, payload, p.stream, "re: "+p.msg)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// streamingOnlyHandler rejects synchronous inference the way
+// examples/inference does.
+type streamingOnlyHandler struct{}
+
+func (streamingOnlyHandler) HandleInference(context.Context, *protocol.InferenceRequest) (*protocol.InferenceResponse, error) {
+	return nil, &protocol.ErrorMessage{Code: protocol.ErrUnsupported, Message: "use streaming mode"}
+}
+
+// TestStrandAPIInferFallsBackToStream verifies that Infer against a
+// stream-only server returns the assembled text instead of an error.
+func TestStrandAPIInferFallsBackToStream(t *testing.T) {
+	srv := server.New(streamingOnlyHandler{}, server.WithStreamHandler(&wordStreamHandler{}))
+
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &protocol.InferenceRequest{ID: [16]byte{0x5E}, Prompt: "alpha beta gamma", Metadata: map[string]string{}}
+	resp, err := c.Infer(ctx, req)
+	if err != nil {
+		t.Fatalf("Infer: %v", err)
+	}
+	if resp.Text != "alphabetagamma" {
+		t.Errorf("Text = %q, want %q", resp.Text, "alphabetagamma")
+	}
+	if resp.ID != req.ID || resp.PromptTokens != 3 || resp.CompletionTokens != 3 || resp.FinishReason != "stop" {
+		t.Errorf("resp = %+v, want ID %x, 3 prompt and completion tokens, finish stop", resp, req.ID)
+	}
+}
+
+// textOnlyHandler generates a reply but leaves usage and finish reason to
+// the server.
+type textOnlyHandler struct{}
+
+func (textOnlyHandler) HandleInference(_ context.Context, req *protocol.InferenceRequest) (*protocol.InferenceResponse, error) {
+	return &protocol.InferenceResponse{ID: req.ID, Text: "the quick brown fox"}, nil
+}
+
+// TestStrandAPIInferReportsUsage verifies that a synchronous response
+// carries usage and a finish reason even when the handler sets none.
+func TestStrandAPIInferReportsUsage(t *testing.T) {
+	srv := server.New(textOnlyHandler{})
+
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name       string
+		maxTokens  uint32
+		wantFinish string
+	}{
+		{"stop", 100, "stop"},
+		{"length", 4, "length"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := c.Infer(ctx, &protocol.InferenceRequest{Prompt: "tell me a story", MaxTokens: tc.maxTokens, Metadata: map[string]string{}})
+			if err != nil {
+				t.Fatalf("Infer: %v", err)
+			}
+			if resp.PromptTokens != 4 || resp.CompletionTokens != 4 {
+				t.Errorf("usage = %d prompt, %d completion tokens, want 4 and 4", resp.PromptTokens, resp.CompletionTokens)
+			}
+			if resp.FinishReason != tc.wantFinish {
+				t.Errorf("FinishReason = %q, want %q", resp.FinishReason, tc.wantFinish)
+			}
+		})
+	}
+
+	// Usage the handler reports is passed through unchanged.
+	srv2 := server.New(&echoHandler{})
+	clientT2, serverT2 := newChannelTransportPair()
+	stop2 := startServer(t, srv2, serverT2)
+	defer stop2()
+	c2, err := client.Dial("unused", client.WithTransport(clientT2))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c2.Close()
+	resp, err := c2.Infer(ctx, &protocol.InferenceRequest{Prompt: "hello", Metadata: map[string]string{}})
+	if err != nil {
+		t.Fatalf("Infer: %v", err)
+	}
+	if resp.PromptTokens != 5 || resp.CompletionTokens != 11 {
+		t.Errorf("echo usage = %d/%d, want the handler's 5/11", resp.PromptTokens, resp.CompletionTokens)
+	}
+}
+
+// TestStrandAPIPromptCache verifies that a prompt prefix cached under a key
+// is prepended to later requests that reference the key, so the handler sees
+// the full prompt either way.
+func TestStrandAPIPromptCache(t *testing.T) {
+	var mu sync.Mutex
+	var prompts []string
+	srv := server.New(server.HandlerFunc(func(_ context.Context, req *protocol.InferenceRequest) (*protocol.InferenceResponse, error) {
+		mu.Lock()
+		prompts = append(prompts, req.Prompt)
+		mu.Unlock()
+		return &protocol.InferenceResponse{ID: req.ID, Text: "ok"}, nil
+	}))
+
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	const system = "You are a terse assistant. "
+	reqs := []*protocol.InferenceRequest{
+		{Prompt: system + "first question", Metadata: map[string]string{}, PromptCacheKey: "sys", PromptCachePrefixLen: uint32(len(system))},
+		{Prompt: "second question", Metadata: map[string]string{}, PromptCacheKey: "sys"},
+	}
+	for i, req := range reqs {
+		if _, err := c.Infer(ctx, req); err != nil {
+			t.Fatalf("request %d: Infer: %v", i+1, err)
+		}
+	}
+	mu.Lock()
+	got := prompts
+	mu.Unlock()
+	want := []string{system + "first question", system + "second question"}
+	if !slices.Equal(got, want) {
+		t.Errorf("handler prompts = %q, want %q", got, want)
+	}
+
+	// A key that was never cached is reported, not silently dropped.
+	_, err = c.Infer(ctx, &protocol.InferenceRequest{Prompt: "third", Metadata: map[string]string{}, PromptCacheKey: "other"})
+	var serr *client.ServerError
+	if !errors.As(err, &serr) || serr.Code != protocol.ErrNotFound {
+		t.Errorf("Infer with an uncached key: err = %v, want ErrNotFound", err)
+	}
+}
+
+// panickyStreamHandler streams the prompt's words but panics on the word
+// "boom".
+type panickyStreamHandler struct{}
+
+func (panickyStreamHandler) HandleTokenStream(_ context.Context, req *protocol.InferenceRequest, sender server.TokenSender) error {
+	for i, w := range strings.Fields(req.Prompt) {
+		if w == "boom" {
+			panic("stream handler exploded")
+		}
+		if err := sender.Send(&protocol.TokenStreamChunk{RequestID: req.ID, SeqNum: uint32(i), Token: w}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TestStrandAPIHandlerPanic verifies that a panicking handler is answered
+// with ErrInternal and that the server goes on serving later requests.
+func TestStrandAPIHandlerPanic(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	wantInternal := func(t *testing.T, err error) {
+		t.Helper()
+		var serr *client.ServerError
+		if !errors.As(err, &serr) || serr.Code != protocol.ErrInternal {
+			t.Errorf("err = %v, want an ErrInternal server error", err)
+		}
+	}
+
+	t.Run("sync", func(t *testing.T) {
+		srv := server.New(server.HandlerFunc(func(_ context.Context, req *protocol.InferenceRequest) (*protocol.InferenceResponse, error) {
+			if req.Prompt == "boom" {
+				panic("handler exploded")
+			}
+			return &protocol.InferenceResponse{ID: req.ID, Text: "ok"}, nil
+		}))
+		clientT, serverT := newChannelTransportPair()
+		stop := startServer(t, srv, serverT)
+		defer stop()
+		c, err := client.Dial("unused", client.WithTransport(clientT))
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer c.Close()
+
+		_, err = c.Infer(ctx, &protocol.InferenceRequest{Prompt: "boom", Metadata: map[string]string{}})
+		wantInternal(t, err)
+		resp, err := c.Infer(ctx, &protocol.InferenceRequest{Prompt: "fine", Metadata: map[string]string{}})
+		if err != nil || resp.Text != "ok" {
+			t.Fatalf("request after the panic: resp = %+v, err = %v", resp, err)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		srv := server.New(nil, server.WithStreamHandler(panickyStreamHandler{}))
+		clientT, serverT := newChannelTransportPair()
+		stop := startServer(t, srv, serverT)
+		defer stop()
+		c, err := client.Dial("unused", client.WithTransport(clientT))
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer c.Close()
+
+		ts, err := c.OpenStream(ctx, &protocol.InferenceRequest{Prompt: "one two boom", Metadata: map[string]string{}})
+		if err != nil {
+			t.Fatalf("OpenStream: %v", err)
+		}
+		var tokens []string
+		for chunk := range ts.C {
+			tokens = append(tokens, chunk.Token)
+		}
+		if !slices.Equal(tokens, []string{"one", "two"}) {
+			t.Errorf("tokens before the panic = %q, want one and two", tokens)
+		}
+		wantInternal(t, ts.Err())
+
+		resp, err := c.Infer(ctx, &protocol.InferenceRequest{Prompt: "still serving", Metadata: map[string]string{}})
+		if err != nil || resp.Text != "stillserving" {
+			t.Fatalf("request after the panic: resp = %+v, err = %v", resp, err)
+		}
+	})
+}
+
+// TestStrandAPIInferRetriesStreamingOnlyError verifies that an OpError
+// saying the server only streams makes Infer retry as a flagged stream
+// request.
+func TestStrandAPIInferRetriesStreamingOnlyError(t *testing.T) {
+	srv := server.New(nil)
+	srv.Handle(protocol.OpInferenceRequest, func(ctx context.Context, payload []byte, w server.FrameWriter) {
+		req := &protocol.InferenceRequest{}
+		if err := req.Decode(strandbuf.NewReader(payload)); err != nil {
+			_ = w.Send(ctx, protocol.OpError, []byte(err.Error()))
+			return
+		}
+		if req.Metadata[protocol.InferenceMetaStream] != "true" {
+			em := &protocol.ErrorMessage{Code: protocol.ErrUnsupported, Message: "streaming only"}
+			_ = w.Send(ctx, protocol.OpError, em.Payload())
+			return
+		}
+		_ = w.Send(ctx, protocol.OpTokenStreamStart, nil)
+		for i, tok := range []string{"streamed ", "reply"} {
+			buf := strandbuf.NewBuffer(64)
+			(&protocol.TokenStreamChunk{RequestID: req.ID, SeqNum: uint32(i), Token: tok}).Encode(buf)
+			_ = w.Send(ctx, protocol.OpTokenStreamChunk, buf.Bytes())
+		}
+		_ = w.Send(ctx, protocol.OpTokenStreamEnd, nil)
+	})
+
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &protocol.InferenceRequest{Prompt: "hi", Metadata: map[string]string{"user": "t"}}
+	resp, err := c.Infer(ctx, req)
+	if err != nil {
+		t.Fatalf("Infer: %v", err)
+	}
+	if resp.Text != "streamed reply" || resp.CompletionTokens != 2 {
+		t.Errorf("resp = %+v, want assembled %q", resp, "streamed reply")
+	}
+	if _, ok := req.Metadata[protocol.InferenceMetaStream]; ok {
+		t.Error("Infer modified the caller's request metadata")
+	}
+
+	// Unrelated server errors are still returned, even when their text
+	// mentions streaming; only the code triggers the retry.
+	for _, msg := range []string{"model overloaded", "use streaming mode"} {
+		srv.Handle(protocol.OpInferenceRequest, func(ctx context.Context, _ []byte, w server.FrameWriter) {
+			em := &protocol.ErrorMessage{Code: protocol.ErrInternal, Message: msg}
+			_ = w.Send(ctx, protocol.OpError, em.Payload())
+		})
+		var se *client.ServerError
+		if _, err := c.Infer(ctx, req); !errors.As(err, &se) || se.Code != protocol.ErrInternal || se.Message != msg {
+			t.Errorf("Infer with server error %q: err = %v", msg, err)
+		}
+	}
+}
+
+// peerFrame is a frame tagged with the peer that sent it.
+type peerFrame struct {
+	peer string
+	frame
+}
+
+// peerTransport is a server-side transport.PeerTransport fed from a channel of
+// peer-tagged frames. Replies are discarded.
+type peerTransport struct {
+	in   chan peerFrame
+	done chan struct{}
+	once sync.Once
+}
+
+func newPeerTransport(buffer int) *peerTransport {
+	return &peerTransport{in: make(chan peerFrame, buffer), done: make(chan struct{})}
+}
+
+func (t *peerTransport) Send(context.Context, byte, []byte) error { return nil }
+
+func (t *peerTransport) Recv(ctx context.Context) (byte, []byte, error) {
+	_, opcode, payload, err := t.RecvFrom(ctx)
+	return opcode, payload, err
+}
+
+func (t *peerTransport) RecvFrom(ctx context.Context) (string, byte, []byte, error) {
+	select {
+	case f := <-t.in:
+		return f.peer, f.opcode, f.payload, nil
+	case <-ctx.Done():
+		return "", 0, nil, ctx.Err()
+	case <-t.done:
+		return "", 0, nil, transport.ErrTransportClosed
+	}
+}
+
+func (t *peerTransport) Close() error {
+	t.once.Do(func() { close(t.done) })
+	return nil
+}
+
+func TestStrandAPIPerPeerConcurrency(t *testing.T) {
+	const opWork byte = 0x70
+	const perPeer = 2
+	// Enough frames from one peer to fill the global limit on their own.
+	const flood = 1200
+
+	release := make(chan struct{})
+	victimDone := make(chan struct{})
+	var floodInflight, floodMax atomic.Int32
+
+	srv := server.New(&echoHandler{}, server.WithPerPeerConcurrency(perPeer), server.WithShutdownTimeout(time.Second))
+	srv.Handle(opWork, func(ctx context.Context, payload []byte, _ server.FrameWriter) {
+		if string(payload) == "victim" {
+			close(victimDone)
+			return
+		}
+		n := floodInflight.Add(1)
+		defer floodInflight.Add(-1)
+		for {
+			max := floodMax.Load()
+			if n <= max || floodMax.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+	})
+
+	pt := newPeerTransport(flood + 1)
+	stop := startServer(t, srv, pt)
+	defer stop()
+	defer close(release)
+
+	for i := 0; i < flood; i++ {
+		pt.in <- peerFrame{peer: "10.0.0.1:5000", frame: frame{opcode: opWork, payload: []byte("flood")}}
+	}
+	pt.in <- peerFrame{peer: "10.0.0.2:5000", frame: frame{opcode: opWork, payload: []byte("victim")}}
+
+	select {
+	case <-victimDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("second peer's frame was not admitted while the first peer flooded")
+	}
+	if got := floodMax.Load(); got > perPeer {
+		t.Errorf("flooding peer reached %d in-flight frames, want at most %d", got, perPeer)
+	}
+}
+
+// TestStrandAPICancelIsPerPeer verifies that an OpCancel frame only cancels
+// requests from the peer that sent it, and that a finished request does not
+// unregister another in-flight request with the same peer and ID.
+func TestStrandAPICancelIsPerPeer(t *testing.T) {
+	const peerA, peerB = "10.0.0.1:5000", "10.0.0.2:5000"
+	started := make(chan string, 4)
+	cancelled := make(chan string, 4)
+	quickDone := make(chan struct{})
+	srv := server.New(server.HandlerFunc(func(ctx context.Context, req *protocol.InferenceRequest) (*protocol.InferenceResponse, error) {
+		if req.Prompt == "quick" {
+			defer close(quickDone)
+			return &protocol.InferenceResponse{ID: req.ID}, nil
+		}
+		started <- server.PeerAddr(ctx)
+		select {
+		case <-ctx.Done():
+			cancelled <- server.PeerAddr(ctx)
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil, fmt.Errorf("request from %s was never cancelled", server.PeerAddr(ctx))
+		}
+	}))
+
+	pt := newPeerTransport(8)
+	stop := startServer(t, srv, pt)
+	defer stop()
+
+	// Both peers use the zero request ID.
+	encode := func(msg interface{ Encode(*strandbuf.Buffer) }) []byte {
+		buf := strandbuf.NewBuffer(64)
+		msg.Encode(buf)
+		return buf.Bytes()
+	}
+	infer := func(peer, prompt string) {
+		req := &protocol.InferenceRequest{Prompt: prompt, Metadata: map[string]string{}}
+		pt.in <- peerFrame{peer: peer, frame: frame{opcode: protocol.OpInferenceRequest, payload: encode(req)}}
+	}
+	cancelFrom := func(peer string) {
+		pt.in <- peerFrame{peer: peer, frame: frame{opcode: protocol.OpCancel, payload: encode(&protocol.Cancel{})}}
+	}
+	expect := func(ch <-chan string, want, what string) {
+		t.Helper()
+		select {
+		case got := <-ch:
+			if got != want {
+				t.Fatalf("%s: got peer %s, want %s", what, got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s: timed out waiting for %s", what, want)
+		}
+	}
+
+	infer(peerA, "wait")
+	infer(peerB, "wait")
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(2 * time.Second):
+			t.Fatal("requests did not start")
+		}
+	}
+
+	cancelFrom(peerB)
+	expect(cancelled, peerB, "cancel from B")
+	select {
+	case got := <-cancelled:
+		t.Fatalf("cancel from B also cancelled %s's request", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// A second request from A with the same ID finishes first; A's cancel
+	// must still reach the one left running.
+	infer(peerA, "quick")
+	<-quickDone
+	cancelFrom(peerA)
+	expect(cancelled, peerA, "cancel from A")
+}
+
+// TestStrandAPIOverflowQueue verifies that WithOverflowQueue lets a burst
+// slightly over the server's concurrency limit through instead of dropping
+// the excess.
+func TestStrandAPIOverflowQueue(t *testing.T) {
+	const opWork byte = 0x71
+	// The server runs at most 1000 frame handlers at once.
+	const limit, burst = 1000, 10
+
+	run := func(t *testing.T, opts ...server.ServerOption) (handled int32) {
+		t.Helper()
+		release := make(chan struct{})
+		var inflight, done atomic.Int32
+		srv := server.New(nil, append(opts, server.WithShutdownTimeout(time.Second))...)
+		srv.Handle(opWork, func(context.Context, []byte, server.FrameWriter) {
+			inflight.Add(1)
+			<-release
+			done.Add(1)
+		})
+
+		pt := newPeerTransport(limit + burst)
+		stop := startServer(t, srv, pt)
+		defer stop()
+
+		for i := 0; i < limit+burst; i++ {
+			pt.in <- peerFrame{peer: "10.0.0.1:5000", frame: frame{opcode: opWork}}
+		}
+		// Hold every handler until the whole burst has been received.
+		deadline := time.Now().Add(5 * time.Second)
+		for inflight.Load() < limit || len(pt.in) > 0 {
+			if time.Now().After(deadline) {
+				t.Fatalf("%d handlers started, %d frames unread", inflight.Load(), len(pt.in))
+			}
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+
+		// Wait for every started handler, including queued frames
+		// dispatched after the release, to finish.
+		for settled := 0; settled < 2; {
+			if time.Now().After(deadline) {
+				t.Fatalf("%d of %d started handlers finished", done.Load(), inflight.Load())
+			}
+			time.Sleep(10 * time.Millisecond)
+			if done.Load() == inflight.Load() {
+				settled++
+			} else {
+				settled = 0
+			}
+		}
+		return done.Load()
+	}
+
+	unqueued := run(t)
+	if unqueued >= limit+burst {
+		t.Fatalf("without a queue all %d frames were handled; the burst did not overload the server", unqueued)
+	}
+	if got := run(t, server.WithOverflowQueue(16)); got != limit+burst {
+		t.Errorf("with an overflow queue %d of %d frames were handled (%d without)", got, limit+burst, unqueued)
+	}
+}
+
+// TestStrandAPITokenBatching verifies that WithTokenBatching coalesces
+// streamed tokens into fewer frames while the client still sees every chunk
+// in order with its original SeqNum.
+func TestStrandAPITokenBatching(t *testing.T) {
+	prompt := strings.TrimSpace(strings.Repeat("alpha beta gamma delta ", 10))
+	words := strings.Fields(prompt)
+
+	run := func(t *testing.T, opts ...server.ServerOption) (frames int32, tokens []*protocol.TokenStreamChunk) {
+		t.Helper()
+		opts = append(opts, server.WithStreamHandler(&wordStreamHandler{}))
+		srv := server.New(nil, opts...)
+
+		clientT, serverT := newChannelTransportPair()
+		counting := &countingTransport{Transport: serverT}
+		stop := startServer(t, srv, counting)
+		defer stop()
+
+		c, err := client.Dial("unused", client.WithTransport(clientT))
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer c.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		ts, err := c.OpenStream(ctx, &protocol.InferenceRequest{Prompt: prompt, Metadata: map[string]string{}})
+		if err != nil {
+			t.Fatalf("OpenStream: %v", err)
+		}
+		for chunk := range ts.C {
+			tokens = append(tokens, chunk)
+		}
+		if err := ts.Err(); err != nil {
+			t.Fatalf("stream Err: %v", err)
+		}
+		if s := ts.Summary(); s == nil || s.CompletionTokens != uint32(len(words)) {
+			t.Errorf("Summary = %+v, want %d completion tokens", s, len(words))
+		}
+		frames = counting.sends[protocol.OpTokenStreamChunk].Load() + counting.sends[protocol.OpTokenStreamBatch].Load()
+		return frames, tokens
+	}
+
+	plainFrames, plain := run(t)
+	batchedFrames, batched := run(t, server.WithTokenBatching(8, 50*time.Millisecond))
+
+	if plainFrames != int32(len(words)) {
+		t.Errorf("unbatched stream used %d frames, want %d", plainFrames, len(words))
+	}
+	if batchedFrames >= plainFrames {
+		t.Errorf("batched stream used %d frames, want fewer than %d", batchedFrames, plainFrames)
+	}
+	if len(batched) != len(plain) {
+		t.Fatalf("batched stream delivered %d tokens, want %d", len(batched), len(plain))
+	}
+	for i, chunk := range batched {
+		if chunk.SeqNum != uint32(i) || chunk.Token != plain[i].Token {
+			t.Fatalf("token %d = {%d %q}, want {%d %q}", i, chunk.SeqNum, chunk.Token, i, plain[i].Token)
+		}
+	}
+}
+
+// TestStrandAPIRequestDeadline verifies that a request's DeadlineUnixMs
+// aborts a slow handler and is reported back as DEADLINE_EXCEEDED, for both
+// synchronous and streaming inference.
+func TestStrandAPIRequestDeadline(t *testing.T) {
+	t.Run("sync", func(t *testing.T) {
+		slowHandler := server.HandlerFunc(func(ctx context.Context, req *protocol.InferenceRequest) (*protocol.InferenceResponse, error) {
+			time.Sleep(2 * time.Second)
+			return &protocol.InferenceResponse{ID: req.ID, Text: "too late"}, nil
+		})
+		clientT, serverT := newChannelTransportPair()
+		stop := startServer(t, server.New(slowHandler), serverT)
+		defer stop()
+
+		c, err := client.Dial("unused", client.WithTransport(clientT))
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer c.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		start := time.Now()
+		_, err = c.Infer(ctx, &protocol.InferenceRequest{
+			Prompt:         "deadline test",
+			Metadata:       map[string]string{},
+			DeadlineUnixMs: uint64(time.Now().Add(50 * time.Millisecond).UnixMilli()),
+		})
+		if err == nil || !strings.Contains(err.Error(), "DEADLINE_EXCEEDED") {
+			t.Fatalf("Infer err = %v, want DEADLINE_EXCEEDED", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("server took %v to abort; want well under the 2s handler", elapsed)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		h := &blockingStreamHandler{cancelled: make(chan struct{})}
+		srv := server.New(nil, server.WithStreamHandler(h))
+		clientT, serverT := newChannelTransportPair()
+		stop := startServer(t, srv, serverT)
+		defer stop()
+
+		c, err := client.Dial("unused", client.WithTransport(clientT))
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer c.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		ts, err := c.OpenStream(ctx, &protocol.InferenceRequest{
+			Prompt:         "deadline test",
+			Metadata:       map[string]string{},
+			DeadlineUnixMs: uint64(time.Now().Add(100 * time.Millisecond).UnixMilli()),
+		})
+		if err != nil {
+			t.Fatalf("OpenStream: %v", err)
+		}
+		for range ts.C {
+		}
+		if err := ts.Err(); err == nil || !strings.Contains(err.Error(), "DEADLINE_EXCEEDED") {
+			t.Fatalf("stream Err = %v, want DEADLINE_EXCEEDED", err)
+		}
+		select {
+		case <-h.cancelled:
+		case <-time.After(2 * time.Second):
+			t.Error("stream handler context was not cancelled at the deadline")
+		}
+	})
+}
+
+// TestStrandAPIErrorCodes verifies that each server error path reports its
+// own protocol error code in the OpError frame.
+func TestStrandAPIErrorCodes(t *testing.T) {
+	encode := func(req *protocol.InferenceRequest) []byte {
+		buf := strandbuf.NewBuffer(64)
+		req.Encode(buf)
+		return buf.Bytes()
+	}
+	valid := encode(&protocol.InferenceRequest{Prompt: "hi", Metadata: map[string]string{}})
+
+	// errorCode sends each payload as an inference request and returns the
+	// code of the first OpError frame received.
+	errorCode := func(t *testing.T, srv *server.Server, payloads ...[]byte) uint16 {
+		t.Helper()
+		clientT, serverT := newChannelTransportPair()
+		stop := startServer(t, srv, serverT)
+		defer stop()
+		c, err := client.Dial("unused", client.WithTransport(clientT))
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer c.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for _, p := range payloads {
+			if err := c.RawSend(ctx, protocol.OpInferenceRequest, p); err != nil {
+				t.Fatalf("RawSend: %v", err)
+			}
+		}
+		for {
+			opcode, payload, err := c.RawRecv(ctx)
+			if err != nil {
+				t.Fatalf("RawRecv: %v", err)
+			}
+			if opcode == protocol.OpError {
+				return protocol.ParseErrorMessage(payload).Code
+			}
+		}
+	}
+
+	failing := func(err error) server.Handler {
+		return server.HandlerFunc(func(context.Context, *protocol.InferenceRequest) (*protocol.InferenceResponse, error) {
+			return nil, err
+		})
+	}
+	release := make(chan struct{})
+	defer close(release)
+	blocking := server.HandlerFunc(func(ctx context.Context, req *protocol.InferenceRequest) (*protocol.InferenceResponse, error) {
+		<-release
+		return &protocol.InferenceResponse{ID: req.ID}, nil
+	})
+
+	tests := []struct {
+		name     string
+		srv      *server.Server
+		payloads [][]byte
+		want     uint16
+	}{
+		{"decode failure", server.New(&echoHandler{}), [][]byte{{0x01, 0x02}}, protocol.ErrInvalidRequest},
+		{"oversized frame", server.New(&echoHandler{}, server.WithMaxMessageSize(16)),
+			[][]byte{encode(&protocol.InferenceRequest{Prompt: strings.Repeat("x", 64), Metadata: map[string]string{}})},
+			protocol.ErrInvalidRequest},
+		{"no handler", server.New(nil), [][]byte{valid}, protocol.ErrCapabilities},
+		{"overloaded", server.New(blocking, server.WithPerPeerConcurrency(1)), [][]byte{valid, valid}, protocol.ErrRateLimited},
+		{"deadline", server.New(blocking), [][]byte{encode(&protocol.InferenceRequest{
+			Prompt: "late", Metadata: map[string]string{}, DeadlineUnixMs: uint64(time.Now().Add(20 * time.Millisecond).UnixMilli()),
+		})}, protocol.ErrDeadlineExceeded},
+		{"handler timeout", server.New(failing(fmt.Errorf("backend: %w", context.DeadlineExceeded))), [][]byte{valid}, protocol.ErrTimeout},
+		{"handler error", server.New(failing(fmt.Errorf("model exploded"))), [][]byte{valid}, protocol.ErrInternal},
+		{"handler chosen code", server.New(failing(&protocol.ErrorMessage{Code: protocol.ErrModelUnavail, Message: "warming up"})),
+			[][]byte{valid}, protocol.ErrModelUnavail},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := errorCode(t, tc.srv, tc.payloads...); got != tc.want {
+				t.Errorf("error code = %s, want %s", protocol.ErrCodeNames[got], protocol.ErrCodeNames[tc.want])
+			}
+		})
+	}
+}
+
+// TestStrandAPIMaxPromptBytes verifies that WithMaxPromptBytes rejects longer
+// prompts with ErrInvalidRequest and still serves prompts within the limit.
+func TestStrandAPIMaxPromptBytes(t *testing.T) {
+	const limit = 1024
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, server.New(&echoHandler{}, server.WithMaxPromptBytes(limit)), serverT)
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = c.Infer(ctx, &protocol.InferenceRequest{Prompt: strings.Repeat("x", limit+1), Metadata: map[string]string{}})
+	var srvErr *client.ServerError
+	if !errors.As(err, &srvErr) {
+		t.Fatalf("oversized prompt: err = %v, want *client.ServerError", err)
+	}
+	if srvErr.Code != protocol.ErrInvalidRequest || !strings.Contains(srvErr.Message, "prompt of 1025 bytes exceeds maximum of 1024") {
+		t.Errorf("oversized prompt: ServerError = {%s %q}", protocol.ErrCodeNames[srvErr.Code], srvErr.Message)
+	}
+
+	prompt := strings.Repeat("y", limit)
+	resp, err := c.Infer(ctx, &protocol.InferenceRequest{Prompt: prompt, Metadata: map[string]string{}})
+	if err != nil {
+		t.Fatalf("prompt at the limit: %v", err)
+	}
+	if resp.Text != "echo: "+prompt {
+		t.Errorf("prompt at the limit: response text has %d bytes, want the echoed prompt", len(resp.Text))
+	}
+}
+
+// TestStrandAPIStreamStartEchoesRequestID verifies that the stream start
+// frame carries the ID of the request it answers, and that a client rejects
+// a start frame naming another request.
+func TestStrandAPIStreamStartEchoesRequestID(t *testing.T) {
+	t.Run("server", func(t *testing.T) {
+		srv := server.New(nil, server.WithStreamHandler(&wordStreamHandler{}))
+		clientT, serverT := newChannelTransportPair()
+		stop := startServer(t, srv, serverT)
+		defer stop()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		req := &protocol.InferenceRequest{ID: [16]byte{0xAB, 1, 2, 3, 15: 0xCD}, Prompt: "one two", Metadata: map[string]string{}, Stream: true}
+		buf := strandbuf.NewBuffer(64)
+		req.Encode(buf)
+		if err := clientT.Send(ctx, protocol.OpInferenceRequest, buf.Bytes()); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+		opcode, payload, err := clientT.Recv(ctx)
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		if opcode != protocol.OpTokenStreamStart {
+			t.Fatalf("first frame opcode = %#02x, want OpTokenStreamStart", opcode)
+		}
+		start := &protocol.StreamStart{}
+		if err := start.Decode(strandbuf.NewReader(payload)); err != nil {
+			t.Fatalf("decode stream start: %v", err)
+		}
+		if start.RequestID != req.ID {
+			t.Errorf("stream start RequestID = %x, want %x", start.RequestID, req.ID)
+		}
+	})
+
+	t.Run("client rejects mismatch", func(t *testing.T) {
+		srv := server.New(nil)
+		srv.Handle(protocol.OpInferenceRequest, func(ctx context.Context, payload []byte, w server.FrameWriter) {
+			buf := strandbuf.NewBuffer(16)
+			(&protocol.StreamStart{RequestID: [16]byte{0xFF}}).Encode(buf)
+			_ = w.Send(ctx, protocol.OpTokenStreamStart, buf.Bytes())
+			_ = w.Send(ctx, protocol.OpTokenStreamEnd, nil)
+		})
+		clientT, serverT := newChannelTransportPair()
+		stop := startServer(t, srv, serverT)
+		defer stop()
+
+		c, err := client.Dial("unused", client.WithTransport(clientT))
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer c.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		ts, err := c.OpenStream(ctx, &protocol.InferenceRequest{ID: [16]byte{1}, Prompt: "hi", Metadata: map[string]string{}})
+		if err != nil {
+			t.Fatalf("OpenStream: %v", err)
+		}
+		for range ts.C {
+		}
+		if err := ts.Err(); !errors.Is(err, client.ErrResponseMismatch) {
+			t.Errorf("Err() = %v, want ErrResponseMismatch", err)
+		}
+	})
+}
+
+// TestStrandAPIHeartbeatHealth verifies that heartbeats can carry NodeHealth
+// in both directions and that empty heartbeats keep working.
+func TestStrandAPIHeartbeatHealth(t *testing.T) {
+	t.Run("health payload", func(t *testing.T) {
+		observed := make(chan protocol.NodeHealth, 1)
+		srv := server.New(&echoHandler{},
+			server.WithHealthReporter(func() protocol.NodeHealth {
+				return protocol.NodeHealth{Load: 0.5, ActiveStreams: 4, QueueDepth: 2}
+			}),
+			server.WithHealthObserver(func(_ context.Context, h protocol.NodeHealth) { observed <- h }),
+		)
+		clientT, serverT := newChannelTransportPair()
+		stop := startServer(t, srv, serverT)
+		defer stop()
+
+		c, err := client.Dial("unused", client.WithTransport(clientT))
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer c.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		sent := protocol.NodeHealth{Load: 0.1, ActiveStreams: 1}
+		got, err := c.Heartbeat(ctx, &sent)
+		if err != nil {
+			t.Fatalf("Heartbeat: %v", err)
+		}
+		if want := (protocol.NodeHealth{Load: 0.5, ActiveStreams: 4, QueueDepth: 2}); got == nil || *got != want {
+			t.Errorf("Heartbeat reply = %+v, want %+v", got, want)
+		}
+		select {
+		case h := <-observed:
+			if h != sent {
+				t.Errorf("observed health = %+v, want %+v", h, sent)
+			}
+		case <-ctx.Done():
+			t.Fatal("health observer not called")
+		}
+	})
+
+	t.Run("legacy empty", func(t *testing.T) {
+		var observed atomic.Int32
+		srv := server.New(&echoHandler{}, server.WithHealthObserver(func(context.Context, protocol.NodeHealth) { observed.Add(1) }))
+		clientT, serverT := newChannelTransportPair()
+		stop := startServer(t, srv, serverT)
+		defer stop()
+
+		c, err := client.Dial("unused", client.WithTransport(clientT))
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer c.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		got, err := c.Heartbeat(ctx, nil)
+		if err != nil {
+			t.Fatalf("Heartbeat: %v", err)
+		}
+		if got != nil {
+			t.Errorf("Heartbeat reply = %+v, want nil from a server reporting no health", got)
+		}
+		if err := c.RawSend(ctx, protocol.OpHeartbeat, nil); err != nil {
+			t.Fatalf("RawSend: %v", err)
+		}
+		if opcode, payload, err := c.RawRecv(ctx); err != nil || opcode != protocol.OpHeartbeat || len(payload) != 0 {
+			t.Errorf("raw heartbeat reply = 0x%02x %q %v, want empty heartbeat", opcode, payload, err)
+		}
+		if n := observed.Load(); n != 0 {
+			t.Errorf("observer called %d times for empty heartbeats", n)
+		}
+	})
+}
+
+// TestStrandAPIServerMetrics verifies that Server.Metrics counts each frame
+// under its opcode, along with frames that had no handler.
+func TestStrandAPIServerMetrics(t *testing.T) {
+	srv := server.New(&echoHandler{})
+	clientT, serverT := newChannelTransportPair()
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// An opcode with no handler gets no reply; the frames after it ensure it
+	// has been dispatched by the time the server stops.
+	if err := c.RawSend(ctx, 0x7E, nil); err != nil {
+		t.Fatalf("RawSend: %v", err)
+	}
+	if _, err := c.Hello(ctx); err != nil {
+		t.Fatalf("Hello: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.Infer(ctx, &protocol.InferenceRequest{Prompt: "count me", Metadata: map[string]string{}}); err != nil {
+			t.Fatalf("Infer: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := c.Heartbeat(ctx, nil); err != nil {
+			t.Fatalf("Heartbeat: %v", err)
+		}
+	}
+
+	// Stop drains in-flight handlers, so every frame has been recorded.
+	stop()
+	m := srv.Metrics()
+
+	want := map[byte]uint64{
+		protocol.OpHello:            1,
+		protocol.OpInferenceRequest: 2,
+		protocol.OpHeartbeat:        3,
+	}
+	for op, n := range want {
+		if got := m.Opcodes[op].Count; got != n {
+			t.Errorf("%s count = %d, want %d", protocol.OpcodeNames[op], got, n)
+		}
+	}
+	if len(m.Opcodes) != len(want) {
+		t.Errorf("metrics cover %d opcodes, want %d: %+v", len(m.Opcodes), len(want), m.Opcodes)
+	}
+	if m.Unhandled != 1 {
+		t.Errorf("Unhandled = %d, want 1", m.Unhandled)
+	}
+	infer := m.Opcodes[protocol.OpInferenceRequest]
+	if infer.TotalLatency <= 0 || infer.MaxLatency > infer.TotalLatency || infer.MeanLatency() > infer.MaxLatency {
+		t.Errorf("inconsistent inference latencies: %+v (mean %v)", infer, infer.MeanLatency())
+	}
+}
+
+// TestStrandAPIHandlerSeesPeer verifies that handlers can read the address
+// of the connecting client, and its handshake, from their context.
+func TestStrandAPIHandlerSeesPeer(t *testing.T) {
+	type seen struct {
+		info server.PeerInfo
+		ok   bool
+	}
+	peers := make(chan seen, 1)
+	srv := server.New(server.HandlerFunc(func(ctx context.Context, req *protocol.InferenceRequest) (*protocol.InferenceResponse, error) {
+		info, ok := server.PeerFromContext(ctx)
+		peers <- seen{info, ok}
+		return &protocol.InferenceResponse{ID: req.ID, Text: server.PeerAddr(ctx)}, nil
+	}), server.WithMaxMessageSize(8192))
+
+	serverT, err := transport.ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	clientT, err := transport.DialOverlay(serverT.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("DialOverlay: %v", err)
+	}
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := c.Hello(ctx); err != nil {
+		t.Fatalf("Hello: %v", err)
+	}
+	resp, err := c.Infer(ctx, &protocol.InferenceRequest{Prompt: "who am I", Metadata: map[string]string{}})
+	if err != nil {
+		t.Fatalf("Infer: %v", err)
+	}
+
+	want := clientT.LocalAddr().String()
+	got := <-peers
+	if !got.ok {
+		t.Fatal("handler context carries no PeerInfo")
+	}
+	if got.info.Addr != want || resp.Text != want {
+		t.Errorf("peer address = %q (reply %q), want %q", got.info.Addr, resp.Text, want)
+	}
+	if got.info.Hello == nil || got.info.Hello.MaxMessageSize != 8192 {
+		t.Errorf("PeerInfo.Hello = %+v, want MaxMessageSize 8192", got.info.Hello)
+	}
+	if got.info.SessionID != 0 {
+		t.Errorf("SessionID = %d, want 0 for a frame without a stream", got.info.SessionID)
+	}
+}
+
+// TestStrandAPIStrictOpcodes verifies that WithStrictOpcodes answers an
+// unregistered opcode with an ErrUnsupported OpError, while the default
+// server stays silent.
+func TestStrandAPIStrictOpcodes(t *testing.T) {
+	const unknown = 0x7E
+
+	t.Run("strict", func(t *testing.T) {
+		srv := server.New(&echoHandler{}, server.WithStrictOpcodes())
+		clientT, serverT := newChannelTransportPair()
+		stop := startServer(t, srv, serverT)
+		defer stop()
+
+		c, err := client.Dial("unused", client.WithTransport(clientT))
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer c.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := c.RawSend(ctx, unknown, []byte("?")); err != nil {
+			t.Fatalf("RawSend: %v", err)
+		}
+		opcode, payload, err := c.RawRecv(ctx)
+		if err != nil {
+			t.Fatalf("RawRecv: %v", err)
+		}
+		if opcode != protocol.OpError {
+			t.Fatalf("reply opcode 0x%02x, want OpError", opcode)
+		}
+		em := protocol.ParseErrorMessage(payload)
+		if em.Code != protocol.ErrUnsupported || !strings.Contains(em.Message, "0x7e") {
+			t.Errorf("error = {%s %q}, want UNSUPPORTED naming 0x7e", protocol.ErrCodeNames[em.Code], em.Message)
+		}
+	})
+
+	t.Run("lenient default", func(t *testing.T) {
+		srv := server.New(&echoHandler{})
+		clientT, serverT := newChannelTransportPair()
+		stop := startServer(t, srv, serverT)
+		defer stop()
+
+		c, err := client.Dial("unused", client.WithTransport(clientT))
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer c.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := c.RawSend(ctx, unknown, []byte("?")); err != nil {
+			t.Fatalf("RawSend: %v", err)
+		}
+		// The next reply belongs to the heartbeat, not the unknown frame.
+		if _, err := c.Heartbeat(ctx, nil); err != nil {
+			t.Fatalf("Heartbeat after unknown opcode: %v", err)
+		}
+	})
+}
+
+// revisingStreamHandler streams words while sending a PartialResponse
+// snapshot after each one, with a correction in the middle.
+type revisingStreamHandler struct{}
+
+func (revisingStreamHandler) HandleTokenStream(ctx context.Context, req *protocol.InferenceRequest, sender server.TokenSender) error {
+	ps, ok := sender.(server.PartialSender)
+	if !ok {
+		return errors.New("sender does not support partial responses")
+	}
+	snapshots := []string{"the cat", "the cap", "the cap fits"}
+	for i, text := range snapshots {
+		if err := sender.Send(&protocol.TokenStreamChunk{RequestID: req.ID, SeqNum: uint32(i), Token: text}); err != nil {
+			return err
+		}
+		if err := ps.SendPartial(&protocol.PartialResponse{RequestID: req.ID, Revision: uint32(i + 1), Text: text}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TestStrandAPIPartialResponses verifies that PartialResponse revisions reach
+// the client's TokenStream alongside the token chunks, with and without
+// token batching.
+func TestStrandAPIPartialResponses(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []server.ServerOption
+	}{
+		{"unbatched", nil},
+		{"batched", []server.ServerOption{server.WithTokenBatching(8, 20*time.Millisecond)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := server.New(nil, append(tc.opts, server.WithStreamHandler(revisingStreamHandler{}))...)
+			clientT, serverT := newChannelTransportPair()
+			stop := startServer(t, srv, serverT)
+			defer stop()
+
+			c, err := client.Dial("unused", client.WithTransport(clientT))
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer c.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			req := &protocol.InferenceRequest{Prompt: "fit", Metadata: map[string]string{}}
+			ts, err := c.OpenStream(ctx, req)
+			if err != nil {
+				t.Fatalf("OpenStream: %v", err)
+			}
+
+			var revisions []uint32
+			chunks := 0
+			for c, p := ts.C, ts.Partials; c != nil || p != nil; {
+				select {
+				case _, ok := <-c:
+					if !ok {
+						c = nil
+						continue
+					}
+					chunks++
+				case partial, ok := <-p:
+					if !ok {
+						p = nil
+						continue
+					}
+					revisions = append(revisions, partial.Revision)
+				}
+			}
+			if err := ts.Err(); err != nil {
+				t.Fatalf("stream Err: %v", err)
+			}
+			if chunks != 3 {
+				t.Errorf("got %d chunks, want 3", chunks)
+			}
+			if len(revisions) == 0 || revisions[len(revisions)-1] != 3 {
+				t.Errorf("revisions received = %v, want them to end with 3", revisions)
+			}
+			for i := 1; i < len(revisions); i++ {
+				if revisions[i] <= revisions[i-1] {
+					t.Errorf("revisions out of order: %v", revisions)
+				}
+			}
+			final := ts.Partial()
+			if final == nil || final.Revision != 3 || final.Text != "the cap fits" || final.RequestID != req.ID {
+				t.Errorf("final partial = %+v", final)
+			}
+		})
+	}
+}
+
+// TestStrandAPICapabilitiesQuery verifies that a node returns the SADs it was
+// configured to serve, decodable by the client, and an empty list otherwise.
+func TestStrandAPICapabilitiesQuery(t *testing.T) {
+	served := []*sad.SAD{
+		{ModelType: "llm", Capabilities: sad.TextGen | sad.CodeGen, ContextWindow: 32768, LatencySLA: 200, Version: 1},
+		{ModelType: "embedding", Capabilities: sad.Embedding, ContextWindow: 8192, Version: 1},
+	}
+	for _, tc := range []struct {
+		name string
+		opts []server.ServerOption
+		want []*sad.SAD
+	}{
+		{"served", []server.ServerOption{server.WithServedSADs(served)}, served},
+		{"none", nil, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := server.New(&echoHandler{}, tc.opts...)
+			clientT, serverT := newChannelTransportPair()
+			stop := startServer(t, srv, serverT)
+			defer stop()
+
+			c, err := client.Dial("unused", client.WithTransport(clientT))
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer c.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			got, err := c.QueryCapabilities(ctx)
+			if err != nil {
+				t.Fatalf("QueryCapabilities: %v", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("QueryCapabilities returned %d SADs, want %d", len(got), len(tc.want))
+			}
+			for i := range tc.want {
+				if *got[i] != *tc.want[i] {
+					t.Errorf("SAD %d = %+v, want %+v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestStrandAPIServerSurvivesTruncatedDatagram verifies that a datagram too
+// large for the server's read buffer is dropped and counted, and the server
+// keeps answering.
+func TestStrandAPIServerSurvivesTruncatedDatagram(t *testing.T) {
+	serverT, err := transport.ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	serverT.SetReadBufferSize(512)
+	srv := server.New(&echoHandler{})
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	flooder, err := transport.DialOverlay(serverT.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("DialOverlay: %v", err)
+	}
+	defer flooder.Close()
+	if err := flooder.Send(ctx, protocol.OpInferenceRequest, make([]byte, 2000)); err != nil {
+		t.Fatalf("Send oversized: %v", err)
+	}
+
+	c, err := client.Dial(serverT.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+	resp, err := c.Infer(ctx, &protocol.InferenceRequest{Prompt: "still there", Metadata: map[string]string{}})
+	if err != nil {
+		t.Fatalf("Infer after oversized datagram: %v", err)
+	}
+	if resp.Text != "echo: still there" {
+		t.Errorf("Infer: got %q", resp.Text)
+	}
+
+	st, _ := srv.Stats()
+	stop()
+	if m := srv.Metrics(); m.Dropped != 1 || st.FramesDropped != 1 {
+		t.Errorf("Metrics.Dropped = %d, FramesDropped = %d; want 1 and 1", m.Dropped, st.FramesDropped)
+	}
+}
+
+func TestStrandAPIServerSurvivesRejectedDatagrams(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	serverT, err := transport.ListenOverlay("127.0.0.1:0", transport.WithFrameVerification(pub))
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	addr := serverT.LocalAddr().String()
+	srv := server.New(&echoHandler{})
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// A datagram that is not an overlay frame at all...
+	raw, err := net.Dial("udp", addr)
+	if err != nil {
+		t.Fatalf("net.Dial: %v", err)
+	}
+	defer raw.Close()
+	if _, err := raw.Write([]byte("not a strand frame")); err != nil {
+		t.Fatalf("write garbage: %v", err)
+	}
+	// ...and a well-formed but unsigned one.
+	unsigned, err := transport.DialOverlay(addr)
+	if err != nil {
+		t.Fatalf("DialOverlay: %v", err)
+	}
+	defer unsigned.Close()
+	if err := unsigned.Send(ctx, protocol.OpInferenceRequest, []byte("forged")); err != nil {
+		t.Fatalf("Send unsigned: %v", err)
+	}
+
+	// Neither stops the server nor claims its replies.
+	clientT, err := transport.DialOverlay(addr, transport.WithFrameSigning(priv))
+	if err != nil {
+		t.Fatalf("DialOverlay signed: %v", err)
+	}
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+	resp, err := c.Infer(ctx, &protocol.InferenceRequest{Prompt: "still there", Metadata: map[string]string{}})
+	if err != nil {
+		t.Fatalf("Infer after rejected datagrams: %v", err)
+	}
+	if resp.Text != "echo: still there" {
+		t.Errorf("Infer: got %q", resp.Text)
+	}
+
+	st, _ := srv.Stats()
+	stop()
+	if m := srv.Metrics(); m.Dropped != 2 || st.FramesDropped != 2 {
+		t.Errorf("Metrics.Dropped = %d, FramesDropped = %d; want 2 and 2", m.Dropped, st.FramesDropped)
+	}
+}
+
+func TestStrandAPIServerRepliesToEachPeer(t *testing.T) {
+	serverT, err := transport.ListenOverlay("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenOverlay: %v", err)
+	}
+	// Hold each request until both have arrived, so both peers are in flight
+	// when the replies go out.
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	srv := server.New(server.HandlerFunc(func(ctx context.Context, req *protocol.InferenceRequest) (*protocol.InferenceResponse, error) {
+		arrived.Done()
+		arrived.Wait()
+		if req.Prompt == "fail" {
+			return nil, errors.New("refused")
+		}
+		return &protocol.InferenceResponse{ID: req.ID, Text: "echo: " + req.Prompt}, nil
+	}))
+	stop := startServer(t, srv, serverT)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dial := func() *client.Client {
+		c, err := client.Dial(serverT.LocalAddr().String())
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		return c
+	}
+	first, second := dial(), dial()
+	defer first.Close()
+	defer second.Close()
+
+	var (
+		wg      sync.WaitGroup
+		resp    *protocol.InferenceResponse
+		respErr error
+		failErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		resp, respErr = first.Infer(ctx, &protocol.InferenceRequest{Prompt: "first", Metadata: map[string]string{}})
+	}()
+	go func() {
+		defer wg.Done()
+		_, failErr = second.Infer(ctx, &protocol.InferenceRequest{Prompt: "fail", Metadata: map[string]string{}})
+	}()
+	wg.Wait()
+
+	if respErr != nil {
+		t.Fatalf("first peer: %v", respErr)
+	}
+	if resp.Text != "echo: first" {
+		t.Errorf("first peer got %q, want %q", resp.Text, "echo: first")
+	}
+	var se *client.ServerError
+	if !errors.As(failErr, &se) || se.Code != protocol.ErrInternal {
+		t.Errorf("second peer: err = %v, want ServerError with ErrInternal", failErr)
+	}
+}
+
+// TestStrandAPIServerStopDuringTraffic stops the server while frames are
+// still being dispatched; run with -race it checks that Stop's drain does
+// not race with the dispatch of late frames.
+func TestStrandAPIServerStopDuringTraffic(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		clientT, serverT := newChannelTransportPair()
+		srv := server.New(&echoHandler{}, server.WithShutdownTimeout(time.Second))
+		stop := startServer(t, srv, serverT)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		sent := make(chan struct{})
+		go func() {
+			defer close(sent)
+			for ctx.Err() == nil {
+				if clientT.Send(ctx, protocol.OpHeartbeat, nil) != nil {
+					return
+				}
+			}
+		}()
+		go func() {
+			for ctx.Err() == nil {
+				if _, _, err := clientT.Recv(ctx); err != nil {
+					return
+				}
+			}
+		}()
+		time.Sleep(time.Millisecond)
+		stop()
+		cancel()
+		<-sent
+		clientT.Close()
+	}
+}
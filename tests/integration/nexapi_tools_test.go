@@ -0,0 +1,193 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/strand-protocol/strand/strandapi/pkg/client"
+	"github.com/strand-protocol/strand/strandapi/pkg/protocol"
+	"github.com/strand-protocol/strand/strandapi/pkg/strandbuf"
+)
+
+// runToolModel plays the model side of the tool_use example on serverT: it
+// streams some text, invokes the calculator tool, waits for the ToolResult
+// and finishes the response using the tool output, ending the stream with a
+// summary.
+func runToolModel(t *testing.T, serverT *channelTransport) <-chan error {
+	t.Helper()
+	errCh := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		errCh <- func() error {
+			opcode, payload, err := serverT.Recv(ctx)
+			if err != nil {
+				return err
+			}
+			if opcode != protocol.OpInferenceRequest {
+				return fmt.Errorf("unexpected opcode 0x%02x", opcode)
+			}
+			req := &protocol.InferenceRequest{}
+			if err := req.Decode(strandbuf.NewReader(payload)); err != nil {
+				return err
+			}
+			if !req.Stream {
+				return fmt.Errorf("tool request not marked Stream")
+			}
+
+			send := func(op byte, msg interface{ Encode(*strandbuf.Buffer) }) error {
+				buf := strandbuf.NewBuffer(128)
+				msg.Encode(buf)
+				return serverT.Send(ctx, op, buf.Bytes())
+			}
+			token := func(seq uint32, text string) error {
+				return send(protocol.OpTokenStreamChunk, &protocol.TokenStreamChunk{RequestID: req.ID, SeqNum: seq, Token: text})
+			}
+
+			if err := serverT.Send(ctx, protocol.OpTokenStreamStart, nil); err != nil {
+				return err
+			}
+			if err := token(0, "Let me calculate that for you. "); err != nil {
+				return err
+			}
+			if err := send(protocol.OpToolInvoke, &protocol.ToolInvoke{
+				RequestID: req.ID,
+				ToolName:  "calculator",
+				Arguments: []byte(`{"expression": "42 * 137"}`),
+			}); err != nil {
+				return err
+			}
+
+			opcode, payload, err = serverT.Recv(ctx)
+			if err != nil {
+				return err
+			}
+			if opcode != protocol.OpToolResult {
+				return fmt.Errorf("expected tool result, got opcode 0x%02x", opcode)
+			}
+			result := &protocol.ToolResult{}
+			if err := result.Decode(strandbuf.NewReader(payload)); err != nil {
+				return err
+			}
+			if result.ErrorCode != protocol.ErrOK {
+				return fmt.Errorf("tool result error code 0x%04x", result.ErrorCode)
+			}
+
+			if err := token(1, "The result is "); err != nil {
+				return err
+			}
+			if err := token(2, string(result.ResultPayload)); err != nil {
+				return err
+			}
+			if err := token(3, "."); err != nil {
+				return err
+			}
+			return send(protocol.OpTokenStreamEnd, &protocol.StreamSummary{
+				PromptTokens:     9,
+				CompletionTokens: 12,
+				FinishReason:     "length",
+			})
+		}()
+	}()
+	return errCh
+}
+
+// calculatorTool evaluates "A * B" expressions like the tool_use example.
+func calculatorTool(_ context.Context, args []byte) ([]byte, error) {
+	var in struct {
+		Expression string `json:"expression"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return nil, err
+	}
+	parts := strings.Split(in.Expression, "*")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("unsupported expression %q", in.Expression)
+	}
+	a, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return nil, err
+	}
+	b, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strconv.Itoa(a * b)), nil
+}
+
+// TestInferWithToolsCalculator drives the tool_use scenario through
+// client.InferWithTools and checks the tool output lands in the response
+// along with the usage and finish reason from the stream summary.
+func TestInferWithToolsCalculator(t *testing.T) {
+	clientT, serverT := newChannelTransportPair()
+	defer serverT.Close()
+	modelErr := runToolModel(t, serverT)
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &protocol.InferenceRequest{
+		ID:       [16]byte{0xDE, 0xAD},
+		Prompt:   "What is 42 times 137?",
+		Metadata: map[string]string{},
+	}
+	resp, err := c.InferWithTools(ctx, req, map[string]client.ToolFunc{
+		"calculator": calculatorTool,
+	})
+	if err != nil {
+		t.Fatalf("InferWithTools: %v", err)
+	}
+	if err := <-modelErr; err != nil {
+		t.Fatalf("model side: %v", err)
+	}
+
+	want := "Let me calculate that for you. The result is 5754."
+	if resp.Text != want {
+		t.Errorf("Text = %q, want %q", resp.Text, want)
+	}
+	if resp.ID != req.ID {
+		t.Errorf("ID mismatch: got %v, want %v", resp.ID, req.ID)
+	}
+	if resp.PromptTokens != 9 || resp.CompletionTokens != 12 {
+		t.Errorf("usage = %d/%d, want 9/12", resp.PromptTokens, resp.CompletionTokens)
+	}
+	if resp.FinishReason != "length" {
+		t.Errorf("FinishReason = %q, want %q", resp.FinishReason, "length")
+	}
+}
+
+// TestInferWithToolsUnknownTool verifies that an invocation of an
+// unregistered tool is answered with ErrNotFound rather than hanging.
+func TestInferWithToolsUnknownTool(t *testing.T) {
+	clientT, serverT := newChannelTransportPair()
+	defer serverT.Close()
+	modelErr := runToolModel(t, serverT)
+
+	c, err := client.Dial("unused", client.WithTransport(clientT))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &protocol.InferenceRequest{Prompt: "What is 42 times 137?", Metadata: map[string]string{}}
+	go func() { _, _ = c.InferWithTools(ctx, req, nil) }()
+
+	err = <-modelErr
+	if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("0x%04x", protocol.ErrNotFound)) {
+		t.Errorf("model side error = %v, want tool result error code ErrNotFound", err)
+	}
+}